@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ApiKeyApi AI访问密钥管理接口
+type ApiKeyApi struct{}
+
+// CreateApiKey 创建访问密钥
+// @Tags AiApiKey
+// @Summary 创建访问密钥，密钥明文只在创建时返回一次
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.CreateApiKeyReq true "密钥信息"
+// @Success 200 {object} response.Response{data=aiRes.CreateApiKeyRes,msg=string} "创建成功"
+// @Router /aiApiKey/createApiKey [post]
+func (api *ApiKeyApi) CreateApiKey(c *gin.Context) {
+	var req aiReq.CreateApiKeyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	res, err := apiKeyService.CreateApiKey(req)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "创建成功", c)
+}
+
+// UpdateApiKey 更新访问密钥
+// @Tags AiApiKey
+// @Summary 更新访问密钥的名称、有效期、模型范围和IP白名单
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.UpdateApiKeyReq true "密钥信息"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /aiApiKey/updateApiKey [put]
+func (api *ApiKeyApi) UpdateApiKey(c *gin.Context) {
+	var req aiReq.UpdateApiKeyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := apiKeyService.UpdateApiKey(req); err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// RevokeApiKey 吊销访问密钥
+// @Tags AiApiKey
+// @Summary 吊销访问密钥，吊销后立即失效
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.ApiKeyIdReq true "密钥ID"
+// @Success 200 {object} response.Response{msg=string} "吊销成功"
+// @Router /aiApiKey/revokeApiKey [post]
+func (api *ApiKeyApi) RevokeApiKey(c *gin.Context) {
+	var req aiReq.ApiKeyIdReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := apiKeyService.RevokeApiKey(req.ID); err != nil {
+		global.GVA_LOG.Error("吊销失败!", zap.Error(err))
+		response.FailWithMessage("吊销失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("吊销成功", c)
+}
+
+// DeleteApiKey 删除访问密钥
+// @Tags AiApiKey
+// @Summary 删除访问密钥
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.ApiKeyIdReq true "密钥ID"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /aiApiKey/deleteApiKey [delete]
+func (api *ApiKeyApi) DeleteApiKey(c *gin.Context) {
+	var req aiReq.ApiKeyIdReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := apiKeyService.DeleteApiKey(req.ID); err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// GetApiKeyList 分页获取访问密钥列表
+// @Tags AiApiKey
+// @Summary 分页获取访问密钥列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query aiReq.ApiKeySearch true "分页获取访问密钥列表"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /aiApiKey/getApiKeyList [get]
+func (api *ApiKeyApi) GetApiKeyList(c *gin.Context) {
+	var pageInfo aiReq.ApiKeySearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	list, total, err := apiKeyService.GetApiKeyList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
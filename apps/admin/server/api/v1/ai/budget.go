@@ -0,0 +1,166 @@
+package ai
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BudgetApi AI预算管理接口
+type BudgetApi struct{}
+
+// CreateBudget 创建预算
+// @Tags AiBudget
+// @Summary 为用户、角色或访问密钥创建每天或每月的Token和费用预算
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.CreateBudgetReq true "预算信息"
+// @Success 200 {object} response.Response{data=ai.AiBudget,msg=string} "创建成功"
+// @Router /aiBudget/createBudget [post]
+func (api *BudgetApi) CreateBudget(c *gin.Context) {
+	var req aiReq.CreateBudgetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	budget, err := budgetService.CreateBudget(req)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(budget, "创建成功", c)
+}
+
+// UpdateBudget 更新预算
+// @Tags AiBudget
+// @Summary 更新预算的上限、预警设置和启用状态
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.UpdateBudgetReq true "预算信息"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /aiBudget/updateBudget [put]
+func (api *BudgetApi) UpdateBudget(c *gin.Context) {
+	var req aiReq.UpdateBudgetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := budgetService.UpdateBudget(req); err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteBudget 删除预算
+// @Tags AiBudget
+// @Summary 删除预算及其用量和临时额度
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.BudgetIdReq true "预算ID"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /aiBudget/deleteBudget [delete]
+func (api *BudgetApi) DeleteBudget(c *gin.Context) {
+	var req aiReq.BudgetIdReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := budgetService.DeleteBudget(req.ID); err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// TopUpBudget 临时增加预算额度
+// @Tags AiBudget
+// @Summary 临时增加预算的Token或费用上限，到期后自动失效
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.TopUpBudgetReq true "临时额度"
+// @Success 200 {object} response.Response{data=ai.AiBudgetTopUp,msg=string} "增加成功"
+// @Router /aiBudget/topUpBudget [post]
+func (api *BudgetApi) TopUpBudget(c *gin.Context) {
+	var req aiReq.TopUpBudgetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	topUp, err := budgetService.TopUpBudget(req, utils.GetUserID(c))
+	if err != nil {
+		global.GVA_LOG.Error("增加额度失败!", zap.Error(err))
+		response.FailWithMessage("增加额度失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(topUp, "增加成功", c)
+}
+
+// GetBudgetList 分页获取预算列表
+// @Tags AiBudget
+// @Summary 分页获取预算列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query aiReq.BudgetSearch true "分页获取预算列表"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /aiBudget/getBudgetList [get]
+func (api *BudgetApi) GetBudgetList(c *gin.Context) {
+	var pageInfo aiReq.BudgetSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	list, total, err := budgetService.GetBudgetList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// GetBudgetRemaining 获取预算剩余额度
+// @Tags AiBudget
+// @Summary 获取预算在当前周期的用量、上限(含临时额度)和剩余额度
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query aiReq.BudgetIdReq true "预算ID"
+// @Success 200 {object} response.Response{data=aiRes.BudgetRemainingRes,msg=string} "获取成功"
+// @Router /aiBudget/getBudgetRemaining [get]
+func (api *BudgetApi) GetBudgetRemaining(c *gin.Context) {
+	var req aiReq.BudgetIdReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	res, err := budgetService.GetBudgetRemaining(c.Request.Context(), req.ID)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
@@ -1,15 +1,37 @@
 package ai
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gaia-x/server/service/llmadapter"
 	"net/http"
+	"strings"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
-	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	aiService "github.com/flipped-aurora/gin-vue-admin/server/service/ai"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
+// requestIDHeader 请求ID的请求头，客户端未传时由服务端生成，并在响应头中返回
+const requestIDHeader = "X-Request-Id"
+
+// streamHeaders 流式响应的响应头，开始输出前失败时删除
+var streamHeaders = [][2]string{
+	{"Content-Type", "text/event-stream"},
+	{"Cache-Control", "no-cache"},
+	{"Connection", "keep-alive"},
+	{"Transfer-Encoding", "chunked"},
+	// 禁止Nginx等反向代理缓冲，保证每个分片及时送达
+	{"X-Accel-Buffering", "no"},
+}
+
 type ChatApi struct{}
 
 // CreateChatCompletion 创建聊天完成
@@ -19,34 +41,50 @@ type ChatApi struct{}
 // @accept application/json
 // @Produce application/json,text/event-stream
 // @Param data body ai.ChatRequest true "聊天请求参数，stream=true时为流式响应"
-// @Success 200 {object} response.Response{data=ai.ChatResponse} "非流式聊天响应"
+// @Success 200 {object} ai.ChatResponse "非流式聊天响应(OpenAI chat.completion 格式)"
 // @Success 200 {object} ai.StreamResponse "流式聊天响应"
 // @Router /v1/chat/completion [post]
 func (api *ChatApi) CreateChatCompletion(c *gin.Context) {
 	var req llmadapter.ChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.FailWithMessage("参数解析失败: "+err.Error(), c)
+		c.JSON(http.StatusBadRequest, ai.NewErrorResponse("参数解析失败: "+err.Error(), "invalid_request_error", "invalid_request"))
 		return
 	}
 
+	caller := chatCaller(c)
+	c.Header(requestIDHeader, caller.RequestID)
+
 	// 如果是流式响应
 	if req.Stream {
 		// 设置流式响应头
-		c.Header("Content-Type", "text/event-stream")
-		c.Header("Cache-Control", "no-cache")
-		c.Header("Connection", "keep-alive")
-		c.Header("Transfer-Encoding", "chunked")
+		for _, header := range streamHeaders {
+			c.Header(header[0], header[1])
+		}
 
-		// 刷新缓冲区，确保头信息被发送
-		c.Writer.Flush()
+		// 确定供应商后设置参数告警头，头信息随第一个分片发送，
+		// 在此之前失败(如所有供应商都不可用)时仍可以返回错误状态码
+		ctx := llmadapter.WithUnsupportedParamsHandler(c.Request.Context(), func(params []string) {
+			setParamsWarning(c, params)
+		})
 
-		// 调用服务
-		_, err := chatService.CreateChatCompletion(req, c.Writer)
+		// 调用服务，使用请求上下文以便客户端断开时取消上游调用
+		_, err := chatService.CreateChatCompletion(ctx, caller, req, c.Writer)
+		if errors.Is(err, context.Canceled) {
+			global.GVA_LOG.Info("客户端已断开，停止流式聊天完成")
+			return
+		}
 		if err != nil {
 			global.GVA_LOG.Error("创建流式聊天完成失败", zap.Error(err))
-			// 由于已经开始流式响应，无法使用标准响应格式
-			// 这里直接写入错误信息
-			c.Writer.Write([]byte("错误: " + err.Error()))
+			// 还没有输出任何内容时与非流式请求一样返回JSON格式的错误和对应的状态码
+			if !c.Writer.Written() {
+				for _, header := range streamHeaders {
+					c.Writer.Header().Del(header[0])
+				}
+				c.JSON(chatErrorResponse(err))
+				return
+			}
+			// 已经开始流式响应，无法再返回错误状态码，按OpenAI的格式写入错误事件后结束
+			writeStreamError(c, err)
 			return
 		}
 
@@ -55,13 +93,106 @@ func (api *ChatApi) CreateChatCompletion(c *gin.Context) {
 		return
 	}
 
-	// 非流式响应
-	resp, err := chatService.CreateChatCompletion(req, nil)
+	// 非流式响应，直接返回OpenAI格式的 chat.completion 对象，不使用统一的响应结构
+	ctx := llmadapter.WithUnsupportedParamsHandler(c.Request.Context(), func(params []string) {
+		setParamsWarning(c, params)
+	})
+	resp, err := chatService.CreateChatCompletion(ctx, caller, req, nil)
+	if errors.Is(err, context.Canceled) {
+		global.GVA_LOG.Info("客户端已断开，停止聊天完成")
+		return
+	}
 	if err != nil {
 		global.GVA_LOG.Error("创建聊天完成失败", zap.Error(err))
-		response.FailWithMessage("创建聊天完成失败: "+err.Error(), c)
+		c.JSON(chatErrorResponse(err))
 		return
 	}
 
-	response.OkWithData(resp, c)
+	c.JSON(http.StatusOK, resp)
+}
+
+// setParamsWarning 供应商不支持而被忽略的请求参数通过 Warning 响应头(RFC 7234，告警码299)告知客户端
+func setParamsWarning(c *gin.Context, params []string) {
+	if len(params) == 0 {
+		return
+	}
+	c.Header("Warning", fmt.Sprintf("299 - %q", "unsupported parameters ignored: "+strings.Join(params, ", ")))
+}
+
+// chatErrorResponse 将聊天失败的原因转换为HTTP状态码和OpenAI格式的错误响应
+// 只有请求本身的问题返回400：请求参数校验失败(llmadapter.ErrInvalidRequest)、供应商不存在、上游返回的4xx请求错误和上下文超限；
+// 限流、没有可用凭证和预算已用完返回429，访问密钥无权调用路由后的模型时返回403，
+// 已分类的上游错误和模型输出修正后仍不符合 json_schema 返回502，
+// 其他错误(如凭证解密失败、配置错误、无法识别的错误)返回500，错误详情只记录在日志中
+func chatErrorResponse(err error) (int, ai.ErrorResponse) {
+	if errors.Is(err, aiService.ErrInsufficientQuota) {
+		return http.StatusTooManyRequests, ai.NewErrorResponse(err.Error(), "insufficient_quota", "insufficient_quota")
+	}
+	if errors.Is(err, llmadapter.ErrModelNotAllowed) {
+		return http.StatusForbidden, ai.NewErrorResponse(err.Error(), "invalid_request_error", "model_not_allowed")
+	}
+	if errors.Is(err, llmadapter.ErrInvalidRequest) || errors.Is(err, llmadapter.ErrUnsupportedProvider) {
+		return http.StatusBadRequest, ai.NewErrorResponse(err.Error(), "invalid_request_error", "invalid_request")
+	}
+	var structuredErr *llmadapter.StructuredOutputError
+	if errors.As(err, &structuredErr) {
+		return http.StatusBadGateway, ai.NewErrorResponse(err.Error(), "upstream_error", "json_schema_validation_failed")
+	}
+
+	var upstreamErr *llmadapter.UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		if errors.Is(err, llmadapter.ErrNoCapacity) {
+			return http.StatusTooManyRequests, ai.NewErrorResponse(err.Error(), "rate_limit_error", "no_capacity")
+		}
+		return internalErrorResponse()
+	}
+
+	switch upstreamErr.Kind {
+	case llmadapter.ErrorKindRateLimit:
+		return http.StatusTooManyRequests, ai.NewErrorResponse(err.Error(), "rate_limit_error", "rate_limit_exceeded")
+	case llmadapter.ErrorKindNoCapacity:
+		return http.StatusTooManyRequests, ai.NewErrorResponse(err.Error(), "rate_limit_error", "no_capacity")
+	case llmadapter.ErrorKindContextLength:
+		return http.StatusBadRequest, ai.NewErrorResponse(err.Error(), "invalid_request_error", "context_length_exceeded")
+	case llmadapter.ErrorKindInvalidRequest:
+		return http.StatusBadRequest, ai.NewErrorResponse(err.Error(), "invalid_request_error", "invalid_request")
+	case llmadapter.ErrorKindUnknown:
+		return internalErrorResponse()
+	default:
+		return http.StatusBadGateway, ai.NewErrorResponse(err.Error(), "upstream_error", upstreamErr.Kind.String())
+	}
+}
+
+// internalErrorResponse 服务端内部错误，错误信息可能包含配置或凭证的细节，不返回给客户端
+func internalErrorResponse() (int, ai.ErrorResponse) {
+	return http.StatusInternalServerError, ai.NewErrorResponse("服务内部错误，请稍后重试或联系管理员", "server_error", "server_error")
+}
+
+// writeStreamError 在流式响应中写入 data: {"error":{...}} 错误事件和 [DONE] 结束标记
+func writeStreamError(c *gin.Context, err error) {
+	_, errResp := chatErrorResponse(err)
+	data, marshalErr := json.Marshal(errResp)
+	if marshalErr != nil {
+		global.GVA_LOG.Error("序列化流式错误事件失败", zap.Error(marshalErr))
+		return
+	}
+	_, _ = c.Writer.Write([]byte("data: " + string(data) + "\n\ndata: [DONE]\n\n"))
+	c.Writer.Flush()
+}
+
+// chatCaller 获取当前请求的调用方，请求ID优先使用客户端传入的 X-Request-Id
+func chatCaller(c *gin.Context) aiReq.ChatCaller {
+	caller := aiReq.ChatCaller{
+		RequestID:   c.GetHeader(requestIDHeader),
+		UserID:      utils.GetUserID(c),
+		AuthorityId: utils.GetUserAuthorityId(c),
+	}
+	if caller.RequestID == "" || len(caller.RequestID) > 64 {
+		caller.RequestID = uuid.NewString()
+	}
+	if apiKey := utils.GetAiApiKey(c); apiKey != nil {
+		caller.ApiKeyID = apiKey.ID
+		caller.AllowedModels = apiKey.Models
+	}
+	return caller
 }
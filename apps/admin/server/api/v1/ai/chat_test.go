@@ -0,0 +1,41 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	aiService "github.com/flipped-aurora/gin-vue-admin/server/service/ai"
+	"github.com/gaia-x/server/service/llmadapter"
+)
+
+func TestChatErrorResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{name: "预算已用完", err: fmt.Errorf("%w: 用户1每天的Token预算已用完", aiService.ErrInsufficientQuota), status: http.StatusTooManyRequests, code: "insufficient_quota"},
+		{name: "模型不允许", err: fmt.Errorf("%w: gpt-4o", llmadapter.ErrModelNotAllowed), status: http.StatusForbidden, code: "model_not_allowed"},
+		{name: "供应商不存在", err: fmt.Errorf("%w: foo", llmadapter.ErrUnsupportedProvider), status: http.StatusBadRequest, code: "invalid_request"},
+		{name: "请求参数错误", err: &llmadapter.UpstreamError{Kind: llmadapter.ErrorKindInvalidRequest, Err: fmt.Errorf("转换第1条消息失败: %w", llmadapter.ErrInvalidRequest)}, status: http.StatusBadRequest, code: "invalid_request"},
+		{name: "没有可用凭证", err: fmt.Errorf("%w: azure", llmadapter.ErrNoCapacity), status: http.StatusTooManyRequests, code: "no_capacity"},
+		{name: "上游限流", err: &llmadapter.UpstreamError{Kind: llmadapter.ErrorKindRateLimit, Err: errors.New("429")}, status: http.StatusTooManyRequests, code: "rate_limit_exceeded"},
+		{name: "上游服务错误", err: &llmadapter.UpstreamError{Kind: llmadapter.ErrorKindServer, Err: errors.New("500")}, status: http.StatusBadGateway, code: "server"},
+		{name: "凭证解密失败", err: &llmadapter.UpstreamError{Kind: llmadapter.ErrorKindUnknown, Err: errors.New("获取Azure配置失败: 解密API密钥失败")}, status: http.StatusInternalServerError, code: "server_error"},
+		{name: "内部错误", err: errors.New("初始化RSA密钥管理器失败"), status: http.StatusInternalServerError, code: "server_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := chatErrorResponse(tt.err)
+			if status != tt.status || resp.Error.Code != tt.code {
+				t.Errorf("状态码和错误代码 = %d %s，期望 %d %s", status, resp.Error.Code, tt.status, tt.code)
+			}
+			if status == http.StatusInternalServerError && resp.Error.Message == tt.err.Error() {
+				t.Errorf("内部错误不应返回错误详情: %s", resp.Error.Message)
+			}
+		})
+	}
+}
@@ -5,6 +5,20 @@ import "github.com/flipped-aurora/gin-vue-admin/server/service"
 type ApiGroup struct {
 	ChatApi
 	RSAApi
+	ApiKeyApi
+	BudgetApi
+	ModelPriceApi
+	ModelApi
+	ModelRouteApi
+	LlmCredentialApi
 }
 
-var chatService = service.ServiceGroupApp.AiServiceGroup.ChatService
+var (
+	chatService       = service.ServiceGroupApp.AiServiceGroup.ChatService
+	apiKeyService     = service.ServiceGroupApp.AiServiceGroup.ApiKeyService
+	budgetService     = service.ServiceGroupApp.AiServiceGroup.BudgetService
+	priceService      = service.ServiceGroupApp.AiServiceGroup.ModelPriceService
+	modelService      = service.ServiceGroupApp.AiServiceGroup.ModelService
+	routeService      = service.ServiceGroupApp.AiServiceGroup.ModelRouteService
+	credentialService = service.ServiceGroupApp.AiServiceGroup.LlmCredentialService
+)
@@ -0,0 +1,180 @@
+package ai
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// LlmCredentialApi LLM凭证管理接口
+type LlmCredentialApi struct{}
+
+// CreateLlmCredential 创建LLM凭证
+// @Tags AiLlmCredential
+// @Summary 创建LLM凭证，敏感字段传明文，保存时加密
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.CreateLlmCredentialReq true "凭证信息"
+// @Success 200 {object} response.Response{data=ai.AiLlmCredential,msg=string} "创建成功"
+// @Router /aiLlmCredential/createLlmCredential [post]
+func (api *LlmCredentialApi) CreateLlmCredential(c *gin.Context) {
+	var req aiReq.CreateLlmCredentialReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	cred, err := credentialService.CreateLlmCredential(req)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(cred, "创建成功", c)
+}
+
+// UpdateLlmCredential 更新LLM凭证
+// @Tags AiLlmCredential
+// @Summary 更新LLM凭证，环境、供应商和名称不可修改，未传入的敏感字段保持不变
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.UpdateLlmCredentialReq true "凭证信息"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /aiLlmCredential/updateLlmCredential [put]
+func (api *LlmCredentialApi) UpdateLlmCredential(c *gin.Context) {
+	var req aiReq.UpdateLlmCredentialReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := credentialService.UpdateLlmCredential(req); err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DisableLlmCredential 停用LLM凭证
+// @Tags AiLlmCredential
+// @Summary 停用LLM凭证
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.LlmCredentialIdReq true "凭证ID"
+// @Success 200 {object} response.Response{msg=string} "停用成功"
+// @Router /aiLlmCredential/disableLlmCredential [post]
+func (api *LlmCredentialApi) DisableLlmCredential(c *gin.Context) {
+	var req aiReq.LlmCredentialIdReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := credentialService.DisableLlmCredential(req.ID); err != nil {
+		global.GVA_LOG.Error("停用失败!", zap.Error(err))
+		response.FailWithMessage("停用失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("停用成功", c)
+}
+
+// DeleteLlmCredential 删除LLM凭证
+// @Tags AiLlmCredential
+// @Summary 删除LLM凭证，配置文件中的同名凭证重新生效
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.LlmCredentialIdReq true "凭证ID"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /aiLlmCredential/deleteLlmCredential [delete]
+func (api *LlmCredentialApi) DeleteLlmCredential(c *gin.Context) {
+	var req aiReq.LlmCredentialIdReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := credentialService.DeleteLlmCredential(req.ID); err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// TestLlmCredential 测试LLM凭证
+// @Tags AiLlmCredential
+// @Summary 使用凭证调用一次供应商，检查凭证是否可用，凭证不需要启用
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.TestLlmCredentialReq true "凭证ID和测试模型"
+// @Success 200 {object} response.Response{msg=string} "凭证可用"
+// @Router /aiLlmCredential/testLlmCredential [post]
+func (api *LlmCredentialApi) TestLlmCredential(c *gin.Context) {
+	var req aiReq.TestLlmCredentialReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := credentialService.TestLlmCredential(c.Request.Context(), req); err != nil {
+		global.GVA_LOG.Warn("测试凭证失败", zap.Uint("id", req.ID), zap.Error(err))
+		response.FailWithMessage("凭证不可用:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("凭证可用", c)
+}
+
+// ImportLlmCredentials 导入配置文件中的凭证
+// @Tags AiLlmCredential
+// @Summary 将 config/llm 下所有配置文件的凭证导入数据库，已存在的同名凭证跳过
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Success 200 {object} response.Response{data=aiRes.ImportLlmCredentialsRes,msg=string} "导入成功"
+// @Router /aiLlmCredential/importLlmCredentials [post]
+func (api *LlmCredentialApi) ImportLlmCredentials(c *gin.Context) {
+	res, err := credentialService.ImportLlmCredentials()
+	if err != nil {
+		global.GVA_LOG.Error("导入失败!", zap.Error(err))
+		response.FailWithMessage("导入失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "导入成功", c)
+}
+
+// GetLlmCredentialList 分页获取LLM凭证列表
+// @Tags AiLlmCredential
+// @Summary 分页获取LLM凭证列表，不返回敏感字段
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query aiReq.LlmCredentialSearch true "分页获取LLM凭证列表"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /aiLlmCredential/getLlmCredentialList [get]
+func (api *LlmCredentialApi) GetLlmCredentialList(c *gin.Context) {
+	var pageInfo aiReq.LlmCredentialSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	list, total, err := credentialService.GetLlmCredentialList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ModelApi 可调用模型查询接口，与OpenAI的 /v1/models 兼容
+type ModelApi struct{}
+
+// ListModels 获取可调用的模型列表
+// @Tags AI
+// @Summary 获取当前环境启用凭证配置的模型，使用访问密钥时只返回该密钥允许调用的模型
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Success 200 {object} aiRes.ModelListRes "模型列表"
+// @Router /v1/models [get]
+func (api *ModelApi) ListModels(c *gin.Context) {
+	c.JSON(http.StatusOK, modelService.ListModels(utils.GetAiApiKey(c)))
+}
+
+// RetrieveModel 获取模型信息
+// @Tags AI
+// @Summary 获取模型的供应商、上下文窗口、能力和价格
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param id path string true "模型名称"
+// @Success 200 {object} aiRes.ModelRes "模型信息"
+// @Router /v1/models/{id} [get]
+func (api *ModelApi) RetrieveModel(c *gin.Context) {
+	id := strings.TrimPrefix(c.Param("id"), "/")
+	model, err := modelService.GetModel(utils.GetAiApiKey(c), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ai.NewErrorResponse(err.Error()+": "+id, "invalid_request_error", "model_not_found"))
+		return
+	}
+	c.JSON(http.StatusOK, model)
+}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ModelPriceApi 模型价格管理接口
+type ModelPriceApi struct{}
+
+// CreateModelPrice 创建模型价格
+// @Tags AiModelPrice
+// @Summary 创建模型价格，价格单位为每百万Token，调价时新增一条生效时间更晚的价格
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.CreateModelPriceReq true "价格信息"
+// @Success 200 {object} response.Response{data=ai.AiModelPrice,msg=string} "创建成功"
+// @Router /aiModelPrice/createModelPrice [post]
+func (api *ModelPriceApi) CreateModelPrice(c *gin.Context) {
+	var req aiReq.CreateModelPriceReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	price, err := priceService.CreateModelPrice(req)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(price, "创建成功", c)
+}
+
+// UpdateModelPrice 更新模型价格
+// @Tags AiModelPrice
+// @Summary 更新模型价格的币种、单价和备注
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.UpdateModelPriceReq true "价格信息"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /aiModelPrice/updateModelPrice [put]
+func (api *ModelPriceApi) UpdateModelPrice(c *gin.Context) {
+	var req aiReq.UpdateModelPriceReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := priceService.UpdateModelPrice(req); err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteModelPrice 删除模型价格
+// @Tags AiModelPrice
+// @Summary 删除模型价格
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.ModelPriceIdReq true "价格ID"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /aiModelPrice/deleteModelPrice [delete]
+func (api *ModelPriceApi) DeleteModelPrice(c *gin.Context) {
+	var req aiReq.ModelPriceIdReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := priceService.DeleteModelPrice(req.ID); err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// GetModelPriceList 分页获取模型价格列表
+// @Tags AiModelPrice
+// @Summary 分页获取模型价格列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query aiReq.ModelPriceSearch true "分页获取模型价格列表"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /aiModelPrice/getModelPriceList [get]
+func (api *ModelPriceApi) GetModelPriceList(c *gin.Context) {
+	var pageInfo aiReq.ModelPriceSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	list, total, err := priceService.GetModelPriceList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ModelRouteApi 逻辑模型路由管理接口
+type ModelRouteApi struct{}
+
+// CreateModelRoute 创建逻辑模型路由
+// @Tags AiModelRoute
+// @Summary 创建逻辑模型路由，角色ID为0时对所有角色生效
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.CreateModelRouteReq true "路由信息"
+// @Success 200 {object} response.Response{data=ai.AiModelRoute,msg=string} "创建成功"
+// @Router /aiModelRoute/createModelRoute [post]
+func (api *ModelRouteApi) CreateModelRoute(c *gin.Context) {
+	var req aiReq.CreateModelRouteReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	route, err := routeService.CreateModelRoute(req)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(route, "创建成功", c)
+}
+
+// UpdateModelRoute 更新逻辑模型路由
+// @Tags AiModelRoute
+// @Summary 更新逻辑模型路由的目标、状态和备注
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.UpdateModelRouteReq true "路由信息"
+// @Success 200 {object} response.Response{msg=string} "更新成功"
+// @Router /aiModelRoute/updateModelRoute [put]
+func (api *ModelRouteApi) UpdateModelRoute(c *gin.Context) {
+	var req aiReq.UpdateModelRouteReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := routeService.UpdateModelRoute(req); err != nil {
+		global.GVA_LOG.Error("更新失败!", zap.Error(err))
+		response.FailWithMessage("更新失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("更新成功", c)
+}
+
+// DeleteModelRoute 删除逻辑模型路由
+// @Tags AiModelRoute
+// @Summary 删除逻辑模型路由
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body aiReq.ModelRouteIdReq true "路由ID"
+// @Success 200 {object} response.Response{msg=string} "删除成功"
+// @Router /aiModelRoute/deleteModelRoute [delete]
+func (api *ModelRouteApi) DeleteModelRoute(c *gin.Context) {
+	var req aiReq.ModelRouteIdReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	if err := routeService.DeleteModelRoute(req.ID); err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// GetModelRouteList 分页获取逻辑模型路由列表
+// @Tags AiModelRoute
+// @Summary 分页获取逻辑模型路由列表
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query aiReq.ModelRouteSearch true "分页获取逻辑模型路由列表"
+// @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
+// @Router /aiModelRoute/getModelRouteList [get]
+func (api *ModelRouteApi) GetModelRouteList(c *gin.Context) {
+	var pageInfo aiReq.ModelRouteSearch
+	if err := c.ShouldBindQuery(&pageInfo); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	list, total, err := routeService.GetModelRouteList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
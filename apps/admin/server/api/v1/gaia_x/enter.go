@@ -6,10 +6,12 @@ type ApiGroup struct {
 	UserApi
 	VersionApi
 	UsageReportExtendApi
+	LlmUsageApi
 }
 
 var (
 	gaiaXUserService        = service.ServiceGroupApp.GaiaXServiceGroup.GaiaXUserService
 	gaiaXVersionService     = service.ServiceGroupApp.GaiaXServiceGroup.GaiaXVersionService
 	gaiaXusageReportService = service.ServiceGroupApp.GaiaXServiceGroup.GaiaXUsageReportService
+	gaiaXLlmUsageService    = service.ServiceGroupApp.GaiaXServiceGroup.GaiaXLlmUsageService
 )
@@ -0,0 +1,81 @@
+package gaia_x
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x/request"
+	"github.com/gin-gonic/gin"
+)
+
+type LlmUsageApi struct{}
+
+// GetLlmUsageSummary 获取LLM用量汇总
+// @Tags LlmUsage
+// @Summary 按用户、角色、模型或天汇总LLM用量
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.GetLlmUsageSummaryReq true "分组方式和筛选条件"
+// @Success 200 {object} response.Response{data=response.GetLlmUsageSummaryRes,msg=string} "获取成功"
+// @Router /gaia-x/v1/llm-usage/getUsageSummary [get]
+func (api *LlmUsageApi) GetLlmUsageSummary(c *gin.Context) {
+	var req request.GetLlmUsageSummaryReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	res, err := gaiaXLlmUsageService.GetLlmUsageSummary(req)
+	if err != nil {
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
+
+// GetLlmCostReport 获取LLM费用报表
+// @Tags LlmUsage
+// @Summary 按用户、角色、模型、天或供应商汇总LLM费用，明细可通过导出模板 llm_usage_cost 导出Excel
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.GetLlmCostReportReq true "分组方式和筛选条件"
+// @Success 200 {object} response.Response{data=response.GetLlmCostReportRes,msg=string} "获取成功"
+// @Router /gaia-x/v1/llm-usage/getCostReport [get]
+func (api *LlmUsageApi) GetLlmCostReport(c *gin.Context) {
+	var req request.GetLlmCostReportReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	res, err := gaiaXLlmUsageService.GetLlmCostReport(req)
+	if err != nil {
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
+
+// GetLlmUsageRecordList 获取LLM用量记录列表
+// @Tags LlmUsage
+// @Summary 分页获取LLM用量记录
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data query request.GetLlmUsageRecordListReq true "分页和筛选条件"
+// @Success 200 {object} response.Response{data=response.GetLlmUsageRecordListRes,msg=string} "获取成功"
+// @Router /gaia-x/v1/llm-usage/getUsageRecordList [get]
+func (api *LlmUsageApi) GetLlmUsageRecordList(c *gin.Context) {
+	var req request.GetLlmUsageRecordListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+
+	res, err := gaiaXLlmUsageService.GetLlmUsageRecordList(req)
+	if err != nil {
+		response.FailWithMessage("获取失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
@@ -0,0 +1,81 @@
+// rotatekey 使用当前密钥重新加密LLM凭证配置文件和数据库中的敏感字段
+//
+// 使用方法(在 apps/admin/server 目录下):
+//
+//	go run ./cmd/rotatekey -c config.yaml -new-key
+//
+// -new-key 先在密钥目录中生成新密钥并设为当前密钥；不指定时使用已配置的当前密钥，
+// 适用于通过 primary 文件或 LLM_PRIVATE_KEYS 手动切换密钥的场景。
+// 旧密钥保留在密钥目录中，所有字段重新加密且没有被跳过的字段后才可以删除
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/core"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/initialize"
+	"github.com/flipped-aurora/gin-vue-admin/server/service"
+	"github.com/gaia-x/server/service/llmadapter"
+)
+
+func main() {
+	newKey := flag.Bool("new-key", false, "生成新密钥并设为当前密钥")
+	configDir := flag.String("config-dir", llmadapter.LLMConfigPath, "LLM凭证配置文件目录")
+	skipDB := flag.Bool("skip-db", false, "不处理数据库中的凭证")
+
+	global.GVA_VP = core.Viper() // 解析命令行参数并读取配置
+	global.GVA_LOG = core.Zap()
+	llmadapter.SetKeyDir(global.GVA_CONFIG.AI.KeyDir)
+
+	if *newKey {
+		if os.Getenv(llmadapter.PrivateKeysEnv) != "" {
+			exit("密钥来自环境变量 %s，请在环境变量中添加新密钥后不带 -new-key 重新运行", llmadapter.PrivateKeysEnv)
+		}
+		id, err := llmadapter.AddKey(llmadapter.KeyDir())
+		if err != nil {
+			exit("生成新密钥失败: %v", err)
+		}
+		llmadapter.ReloadKeyring()
+		fmt.Printf("已生成新密钥 %s\n", id)
+	}
+	keyring, err := llmadapter.DefaultKeyring()
+	if err != nil {
+		exit("加载密钥失败: %v", err)
+	}
+	fmt.Printf("当前密钥: %s，全部密钥: %v\n", keyring.Primary(), keyring.KeyIDs())
+
+	result, err := llmadapter.RotateCredentialFiles(*configDir, keyring)
+	if err != nil {
+		exit("重新加密配置文件失败: %v", err)
+	}
+	report("配置文件", result)
+
+	if *skipDB {
+		return
+	}
+	global.GVA_DB = initialize.Gorm()
+	if global.GVA_DB == nil {
+		fmt.Println("未配置数据库，跳过数据库中的凭证")
+		return
+	}
+	result, err = service.ServiceGroupApp.AiServiceGroup.RotateLlmCredentials(keyring)
+	if err != nil {
+		exit("重新加密数据库凭证失败: %v", err)
+	}
+	report("数据库", result)
+}
+
+func report(source string, result llmadapter.RotateResult) {
+	fmt.Printf("%s: 重新加密 %d 个字段\n", source, result.Rotated)
+	for _, field := range result.Skipped {
+		fmt.Printf("  跳过无法解密的字段 %s\n", field)
+	}
+}
+
+func exit(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
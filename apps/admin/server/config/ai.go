@@ -2,11 +2,17 @@ package config
 
 // AIConfig 是AI服务的配置
 type AIConfig struct {
-	Provider string                 `mapstructure:"provider" json:"provider" yaml:"provider"` // AI供应商，如openai, azure, anthropic等
-	OpenAI   OpenAIConf             `mapstructure:"openai" json:"openai" yaml:"openai"`       // OpenAI配置
-	Azure    AzureConf              `mapstructure:"azure" json:"azure" yaml:"azure"`          // Azure OpenAI配置
-	DeepSeek DeepSeekConf           `mapstructure:"deepseek" json:"deepseek" yaml:"deepseek"` // DeepSeek配置
-	Extra    map[string]interface{} `mapstructure:"extra" json:"extra" yaml:"extra"`
+	Provider            string                 `mapstructure:"provider" json:"provider" yaml:"provider"`                                           // AI供应商，如openai, azure, anthropic等
+	OpenAI              OpenAIConf             `mapstructure:"openai" json:"openai" yaml:"openai"`                                                 // OpenAI配置
+	Azure               AzureConf              `mapstructure:"azure" json:"azure" yaml:"azure"`                                                    // Azure OpenAI配置
+	DeepSeek            DeepSeekConf           `mapstructure:"deepseek" json:"deepseek" yaml:"deepseek"`                                           // DeepSeek配置
+	Failover            FailoverConf           `mapstructure:"failover" json:"failover" yaml:"failover"`                                           // 重试与故障转移配置
+	Usage               UsageConf              `mapstructure:"usage" json:"usage" yaml:"usage"`                                                    // 用量记录配置
+	KeyDir              string                 `mapstructure:"key-dir" json:"key-dir" yaml:"key-dir"`                                              // 加密LLM凭证的密钥目录，环境变量 LLM_KEY_DIR、LLM_PRIVATE_KEYS 优先，为空时使用 llmadapter 包目录下的 rsa_keys
+	StrictConfig        bool                   `mapstructure:"strict-config" json:"strict-config" yaml:"strict-config"`                            // 启动时LLM凭证配置校验有错误则拒绝启动，否则只记录错误日志
+	StreamHeartbeat     int                    `mapstructure:"stream-heartbeat" json:"stream-heartbeat" yaml:"stream-heartbeat"`                   // 流式响应的心跳间隔（秒），上游超过该时间没有输出时发送 ": ping"，默认15，-1表示不发送
+	RateLimitFailClosed bool                   `mapstructure:"rate-limit-fail-closed" json:"rate-limit-fail-closed" yaml:"rate-limit-fail-closed"` // 凭证QPS限流检查出错(如Redis不可用)时跳过该凭证，默认放行
+	Extra               map[string]interface{} `mapstructure:"extra" json:"extra" yaml:"extra"`
 }
 
 // OpenAIConf OpenAI配置
@@ -46,3 +52,33 @@ type DeepSeekConf struct {
 	ProxyURL       string            `mapstructure:"proxy-url" json:"proxy-url" yaml:"proxy-url"`                   // 代理URL
 	DefaultHeaders map[string]string `mapstructure:"default-headers" json:"default-headers" yaml:"default-headers"` // 默认请求头
 }
+
+// FailoverConf LLM调用的重试与故障转移配置，未配置的字段使用默认值
+type FailoverConf struct {
+	MaxAttempts      int            `mapstructure:"max-attempts" json:"max-attempts" yaml:"max-attempts"`                // 每个供应商最多尝试的次数(含首次)，默认3
+	BaseDelay        int            `mapstructure:"base-delay" json:"base-delay" yaml:"base-delay"`                      // 首次重试前的等待时间（毫秒），默认200
+	MaxDelay         int            `mapstructure:"max-delay" json:"max-delay" yaml:"max-delay"`                         // 单次等待时间的上限（毫秒），默认2000
+	BreakerThreshold int            `mapstructure:"breaker-threshold" json:"breaker-threshold" yaml:"breaker-threshold"` // 凭证连续失败多少次后进入冷却，默认3，-1表示不启用熔断
+	BreakerCooldown  int            `mapstructure:"breaker-cooldown" json:"breaker-cooldown" yaml:"breaker-cooldown"`    // 凭证冷却时间（秒），默认30
+	Fallbacks        []FallbackConf `mapstructure:"fallbacks" json:"fallbacks" yaml:"fallbacks"`                         // 故障转移规则，按顺序匹配第一条
+}
+
+// FallbackConf 故障转移规则，from(及model)匹配的请求重试失败后依次尝试to中的供应商和模型
+type FallbackConf struct {
+	From  string           `mapstructure:"from" json:"from" yaml:"from"`    // 匹配的供应商
+	Model string           `mapstructure:"model" json:"model" yaml:"model"` // 匹配的模型，为空时匹配该供应商的所有模型
+	To    []FallbackTarget `mapstructure:"to" json:"to" yaml:"to"`          // 备用供应商和模型
+}
+
+// FallbackTarget 备用供应商和模型
+type FallbackTarget struct {
+	Provider string `mapstructure:"provider" json:"provider" yaml:"provider"` // 供应商
+	Model    string `mapstructure:"model" json:"model" yaml:"model"`          // 模型，为空时沿用请求的模型
+}
+
+// UsageConf LLM调用用量记录的配置，用量异步批量写入数据库，未配置的字段使用默认值
+type UsageConf struct {
+	BufferSize    int `mapstructure:"buffer-size" json:"buffer-size" yaml:"buffer-size"`          // 待写入记录的缓冲区大小，缓冲区满时丢弃新记录，默认4096
+	BatchSize     int `mapstructure:"batch-size" json:"batch-size" yaml:"batch-size"`             // 每批写入的最大记录数，默认100
+	FlushInterval int `mapstructure:"flush-interval" json:"flush-interval" yaml:"flush-interval"` // 不足一批时的写入间隔（毫秒），默认1000
+}
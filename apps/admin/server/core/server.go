@@ -18,6 +18,8 @@ func RunWindowsServer() {
 		initialize.Redis()
 		initialize.RedisList()
 	}
+	initialize.LLMAdapter()
+	defer initialize.CloseLLMAdapter()
 
 	if global.GVA_CONFIG.System.UseMongo {
 		err := initialize.Mongo.Initialization()
@@ -2,13 +2,69 @@ package initialize
 
 import (
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"gorm.io/gorm"
 )
 
 func bizModel() error {
 	db := global.GVA_DB
-	err := db.AutoMigrate()
+	err := db.AutoMigrate(ai.AiApiKey{}, ai.AiBudget{}, ai.AiBudgetUsage{}, ai.AiBudgetTopUp{}, ai.AiModelPrice{}, ai.AiModelRoute{}, ai.AiLlmCredential{}, gaia_x.LlmUsageRecord{})
 	if err != nil {
 		return err
 	}
-	return nil
+	return ensureLlmUsageExportTemplate(db)
+}
+
+// llmUsageExportTemplateID LLM用量费用明细的导出模板标识
+const llmUsageExportTemplateID = "llm_usage_cost"
+
+// ensureLlmUsageExportTemplate 创建LLM用量费用明细的导出模板，已存在时不覆盖，以保留管理员的修改
+// 通过 /sysExportTemplate/exportExcel?templateID=llm_usage_cost 导出，
+// 支持 start_time、end_time、user_id、authority_id、vendor、model 查询参数
+func ensureLlmUsageExportTemplate(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&system.SysExportTemplate{}) {
+		return nil
+	}
+	var count int64
+	if err := db.Model(&system.SysExportTemplate{}).Where("template_id = ?", llmUsageExportTemplateID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	template := system.SysExportTemplate{
+		Name:       "LLM用量费用明细",
+		TableName:  "llm_usage_records",
+		TemplateID: llmUsageExportTemplateID,
+		TemplateInfo: `{
+"request_time":"请求时间",
+"request_id":"请求ID",
+"user_id":"用户ID",
+"authority_id":"角色ID",
+"api_key_id":"访问密钥ID",
+"vendor":"供应商",
+"credential":"凭证",
+"model":"模型",
+"status":"状态",
+"prompt_tokens":"提示Token",
+"cached_tokens":"缓存命中Token",
+"completion_tokens":"补全Token",
+"reasoning_tokens":"推理Token",
+"total_tokens":"总Token",
+"cost":"费用",
+"currency":"币种"
+}`,
+		Order: "request_time desc",
+		Conditions: []system.Condition{
+			{From: "start_time", Column: "request_time", Operator: ">="},
+			{From: "end_time", Column: "request_time", Operator: "<="},
+			{From: "user_id", Column: "user_id", Operator: "="},
+			{From: "authority_id", Column: "authority_id", Operator: "="},
+			{From: "vendor", Column: "vendor", Operator: "="},
+			{From: "model", Column: "model", Operator: "="},
+		},
+	}
+	return db.Create(&template).Error
 }
@@ -0,0 +1,140 @@
+package initialize
+
+import (
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/service/ai"
+	"github.com/gaia-x/server/service/llmadapter"
+	"go.uber.org/zap"
+)
+
+// credentialReloadInterval 定期重新加载数据库中LLM凭证的间隔，多实例部署时其他实例修改凭证后最多延迟这么久生效
+const credentialReloadInterval = time.Minute
+
+var (
+	credentialReloadOnce sync.Once
+	credentialReloadDone = make(chan struct{})
+)
+
+// LLMAdapter 初始化LLM适配器
+// 适配器的警告和错误日志(如重试、凭证配置修改被拒绝)输出到系统日志；
+// 按 ai.key-dir 加载加密凭证的密钥，密钥不存在或无效时拒绝启动，避免已加密的凭证全部无法解密；
+// 启动时校验凭证配置，见 validateCredentials；
+// 开启 system.use-redis 时凭证QPS限流使用Redis令牌桶，多个实例共享配额，否则使用进程内令牌桶，
+// 限流检查出错时按 ai.rate-limit-fail-closed 放行或跳过凭证；
+// 按 ai.failover 配置重试、凭证熔断和供应商故障转移，按 ai.stream-heartbeat 配置流式响应的心跳间隔；
+// 连接了数据库时按 ai.usage 配置启动用量记录器，每次调用的用量异步写入 llm_usage_records 表，
+// 并将 ai_llm_credentials 表作为配置文件以外的凭证来源，定期重新加载
+func LLMAdapter() {
+	llmadapter.SetLogger(global.GVA_LOG)
+	llmadapter.SetKeyDir(global.GVA_CONFIG.AI.KeyDir)
+	keyring, err := llmadapter.DefaultKeyring()
+	if err != nil {
+		global.GVA_LOG.Fatal("加载LLM凭证密钥失败", zap.String("keyDir", llmadapter.KeyDir()), zap.Error(err))
+	}
+	global.GVA_LOG.Info("LLM凭证密钥已加载", zap.String("primary", keyring.Primary()), zap.Strings("keys", keyring.KeyIDs()))
+
+	if global.GVA_CONFIG.System.UseRedis && global.GVA_REDIS != nil {
+		llmadapter.SetRateLimiter(ai.NewRedisRateLimiter(global.GVA_REDIS))
+		global.GVA_LOG.Info("LLM凭证QPS限流使用Redis令牌桶")
+	}
+	llmadapter.SetRateLimitFailClosed(global.GVA_CONFIG.AI.RateLimitFailClosed)
+	llmadapter.SetRetryPolicy(retryPolicy(global.GVA_CONFIG.AI.Failover))
+	llmadapter.SetStreamHeartbeat(streamHeartbeat(global.GVA_CONFIG.AI.StreamHeartbeat))
+	var source llmadapter.CredentialSource
+	if global.GVA_DB != nil {
+		ai.StartUsageRecorder(global.GVA_DB, global.GVA_CONFIG.AI.Usage)
+		source = ai.LlmCredentialSource{}
+		if err := llmadapter.SetCredentialSource(source); err != nil {
+			global.GVA_LOG.Warn("加载数据库中的LLM凭证失败", zap.Error(err))
+		}
+		go reloadCredentials(credentialReloadInterval)
+	}
+	validateCredentials(source)
+}
+
+// validateCredentials 校验当前环境的LLM凭证配置(与 llmctl validate 相同)，逐条记录发现的问题
+// 开启 ai.strict-config 时有错误则拒绝启动
+func validateCredentials(source llmadapter.CredentialSource) {
+	issues := llmadapter.ValidateCredentials(llmadapter.LLMConfigPath, source, "", nil)
+	for _, issue := range issues {
+		fields := []zap.Field{zap.String("vendor", issue.Vendor), zap.String("env", issue.Env), zap.String("credential", issue.Credential)}
+		if issue.Level == llmadapter.IssueError {
+			global.GVA_LOG.Error("LLM凭证配置错误: "+issue.Message, fields...)
+		} else {
+			global.GVA_LOG.Warn("LLM凭证配置警告: "+issue.Message, fields...)
+		}
+	}
+	if !llmadapter.HasErrors(issues) {
+		return
+	}
+	if global.GVA_CONFIG.AI.StrictConfig {
+		global.GVA_LOG.Fatal("LLM凭证配置有错误，已开启 ai.strict-config，拒绝启动")
+	}
+	global.GVA_LOG.Error("LLM凭证配置有错误，相关凭证无法使用，可运行 llmctl validate 查看详情")
+}
+
+// CloseLLMAdapter 服务退出前写入缓冲区中剩余的用量记录，并停止定期重新加载凭证
+func CloseLLMAdapter() {
+	ai.StopUsageRecorder()
+	credentialReloadOnce.Do(func() { close(credentialReloadDone) })
+}
+
+// reloadCredentials 定期重新加载LLM凭证，直到 CloseLLMAdapter
+func reloadCredentials(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-credentialReloadDone:
+			return
+		case <-ticker.C:
+			if err := llmadapter.ReloadCredentials(); err != nil {
+				global.GVA_LOG.Warn("重新加载LLM凭证失败", zap.Error(err))
+			}
+		}
+	}
+}
+
+// streamHeartbeat 将 ai.stream-heartbeat 配置转换为心跳间隔，未配置时使用默认值，负数表示不发送心跳
+func streamHeartbeat(seconds int) time.Duration {
+	switch {
+	case seconds > 0:
+		return time.Duration(seconds) * time.Second
+	case seconds < 0:
+		return 0
+	default:
+		return llmadapter.DefaultStreamHeartbeat
+	}
+}
+
+// retryPolicy 将 ai.failover 配置转换为重试策略，未配置的字段使用默认值
+func retryPolicy(conf config.FailoverConf) llmadapter.RetryPolicy {
+	policy := llmadapter.DefaultRetryPolicy()
+	if conf.MaxAttempts > 0 {
+		policy.MaxAttempts = conf.MaxAttempts
+	}
+	if conf.BaseDelay > 0 {
+		policy.BaseDelay = time.Duration(conf.BaseDelay) * time.Millisecond
+	}
+	if conf.MaxDelay > 0 {
+		policy.MaxDelay = time.Duration(conf.MaxDelay) * time.Millisecond
+	}
+	if conf.BreakerThreshold != 0 {
+		policy.BreakerThreshold = max(conf.BreakerThreshold, 0)
+	}
+	if conf.BreakerCooldown > 0 {
+		policy.BreakerCooldown = time.Duration(conf.BreakerCooldown) * time.Second
+	}
+	for _, fallback := range conf.Fallbacks {
+		rule := llmadapter.FallbackRule{Provider: fallback.From, Model: fallback.Model}
+		for _, target := range fallback.To {
+			rule.Targets = append(rule.Targets, llmadapter.FallbackTarget{Provider: target.Provider, Model: target.Model})
+		}
+		policy.Fallbacks = append(policy.Fallbacks, rule)
+	}
+	return policy
+}
@@ -17,8 +17,13 @@ func initBizRouter(routers ...*gin.RouterGroup) {
 
 	aiRouter := router.RouterGroupApp.Ai
 	{
-		aiRouter.InitChatRouter(privateGroup, publicGroup) // AI路由
-		aiRouter.InitRSARouter(privateGroup, publicGroup)  // RSA加密路由
+		aiRouter.InitChatRouter(privateGroup, publicGroup)          // AI路由
+		aiRouter.InitRSARouter(privateGroup, publicGroup)           // RSA加密路由
+		aiRouter.InitApiKeyRouter(privateGroup, publicGroup)        // AI访问密钥管理路由
+		aiRouter.InitBudgetRouter(privateGroup, publicGroup)        // AI预算管理路由
+		aiRouter.InitModelPriceRouter(privateGroup, publicGroup)    // 模型价格管理路由
+		aiRouter.InitModelRouteRouter(privateGroup, publicGroup)    // 逻辑模型路由管理路由
+		aiRouter.InitLlmCredentialRouter(privateGroup, publicGroup) // LLM凭证管理路由
 	}
 
 	gaiaXRouter := router.RouterGroupApp.GaiaX
@@ -26,6 +31,7 @@ func initBizRouter(routers ...*gin.RouterGroup) {
 		gaiaXRouter.InitGaiaXRouter(privateGroup, publicGroup)
 		gaiaXRouter.InitGaiaXVersionRouter(privateGroup, publicGroup)
 		gaiaXRouter.InitGaiaXUsageReportRouter(privateGroup, publicGroup)
+		gaiaXRouter.InitGaiaXLlmUsageRouter(privateGroup, publicGroup) // LLM用量统计路由
 	}
 
 	holder(publicGroup, privateGroup)
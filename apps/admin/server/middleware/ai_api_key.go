@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/service"
+	aiService "github.com/flipped-aurora/gin-vue-admin/server/service/ai"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var apiKeyService = service.ServiceGroupApp.AiServiceGroup.ApiKeyService
+
+// ApiKeyAuth AI接口鉴权
+// 支持 Authorization: Bearer sk-... 形式的访问密钥，OpenAI SDK和桌面客户端无需修改即可调用；
+// 未携带访问密钥时按登录令牌(x-token)鉴权
+func ApiKeyAuth() gin.HandlerFunc {
+	jwtAuth := JWTAuth()
+	return func(c *gin.Context) {
+		key, ok := bearerApiKey(c)
+		if !ok {
+			jwtAuth(c)
+			return
+		}
+
+		apiKey, user, err := apiKeyService.Authenticate(key, c.ClientIP())
+		if err != nil {
+			status, code, message := http.StatusUnauthorized, "invalid_api_key", err.Error()
+			switch {
+			case errors.Is(err, aiService.ErrApiKeyIPDenied):
+				status, code = http.StatusForbidden, "ip_not_allowed"
+			case errors.Is(err, aiService.ErrInvalidApiKey), errors.Is(err, aiService.ErrApiKeyRevoked),
+				errors.Is(err, aiService.ErrApiKeyExpired), errors.Is(err, aiService.ErrApiKeyUserDisabled),
+				errors.Is(err, aiService.ErrApiKeyAuthority):
+				// 密钥本身无效，返回401
+			default:
+				global.GVA_LOG.Error("校验API密钥失败", zap.Error(err))
+				status, code, message = http.StatusInternalServerError, "internal_error", "校验API密钥失败"
+			}
+			c.AbortWithStatusJSON(status, ai.NewErrorResponse(message, "invalid_request_error", code))
+			return
+		}
+
+		// 与登录令牌一致写入claims，后续按用户ID、角色处理的逻辑无需区分鉴权方式
+		c.Set("claims", &systemReq.CustomClaims{BaseClaims: systemReq.BaseClaims{
+			UUID:        user.UUID,
+			ID:          user.ID,
+			Username:    user.Username,
+			NickName:    user.NickName,
+			AuthorityId: apiKey.AuthorityId,
+		}})
+		c.Set(ai.ApiKeyContextKey, apiKey)
+		c.Next()
+	}
+}
+
+// bearerApiKey 从 Authorization 头中提取 sk- 开头的访问密钥
+func bearerApiKey(c *gin.Context) (string, bool) {
+	authorization := c.GetHeader("Authorization")
+	scheme, key, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	key = strings.TrimSpace(key)
+	return key, strings.HasPrefix(key, ai.ApiKeyPrefix)
+}
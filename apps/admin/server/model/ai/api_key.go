@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+const (
+	// ApiKeyPrefix AI网关访问密钥的前缀，与OpenAI的密钥格式保持一致
+	ApiKeyPrefix = "sk-"
+	// ApiKeyContextKey 鉴权通过后访问密钥记录在 gin.Context 中的键
+	ApiKeyContextKey = "aiApiKey"
+)
+
+// AiApiKey AI网关访问密钥
+// 密钥明文只在创建时返回一次，数据库中只保存SHA-256摘要
+type AiApiKey struct {
+	global.GVA_MODEL
+	Name        string     `json:"name" gorm:"column:name;comment:密钥名称"`                                                // 密钥名称
+	KeyHash     string     `json:"-" gorm:"column:key_hash;size:64;uniqueIndex;comment:密钥SHA-256摘要"`                    // 密钥SHA-256摘要
+	KeyMask     string     `json:"keyMask" gorm:"column:key_mask;comment:脱敏后的密钥，用于识别密钥"`                                // 脱敏后的密钥，用于识别密钥
+	UserID      uint       `json:"userId" gorm:"column:user_id;index;comment:所属用户ID"`                                   // 所属用户ID
+	AuthorityId uint       `json:"authorityId" gorm:"column:authority_id;comment:调用时使用的角色ID"`                           // 调用时使用的角色ID
+	ExpiresAt   *time.Time `json:"expiresAt" gorm:"column:expires_at;comment:过期时间，为空表示永不过期"`                            // 过期时间，为空表示永不过期
+	Models      []string   `json:"models" gorm:"column:models;serializer:json;type:text;comment:允许调用的模型，为空表示不限制"`       // 允许调用的模型，为空表示不限制
+	AllowedIPs  []string   `json:"allowedIps" gorm:"column:allowed_ips;serializer:json;type:text;comment:IP白名单，支持CIDR"` // IP白名单，支持CIDR，为空表示不限制
+	RevokedAt   *time.Time `json:"revokedAt" gorm:"column:revoked_at;comment:吊销时间"`                                     // 吊销时间
+	LastUsedAt  *time.Time `json:"lastUsedAt" gorm:"column:last_used_at;comment:最近使用时间"`                                // 最近使用时间
+	Description string     `json:"description" gorm:"column:description;comment:备注"`                                    // 备注
+}
+
+// TableName 设置表名
+func (AiApiKey) TableName() string {
+	return "ai_api_keys"
+}
+
+// HashApiKey 计算密钥的SHA-256摘要
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Revoked 密钥是否已吊销
+func (k *AiApiKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Expired 密钥在指定时间是否已过期
+func (k *AiApiKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// AllowModel 密钥是否允许调用指定模型，未限制模型时允许所有模型
+func (k *AiApiKey) AllowModel(model string) bool {
+	return len(k.Models) == 0 || slices.Contains(k.Models, model)
+}
+
+// AllowIP 请求IP是否在白名单中，未配置白名单时允许所有IP
+// 白名单中的条目可以是单个IP或CIDR网段
+func (k *AiApiKey) AllowIP(ip string) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+	clientIP := net.ParseIP(ip)
+	if clientIP == nil {
+		return false
+	}
+	for _, allowed := range k.AllowedIPs {
+		allowed = strings.TrimSpace(allowed)
+		if strings.Contains(allowed, "/") {
+			if _, network, err := net.ParseCIDR(allowed); err == nil && network.Contains(clientIP) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(allowed); allowedIP != nil && allowedIP.Equal(clientIP) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAiApiKeyScope(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	key := AiApiKey{
+		ExpiresAt:  &past,
+		Models:     []string{"gpt-4o"},
+		AllowedIPs: []string{"10.0.0.0/8", "192.168.1.10", "2001:db8::/32"},
+	}
+
+	if !key.Expired(now) {
+		t.Error("过期时间早于当前时间的密钥应当已过期")
+	}
+	if (&AiApiKey{}).Expired(now) {
+		t.Error("未设置过期时间的密钥不应过期")
+	}
+
+	if !key.AllowModel("gpt-4o") || key.AllowModel("claude-3-5-sonnet") {
+		t.Error("只允许调用模型列表中的模型")
+	}
+	if !(&AiApiKey{}).AllowModel("any-model") {
+		t.Error("未限制模型时应允许所有模型")
+	}
+
+	for ip, want := range map[string]bool{
+		"10.1.2.3":     true,
+		"192.168.1.10": true,
+		"192.168.1.11": false,
+		"2001:db8::1":  true,
+		"not-an-ip":    false,
+	} {
+		if got := key.AllowIP(ip); got != want {
+			t.Errorf("AllowIP(%q) = %v, 期望 %v", ip, got, want)
+		}
+	}
+	if !(&AiApiKey{}).AllowIP("8.8.8.8") {
+		t.Error("未配置白名单时应允许所有IP")
+	}
+}
+
+func TestHashApiKey(t *testing.T) {
+	hash := HashApiKey("sk-test")
+	if len(hash) != 64 || hash != HashApiKey("sk-test") || hash == HashApiKey("sk-test2") {
+		t.Errorf("摘要不符合预期: %s", hash)
+	}
+}
@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 预算的适用范围
+const (
+	BudgetScopeUser      = "user"      // 用户
+	BudgetScopeAuthority = "authority" // 角色
+	BudgetScopeApiKey    = "api_key"   // 访问密钥
+)
+
+// 预算周期
+const (
+	BudgetPeriodDaily   = "daily"   // 每天
+	BudgetPeriodMonthly = "monthly" // 每月
+)
+
+// AiBudget AI调用预算
+// 同一范围对象(用户、角色或访问密钥)每个周期只能有一个预算，Token和费用上限为0表示不限制；
+// 费用上限只统计与预算币种相同的费用
+type AiBudget struct {
+	global.GVA_MODEL
+	Scope            string  `json:"scope" gorm:"column:scope;size:16;uniqueIndex:idx_ai_budget_scope;comment:适用范围 user/authority/api_key"` // 适用范围
+	ScopeID          uint    `json:"scopeId" gorm:"column:scope_id;uniqueIndex:idx_ai_budget_scope;comment:用户ID、角色ID或访问密钥ID"`               // 用户ID、角色ID或访问密钥ID
+	Period           string  `json:"period" gorm:"column:period;size:16;uniqueIndex:idx_ai_budget_scope;comment:周期 daily/monthly"`          // 周期
+	TokenLimit       int64   `json:"tokenLimit" gorm:"column:token_limit;comment:每周期Token上限，0表示不限制"`                                        // 每周期Token上限，0表示不限制
+	CostLimit        float64 `json:"costLimit" gorm:"column:cost_limit;comment:每周期费用上限，0表示不限制"`                                             // 每周期费用上限，0表示不限制
+	Currency         string  `json:"currency" gorm:"column:currency;size:8;default:USD;comment:费用上限的币种，如USD、CNY"`                           // 费用上限的币种
+	SoftLimitPercent int     `json:"softLimitPercent" gorm:"column:soft_limit_percent;comment:用量达到上限的百分比时发送预警邮件，0表示不预警"`                    // 用量达到上限的百分比时发送预警邮件，0表示不预警
+	WarnEmail        string  `json:"warnEmail" gorm:"column:warn_email;comment:预警邮件收件人，多个用逗号分隔"`                                            // 预警邮件收件人，多个用逗号分隔
+	Enabled          bool    `json:"enabled" gorm:"column:enabled;comment:是否启用"`                                                            // 是否启用
+	Description      string  `json:"description" gorm:"column:description;comment:备注"`                                                      // 备注
+}
+
+// TableName 设置表名
+func (AiBudget) TableName() string {
+	return "ai_budgets"
+}
+
+// PeriodKey 返回 t 所在周期的标识，每天为 2006-01-02，每月为 2006-01
+func (b AiBudget) PeriodKey(t time.Time) string {
+	if b.Period == BudgetPeriodMonthly {
+		return t.Format("2006-01")
+	}
+	return t.Format(time.DateOnly)
+}
+
+// PeriodEnd 返回 t 所在周期的结束时间
+func (b AiBudget) PeriodEnd(t time.Time) time.Time {
+	year, month, day := t.Date()
+	if b.Period == BudgetPeriodMonthly {
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+}
+
+// AiBudgetUsage 预算在一个周期内的已用量，未开启Redis时作为计数器使用
+type AiBudgetUsage struct {
+	global.GVA_MODEL
+	BudgetID  uint    `json:"budgetId" gorm:"column:budget_id;uniqueIndex:idx_ai_budget_usage_period;comment:预算ID"`           // 预算ID
+	PeriodKey string  `json:"periodKey" gorm:"column:period_key;size:16;uniqueIndex:idx_ai_budget_usage_period;comment:周期标识"` // 周期标识
+	Tokens    int64   `json:"tokens" gorm:"column:tokens;comment:已用Token数"`                                                   // 已用Token数
+	Cost      float64 `json:"cost" gorm:"column:cost;comment:已用费用"`                                                           // 已用费用
+	Warned    bool    `json:"warned" gorm:"column:warned;comment:本周期是否已发送预警"`                                                 // 本周期是否已发送预警
+}
+
+// TableName 设置表名
+func (AiBudgetUsage) TableName() string {
+	return "ai_budget_usages"
+}
+
+// AiBudgetTopUp 预算的临时额度，到期前计入预算上限
+type AiBudgetTopUp struct {
+	global.GVA_MODEL
+	BudgetID  uint      `json:"budgetId" gorm:"column:budget_id;index;comment:预算ID"`              // 预算ID
+	Tokens    int64     `json:"tokens" gorm:"column:tokens;comment:增加的Token上限"`                   // 增加的Token上限
+	Cost      float64   `json:"cost" gorm:"column:cost;comment:增加的费用上限"`                          // 增加的费用上限
+	Currency  string    `json:"currency" gorm:"column:currency;size:8;default:USD;comment:费用的币种"` // 费用的币种，与预算的币种相同
+	ExpiresAt time.Time `json:"expiresAt" gorm:"column:expires_at;index;comment:到期时间"`            // 到期时间
+	Reason    string    `json:"reason" gorm:"column:reason;comment:原因"`                           // 原因
+	CreatedBy uint      `json:"createdBy" gorm:"column:created_by;comment:操作人ID"`                 // 操作人ID
+}
+
+// TableName 设置表名
+func (AiBudgetTopUp) TableName() string {
+	return "ai_budget_top_ups"
+}
@@ -133,3 +133,12 @@ type ErrorResponse struct {
 		Code    string `json:"code"`    // 错误代码
 	} `json:"error"`
 }
+
+// NewErrorResponse 创建OpenAI格式的错误响应
+func NewErrorResponse(message, errType, code string) ErrorResponse {
+	var resp ErrorResponse
+	resp.Error.Message = message
+	resp.Error.Type = errType
+	resp.Error.Code = code
+	return resp
+}
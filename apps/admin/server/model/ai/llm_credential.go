@@ -0,0 +1,45 @@
+package ai
+
+import (
+	"sort"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"gorm.io/gorm"
+)
+
+// AiLlmCredential 保存在数据库中的LLM供应商凭证
+// 与 config/llm 下的配置文件一起生效，同一环境中与配置文件同名的凭证以数据库为准；
+// 敏感字段加密保存，接口中只返回已设置的字段名，不返回密文或明文
+type AiLlmCredential struct {
+	global.GVA_MODEL
+	Env         string            `json:"env" gorm:"column:env;size:32;uniqueIndex:idx_ai_llm_credential;comment:运行环境"`                   // 运行环境 development/production/test
+	Vendor      string            `json:"vendor" gorm:"column:vendor;size:32;uniqueIndex:idx_ai_llm_credential;comment:供应商"`              // 供应商
+	Name        string            `json:"name" gorm:"column:name;size:64;uniqueIndex:idx_ai_llm_credential;comment:凭证名称"`                 // 凭证名称，同一环境和供应商内唯一
+	Enabled     bool              `json:"enabled" gorm:"column:enabled;comment:是否启用"`                                                     // 是否启用
+	Weight      int               `json:"weight" gorm:"column:weight;comment:负载均衡权重"`                                                     // 负载均衡权重
+	QPSLimit    int               `json:"qpsLimit" gorm:"column:qps_limit;comment:QPS限制，0表示不限制"`                                          // QPS限制，0表示不限制
+	Models      []string          `json:"models" gorm:"column:models;serializer:json;type:text;comment:支持的模型，为空表示不限制"`                    // 支持的模型，为空表示不限制
+	Region      string            `json:"region" gorm:"column:region;size:64;comment:地域"`                                                 // 地域
+	BaseURL     string            `json:"baseUrl" gorm:"column:base_url;comment:接口地址"`                                                    // 接口地址
+	Proxy       string            `json:"proxy" gorm:"column:proxy;comment:代理地址"`                                                         // 代理地址
+	Options     map[string]any    `json:"options" gorm:"column:options;serializer:json;type:text;comment:其他供应商配置，如deployment_id、timeout"` // 其他供应商配置，如deployment_id、timeout
+	Secrets     map[string]string `json:"-" gorm:"column:secrets;serializer:json;type:text;comment:加密后的敏感字段，如api_key"`                    // 加密后的敏感字段，如api_key
+	Description string            `json:"description" gorm:"column:description;comment:备注"`                                               // 备注
+
+	SecretFields []string `json:"secretFields" gorm:"-"` // 已设置的敏感字段名
+}
+
+// TableName 设置表名
+func (AiLlmCredential) TableName() string {
+	return "ai_llm_credentials"
+}
+
+// AfterFind 查询后填充已设置的敏感字段名
+func (c *AiLlmCredential) AfterFind(tx *gorm.DB) error {
+	c.SecretFields = make([]string, 0, len(c.Secrets))
+	for field := range c.Secrets {
+		c.SecretFields = append(c.SecretFields, field)
+	}
+	sort.Strings(c.SecretFields)
+	return nil
+}
@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// DefaultPriceCurrency 未指定币种时使用的币种
+const DefaultPriceCurrency = "USD"
+
+// AiModelPrice 模型价格，价格单位为每百万Token
+// 同一供应商和模型可以配置多条价格，调用时使用生效时间不晚于请求时间的最新一条；
+// 供应商为空的价格对所有供应商的该模型生效，优先级低于指定供应商的价格
+type AiModelPrice struct {
+	global.GVA_MODEL
+	Vendor           string    `json:"vendor" gorm:"column:vendor;size:32;uniqueIndex:idx_ai_model_price;comment:供应商，为空表示所有供应商"` // 供应商，为空表示所有供应商
+	Model            string    `json:"model" gorm:"column:model;size:128;uniqueIndex:idx_ai_model_price;comment:模型"`             // 模型
+	EffectiveFrom    time.Time `json:"effectiveFrom" gorm:"column:effective_from;uniqueIndex:idx_ai_model_price;comment:生效时间"`   // 生效时间
+	Currency         string    `json:"currency" gorm:"column:currency;size:8;comment:币种，如USD、CNY"`                               // 币种
+	InputPrice       float64   `json:"inputPrice" gorm:"column:input_price;comment:输入价格(每百万Token)"`                              // 输入价格(每百万Token)
+	CachedInputPrice float64   `json:"cachedInputPrice" gorm:"column:cached_input_price;comment:缓存命中的输入价格(每百万Token)，0表示按输入价格计算"` // 缓存命中的输入价格(每百万Token)，0表示按输入价格计算
+	OutputPrice      float64   `json:"outputPrice" gorm:"column:output_price;comment:输出价格(每百万Token)"`                            // 输出价格(每百万Token)
+	Description      string    `json:"description" gorm:"column:description;comment:备注"`                                         // 备注
+}
+
+// TableName 设置表名
+func (AiModelPrice) TableName() string {
+	return "ai_model_prices"
+}
+
+// Cost 计算一次调用的费用，promptTokens 包含缓存命中的 cachedTokens
+func (p AiModelPrice) Cost(promptTokens, cachedTokens, completionTokens int) float64 {
+	cachedTokens = min(max(cachedTokens, 0), promptTokens)
+	cachedPrice := p.CachedInputPrice
+	if cachedPrice == 0 {
+		cachedPrice = p.InputPrice
+	}
+	return (float64(promptTokens-cachedTokens)*p.InputPrice +
+		float64(cachedTokens)*cachedPrice +
+		float64(completionTokens)*p.OutputPrice) / 1e6
+}
@@ -0,0 +1,33 @@
+package ai
+
+import (
+	"math"
+	"testing"
+)
+
+func TestModelPriceCost(t *testing.T) {
+	price := AiModelPrice{InputPrice: 2.5, CachedInputPrice: 1.25, OutputPrice: 10}
+	tests := []struct {
+		name                       string
+		prompt, cached, completion int
+		want                       float64
+	}{
+		{"无缓存", 1000000, 0, 100000, 2.5 + 1},
+		{"部分缓存", 1000000, 400000, 0, 0.6*2.5 + 0.4*1.25},
+		{"缓存数超过提示数", 1000, 5000, 0, 1000 * 1.25 / 1e6},
+		{"无用量", 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := price.Cost(tt.prompt, tt.cached, tt.completion); math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("期望费用 %v，实际为 %v", tt.want, got)
+			}
+		})
+	}
+
+	// 未配置缓存价格时按输入价格计算
+	price.CachedInputPrice = 0
+	if got := price.Cost(1000000, 1000000, 0); math.Abs(got-2.5) > 1e-9 {
+		t.Fatalf("期望费用 2.5，实际为 %v", got)
+	}
+}
@@ -0,0 +1,55 @@
+package ai
+
+import "github.com/flipped-aurora/gin-vue-admin/server/global"
+
+// AiModelRoute 逻辑模型路由，将 default-chat、fast 等逻辑模型名称映射到具体的供应商和模型
+// 角色ID为0的路由对所有角色生效，指定角色的路由优先；同一逻辑模型每个角色只能有一条路由
+type AiModelRoute struct {
+	global.GVA_MODEL
+	Name        string             `json:"name" gorm:"column:name;size:128;uniqueIndex:idx_ai_model_route;comment:逻辑模型名称"`             // 逻辑模型名称
+	AuthorityId uint               `json:"authorityId" gorm:"column:authority_id;uniqueIndex:idx_ai_model_route;comment:角色ID，0表示所有角色"` // 角色ID，0表示所有角色
+	Targets     []ModelRouteTarget `json:"targets" gorm:"column:targets;serializer:json;type:text;comment:路由目标，按顺序故障转移"`               // 路由目标，按顺序故障转移
+	Enabled     bool               `json:"enabled" gorm:"column:enabled;comment:是否启用"`                                                 // 是否启用
+	Description string             `json:"description" gorm:"column:description;comment:备注"`                                           // 备注
+}
+
+// ModelRouteTarget 路由目标
+type ModelRouteTarget struct {
+	Vendor string `json:"vendor" binding:"required"` // 供应商
+	Model  string `json:"model" binding:"required"`  // 模型
+	Weight int    `json:"weight" binding:"min=0"`    // 作为首选目标的权重，0表示只作为备用目标
+}
+
+// TableName 设置表名
+func (AiModelRoute) TableName() string {
+	return "ai_model_routes"
+}
+
+// Plan 返回本次请求依次尝试的目标
+// 首选目标在权重大于0的目标中按权重随机选择，都为0时使用第一个；其余目标按配置顺序作为备用
+// intn 返回 [0,n) 之间的随机数
+func (r AiModelRoute) Plan(intn func(n int) int) []ModelRouteTarget {
+	if len(r.Targets) == 0 {
+		return nil
+	}
+
+	primary, total := 0, 0
+	for _, target := range r.Targets {
+		total += max(target.Weight, 0)
+	}
+	if total > 0 {
+		n := intn(total)
+		for i, target := range r.Targets {
+			if n < max(target.Weight, 0) {
+				primary = i
+				break
+			}
+			n -= max(target.Weight, 0)
+		}
+	}
+
+	plan := make([]ModelRouteTarget, 0, len(r.Targets))
+	plan = append(plan, r.Targets[primary])
+	plan = append(plan, r.Targets[:primary]...)
+	return append(plan, r.Targets[primary+1:]...)
+}
@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModelRoutePlan(t *testing.T) {
+	a := ModelRouteTarget{Vendor: "bedrock", Model: "claude", Weight: 3}
+	b := ModelRouteTarget{Vendor: "claude", Model: "claude", Weight: 1}
+	c := ModelRouteTarget{Vendor: "openai", Model: "gpt-4o"}
+	route := AiModelRoute{Targets: []ModelRouteTarget{a, b, c}}
+
+	tests := []struct {
+		name string
+		n    int
+		want []ModelRouteTarget
+	}{
+		{"命中第一个目标", 2, []ModelRouteTarget{a, b, c}},
+		{"命中第二个目标", 3, []ModelRouteTarget{b, a, c}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := route.Plan(func(n int) int {
+				if n != 4 {
+					t.Fatalf("期望权重总和为4，实际为 %d", n)
+				}
+				return tt.n
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("期望 %+v，实际为 %+v", tt.want, got)
+			}
+		})
+	}
+
+	// 权重都为0时按配置顺序
+	route = AiModelRoute{Targets: []ModelRouteTarget{c, {Vendor: "deepseek", Model: "deepseek-chat"}}}
+	if got := route.Plan(nil); got[0] != c || len(got) != 2 {
+		t.Fatalf("期望首选 %+v，实际为 %+v", c, got)
+	}
+	if got := (AiModelRoute{}).Plan(nil); got != nil {
+		t.Fatalf("没有目标时期望nil，实际为 %+v", got)
+	}
+}
@@ -0,0 +1,41 @@
+package request
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// CreateApiKeyReq 创建访问密钥请求值
+type CreateApiKeyReq struct {
+	Name        string     `json:"name" binding:"required"`   // 密钥名称
+	UserID      uint       `json:"userId" binding:"required"` // 所属用户ID
+	AuthorityId uint       `json:"authorityId"`               // 调用时使用的角色ID，为空时使用用户当前的角色
+	ExpiresAt   *time.Time `json:"expiresAt"`                 // 过期时间，为空表示永不过期
+	Models      []string   `json:"models"`                    // 允许调用的模型，为空表示不限制
+	AllowedIPs  []string   `json:"allowedIps"`                // IP白名单，支持CIDR，为空表示不限制
+	Description string     `json:"description"`               // 备注
+}
+
+// UpdateApiKeyReq 更新访问密钥请求值，密钥本身不可修改
+type UpdateApiKeyReq struct {
+	ID          uint       `json:"id" binding:"required"` // 密钥ID
+	Name        string     `json:"name"`                  // 密钥名称
+	ExpiresAt   *time.Time `json:"expiresAt"`             // 过期时间，为空表示永不过期
+	Models      []string   `json:"models"`                // 允许调用的模型，为空表示不限制
+	AllowedIPs  []string   `json:"allowedIps"`            // IP白名单，支持CIDR，为空表示不限制
+	Description string     `json:"description"`           // 备注
+}
+
+// ApiKeyIdReq 按ID操作访问密钥的请求值
+type ApiKeyIdReq struct {
+	ID uint `json:"id" form:"id" binding:"required"` // 密钥ID
+}
+
+// ApiKeySearch 访问密钥列表查询条件
+type ApiKeySearch struct {
+	UserID         uint   `json:"userId" form:"userId"`                 // 所属用户ID
+	Name           string `json:"name" form:"name"`                     // 密钥名称
+	IncludeRevoked bool   `json:"includeRevoked" form:"includeRevoked"` // 是否包含已吊销的密钥
+	request.PageInfo
+}
@@ -0,0 +1,55 @@
+package request
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// CreateBudgetReq 创建预算请求值
+type CreateBudgetReq struct {
+	Scope            string  `json:"scope" binding:"required,oneof=user authority api_key"` // 适用范围 user/authority/api_key
+	ScopeID          uint    `json:"scopeId" binding:"required"`                            // 用户ID、角色ID或访问密钥ID
+	Period           string  `json:"period" binding:"required,oneof=daily monthly"`         // 周期 daily/monthly
+	TokenLimit       int64   `json:"tokenLimit" binding:"min=0"`                            // 每周期Token上限，0表示不限制
+	CostLimit        float64 `json:"costLimit" binding:"min=0"`                             // 每周期费用上限，0表示不限制
+	Currency         string  `json:"currency"`                                              // 费用上限的币种，为空时使用USD
+	SoftLimitPercent int     `json:"softLimitPercent" binding:"min=0,max=100"`              // 预警百分比，0表示不预警
+	WarnEmail        string  `json:"warnEmail"`                                             // 预警邮件收件人，多个用逗号分隔
+	Enabled          bool    `json:"enabled"`                                               // 是否启用
+	Description      string  `json:"description"`                                           // 备注
+}
+
+// UpdateBudgetReq 更新预算请求值，适用范围、周期和币种不可修改
+type UpdateBudgetReq struct {
+	ID               uint    `json:"id" binding:"required"`                    // 预算ID
+	TokenLimit       int64   `json:"tokenLimit" binding:"min=0"`               // 每周期Token上限，0表示不限制
+	CostLimit        float64 `json:"costLimit" binding:"min=0"`                // 每周期费用上限，0表示不限制
+	SoftLimitPercent int     `json:"softLimitPercent" binding:"min=0,max=100"` // 预警百分比，0表示不预警
+	WarnEmail        string  `json:"warnEmail"`                                // 预警邮件收件人，多个用逗号分隔
+	Enabled          bool    `json:"enabled"`                                  // 是否启用
+	Description      string  `json:"description"`                              // 备注
+}
+
+// BudgetIdReq 按ID操作预算的请求值
+type BudgetIdReq struct {
+	ID uint `json:"id" form:"id" binding:"required"` // 预算ID
+}
+
+// TopUpBudgetReq 临时增加预算额度的请求值
+type TopUpBudgetReq struct {
+	BudgetID  uint      `json:"budgetId" binding:"required"`  // 预算ID
+	Tokens    int64     `json:"tokens" binding:"min=0"`       // 增加的Token上限
+	Cost      float64   `json:"cost" binding:"min=0"`         // 增加的费用上限
+	Currency  string    `json:"currency"`                     // 费用的币种，为空时使用预算的币种，不能与预算的币种不同
+	ExpiresAt time.Time `json:"expiresAt" binding:"required"` // 到期时间
+	Reason    string    `json:"reason"`                       // 原因
+}
+
+// BudgetSearch 预算列表查询条件
+type BudgetSearch struct {
+	Scope   string `json:"scope" form:"scope"`     // 适用范围
+	ScopeID uint   `json:"scopeId" form:"scopeId"` // 用户ID、角色ID或访问密钥ID
+	Period  string `json:"period" form:"period"`   // 周期
+	request.PageInfo
+}
@@ -0,0 +1,10 @@
+package request
+
+// ChatCaller 聊天请求的调用方，用于记录用量和限制可调用的模型
+type ChatCaller struct {
+	RequestID     string   // 请求ID
+	UserID        uint     // 用户ID
+	AuthorityId   uint     // 角色ID
+	ApiKeyID      uint     // 访问密钥ID，使用JWT调用时为0
+	AllowedModels []string // 访问密钥允许调用的模型，为空表示不限制
+}
@@ -0,0 +1,55 @@
+package request
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+
+// CreateLlmCredentialReq 创建LLM凭证请求值，敏感字段为明文，保存时加密
+type CreateLlmCredentialReq struct {
+	Env         string            `json:"env" binding:"required"`    // 运行环境
+	Vendor      string            `json:"vendor" binding:"required"` // 供应商
+	Name        string            `json:"name" binding:"required"`   // 凭证名称
+	Enabled     bool              `json:"enabled"`                   // 是否启用
+	Weight      int               `json:"weight" binding:"min=0"`    // 负载均衡权重
+	QPSLimit    int               `json:"qpsLimit" binding:"min=0"`  // QPS限制，0表示不限制
+	Models      []string          `json:"models"`                    // 支持的模型，为空表示不限制
+	Region      string            `json:"region"`                    // 地域
+	BaseURL     string            `json:"baseUrl"`                   // 接口地址
+	Proxy       string            `json:"proxy"`                     // 代理地址
+	Options     map[string]any    `json:"options"`                   // 其他供应商配置
+	Secrets     map[string]string `json:"secrets"`                   // 敏感字段明文，如 api_key
+	Description string            `json:"description"`               // 备注
+}
+
+// UpdateLlmCredentialReq 更新LLM凭证请求值，环境、供应商和名称不可修改
+// Secrets 中只更新传入且不为空的字段，未传入的敏感字段保持不变
+type UpdateLlmCredentialReq struct {
+	ID          uint              `json:"id" binding:"required"`    // 凭证ID
+	Enabled     bool              `json:"enabled"`                  // 是否启用
+	Weight      int               `json:"weight" binding:"min=0"`   // 负载均衡权重
+	QPSLimit    int               `json:"qpsLimit" binding:"min=0"` // QPS限制，0表示不限制
+	Models      []string          `json:"models"`                   // 支持的模型，为空表示不限制
+	Region      string            `json:"region"`                   // 地域
+	BaseURL     string            `json:"baseUrl"`                  // 接口地址
+	Proxy       string            `json:"proxy"`                    // 代理地址
+	Options     map[string]any    `json:"options"`                  // 其他供应商配置
+	Secrets     map[string]string `json:"secrets"`                  // 需要更新的敏感字段明文
+	Description string            `json:"description"`              // 备注
+}
+
+// LlmCredentialIdReq 按ID操作LLM凭证的请求值
+type LlmCredentialIdReq struct {
+	ID uint `json:"id" form:"id" binding:"required"` // 凭证ID
+}
+
+// TestLlmCredentialReq 测试LLM凭证请求值
+type TestLlmCredentialReq struct {
+	ID    uint   `json:"id" binding:"required"` // 凭证ID
+	Model string `json:"model"`                 // 测试使用的模型，为空时使用凭证的第一个模型
+}
+
+// LlmCredentialSearch LLM凭证列表查询条件
+type LlmCredentialSearch struct {
+	Env    string `json:"env" form:"env"`       // 运行环境
+	Vendor string `json:"vendor" form:"vendor"` // 供应商
+	Name   string `json:"name" form:"name"`     // 凭证名称
+	request.PageInfo
+}
@@ -0,0 +1,41 @@
+package request
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// CreateModelPriceReq 创建模型价格请求值，价格单位为每百万Token
+type CreateModelPriceReq struct {
+	Vendor           string    `json:"vendor"`                           // 供应商，为空表示所有供应商
+	Model            string    `json:"model" binding:"required"`         // 模型
+	EffectiveFrom    time.Time `json:"effectiveFrom" binding:"required"` // 生效时间
+	Currency         string    `json:"currency"`                         // 币种，默认USD
+	InputPrice       float64   `json:"inputPrice" binding:"min=0"`       // 输入价格
+	CachedInputPrice float64   `json:"cachedInputPrice" binding:"min=0"` // 缓存命中的输入价格，0表示按输入价格计算
+	OutputPrice      float64   `json:"outputPrice" binding:"min=0"`      // 输出价格
+	Description      string    `json:"description"`                      // 备注
+}
+
+// UpdateModelPriceReq 更新模型价格请求值，供应商、模型和生效时间不可修改
+type UpdateModelPriceReq struct {
+	ID               uint    `json:"id" binding:"required"`            // 价格ID
+	Currency         string  `json:"currency"`                         // 币种，默认USD
+	InputPrice       float64 `json:"inputPrice" binding:"min=0"`       // 输入价格
+	CachedInputPrice float64 `json:"cachedInputPrice" binding:"min=0"` // 缓存命中的输入价格，0表示按输入价格计算
+	OutputPrice      float64 `json:"outputPrice" binding:"min=0"`      // 输出价格
+	Description      string  `json:"description"`                      // 备注
+}
+
+// ModelPriceIdReq 按ID操作模型价格的请求值
+type ModelPriceIdReq struct {
+	ID uint `json:"id" form:"id" binding:"required"` // 价格ID
+}
+
+// ModelPriceSearch 模型价格列表查询条件
+type ModelPriceSearch struct {
+	Vendor string `json:"vendor" form:"vendor"` // 供应商
+	Model  string `json:"model" form:"model"`   // 模型
+	request.PageInfo
+}
@@ -0,0 +1,35 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// CreateModelRouteReq 创建逻辑模型路由请求值
+type CreateModelRouteReq struct {
+	Name        string                `json:"name" binding:"required"`               // 逻辑模型名称
+	AuthorityId uint                  `json:"authorityId"`                           // 角色ID，0表示所有角色
+	Targets     []ai.ModelRouteTarget `json:"targets" binding:"required,min=1,dive"` // 路由目标，按顺序故障转移
+	Enabled     bool                  `json:"enabled"`                               // 是否启用
+	Description string                `json:"description"`                           // 备注
+}
+
+// UpdateModelRouteReq 更新逻辑模型路由请求值，名称和角色不可修改
+type UpdateModelRouteReq struct {
+	ID          uint                  `json:"id" binding:"required"`                 // 路由ID
+	Targets     []ai.ModelRouteTarget `json:"targets" binding:"required,min=1,dive"` // 路由目标，按顺序故障转移
+	Enabled     bool                  `json:"enabled"`                               // 是否启用
+	Description string                `json:"description"`                           // 备注
+}
+
+// ModelRouteIdReq 按ID操作逻辑模型路由的请求值
+type ModelRouteIdReq struct {
+	ID uint `json:"id" form:"id" binding:"required"` // 路由ID
+}
+
+// ModelRouteSearch 逻辑模型路由列表查询条件
+type ModelRouteSearch struct {
+	Name        string `json:"name" form:"name"`               // 逻辑模型名称
+	AuthorityId *uint  `json:"authorityId" form:"authorityId"` // 角色ID，0表示所有角色
+	request.PageInfo
+}
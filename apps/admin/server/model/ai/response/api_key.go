@@ -0,0 +1,9 @@
+package response
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+
+// CreateApiKeyRes 创建访问密钥响应值
+type CreateApiKeyRes struct {
+	ai.AiApiKey
+	Key string `json:"key"` // 密钥明文，只在创建时返回一次
+}
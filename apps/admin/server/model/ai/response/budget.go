@@ -0,0 +1,17 @@
+package response
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+
+// BudgetRemainingRes 预算在当前周期的用量和剩余额度
+// 上限为0表示不限制，此时剩余额度也为0
+type BudgetRemainingRes struct {
+	Budget          ai.AiBudget        `json:"budget"`          // 预算
+	PeriodKey       string             `json:"periodKey"`       // 当前周期标识
+	UsedTokens      int64              `json:"usedTokens"`      // 已用Token数(含进行中的调用预留的额度)
+	UsedCost        float64            `json:"usedCost"`        // 已用费用(含进行中的调用预留的额度)
+	TokenLimit      int64              `json:"tokenLimit"`      // 当前Token上限(含临时额度)
+	CostLimit       float64            `json:"costLimit"`       // 当前费用上限(含临时额度)
+	RemainingTokens int64              `json:"remainingTokens"` // 剩余Token数
+	RemainingCost   float64            `json:"remainingCost"`   // 剩余费用
+	TopUps          []ai.AiBudgetTopUp `json:"topUps"`          // 生效中的临时额度
+}
@@ -0,0 +1,8 @@
+package response
+
+// ImportLlmCredentialsRes 导入配置文件凭证的结果
+type ImportLlmCredentialsRes struct {
+	Imported int      `json:"imported"` // 导入的凭证数
+	Skipped  int      `json:"skipped"`  // 数据库中已存在而跳过的凭证数
+	Errors   []string `json:"errors"`   // 无法读取的配置文件
+}
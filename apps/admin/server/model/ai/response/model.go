@@ -0,0 +1,30 @@
+package response
+
+import "github.com/gaia-x/server/service/llmadapter"
+
+// ModelListRes 模型列表，与OpenAI的 GET /v1/models 格式一致
+type ModelListRes struct {
+	Object string     `json:"object"` // 固定为 list
+	Data   []ModelRes `json:"data"`   // 模型列表
+}
+
+// ModelRes 模型信息，OpenAI格式的基础上增加供应商、上下文窗口、能力和价格
+type ModelRes struct {
+	ID            string                       `json:"id"`                // 模型名称
+	Object        string                       `json:"object"`            // 固定为 model
+	Created       int64                        `json:"created"`           // 凭证配置的加载时间
+	OwnedBy       string                       `json:"owned_by"`          // 所属供应商，即聊天请求的 provider
+	Vendors       []string                     `json:"vendors"`           // 配置了该模型的所有供应商
+	ContextWindow int                          `json:"context_window"`    // 上下文窗口(Token)，未知时为0
+	Capabilities  llmadapter.ModelCapabilities `json:"capabilities"`      // 模型能力
+	Pricing       *ModelPricing                `json:"pricing,omitempty"` // 当前生效的价格，未配置时不返回
+}
+
+// ModelPricing 模型价格，单位为每百万Token
+type ModelPricing struct {
+	Vendor      string  `json:"vendor"`       // 价格对应的供应商
+	Currency    string  `json:"currency"`     // 币种
+	Input       float64 `json:"input"`        // 输入价格
+	CachedInput float64 `json:"cached_input"` // 缓存命中的输入价格，0表示按输入价格计算
+	Output      float64 `json:"output"`       // 输出价格
+}
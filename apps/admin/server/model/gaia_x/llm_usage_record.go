@@ -0,0 +1,49 @@
+package gaia_x
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 调用状态
+const (
+	LlmUsageStatusSuccess  = "success"  // 调用成功
+	LlmUsageStatusError    = "error"    // 调用失败
+	LlmUsageStatusCanceled = "canceled" // 客户端断开，调用被取消
+)
+
+// LlmUsageRecord LLM调用用量记录表，每次调用(含流式和非流式)写入一条
+type LlmUsageRecord struct {
+	gorm.Model
+	RequestID        string    `json:"request_id" gorm:"column:request_id;size:64;index;comment:请求ID"`              // 请求ID
+	UserID           uint      `json:"user_id" gorm:"column:user_id;index;comment:用户ID"`                            // 用户ID
+	AuthorityId      uint      `json:"authority_id" gorm:"column:authority_id;index;comment:角色ID"`                  // 角色ID
+	ApiKeyID         uint      `json:"api_key_id" gorm:"column:api_key_id;index;comment:访问密钥ID，使用JWT调用时为0"`         // 访问密钥ID，使用JWT调用时为0
+	Vendor           string    `json:"vendor" gorm:"column:vendor;size:32;comment:供应商"`                             // 供应商
+	Credential       string    `json:"credential" gorm:"column:credential;size:128;comment:凭证名称"`                   // 凭证名称
+	ModelName        string    `json:"model" gorm:"column:model;size:128;index;comment:模型"`                         // 模型
+	PromptTokens     int       `json:"prompt_tokens" gorm:"column:prompt_tokens;comment:提示Token数"`                  // 提示Token数
+	CachedTokens     int       `json:"cached_tokens" gorm:"column:cached_tokens;comment:提示中缓存命中的Token数"`            // 提示中缓存命中的Token数
+	CompletionTokens int       `json:"completion_tokens" gorm:"column:completion_tokens;comment:补全Token数"`          // 补全Token数
+	ReasoningTokens  int       `json:"reasoning_tokens" gorm:"column:reasoning_tokens;comment:补全中的推理Token数"`        // 补全中的推理Token数
+	TotalTokens      int       `json:"total_tokens" gorm:"column:total_tokens;comment:总Token数"`                     // 总Token数
+	Cost             float64   `json:"cost" gorm:"column:cost;comment:按模型价格计算的费用，未配置价格时为0"`                         // 按模型价格计算的费用，未配置价格时为0
+	Currency         string    `json:"currency" gorm:"column:currency;size:8;comment:费用币种"`                         // 费用币种
+	PriceID          uint      `json:"price_id" gorm:"column:price_id;comment:计算费用使用的价格ID"`                         // 计算费用使用的价格ID
+	LatencyMs        int64     `json:"latency_ms" gorm:"column:latency_ms;comment:总耗时(毫秒)"`                         // 总耗时(毫秒)
+	FirstTokenMs     int64     `json:"first_token_ms" gorm:"column:first_token_ms;comment:首个分片耗时(毫秒)，非流式为0"`        // 首个分片耗时(毫秒)，非流式为0
+	Stream           bool      `json:"stream" gorm:"column:stream;comment:是否流式"`                                    // 是否流式
+	Status           string    `json:"status" gorm:"column:status;size:16;index;comment:状态 success/error/canceled"` // 状态
+	ErrorKind        string    `json:"error_kind" gorm:"column:error_kind;size:32;comment:错误分类"`                    // 错误分类
+	ErrorMessage     string    `json:"error_message" gorm:"column:error_message;size:512;comment:错误信息"`             // 错误信息
+	Attempts         int       `json:"attempts" gorm:"column:attempts;comment:尝试次数"`                                // 尝试次数
+	Fallback         bool      `json:"fallback" gorm:"column:fallback;comment:是否切换到了备用供应商/模型"`                      // 是否切换到了备用供应商/模型
+	RequestTime      time.Time `json:"request_time" gorm:"column:request_time;index;comment:请求时间"`                  // 请求时间
+	Day              string    `json:"day" gorm:"column:day;size:10;index;comment:请求日期(2006-01-02)，用于按天汇总"`         // 请求日期，用于按天汇总
+}
+
+// TableName 设置表名
+func (l *LlmUsageRecord) TableName() string {
+	return "llm_usage_records"
+}
@@ -0,0 +1,44 @@
+package request
+
+import "time"
+
+// 用量汇总的分组方式
+const (
+	LlmUsageGroupByUser      = "user"      // 按用户
+	LlmUsageGroupByAuthority = "authority" // 按角色
+	LlmUsageGroupByModel     = "model"     // 按模型
+	LlmUsageGroupByDay       = "day"       // 按天
+	LlmUsageGroupByVendor    = "vendor"    // 按供应商
+)
+
+// LlmUsageFilter 用量记录的筛选条件，零值表示不筛选
+type LlmUsageFilter struct {
+	StartTime   time.Time `json:"start_time" form:"start_time"`     // 开始时间
+	EndTime     time.Time `json:"end_time" form:"end_time"`         // 结束时间
+	UserID      uint      `json:"user_id" form:"user_id"`           // 用户ID
+	AuthorityId uint      `json:"authority_id" form:"authority_id"` // 角色ID
+	ApiKeyID    uint      `json:"api_key_id" form:"api_key_id"`     // 访问密钥ID
+	Vendor      string    `json:"vendor" form:"vendor"`             // 供应商
+	Model       string    `json:"model" form:"model"`               // 模型
+}
+
+// GetLlmUsageSummaryReq 获取LLM用量汇总请求
+type GetLlmUsageSummaryReq struct {
+	LlmUsageFilter
+	GroupBy string `json:"group_by" form:"group_by" binding:"required,oneof=user authority model day vendor"` // 分组方式 user/authority/model/day/vendor
+}
+
+// GetLlmCostReportReq 获取LLM费用报表请求
+type GetLlmCostReportReq struct {
+	LlmUsageFilter
+	GroupBy string `json:"group_by" form:"group_by" binding:"required,oneof=user authority model day vendor"` // 分组方式 user/authority/model/day/vendor
+}
+
+// GetLlmUsageRecordListReq 获取LLM用量记录列表请求
+type GetLlmUsageRecordListReq struct {
+	LlmUsageFilter
+	Page      int    `json:"page" form:"page"`             // 页码
+	PageSize  int    `json:"page_size" form:"page_size"`   // 每页数量
+	Status    string `json:"status" form:"status"`         // 状态 success/error/canceled
+	RequestID string `json:"request_id" form:"request_id"` // 请求ID
+}
@@ -0,0 +1,49 @@
+package response
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x"
+
+// LlmUsageSummaryItem LLM用量汇总项
+type LlmUsageSummaryItem struct {
+	GroupKey         string  `json:"group_key"`         // 分组键，按分组方式分别为用户ID、角色ID、模型或日期
+	Requests         int64   `json:"requests"`          // 调用次数
+	SuccessRequests  int64   `json:"success_requests"`  // 成功次数
+	FailedRequests   int64   `json:"failed_requests"`   // 失败次数(含取消)
+	PromptTokens     int64   `json:"prompt_tokens"`     // 提示Token数
+	CompletionTokens int64   `json:"completion_tokens"` // 补全Token数
+	ReasoningTokens  int64   `json:"reasoning_tokens"`  // 补全中的推理Token数
+	TotalTokens      int64   `json:"total_tokens"`      // 总Token数
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`    // 平均耗时(毫秒)
+}
+
+// GetLlmUsageSummaryRes 获取LLM用量汇总响应
+type GetLlmUsageSummaryRes struct {
+	GroupBy string                `json:"group_by"` // 分组方式
+	List    []LlmUsageSummaryItem `json:"list"`     // 汇总列表
+}
+
+// LlmCostReportItem LLM费用报表项，同一分组按币种分别汇总
+type LlmCostReportItem struct {
+	GroupKey         string  `json:"group_key"`         // 分组键
+	Currency         string  `json:"currency"`          // 币种，未配置价格的调用为空
+	Requests         int64   `json:"requests"`          // 调用次数
+	PromptTokens     int64   `json:"prompt_tokens"`     // 提示Token数
+	CachedTokens     int64   `json:"cached_tokens"`     // 缓存命中的Token数
+	CompletionTokens int64   `json:"completion_tokens"` // 补全Token数
+	ReasoningTokens  int64   `json:"reasoning_tokens"`  // 补全中的推理Token数
+	TotalTokens      int64   `json:"total_tokens"`      // 总Token数
+	Cost             float64 `json:"cost"`              // 费用
+}
+
+// GetLlmCostReportRes 获取LLM费用报表响应
+type GetLlmCostReportRes struct {
+	GroupBy string              `json:"group_by"` // 分组方式
+	List    []LlmCostReportItem `json:"list"`     // 报表列表
+}
+
+// GetLlmUsageRecordListRes 获取LLM用量记录列表响应
+type GetLlmUsageRecordListRes struct {
+	List     []gaia_x.LlmUsageRecord `json:"list"`     // 记录列表
+	Total    int64                   `json:"total"`    // 总数
+	Page     int                     `json:"page"`     // 当前页码
+	PageSize int                     `json:"pageSize"` // 每页数量
+}
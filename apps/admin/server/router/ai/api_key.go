@@ -0,0 +1,23 @@
+package ai
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type ApiKeyRouter struct{}
+
+// InitApiKeyRouter 初始化 AI访问密钥管理 路由信息
+func (r *RouterGroup) InitApiKeyRouter(privateGroup, publicGroup *gin.RouterGroup) {
+	apiKeyRouter := privateGroup.Group("aiApiKey").Use(middleware.OperationRecord())
+	apiKeyRouterWithoutRecord := privateGroup.Group("aiApiKey")
+	{
+		apiKeyRouter.POST("createApiKey", ApiKeyApi.CreateApiKey)   // 创建访问密钥
+		apiKeyRouter.PUT("updateApiKey", ApiKeyApi.UpdateApiKey)    // 更新访问密钥
+		apiKeyRouter.POST("revokeApiKey", ApiKeyApi.RevokeApiKey)   // 吊销访问密钥
+		apiKeyRouter.DELETE("deleteApiKey", ApiKeyApi.DeleteApiKey) // 删除访问密钥
+	}
+	{
+		apiKeyRouterWithoutRecord.GET("getApiKeyList", ApiKeyApi.GetApiKeyList) // 获取访问密钥列表
+	}
+}
@@ -0,0 +1,24 @@
+package ai
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type BudgetRouter struct{}
+
+// InitBudgetRouter 初始化 AI预算管理 路由信息
+func (r *RouterGroup) InitBudgetRouter(privateGroup, publicGroup *gin.RouterGroup) {
+	budgetRouter := privateGroup.Group("aiBudget").Use(middleware.OperationRecord())
+	budgetRouterWithoutRecord := privateGroup.Group("aiBudget")
+	{
+		budgetRouter.POST("createBudget", BudgetApi.CreateBudget)   // 创建预算
+		budgetRouter.PUT("updateBudget", BudgetApi.UpdateBudget)    // 更新预算
+		budgetRouter.DELETE("deleteBudget", BudgetApi.DeleteBudget) // 删除预算
+		budgetRouter.POST("topUpBudget", BudgetApi.TopUpBudget)     // 临时增加预算额度
+	}
+	{
+		budgetRouterWithoutRecord.GET("getBudgetList", BudgetApi.GetBudgetList)           // 获取预算列表
+		budgetRouterWithoutRecord.GET("getBudgetRemaining", BudgetApi.GetBudgetRemaining) // 获取预算剩余额度
+	}
+}
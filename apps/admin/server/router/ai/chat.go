@@ -1,14 +1,17 @@
 package ai
 
 import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 type ChatRouter struct{}
 
 func (r *RouterGroup) InitChatRouter(privateGroup, publicGroup *gin.RouterGroup) {
-	v1Router := publicGroup.Group("v1")
+	v1Router := publicGroup.Group("v1").Use(middleware.ApiKeyAuth())
 	{
 		v1Router.POST("/chat/completion", ChatApi.CreateChatCompletion) // 创建聊天完成（支持流式和非流式）
+		v1Router.GET("/models", ModelApi.ListModels)                    // 获取可调用的模型列表
+		v1Router.GET("/models/*id", ModelApi.RetrieveModel)             // 获取模型信息，模型名称可能包含 /
 	}
 }
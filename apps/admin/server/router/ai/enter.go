@@ -5,9 +5,20 @@ import api "github.com/flipped-aurora/gin-vue-admin/server/api/v1"
 type RouterGroup struct {
 	ChatRouter
 	RSARouter
+	ApiKeyRouter
+	BudgetRouter
+	ModelPriceRouter
+	ModelRouteRouter
+	LlmCredentialRouter
 }
 
 var (
-	ChatApi = api.ApiGroupApp.AiApiGroup.ChatApi
-	RSAApi  = api.ApiGroupApp.AiApiGroup.RSAApi
+	ChatApi       = api.ApiGroupApp.AiApiGroup.ChatApi
+	RSAApi        = api.ApiGroupApp.AiApiGroup.RSAApi
+	ApiKeyApi     = api.ApiGroupApp.AiApiGroup.ApiKeyApi
+	BudgetApi     = api.ApiGroupApp.AiApiGroup.BudgetApi
+	PriceApi      = api.ApiGroupApp.AiApiGroup.ModelPriceApi
+	ModelApi      = api.ApiGroupApp.AiApiGroup.ModelApi
+	RouteApi      = api.ApiGroupApp.AiApiGroup.ModelRouteApi
+	CredentialApi = api.ApiGroupApp.AiApiGroup.LlmCredentialApi
 )
@@ -0,0 +1,25 @@
+package ai
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type LlmCredentialRouter struct{}
+
+// InitLlmCredentialRouter 初始化 LLM凭证管理 路由信息
+func (r *RouterGroup) InitLlmCredentialRouter(privateGroup, publicGroup *gin.RouterGroup) {
+	credentialRouter := privateGroup.Group("aiLlmCredential").Use(middleware.OperationRecord())
+	credentialRouterWithoutRecord := privateGroup.Group("aiLlmCredential")
+	{
+		credentialRouter.POST("createLlmCredential", CredentialApi.CreateLlmCredential)   // 创建LLM凭证
+		credentialRouter.PUT("updateLlmCredential", CredentialApi.UpdateLlmCredential)    // 更新LLM凭证
+		credentialRouter.POST("disableLlmCredential", CredentialApi.DisableLlmCredential) // 停用LLM凭证
+		credentialRouter.DELETE("deleteLlmCredential", CredentialApi.DeleteLlmCredential) // 删除LLM凭证
+		credentialRouter.POST("testLlmCredential", CredentialApi.TestLlmCredential)       // 测试LLM凭证
+		credentialRouter.POST("importLlmCredentials", CredentialApi.ImportLlmCredentials) // 导入配置文件中的凭证
+	}
+	{
+		credentialRouterWithoutRecord.GET("getLlmCredentialList", CredentialApi.GetLlmCredentialList) // 获取LLM凭证列表
+	}
+}
@@ -0,0 +1,22 @@
+package ai
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type ModelPriceRouter struct{}
+
+// InitModelPriceRouter 初始化 模型价格管理 路由信息
+func (r *RouterGroup) InitModelPriceRouter(privateGroup, publicGroup *gin.RouterGroup) {
+	priceRouter := privateGroup.Group("aiModelPrice").Use(middleware.OperationRecord())
+	priceRouterWithoutRecord := privateGroup.Group("aiModelPrice")
+	{
+		priceRouter.POST("createModelPrice", PriceApi.CreateModelPrice)   // 创建模型价格
+		priceRouter.PUT("updateModelPrice", PriceApi.UpdateModelPrice)    // 更新模型价格
+		priceRouter.DELETE("deleteModelPrice", PriceApi.DeleteModelPrice) // 删除模型价格
+	}
+	{
+		priceRouterWithoutRecord.GET("getModelPriceList", PriceApi.GetModelPriceList) // 获取模型价格列表
+	}
+}
@@ -0,0 +1,22 @@
+package ai
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type ModelRouteRouter struct{}
+
+// InitModelRouteRouter 初始化 逻辑模型路由管理 路由信息
+func (r *RouterGroup) InitModelRouteRouter(privateGroup, publicGroup *gin.RouterGroup) {
+	routeRouter := privateGroup.Group("aiModelRoute").Use(middleware.OperationRecord())
+	routeRouterWithoutRecord := privateGroup.Group("aiModelRoute")
+	{
+		routeRouter.POST("createModelRoute", RouteApi.CreateModelRoute)   // 创建逻辑模型路由
+		routeRouter.PUT("updateModelRoute", RouteApi.UpdateModelRoute)    // 更新逻辑模型路由
+		routeRouter.DELETE("deleteModelRoute", RouteApi.DeleteModelRoute) // 删除逻辑模型路由
+	}
+	{
+		routeRouterWithoutRecord.GET("getModelRouteList", RouteApi.GetModelRouteList) // 获取逻辑模型路由列表
+	}
+}
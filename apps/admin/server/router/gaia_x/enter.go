@@ -6,10 +6,12 @@ type RouterGroup struct {
 	GaiaXRouter
 	GaiaXVersionRouter
 	GaiaXUsageReportRouter
+	GaiaXLlmUsageRouter
 }
 
 var (
 	userApi              = api.ApiGroupApp.GaiaXApiGroup.UserApi
 	versionApi           = api.ApiGroupApp.GaiaXApiGroup.VersionApi
 	usageReportExtendApi = api.ApiGroupApp.GaiaXApiGroup.UsageReportExtendApi
+	llmUsageApi          = api.ApiGroupApp.GaiaXApiGroup.LlmUsageApi
 )
@@ -0,0 +1,18 @@
+package gaia_x
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type GaiaXLlmUsageRouter struct{}
+
+// InitGaiaXLlmUsageRouter 初始化LLM用量统计路由
+func (r *GaiaXLlmUsageRouter) InitGaiaXLlmUsageRouter(Router *gin.RouterGroup, PublicRouter *gin.RouterGroup) {
+	// 需要权限验证的路由
+	privateRouter := Router.Group("gaia-x/v1/llm-usage")
+	{
+		privateRouter.GET("getUsageSummary", llmUsageApi.GetLlmUsageSummary)       // 获取LLM用量汇总
+		privateRouter.GET("getUsageRecordList", llmUsageApi.GetLlmUsageRecordList) // 获取LLM用量记录列表
+		privateRouter.GET("getCostReport", llmUsageApi.GetLlmCostReport)           // 获取LLM费用报表
+	}
+}
@@ -0,0 +1,221 @@
+package ai
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	aiRes "github.com/flipped-aurora/gin-vue-admin/server/model/ai/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidApiKey      = errors.New("无效的API密钥")
+	ErrApiKeyRevoked      = errors.New("API密钥已吊销")
+	ErrApiKeyExpired      = errors.New("API密钥已过期")
+	ErrApiKeyIPDenied     = errors.New("当前IP不在API密钥的白名单中")
+	ErrApiKeyUserDisabled = errors.New("API密钥所属用户不存在或已被冻结")
+	ErrApiKeyAuthority    = errors.New("API密钥所属用户已不拥有密钥绑定的角色")
+)
+
+// lastUsedInterval 最近使用时间的更新间隔，避免每次请求都写数据库
+const lastUsedInterval = time.Minute
+
+// ApiKeyService AI网关访问密钥服务
+type ApiKeyService struct{}
+
+// CreateApiKey 为用户创建访问密钥，返回的密钥明文只在创建时可见
+func (s *ApiKeyService) CreateApiKey(req aiReq.CreateApiKeyReq) (res aiRes.CreateApiKeyRes, err error) {
+	var user system.SysUser
+	if err = global.GVA_DB.Preload("Authorities").First(&user, req.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return res, errors.New("用户不存在")
+		}
+		return res, err
+	}
+
+	authorityId := req.AuthorityId
+	if authorityId == 0 {
+		authorityId = user.AuthorityId
+	} else if !userHasAuthority(user, authorityId) {
+		return res, fmt.Errorf("用户不拥有角色 %d", authorityId)
+	}
+
+	if err = validateAllowedIPs(req.AllowedIPs); err != nil {
+		return res, err
+	}
+
+	key, err := generateApiKey()
+	if err != nil {
+		return res, err
+	}
+
+	apiKey := ai.AiApiKey{
+		Name:        req.Name,
+		KeyHash:     ai.HashApiKey(key),
+		KeyMask:     maskApiKey(key),
+		UserID:      user.ID,
+		AuthorityId: authorityId,
+		ExpiresAt:   req.ExpiresAt,
+		Models:      req.Models,
+		AllowedIPs:  req.AllowedIPs,
+		Description: req.Description,
+	}
+	if err = global.GVA_DB.Create(&apiKey).Error; err != nil {
+		return res, err
+	}
+
+	return aiRes.CreateApiKeyRes{AiApiKey: apiKey, Key: key}, nil
+}
+
+// UpdateApiKey 更新访问密钥的名称、有效期、模型范围和IP白名单
+func (s *ApiKeyService) UpdateApiKey(req aiReq.UpdateApiKeyReq) error {
+	var apiKey ai.AiApiKey
+	if err := global.GVA_DB.First(&apiKey, req.ID).Error; err != nil {
+		return err
+	}
+	if err := validateAllowedIPs(req.AllowedIPs); err != nil {
+		return err
+	}
+
+	if req.Name != "" {
+		apiKey.Name = req.Name
+	}
+	apiKey.ExpiresAt = req.ExpiresAt
+	apiKey.Models = req.Models
+	apiKey.AllowedIPs = req.AllowedIPs
+	apiKey.Description = req.Description
+
+	// 显式指定更新的字段，清空模型范围、白名单和过期时间时同样生效
+	return global.GVA_DB.Model(&apiKey).
+		Select("name", "expires_at", "models", "allowed_ips", "description").
+		Updates(&apiKey).Error
+}
+
+// RevokeApiKey 吊销访问密钥，吊销后立即失效，记录保留用于审计
+func (s *ApiKeyService) RevokeApiKey(id uint) error {
+	result := global.GVA_DB.Model(&ai.AiApiKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("密钥不存在或已吊销")
+	}
+	return nil
+}
+
+// DeleteApiKey 删除访问密钥
+func (s *ApiKeyService) DeleteApiKey(id uint) error {
+	return global.GVA_DB.Delete(&ai.AiApiKey{}, id).Error
+}
+
+// GetApiKeyList 分页获取访问密钥列表
+func (s *ApiKeyService) GetApiKeyList(info aiReq.ApiKeySearch) (list []ai.AiApiKey, total int64, err error) {
+	db := global.GVA_DB.Model(&ai.AiApiKey{})
+	if info.UserID != 0 {
+		db = db.Where("user_id = ?", info.UserID)
+	}
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if !info.IncludeRevoked {
+		db = db.Where("revoked_at IS NULL")
+	}
+
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// Authenticate 校验访问密钥，返回密钥记录和所属用户
+// 密钥不存在、已吊销、已过期、IP不在白名单中、所属用户被冻结或已不拥有密钥绑定的角色时返回错误
+func (s *ApiKeyService) Authenticate(key, clientIP string) (*ai.AiApiKey, *system.SysUser, error) {
+	var apiKey ai.AiApiKey
+	err := global.GVA_DB.Where("key_hash = ?", ai.HashApiKey(key)).First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidApiKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case apiKey.Revoked():
+		return nil, nil, ErrApiKeyRevoked
+	case apiKey.Expired(now):
+		return nil, nil, ErrApiKeyExpired
+	case !apiKey.AllowIP(clientIP):
+		return nil, nil, ErrApiKeyIPDenied
+	}
+
+	var user system.SysUser
+	if err = global.GVA_DB.Preload("Authorities").First(&user, apiKey.UserID).Error; err != nil || user.Enable != 1 {
+		return nil, nil, ErrApiKeyUserDisabled
+	}
+	// 创建密钥后用户的角色可能被移除，每次都重新校验，避免密钥保留已移除角色的权限
+	if !userHasAuthority(user, apiKey.AuthorityId) {
+		return nil, nil, ErrApiKeyAuthority
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > lastUsedInterval {
+		apiKey.LastUsedAt = &now
+		if err = global.GVA_DB.Model(&apiKey).UpdateColumn("last_used_at", now).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return &apiKey, &user, nil
+}
+
+// generateApiKey 生成 sk- 开头的随机密钥
+func generateApiKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成密钥失败: %w", err)
+	}
+	return ai.ApiKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// maskApiKey 只保留密钥的前后几位，用于在列表中识别密钥
+func maskApiKey(key string) string {
+	return key[:7] + "..." + key[len(key)-4:]
+}
+
+// userHasAuthority 用户是否拥有指定角色
+func userHasAuthority(user system.SysUser, authorityId uint) bool {
+	if user.AuthorityId == authorityId {
+		return true
+	}
+	for _, authority := range user.Authorities {
+		if authority.AuthorityId == authorityId {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAllowedIPs 校验IP白名单中的每一项都是合法的IP或CIDR
+func validateAllowedIPs(allowedIPs []string) error {
+	for _, allowed := range allowedIPs {
+		allowed = strings.TrimSpace(allowed)
+		if _, _, err := net.ParseCIDR(allowed); err == nil {
+			continue
+		}
+		if net.ParseIP(allowed) == nil {
+			return fmt.Errorf("IP白名单格式不正确: %s", allowed)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+)
+
+func TestAuthenticateAuthority(t *testing.T) {
+	db := newUsageTestDB(t)
+	if err := db.AutoMigrate(&ai.AiApiKey{}, &system.SysAuthority{}, &system.SysUser{}); err != nil {
+		t.Fatalf("迁移访问密钥表失败: %v", err)
+	}
+	global.GVA_DB = db
+
+	authorities := []system.SysAuthority{{AuthorityId: 888, AuthorityName: "普通用户"}, {AuthorityId: 9528, AuthorityName: "测试角色"}}
+	user := system.SysUser{Username: "alice", AuthorityId: 888, Enable: 1, Authorities: authorities}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	service := &ApiKeyService{}
+	res, err := service.CreateApiKey(aiReq.CreateApiKeyReq{Name: "test", UserID: user.ID, AuthorityId: 9528})
+	if err != nil {
+		t.Fatalf("创建访问密钥失败: %v", err)
+	}
+	apiKey, _, err := service.Authenticate(res.Key, "127.0.0.1")
+	if err != nil || apiKey.AuthorityId != 9528 {
+		t.Fatalf("用户拥有角色时应通过校验: %v", err)
+	}
+
+	// 移除用户的角色后密钥不再可用
+	if err := db.Model(&user).Association("Authorities").Replace(authorities[:1]); err != nil {
+		t.Fatalf("移除角色失败: %v", err)
+	}
+	if _, _, err = service.Authenticate(res.Key, "127.0.0.1"); !errors.Is(err, ErrApiKeyAuthority) {
+		t.Fatalf("期望 ErrApiKeyAuthority，实际为 %v", err)
+	}
+}
@@ -0,0 +1,381 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	aiRes "github.com/flipped-aurora/gin-vue-admin/server/model/ai/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	emailUtils "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrInsufficientQuota 调用方的预算已用完
+var ErrInsufficientQuota = errors.New("预算额度不足")
+
+// budgetScopeNames 预算适用范围的名称，用于错误信息和预警邮件
+var budgetScopeNames = map[string]string{
+	ai.BudgetScopeUser:      "用户",
+	ai.BudgetScopeAuthority: "角色",
+	ai.BudgetScopeApiKey:    "访问密钥",
+}
+
+// budgetPeriodNames 预算周期的名称
+var budgetPeriodNames = map[string]string{
+	ai.BudgetPeriodDaily:   "每天",
+	ai.BudgetPeriodMonthly: "每月",
+}
+
+// BudgetService AI调用预算服务
+// 调用上游前按预估用量预留调用方的用户、角色和访问密钥预算，调用结束后按实际用量结算差额；
+// 开启 system.use-redis 时用量计数保存在Redis中，否则保存在 ai_budget_usages 表中
+type BudgetService struct{}
+
+// CreateBudget 创建预算
+func (s *BudgetService) CreateBudget(req aiReq.CreateBudgetReq) (budget ai.AiBudget, err error) {
+	if err = budgetScopeExists(req.Scope, req.ScopeID); err != nil {
+		return
+	}
+	budget = ai.AiBudget{
+		Scope:            req.Scope,
+		ScopeID:          req.ScopeID,
+		Period:           req.Period,
+		TokenLimit:       req.TokenLimit,
+		CostLimit:        req.CostLimit,
+		Currency:         normalizeCurrency(req.Currency),
+		SoftLimitPercent: req.SoftLimitPercent,
+		WarnEmail:        req.WarnEmail,
+		Enabled:          req.Enabled,
+		Description:      req.Description,
+	}
+	err = global.GVA_DB.Create(&budget).Error
+	return
+}
+
+// UpdateBudget 更新预算的上限、预警设置和启用状态
+func (s *BudgetService) UpdateBudget(req aiReq.UpdateBudgetReq) error {
+	result := global.GVA_DB.Model(&ai.AiBudget{}).Where("id = ?", req.ID).
+		Select("token_limit", "cost_limit", "soft_limit_percent", "warn_email", "enabled", "description").
+		Updates(ai.AiBudget{
+			TokenLimit:       req.TokenLimit,
+			CostLimit:        req.CostLimit,
+			SoftLimitPercent: req.SoftLimitPercent,
+			WarnEmail:        req.WarnEmail,
+			Enabled:          req.Enabled,
+			Description:      req.Description,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("预算不存在")
+	}
+	return nil
+}
+
+// DeleteBudget 删除预算及其用量和临时额度
+// 直接物理删除，以便之后为同一对象和周期重新创建预算
+func (s *BudgetService) DeleteBudget(id uint) error {
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("budget_id = ?", id).Delete(&ai.AiBudgetTopUp{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("budget_id = ?", id).Delete(&ai.AiBudgetUsage{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&ai.AiBudget{}, id).Error
+	})
+}
+
+// GetBudgetList 分页获取预算列表
+func (s *BudgetService) GetBudgetList(info aiReq.BudgetSearch) (list []ai.AiBudget, total int64, err error) {
+	db := global.GVA_DB.Model(&ai.AiBudget{})
+	if info.Scope != "" {
+		db = db.Where("scope = ?", info.Scope)
+	}
+	if info.ScopeID != 0 {
+		db = db.Where("scope_id = ?", info.ScopeID)
+	}
+	if info.Period != "" {
+		db = db.Where("period = ?", info.Period)
+	}
+
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("id desc").Find(&list).Error
+	return list, total, err
+}
+
+// GetBudgetRemaining 获取预算在当前周期的用量和剩余额度，用量包含进行中的调用预留的额度
+func (s *BudgetService) GetBudgetRemaining(ctx context.Context, id uint) (res aiRes.BudgetRemainingRes, err error) {
+	if err = global.GVA_DB.First(&res.Budget, id).Error; err != nil {
+		return
+	}
+	now := time.Now()
+	res.PeriodKey = res.Budget.PeriodKey(now)
+	if res.UsedTokens, res.UsedCost, err = newBudgetCounter().Usage(ctx, res.Budget, res.PeriodKey); err != nil {
+		return
+	}
+	if err = global.GVA_DB.Where("budget_id = ? AND expires_at > ?", id, now).Order("expires_at").Find(&res.TopUps).Error; err != nil {
+		return
+	}
+
+	limit := budgetLimit{tokens: res.Budget.TokenLimit, cost: res.Budget.CostLimit}
+	for _, topUp := range res.TopUps {
+		limit = limit.withTopUp(res.Budget, topUp)
+	}
+	res.TokenLimit, res.CostLimit = limit.tokens, limit.cost
+	if limit.tokens > 0 {
+		res.RemainingTokens = max(limit.tokens-res.UsedTokens, 0)
+	}
+	if limit.cost > 0 {
+		res.RemainingCost = max(limit.cost-res.UsedCost, 0)
+	}
+	return
+}
+
+// TopUpBudget 临时增加预算额度，到期后自动失效
+// 只对已设置上限的项生效，上限为0(不限制)的项不受影响；费用的币种必须与预算的币种相同
+func (s *BudgetService) TopUpBudget(req aiReq.TopUpBudgetReq, createdBy uint) (topUp ai.AiBudgetTopUp, err error) {
+	if req.Tokens == 0 && req.Cost == 0 {
+		return topUp, errors.New("临时额度的Token数和费用不能同时为0")
+	}
+	if !req.ExpiresAt.After(time.Now()) {
+		return topUp, errors.New("到期时间必须晚于当前时间")
+	}
+	var budget ai.AiBudget
+	if err = global.GVA_DB.First(&budget, req.BudgetID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return topUp, errors.New("预算不存在")
+		}
+		return
+	}
+	currency := normalizeCurrency(budget.Currency)
+	if req.Currency != "" && normalizeCurrency(req.Currency) != currency {
+		return topUp, fmt.Errorf("临时额度的币种 %s 与预算的币种 %s 不一致", normalizeCurrency(req.Currency), currency)
+	}
+	topUp = ai.AiBudgetTopUp{
+		BudgetID:  req.BudgetID,
+		Tokens:    req.Tokens,
+		Cost:      req.Cost,
+		Currency:  currency,
+		ExpiresAt: req.ExpiresAt,
+		Reason:    req.Reason,
+		CreatedBy: createdBy,
+	}
+	err = global.GVA_DB.Create(&topUp).Error
+	return
+}
+
+// ReserveBudget 调用上游前按预估用量预留调用方的所有预算，预留后的用量包含其他进行中的调用
+// 预留前的用量已达到任一预算的Token或费用上限时撤销已预留的额度并返回 ErrInsufficientQuota；
+// 预留是原子累加，并发请求各自计入其他请求的预留，超出上限的部分最多为最后一个放行请求的预估误差。
+// 费用只预留到币种与 currency 相同的预算；读取预算或预留失败时只记录日志并放行，避免统计故障导致AI服务整体不可用
+func (s *BudgetService) ReserveBudget(ctx context.Context, caller aiReq.ChatCaller, tokens int64, cost float64, currency string) (*BudgetReservation, error) {
+	now := time.Now()
+	reservation := &BudgetReservation{caller: caller, tokens: tokens, cost: cost, currency: normalizeCurrency(currency)}
+	budgets, limits, err := callerBudgets(caller, now)
+	if err != nil {
+		global.GVA_LOG.Error("读取预算失败，跳过预算检查", zap.String("requestId", caller.RequestID), zap.Error(err))
+		return reservation, nil
+	}
+
+	counter := newBudgetCounter()
+	for _, budget := range budgets {
+		periodKey := budget.PeriodKey(now)
+		reservedCost := budgetCost(budget, cost, currency)
+		usedTokens, usedCost, err := counter.Add(ctx, budget, periodKey, tokens, reservedCost)
+		if err != nil {
+			global.GVA_LOG.Error("预留预算失败，跳过预算检查", zap.Uint("budgetId", budget.ID), zap.String("requestId", caller.RequestID), zap.Error(err))
+			continue
+		}
+		reservation.budgets = append(reservation.budgets, reservedBudget{AiBudget: budget, limit: limits[budget.ID], periodKey: periodKey})
+
+		// 按预留前的用量判断，预估用量超过剩余额度的请求仍然放行，结算时按实际用量扣减
+		limit := limits[budget.ID]
+		usedTokens, usedCost = usedTokens-tokens, usedCost-reservedCost
+		if limit.tokens > 0 && usedTokens >= limit.tokens {
+			reservation.cancel(ctx)
+			return nil, fmt.Errorf("%w: %s的Token预算已用完(%d/%d)", ErrInsufficientQuota, budgetName(budget), usedTokens, limit.tokens)
+		}
+		if limit.cost > 0 && usedCost >= limit.cost {
+			reservation.cancel(ctx)
+			return nil, fmt.Errorf("%w: %s的费用预算已用完(%.4f/%.4f %s)", ErrInsufficientQuota, budgetName(budget), usedCost, limit.cost, normalizeCurrency(budget.Currency))
+		}
+	}
+	return reservation, nil
+}
+
+// BudgetReservation 调用上游前预留的预算额度，调用结束后通过 Settle 按实际用量结算
+type BudgetReservation struct {
+	caller   aiReq.ChatCaller
+	budgets  []reservedBudget // 预留成功的预算
+	tokens   int64            // 预留的Token数
+	cost     float64          // 预留的费用
+	currency string           // 预留费用的币种
+}
+
+// reservedBudget 已预留的预算及其上限和预留时的周期
+// 调用跨越周期时仍在预留时的周期中结算，避免上一周期的预留无法撤销
+type reservedBudget struct {
+	ai.AiBudget
+	limit     budgetLimit
+	periodKey string
+}
+
+// Settle 按实际用量结算预留的额度，差额原子累加到预留的周期中，用量首次达到预警百分比时发送预警邮件
+// 费用只扣减币种与 currency 相同的预算；调用已经完成，结算失败只记录日志
+func (r *BudgetReservation) Settle(ctx context.Context, tokens int64, cost float64, currency string) {
+	if r == nil {
+		return
+	}
+	counter := newBudgetCounter()
+	for _, budget := range r.budgets {
+		deltaTokens := tokens - r.tokens
+		deltaCost := budgetCost(budget.AiBudget, cost, currency) - budgetCost(budget.AiBudget, r.cost, r.currency)
+		usedTokens, usedCost, err := counter.Add(ctx, budget.AiBudget, budget.periodKey, deltaTokens, deltaCost)
+		if err != nil {
+			global.GVA_LOG.Error("结算预算失败", zap.Uint("budgetId", budget.ID), zap.String("requestId", r.caller.RequestID), zap.Error(err))
+			continue
+		}
+		if budget.WarnEmail == "" || !budget.limit.reachSoftLimit(budget.SoftLimitPercent, usedTokens, usedCost) {
+			continue
+		}
+		first, err := counter.MarkWarned(ctx, budget.AiBudget, budget.periodKey)
+		if err != nil {
+			global.GVA_LOG.Error("标记预算预警失败", zap.Uint("budgetId", budget.ID), zap.Error(err))
+			continue
+		}
+		if first {
+			go sendBudgetWarning(budget.AiBudget, budget.limit, budget.periodKey, usedTokens, usedCost)
+		}
+	}
+}
+
+// cancel 撤销已预留的额度
+func (r *BudgetReservation) cancel(ctx context.Context) {
+	counter := newBudgetCounter()
+	for _, budget := range r.budgets {
+		reservedCost := budgetCost(budget.AiBudget, r.cost, r.currency)
+		if _, _, err := counter.Add(ctx, budget.AiBudget, budget.periodKey, -r.tokens, -reservedCost); err != nil {
+			global.GVA_LOG.Error("撤销预算预留失败", zap.Uint("budgetId", budget.ID), zap.String("requestId", r.caller.RequestID), zap.Error(err))
+		}
+	}
+	r.budgets = nil
+}
+
+// budgetCost 返回计入预算的费用，币种与预算不同的费用不计入
+func budgetCost(budget ai.AiBudget, cost float64, currency string) float64 {
+	if normalizeCurrency(currency) != normalizeCurrency(budget.Currency) {
+		return 0
+	}
+	return cost
+}
+
+// budgetLimit 预算在当前时间的上限(含生效中的临时额度)
+type budgetLimit struct {
+	tokens int64
+	cost   float64
+}
+
+// withTopUp 计入临时额度，不限制的项保持不限制，币种与预算不同的费用不计入
+func (l budgetLimit) withTopUp(budget ai.AiBudget, topUp ai.AiBudgetTopUp) budgetLimit {
+	if budget.TokenLimit > 0 {
+		l.tokens += topUp.Tokens
+	}
+	if budget.CostLimit > 0 {
+		l.cost += budgetCost(budget, topUp.Cost, topUp.Currency)
+	}
+	return l
+}
+
+// reachSoftLimit 判断用量是否达到上限的 percent%
+func (l budgetLimit) reachSoftLimit(percent int, tokens int64, cost float64) bool {
+	if percent <= 0 {
+		return false
+	}
+	if l.tokens > 0 && tokens*100 >= l.tokens*int64(percent) {
+		return true
+	}
+	return l.cost > 0 && cost*100 >= l.cost*float64(percent)
+}
+
+// callerBudgets 获取调用方的用户、角色和访问密钥上启用的预算及其当前上限
+func callerBudgets(caller aiReq.ChatCaller, now time.Time) ([]ai.AiBudget, map[uint]budgetLimit, error) {
+	db := global.GVA_DB.Where("enabled = ?", true)
+	scopes := global.GVA_DB.Where("scope = ? AND scope_id = ?", ai.BudgetScopeUser, caller.UserID).
+		Or("scope = ? AND scope_id = ?", ai.BudgetScopeAuthority, caller.AuthorityId)
+	if caller.ApiKeyID != 0 {
+		scopes = scopes.Or("scope = ? AND scope_id = ?", ai.BudgetScopeApiKey, caller.ApiKeyID)
+	}
+
+	var budgets []ai.AiBudget
+	if err := db.Where(scopes).Find(&budgets).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(budgets) == 0 {
+		return nil, nil, nil
+	}
+
+	limits := make(map[uint]budgetLimit, len(budgets))
+	ids := make([]uint, 0, len(budgets))
+	for _, budget := range budgets {
+		limits[budget.ID] = budgetLimit{tokens: budget.TokenLimit, cost: budget.CostLimit}
+		ids = append(ids, budget.ID)
+	}
+
+	var topUps []ai.AiBudgetTopUp
+	if err := global.GVA_DB.Where("budget_id IN ? AND expires_at > ?", ids, now).Find(&topUps).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, topUp := range topUps {
+		for _, budget := range budgets {
+			if budget.ID == topUp.BudgetID {
+				limits[budget.ID] = limits[budget.ID].withTopUp(budget, topUp)
+			}
+		}
+	}
+	return budgets, limits, nil
+}
+
+// budgetScopeExists 检查预算适用的用户、角色或访问密钥是否存在
+func budgetScopeExists(scope string, scopeID uint) error {
+	var model any
+	switch scope {
+	case ai.BudgetScopeUser:
+		model = &system.SysUser{}
+	case ai.BudgetScopeAuthority:
+		model = &system.SysAuthority{}
+	case ai.BudgetScopeApiKey:
+		model = &ai.AiApiKey{}
+	default:
+		return fmt.Errorf("不支持的预算范围: %s", scope)
+	}
+	err := global.GVA_DB.First(model, scopeID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s %d 不存在", budgetScopeNames[scope], scopeID)
+	}
+	return err
+}
+
+// budgetName 预算的描述，如 "用户1每天"
+func budgetName(budget ai.AiBudget) string {
+	return fmt.Sprintf("%s%d%s", budgetScopeNames[budget.Scope], budget.ScopeID, budgetPeriodNames[budget.Period])
+}
+
+// sendBudgetWarning 通过邮件插件发送预算预警
+func sendBudgetWarning(budget ai.AiBudget, limit budgetLimit, periodKey string, tokens int64, cost float64) {
+	subject := fmt.Sprintf("AI预算预警: %s的用量已达到%d%%", budgetName(budget), budget.SoftLimitPercent)
+	body := fmt.Sprintf("预算ID: %d<br>周期: %s<br>已用Token: %d / %d<br>已用费用: %.4f / %.4f %s<br>上限为0表示不限制。",
+		budget.ID, periodKey, tokens, limit.tokens, cost, limit.cost, normalizeCurrency(budget.Currency))
+	if err := emailUtils.Email(budget.WarnEmail, subject, body); err != nil {
+		global.GVA_LOG.Error("发送预算预警邮件失败", zap.Uint("budgetId", budget.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// budgetCounter 预算周期用量计数器，累加必须是原子操作，多个实例同时扣减时不会丢失用量
+type budgetCounter interface {
+	// Usage 返回预算在周期内的已用Token数和费用
+	Usage(ctx context.Context, budget ai.AiBudget, periodKey string) (tokens int64, cost float64, err error)
+	// Add 累加用量并返回累加后的已用Token数和费用
+	Add(ctx context.Context, budget ai.AiBudget, periodKey string, tokens int64, cost float64) (int64, float64, error)
+	// MarkWarned 标记本周期已发送预警，只有第一次标记返回true
+	MarkWarned(ctx context.Context, budget ai.AiBudget, periodKey string) (bool, error)
+}
+
+// newBudgetCounter 开启 system.use-redis 时使用Redis计数，否则使用数据库计数
+func newBudgetCounter() budgetCounter {
+	if global.GVA_CONFIG.System.UseRedis && global.GVA_REDIS != nil {
+		return redisBudgetCounter{client: global.GVA_REDIS}
+	}
+	return dbBudgetCounter{db: global.GVA_DB}
+}
+
+// dbBudgetCounter 基于 ai_budget_usages 表的计数器，使用 upsert 原子累加
+type dbBudgetCounter struct {
+	db *gorm.DB
+}
+
+func (c dbBudgetCounter) Usage(ctx context.Context, budget ai.AiBudget, periodKey string) (int64, float64, error) {
+	var usage ai.AiBudgetUsage
+	err := c.db.WithContext(ctx).Where("budget_id = ? AND period_key = ?", budget.ID, periodKey).Limit(1).Find(&usage).Error
+	return usage.Tokens, usage.Cost, err
+}
+
+func (c dbBudgetCounter) Add(ctx context.Context, budget ai.AiBudget, periodKey string, tokens int64, cost float64) (int64, float64, error) {
+	table := ai.AiBudgetUsage{}.TableName()
+	usage := ai.AiBudgetUsage{BudgetID: budget.ID, PeriodKey: periodKey, Tokens: tokens, Cost: cost}
+	err := c.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "budget_id"}, {Name: "period_key"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"tokens":     gorm.Expr(table+".tokens + ?", tokens),
+			"cost":       gorm.Expr(table+".cost + ?", cost),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&usage).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	return c.Usage(ctx, budget, periodKey)
+}
+
+func (c dbBudgetCounter) MarkWarned(ctx context.Context, budget ai.AiBudget, periodKey string) (bool, error) {
+	result := c.db.WithContext(ctx).Model(&ai.AiBudgetUsage{}).
+		Where("budget_id = ? AND period_key = ? AND warned = ?", budget.ID, periodKey, false).
+		Update("warned", true)
+	return result.RowsAffected == 1, result.Error
+}
+
+// redisBudgetCounter 基于Redis哈希的计数器，键在周期结束一天后过期
+type redisBudgetCounter struct {
+	client redis.UniversalClient
+}
+
+// budgetKey 预算周期用量的Redis键
+func budgetKey(budget ai.AiBudget, periodKey string) string {
+	return fmt.Sprintf("gva:ai:budget:%d:%s", budget.ID, periodKey)
+}
+
+func (c redisBudgetCounter) Usage(ctx context.Context, budget ai.AiBudget, periodKey string) (int64, float64, error) {
+	values, err := c.client.HMGet(ctx, budgetKey(budget, periodKey), "tokens", "cost").Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	var tokens int64
+	var cost float64
+	if v, ok := values[0].(string); ok {
+		fmt.Sscan(v, &tokens)
+	}
+	if v, ok := values[1].(string); ok {
+		fmt.Sscan(v, &cost)
+	}
+	return tokens, cost, nil
+}
+
+func (c redisBudgetCounter) Add(ctx context.Context, budget ai.AiBudget, periodKey string, tokens int64, cost float64) (int64, float64, error) {
+	key := budgetKey(budget, periodKey)
+	var tokensCmd *redis.IntCmd
+	var costCmd *redis.FloatCmd
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		tokensCmd = pipe.HIncrBy(ctx, key, "tokens", tokens)
+		costCmd = pipe.HIncrByFloat(ctx, key, "cost", cost)
+		pipe.ExpireAt(ctx, key, budget.PeriodEnd(time.Now()).Add(24*time.Hour))
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return tokensCmd.Val(), costCmd.Val(), nil
+}
+
+func (c redisBudgetCounter) MarkWarned(ctx context.Context, budget ai.AiBudget, periodKey string) (bool, error) {
+	expiration := time.Until(budget.PeriodEnd(time.Now()).Add(24 * time.Hour))
+	return c.client.SetNX(ctx, budgetKey(budget, periodKey)+":warned", 1, expiration).Result()
+}
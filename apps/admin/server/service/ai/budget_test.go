@@ -0,0 +1,212 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+)
+
+// newBudgetTestDB 创建内存数据库并迁移预算相关的表
+func newBudgetTestDB(t *testing.T) {
+	t.Helper()
+	db := newUsageTestDB(t)
+	if err := db.AutoMigrate(&ai.AiBudget{}, &ai.AiBudgetUsage{}, &ai.AiBudgetTopUp{}, &system.SysUser{}); err != nil {
+		t.Fatalf("迁移预算表失败: %v", err)
+	}
+	global.GVA_DB = db
+}
+
+func TestBudgetReserveAndSettle(t *testing.T) {
+	newBudgetTestDB(t)
+	ctx := context.Background()
+	service := &BudgetService{}
+	caller := aiReq.ChatCaller{RequestID: "req-1", UserID: 1, AuthorityId: 888, ApiKeyID: 5}
+
+	budgets := []ai.AiBudget{
+		{Scope: ai.BudgetScopeUser, ScopeID: 1, Period: ai.BudgetPeriodDaily, TokenLimit: 100, Enabled: true},
+		{Scope: ai.BudgetScopeAuthority, ScopeID: 888, Period: ai.BudgetPeriodMonthly, TokenLimit: 1000, Enabled: true},
+		{Scope: ai.BudgetScopeApiKey, ScopeID: 6, Period: ai.BudgetPeriodDaily, TokenLimit: 1, Enabled: true},
+		{Scope: ai.BudgetScopeUser, ScopeID: 1, Period: ai.BudgetPeriodMonthly, TokenLimit: 1, Enabled: false},
+	}
+	if err := global.GVA_DB.Create(&budgets).Error; err != nil {
+		t.Fatalf("创建预算失败: %v", err)
+	}
+
+	// 并发的调用各自计入其他调用的预留，预留前已达到上限时拒绝并撤销预留
+	first, err := service.ReserveBudget(ctx, caller, 60, 0, "")
+	if err != nil {
+		t.Fatalf("未使用时不应超出预算: %v", err)
+	}
+	second, err := service.ReserveBudget(ctx, caller, 60, 0, "")
+	if err != nil {
+		t.Fatalf("预留前未达到上限时应放行: %v", err)
+	}
+	if _, err := service.ReserveBudget(ctx, caller, 60, 0, ""); !errors.Is(err, ErrInsufficientQuota) {
+		t.Fatalf("进行中的调用已预留完额度时期望 ErrInsufficientQuota，实际为 %v", err)
+	}
+	res, err := service.GetBudgetRemaining(ctx, budgets[1].ID)
+	if err != nil || res.UsedTokens != 120 {
+		t.Fatalf("拒绝的预留应撤销，角色预算已用 = %d，%v", res.UsedTokens, err)
+	}
+
+	// 按实际用量结算差额
+	first.Settle(ctx, 50, 0, "")
+	second.Settle(ctx, 70, 0, "")
+	if _, err := service.ReserveBudget(ctx, caller, 1, 0, ""); !errors.Is(err, ErrInsufficientQuota) {
+		t.Fatalf("期望 ErrInsufficientQuota，实际为 %v", err)
+	}
+
+	res, err = service.GetBudgetRemaining(ctx, budgets[1].ID)
+	if err != nil {
+		t.Fatalf("获取剩余额度失败: %v", err)
+	}
+	if res.UsedTokens != 120 || res.RemainingTokens != 880 || res.PeriodKey != time.Now().Format("2006-01") {
+		t.Fatalf("角色预算用量不正确: %+v", res)
+	}
+
+	// 其他访问密钥和未启用的预算不扣减
+	var count int64
+	global.GVA_DB.Model(&ai.AiBudgetUsage{}).Where("budget_id IN ?", []uint{budgets[2].ID, budgets[3].ID}).Count(&count)
+	if count != 0 {
+		t.Fatalf("不相关的预算不应扣减，实际有%d条用量", count)
+	}
+
+	// 临时额度到期前计入上限
+	if _, err := service.TopUpBudget(aiReq.TopUpBudgetReq{BudgetID: budgets[0].ID, Tokens: 50, ExpiresAt: time.Now().Add(time.Hour)}, 1); err != nil {
+		t.Fatalf("增加临时额度失败: %v", err)
+	}
+	reservation, err := service.ReserveBudget(ctx, caller, 10, 0, "")
+	if err != nil {
+		t.Fatalf("增加临时额度后不应超出预算: %v", err)
+	}
+	reservation.Settle(ctx, 0, 0, "")
+	res, err = service.GetBudgetRemaining(ctx, budgets[0].ID)
+	if err != nil {
+		t.Fatalf("获取剩余额度失败: %v", err)
+	}
+	if res.TokenLimit != 150 || res.RemainingTokens != 30 || len(res.TopUps) != 1 {
+		t.Fatalf("用户预算剩余额度不正确: %+v", res)
+	}
+
+	if _, err := service.TopUpBudget(aiReq.TopUpBudgetReq{BudgetID: budgets[0].ID, Tokens: 50, ExpiresAt: time.Now().Add(-time.Hour)}, 1); err == nil {
+		t.Fatal("到期时间早于当前时间时应返回错误")
+	}
+
+	if err := service.DeleteBudget(budgets[0].ID); err != nil {
+		t.Fatalf("删除预算失败: %v", err)
+	}
+	if _, err := service.CreateBudget(aiReq.CreateBudgetReq{Scope: ai.BudgetScopeUser, ScopeID: 1, Period: ai.BudgetPeriodDaily}); err == nil || err.Error() != "用户 1 不存在" {
+		t.Fatalf("用户不存在时应返回错误，实际为 %v", err)
+	}
+}
+
+func TestBudgetCurrency(t *testing.T) {
+	newBudgetTestDB(t)
+	ctx := context.Background()
+	service := &BudgetService{}
+	caller := aiReq.ChatCaller{RequestID: "req-1", UserID: 1, AuthorityId: 888}
+
+	if err := global.GVA_DB.Create(&system.SysUser{Username: "budget"}).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	budget, err := service.CreateBudget(aiReq.CreateBudgetReq{Scope: ai.BudgetScopeUser, ScopeID: 1, Period: ai.BudgetPeriodDaily, CostLimit: 1, Currency: "cny", Enabled: true})
+	if err != nil || budget.Currency != "CNY" {
+		t.Fatalf("创建预算失败: %+v %v", budget, err)
+	}
+
+	// 币种不同的费用不计入预算
+	reservation, err := service.ReserveBudget(ctx, caller, 0, 0.8, "USD")
+	if err != nil {
+		t.Fatalf("预留失败: %v", err)
+	}
+	reservation.Settle(ctx, 0, 5, "USD")
+	reservation, err = service.ReserveBudget(ctx, caller, 0, 0.5, "CNY")
+	if err != nil {
+		t.Fatalf("其他币种的费用不应计入预算: %v", err)
+	}
+	reservation.Settle(ctx, 0, 1.2, "cny")
+	if _, err := service.ReserveBudget(ctx, caller, 0, 0, ""); !errors.Is(err, ErrInsufficientQuota) {
+		t.Fatalf("期望 ErrInsufficientQuota，实际为 %v", err)
+	}
+
+	// 临时额度的币种必须与预算相同，未指定时使用预算的币种
+	if _, err := service.TopUpBudget(aiReq.TopUpBudgetReq{BudgetID: budget.ID, Cost: 1, Currency: "USD", ExpiresAt: time.Now().Add(time.Hour)}, 1); err == nil {
+		t.Fatal("临时额度的币种与预算不同时应返回错误")
+	}
+	topUp, err := service.TopUpBudget(aiReq.TopUpBudgetReq{BudgetID: budget.ID, Cost: 1, ExpiresAt: time.Now().Add(time.Hour)}, 1)
+	if err != nil || topUp.Currency != "CNY" {
+		t.Fatalf("增加临时额度失败: %+v %v", topUp, err)
+	}
+	res, err := service.GetBudgetRemaining(ctx, budget.ID)
+	if err != nil {
+		t.Fatalf("获取剩余额度失败: %v", err)
+	}
+	if res.UsedCost != 1.2 || res.CostLimit != 2 {
+		t.Fatalf("预算用量不正确: %+v", res)
+	}
+}
+
+func TestDBBudgetCounterMarkWarned(t *testing.T) {
+	newBudgetTestDB(t)
+	ctx := context.Background()
+	counter := dbBudgetCounter{db: global.GVA_DB}
+	budget := ai.AiBudget{Period: ai.BudgetPeriodDaily}
+	budget.ID = 1
+
+	if _, _, err := counter.Add(ctx, budget, "2026-01-01", 10, 0.5); err != nil {
+		t.Fatalf("累加用量失败: %v", err)
+	}
+	tokens, cost, err := counter.Add(ctx, budget, "2026-01-01", 5, 0.25)
+	if err != nil || tokens != 15 || cost != 0.75 {
+		t.Fatalf("累加结果不正确: %d %v %v", tokens, cost, err)
+	}
+
+	first, err := counter.MarkWarned(ctx, budget, "2026-01-01")
+	if err != nil || !first {
+		t.Fatalf("第一次标记应返回true: %v %v", first, err)
+	}
+	if again, _ := counter.MarkWarned(ctx, budget, "2026-01-01"); again {
+		t.Fatal("重复标记应返回false")
+	}
+}
+
+func TestBudgetPeriod(t *testing.T) {
+	now := time.Date(2026, 12, 31, 15, 4, 5, 0, time.Local)
+	daily := ai.AiBudget{Period: ai.BudgetPeriodDaily}
+	monthly := ai.AiBudget{Period: ai.BudgetPeriodMonthly}
+
+	if key := daily.PeriodKey(now); key != "2026-12-31" {
+		t.Fatalf("每天的周期标识不正确: %s", key)
+	}
+	if key := monthly.PeriodKey(now); key != "2026-12" {
+		t.Fatalf("每月的周期标识不正确: %s", key)
+	}
+	if end := daily.PeriodEnd(now); !end.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("每天的周期结束时间不正确: %v", end)
+	}
+	if end := monthly.PeriodEnd(now); !end.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("每月的周期结束时间不正确: %v", end)
+	}
+}
+
+func TestBudgetSoftLimit(t *testing.T) {
+	limit := budgetLimit{tokens: 100, cost: 10}
+	if limit.reachSoftLimit(0, 100, 10) {
+		t.Fatal("预警百分比为0时不应预警")
+	}
+	if limit.reachSoftLimit(80, 79, 7.9) {
+		t.Fatal("未达到预警百分比时不应预警")
+	}
+	if !limit.reachSoftLimit(80, 80, 0) || !limit.reachSoftLimit(80, 0, 8) {
+		t.Fatal("Token或费用达到预警百分比时应预警")
+	}
+	if (budgetLimit{}).reachSoftLimit(80, 1000, 1000) {
+		t.Fatal("不限制时不应预警")
+	}
+}
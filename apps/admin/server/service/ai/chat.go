@@ -1,42 +1,148 @@
 package ai
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"io"
+	"slices"
+	"time"
+	"unicode/utf8"
 
-	"github.com/flipped-aurora/gin-vue-admin/server/global"
-	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x"
 	"github.com/gaia-x/server/service/llmadapter"
+	"github.com/sashabaranov/go-openai"
 )
 
+// maxUsageErrorLength 用量记录中错误信息的最大长度(字符)
+const maxUsageErrorLength = 500
+
+// defaultReservedCompletionTokens 请求未设置 max_tokens 时每个候选回复预留的输出Token数
+const defaultReservedCompletionTokens = 4096
+
 // ChatService 聊天服务接口
 type ChatService struct{}
 
 // CreateChatCompletion 创建聊天完成
 // 统一的聊天接口，根据req.Stream参数决定是否使用流式响应
-// 如果writer为nil，则返回普通响应；如果writer不为nil，则写入流式响应
-func (s *ChatService) CreateChatCompletion(req llmadapter.ChatRequest, writer io.Writer) (*ai.ChatResponse, error) {
-	// 根据配置选择供应商
-	provider := req.Provider
-	if provider == "" {
-		provider = global.GVA_CONFIG.AI.Provider
-	}
-
-	// 如果是流式响应且writer不为nil
-	if req.Stream && writer != nil {
-		var err error
-		_, err = llmadapter.CreateChatCompletion(req, writer)
+// 如果writer为nil，则返回OpenAI格式的完整响应(n大于1时包含多个候选回复)；如果writer不为nil，则写入流式响应并返回nil
+// ctx 一般为请求上下文，客户端断开后上游供应商的调用会随之取消
+// 请求的模型命中逻辑模型路由时，按路由改写供应商和模型后再调用(见 routeChatRequest)；
+// caller 限制了可调用的模型时按路由后的模型校验，首选模型不允许时返回 llmadapter.ErrModelNotAllowed，不允许的备用模型跳过
+// 调用上游前按预估用量预留调用方的预算(见 estimateChatUsage)，预算已用完时返回 ErrInsufficientQuota；
+// 每次调用上游都会按 caller 记录一条用量，用量异步写入 llm_usage_records 表，并按实际用量结算预留的预算
+func (s *ChatService) CreateChatCompletion(ctx context.Context, caller aiReq.ChatCaller, req llmadapter.ChatRequest, writer io.Writer) (*openai.ChatCompletionResponse, error) {
+	// 按逻辑模型路由选择供应商和模型
+	req = routeChatRequest(caller, req)
+	if writer == nil {
+		req.Stream = false
+	}
+
+	if len(caller.AllowedModels) > 0 {
+		ctx = llmadapter.WithModelFilter(ctx, func(model string) bool {
+			return slices.Contains(caller.AllowedModels, model)
+		})
+	}
+
+	start := time.Now()
+	tokens, cost, currency := estimateChatUsage(req, start)
+	reservation, err := new(BudgetService).ReserveBudget(ctx, caller, tokens, cost, currency)
+	if err != nil {
 		return nil, err
 	}
 
-	//// 非流式响应
-	//switch provider {
-	//case "azure":
-	//	return Azure.CreateChatCompletion(req)
-	//case "deepseek":
-	//	return Deepseek.CreateChatCompletion(req)
-	//default:
-	//	return nil, errors.New("不支持的AI供应商: " + provider)
-	//}
-	return nil, errors.New("暂不支持非流式响应")
+	ctx, info := llmadapter.WithCallInfo(ctx)
+	resp, err := llmadapter.CreateChatCompletion(ctx, req, writer)
+	record := newUsageRecord(caller, req, info, start, err)
+	applyModelPrice(&record)
+	recordUsage(record)
+	// 请求上下文可能已经取消，结算使用不可取消的上下文
+	reservation.Settle(context.WithoutCancel(ctx), int64(record.TotalTokens), record.Cost, record.Currency)
+	return resp, err
+}
+
+// estimateChatUsage 预估请求的Token数和费用，用于调用前预留预算
+// 输入按每4个字符1个Token估算，输出按 max_completion_tokens 或 max_tokens 计算，未设置时为 defaultReservedCompletionTokens，
+// n 大于1时按候选回复数成倍计算；费用按首选供应商和模型的价格计算，没有配置价格时为0
+func estimateChatUsage(req llmadapter.ChatRequest, at time.Time) (tokens int64, cost float64, currency string) {
+	chars := 0
+	for _, message := range req.Messages {
+		chars += utf8.RuneCountInString(message.Content)
+		for _, part := range message.MultiContent {
+			chars += utf8.RuneCountInString(part.Text)
+		}
+	}
+	n := max(req.N, 1)
+	promptTokens := (chars + 3) / 4 * n
+	completionTokens := cmp.Or(req.MaxCompletionTokens, req.MaxTokens, defaultReservedCompletionTokens) * n
+	if price, ok := FindModelPrice(req.Provider, req.Model, at); ok {
+		cost, currency = price.Cost(promptTokens, 0, completionTokens), price.Currency
+	}
+	return int64(promptTokens + completionTokens), cost, currency
+}
+
+// newUsageRecord 根据调用详情生成用量记录
+// 调用失败时 info 可能为空，供应商、凭证和错误分类从 UpstreamError 中获取
+func newUsageRecord(caller aiReq.ChatCaller, req llmadapter.ChatRequest, info *llmadapter.CallInfo, start time.Time, err error) gaia_x.LlmUsageRecord {
+	record := gaia_x.LlmUsageRecord{
+		RequestID:        caller.RequestID,
+		UserID:           caller.UserID,
+		AuthorityId:      caller.AuthorityId,
+		ApiKeyID:         caller.ApiKeyID,
+		Vendor:           info.Provider,
+		Credential:       info.Credential,
+		ModelName:        info.Model,
+		PromptTokens:     info.Usage.PromptTokens,
+		CompletionTokens: info.Usage.CompletionTokens,
+		TotalTokens:      info.Usage.TotalTokens,
+		LatencyMs:        time.Since(start).Milliseconds(),
+		FirstTokenMs:     info.TimeToFirstToken.Milliseconds(),
+		Stream:           req.Stream,
+		Status:           gaia_x.LlmUsageStatusSuccess,
+		Attempts:         info.Attempts,
+		Fallback:         info.Fallback,
+		RequestTime:      start,
+		Day:              start.Format(time.DateOnly),
+	}
+	if details := info.Usage.PromptTokensDetails; details != nil {
+		record.CachedTokens = details.CachedTokens
+	}
+	if details := info.Usage.CompletionTokensDetails; details != nil {
+		record.ReasoningTokens = details.ReasoningTokens
+	}
+	if record.Vendor == "" {
+		record.Vendor = req.Provider
+	}
+	if record.ModelName == "" {
+		record.ModelName = req.Model
+	}
+	if err == nil {
+		return record
+	}
+
+	record.Status = gaia_x.LlmUsageStatusError
+	if errors.Is(err, context.Canceled) {
+		record.Status = gaia_x.LlmUsageStatusCanceled
+	}
+	var upstreamErr *llmadapter.UpstreamError
+	if errors.As(err, &upstreamErr) {
+		if upstreamErr.Provider != "" {
+			record.Vendor = upstreamErr.Provider
+			record.ModelName = upstreamErr.Model
+		}
+		record.Credential = upstreamErr.Credential
+		record.Attempts = upstreamErr.Attempts
+		record.ErrorKind = upstreamErr.Kind.String()
+	}
+	record.ErrorMessage = truncateRunes(err.Error(), maxUsageErrorLength)
+	return record
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
 }
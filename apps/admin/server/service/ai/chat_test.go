@@ -0,0 +1,174 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x"
+	"github.com/gaia-x/server/service/llmadapter"
+	"github.com/sashabaranov/go-openai"
+)
+
+// mockCredentials 模拟供应商的凭证配置，mock-broken 模型返回500，其他模型按规则回复
+const mockCredentials = `environments:
+  test:
+    credentials:
+      - name: mock-chat
+        enabled: true
+        weight: 1
+        rules:
+          - model: mock-broken
+            error: {status: 500}
+          - match: disconnect
+            reply: "连接即将中断"
+            error: {after_chunks: 1}
+          - reply: "你好，我是模拟模型"
+            prompt_tokens: 1000
+            completion_tokens: 500
+`
+
+// useMockProvider 使用模拟供应商的凭证配置，并缩短重试等待时间
+func useMockProvider(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mock.yaml"), []byte(mockCredentials), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	oldPath, oldEnv := llmadapter.LLMConfigPath, llmadapter.ENV
+	llmadapter.LLMConfigPath, llmadapter.ENV = dir, "test"
+	t.Cleanup(func() { llmadapter.LLMConfigPath, llmadapter.ENV = oldPath, oldEnv })
+
+	// 两个路由目标使用同一个凭证，不重试也不熔断，只测试切换到备用目标
+	llmadapter.SetRetryPolicy(llmadapter.RetryPolicy{MaxAttempts: 1, BreakerThreshold: 100, BreakerCooldown: time.Minute})
+	t.Cleanup(func() { llmadapter.SetRetryPolicy(llmadapter.DefaultRetryPolicy()) })
+}
+
+// TestChatCompletionWithMockProvider 通过模拟供应商测试流式和非流式聊天的路由、故障转移、用量记录和预算扣减
+func TestChatCompletionWithMockProvider(t *testing.T) {
+	newBudgetTestDB(t)
+	db := global.GVA_DB
+	if err := db.AutoMigrate(&ai.AiModelRoute{}, &ai.AiModelPrice{}); err != nil {
+		t.Fatalf("迁移路由和价格表失败: %v", err)
+	}
+	invalidateRouteCache()
+	invalidatePriceCache()
+	t.Cleanup(invalidateRouteCache)
+	t.Cleanup(invalidatePriceCache)
+	useMockProvider(t)
+
+	if _, err := (&ModelRouteService{}).CreateModelRoute(aiReq.CreateModelRouteReq{Name: "mock-chat", Enabled: true, Targets: []ai.ModelRouteTarget{
+		{Vendor: "mock", Model: "mock-broken", Weight: 1},
+		{Vendor: "mock", Model: "mock-ok"},
+	}}); err != nil {
+		t.Fatalf("创建路由失败: %v", err)
+	}
+	price, err := (&ModelPriceService{}).CreateModelPrice(aiReq.CreateModelPriceReq{Vendor: "mock", Model: "mock-ok", EffectiveFrom: time.Now().Add(-time.Hour), InputPrice: 1, OutputPrice: 2})
+	if err != nil {
+		t.Fatalf("创建价格失败: %v", err)
+	}
+	budget := ai.AiBudget{Scope: ai.BudgetScopeUser, ScopeID: 1, Period: ai.BudgetPeriodDaily, TokenLimit: 10000, Enabled: true}
+	if err := db.Create(&budget).Error; err != nil {
+		t.Fatalf("创建预算失败: %v", err)
+	}
+	StartUsageRecorder(db, config.UsageConf{BatchSize: 10, FlushInterval: 60000})
+	t.Cleanup(StopUsageRecorder)
+
+	request := func(content string) llmadapter.ChatRequest {
+		return llmadapter.ChatRequest{ChatCompletionRequest: openai.ChatCompletionRequest{
+			Model:    "mock-chat",
+			Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: content}},
+			Stream:   true,
+		}}
+	}
+	service := &ChatService{}
+	caller := aiReq.ChatCaller{RequestID: "req-ok", UserID: 1, AuthorityId: 888}
+
+	// 主目标返回500后切换到备用目标
+	buffer := &bytes.Buffer{}
+	if _, err := service.CreateChatCompletion(context.Background(), caller, request("你好"), buffer); err != nil {
+		t.Fatalf("聊天失败: %v", err)
+	}
+	if output := buffer.String(); !strings.Contains(output, `"content":"你好，我"`) || !strings.HasSuffix(output, "data: [DONE]\n\n") {
+		t.Errorf("流式输出 = %s", output)
+	}
+
+	// 开始输出后连接中断，不再切换
+	caller.RequestID = "req-disconnect"
+	buffer.Reset()
+	if _, err := service.CreateChatCompletion(context.Background(), caller, request("disconnect"), buffer); err == nil {
+		t.Errorf("连接中断时应返回错误")
+	}
+
+	// 非流式请求返回完整响应，n大于1时分别调用后合并
+	caller.RequestID = "req-n"
+	req := request("你好")
+	req.Stream, req.N = false, 2
+	resp, err := service.CreateChatCompletion(context.Background(), caller, req, nil)
+	if err != nil {
+		t.Fatalf("非流式聊天失败: %v", err)
+	}
+	if resp.Object != "chat.completion" || len(resp.Choices) != 2 || resp.Choices[1].Index != 1 ||
+		resp.Choices[1].Message.Content != "你好，我是模拟模型" || resp.Usage.TotalTokens != 3000 {
+		t.Errorf("非流式响应 = %+v", resp)
+	}
+	StopUsageRecorder()
+
+	var records []gaia_x.LlmUsageRecord
+	db.Order("request_id desc").Find(&records)
+	if len(records) != 3 {
+		t.Fatalf("期望3条用量记录，实际为%d", len(records))
+	}
+	if multi := records[1]; multi.RequestID != "req-n" || multi.Stream || multi.TotalTokens != 3000 || multi.Attempts != 4 || multi.Cost != price.Cost(2000, 0, 1000) {
+		t.Errorf("非流式用量记录 = %+v", multi)
+	}
+	records = append(records[:1], records[2])
+	ok := records[0]
+	if ok.RequestID != "req-ok" || ok.Vendor != "mock" || ok.ModelName != "mock-ok" || ok.Credential != "mock-chat" ||
+		ok.Status != gaia_x.LlmUsageStatusSuccess || !ok.Fallback || ok.Attempts != 2 || ok.TotalTokens != 1500 || !ok.Stream {
+		t.Errorf("用量记录 = %+v", ok)
+	}
+	if want := price.Cost(1000, 0, 500); ok.Cost != want || ok.PriceID != price.ID {
+		t.Errorf("费用 = %v(价格%d)，期望 %v", ok.Cost, ok.PriceID, want)
+	}
+	if failed := records[1]; failed.Status != gaia_x.LlmUsageStatusError || failed.ModelName != "mock-ok" || !failed.Fallback {
+		t.Errorf("中断的用量记录 = %+v", failed)
+	}
+
+	res, err := (&BudgetService{}).GetBudgetRemaining(context.Background(), budget.ID)
+	if err != nil {
+		t.Fatalf("获取剩余额度失败: %v", err)
+	}
+	if res.UsedTokens != 4500 {
+		t.Errorf("预算已用 = %d，期望 4500", res.UsedTokens)
+	}
+
+	// 访问密钥的模型白名单按路由后的模型校验，不允许的备用目标跳过
+	caller.RequestID = "req-denied"
+	caller.AllowedModels = []string{"mock-chat"}
+	if _, err := service.CreateChatCompletion(context.Background(), caller, req, nil); !errors.Is(err, llmadapter.ErrModelNotAllowed) {
+		t.Errorf("首选目标不在白名单中时期望 ErrModelNotAllowed，实际为 %v", err)
+	}
+	caller.AllowedModels = []string{"mock-broken"}
+	_, err = service.CreateChatCompletion(context.Background(), caller, req, nil)
+	var upstreamErr *llmadapter.UpstreamError
+	if !errors.As(err, &upstreamErr) || upstreamErr.Model != "mock-broken" {
+		t.Errorf("备用目标不在白名单中时不应切换，实际错误为 %v", err)
+	}
+
+	// 预算已用完时不调用上游
+	caller.RequestID, caller.AllowedModels = "req-quota", nil
+	db.Model(&budget).Update("token_limit", 4500)
+	if _, err := service.CreateChatCompletion(context.Background(), caller, req, nil); !errors.Is(err, ErrInsufficientQuota) {
+		t.Errorf("预算已用完时期望 ErrInsufficientQuota，实际为 %v", err)
+	}
+}
@@ -2,4 +2,10 @@ package ai
 
 type ServiceGroup struct {
 	ChatService
+	ApiKeyService
+	BudgetService
+	ModelPriceService
+	ModelService
+	ModelRouteService
+	LlmCredentialService
 }
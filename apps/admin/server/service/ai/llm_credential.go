@@ -0,0 +1,290 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	aiRes "github.com/flipped-aurora/gin-vue-admin/server/model/ai/response"
+	"github.com/gaia-x/server/service/llmadapter"
+	"go.uber.org/zap"
+)
+
+// LlmCredentialService LLM凭证管理服务
+// 凭证修改后立即重新加载本实例的凭证缓存，其他实例按 initialize.LLMAdapter 中的间隔定期重新加载
+type LlmCredentialService struct{}
+
+// CreateLlmCredential 创建LLM凭证，敏感字段加密后保存
+func (s *LlmCredentialService) CreateLlmCredential(req aiReq.CreateLlmCredentialReq) (cred ai.AiLlmCredential, err error) {
+	if !slices.Contains(llmadapter.CredentialVendors(), req.Vendor) {
+		return cred, fmt.Errorf("%w: %s", llmadapter.ErrUnsupportedProvider, req.Vendor)
+	}
+	secrets, err := encryptSecrets(req.Vendor, nil, req.Secrets)
+	if err != nil {
+		return
+	}
+	cred = ai.AiLlmCredential{
+		Env:         strings.TrimSpace(req.Env),
+		Vendor:      req.Vendor,
+		Name:        strings.TrimSpace(req.Name),
+		Enabled:     req.Enabled,
+		Weight:      req.Weight,
+		QPSLimit:    req.QPSLimit,
+		Models:      req.Models,
+		Region:      req.Region,
+		BaseURL:     req.BaseURL,
+		Proxy:       req.Proxy,
+		Options:     req.Options,
+		Secrets:     secrets,
+		Description: req.Description,
+	}
+	if err = global.GVA_DB.Create(&cred).Error; err != nil {
+		return
+	}
+	_ = cred.AfterFind(nil)
+	return cred, reloadLlmCredentials()
+}
+
+// UpdateLlmCredential 更新LLM凭证，环境、供应商和名称不可修改；未传入的敏感字段保持不变
+func (s *LlmCredentialService) UpdateLlmCredential(req aiReq.UpdateLlmCredentialReq) error {
+	var cred ai.AiLlmCredential
+	if err := global.GVA_DB.First(&cred, req.ID).Error; err != nil {
+		return errors.New("凭证不存在")
+	}
+	secrets, err := encryptSecrets(cred.Vendor, cred.Secrets, req.Secrets)
+	if err != nil {
+		return err
+	}
+	err = global.GVA_DB.Model(&cred).
+		Select("enabled", "weight", "qps_limit", "models", "region", "base_url", "proxy", "options", "secrets", "description").
+		Updates(ai.AiLlmCredential{
+			Enabled:     req.Enabled,
+			Weight:      req.Weight,
+			QPSLimit:    req.QPSLimit,
+			Models:      req.Models,
+			Region:      req.Region,
+			BaseURL:     req.BaseURL,
+			Proxy:       req.Proxy,
+			Options:     req.Options,
+			Secrets:     secrets,
+			Description: req.Description,
+		}).Error
+	if err != nil {
+		return err
+	}
+	return reloadLlmCredentials()
+}
+
+// DisableLlmCredential 停用LLM凭证
+func (s *LlmCredentialService) DisableLlmCredential(id uint) error {
+	result := global.GVA_DB.Model(&ai.AiLlmCredential{}).Where("id = ?", id).Update("enabled", false)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("凭证不存在")
+	}
+	return reloadLlmCredentials()
+}
+
+// DeleteLlmCredential 删除LLM凭证，直接物理删除以便重新创建同名凭证
+// 删除后配置文件中的同名凭证重新生效
+func (s *LlmCredentialService) DeleteLlmCredential(id uint) error {
+	if err := global.GVA_DB.Unscoped().Delete(&ai.AiLlmCredential{}, id).Error; err != nil {
+		return err
+	}
+	return reloadLlmCredentials()
+}
+
+// GetLlmCredentialList 分页获取LLM凭证列表
+func (s *LlmCredentialService) GetLlmCredentialList(info aiReq.LlmCredentialSearch) (list []ai.AiLlmCredential, total int64, err error) {
+	db := global.GVA_DB.Model(&ai.AiLlmCredential{})
+	if info.Env != "" {
+		db = db.Where("env = ?", info.Env)
+	}
+	if info.Vendor != "" {
+		db = db.Where("vendor = ?", info.Vendor)
+	}
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("env, vendor, name").Find(&list).Error
+	return list, total, err
+}
+
+// TestLlmCredential 使用凭证调用一次供应商，检查凭证是否可用
+// 凭证不需要启用，也不需要属于当前环境
+func (s *LlmCredentialService) TestLlmCredential(ctx context.Context, req aiReq.TestLlmCredentialReq) error {
+	var cred ai.AiLlmCredential
+	if err := global.GVA_DB.First(&cred, req.ID).Error; err != nil {
+		return errors.New("凭证不存在")
+	}
+	return llmadapter.TestCredential(ctx, credentialConfig(cred), req.Model)
+}
+
+// ImportLlmCredentials 将配置目录中所有供应商配置文件的凭证导入数据库
+// 敏感字段保持配置文件中的密文；数据库中已存在的同名凭证跳过，可以重复执行
+func (s *LlmCredentialService) ImportLlmCredentials() (res aiRes.ImportLlmCredentialsRes, err error) {
+	res.Errors = []string{}
+	for _, vendor := range llmadapter.CredentialVendors() {
+		configs, readErr := llmadapter.ReadCredentialFile(llmadapter.LLMConfigPath, vendor)
+		if readErr != nil {
+			res.Errors = append(res.Errors, readErr.Error())
+			continue
+		}
+		for _, config := range configs {
+			var count int64
+			if err = global.GVA_DB.Model(&ai.AiLlmCredential{}).
+				Where("env = ? AND vendor = ? AND name = ?", config.Env, config.Vendor, config.Name).
+				Count(&count).Error; err != nil {
+				return
+			}
+			if count > 0 {
+				res.Skipped++
+				continue
+			}
+			cred := ai.AiLlmCredential{
+				Env:         config.Env,
+				Vendor:      config.Vendor,
+				Name:        config.Name,
+				Enabled:     config.Enabled,
+				Weight:      config.Weight,
+				QPSLimit:    config.QPSLimit,
+				Models:      config.Models,
+				Region:      config.Region,
+				BaseURL:     config.BaseURL,
+				Proxy:       config.Proxy,
+				Options:     config.Options,
+				Secrets:     config.Secrets,
+				Description: config.Description,
+			}
+			if err = global.GVA_DB.Create(&cred).Error; err != nil {
+				return
+			}
+			res.Imported++
+		}
+	}
+	if res.Imported > 0 {
+		err = reloadLlmCredentials()
+	}
+	return
+}
+
+// RotateLlmCredentials 使用密钥环的当前密钥重新加密数据库中所有凭证的敏感字段
+// 无法解密的字段保持不变并记录在 Skipped 中；密文变化不影响凭证内容，运行中的实例不需要重新加载
+func (s *LlmCredentialService) RotateLlmCredentials(keyring *llmadapter.Keyring) (result llmadapter.RotateResult, err error) {
+	var creds []ai.AiLlmCredential
+	if err = global.GVA_DB.Order("id").Find(&creds).Error; err != nil {
+		return
+	}
+	for _, cred := range creds {
+		secrets, rotated := make(map[string]string, len(cred.Secrets)), 0
+		for field, value := range cred.Secrets {
+			secrets[field] = value
+			if keyring.IsCurrent(value) {
+				continue
+			}
+			if secrets[field], err = keyring.Reencrypt(value); err != nil {
+				secrets[field], err = value, nil
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s/%s/%s.%s", cred.Vendor, cred.Env, cred.Name, field))
+				continue
+			}
+			rotated++
+		}
+		if rotated == 0 {
+			continue
+		}
+		if err = global.GVA_DB.Model(&cred).Select("secrets").Updates(ai.AiLlmCredential{Secrets: secrets}).Error; err != nil {
+			return result, fmt.Errorf("保存凭证 %s 失败: %v", cred.Name, err)
+		}
+		result.Rotated += rotated
+	}
+	return result, nil
+}
+
+// LlmCredentialSource 从数据库读取凭证，作为 llmadapter 配置文件以外的凭证来源
+type LlmCredentialSource struct{}
+
+// Credentials 实现 llmadapter.CredentialSource，返回供应商所有环境的凭证
+func (LlmCredentialSource) Credentials(vendor string) ([]byte, error) {
+	var creds []ai.AiLlmCredential
+	if err := global.GVA_DB.Where("vendor = ?", vendor).Order("id").Find(&creds).Error; err != nil {
+		return nil, err
+	}
+	if len(creds) == 0 {
+		return nil, nil
+	}
+	configs := make([]llmadapter.CredentialConfig, len(creds))
+	for i, cred := range creds {
+		configs[i] = credentialConfig(cred)
+	}
+	return llmadapter.MarshalCredentials(configs)
+}
+
+// credentialConfig 转换为 llmadapter 的凭证配置
+func credentialConfig(cred ai.AiLlmCredential) llmadapter.CredentialConfig {
+	return llmadapter.CredentialConfig{
+		Env:    cred.Env,
+		Vendor: cred.Vendor,
+		CredentialBase: llmadapter.CredentialBase{
+			Name:        cred.Name,
+			Enabled:     cred.Enabled,
+			Weight:      cred.Weight,
+			QPSLimit:    cred.QPSLimit,
+			Description: cred.Description,
+			Models:      cred.Models,
+		},
+		Region:  cred.Region,
+		BaseURL: cred.BaseURL,
+		Proxy:   cred.Proxy,
+		Secrets: cred.Secrets,
+		Options: cred.Options,
+	}
+}
+
+// encryptSecrets 加密敏感字段明文并与已保存的密文合并，值为空的字段保持不变
+func encryptSecrets(vendor string, current, plain map[string]string) (map[string]string, error) {
+	fields := llmadapter.SecretFields(vendor)
+	secrets := make(map[string]string, len(fields))
+	for field, value := range current {
+		secrets[field] = value
+	}
+	if len(plain) == 0 {
+		return secrets, nil
+	}
+
+	encrypt, _, err := llmadapter.InitRSAKeyManager()
+	if err != nil {
+		return nil, fmt.Errorf("初始化RSA密钥管理器失败: %v", err)
+	}
+	for field, value := range plain {
+		if !slices.Contains(fields, field) {
+			return nil, fmt.Errorf("供应商 %s 的敏感字段只能是 %s", vendor, strings.Join(fields, "、"))
+		}
+		if value == "" {
+			continue
+		}
+		if secrets[field], err = encrypt(value); err != nil {
+			return nil, fmt.Errorf("加密 %s 失败: %v", field, err)
+		}
+	}
+	return secrets, nil
+}
+
+// reloadLlmCredentials 重新加载凭证缓存，修改后的凭证无效时返回原因，原有凭证继续生效
+func reloadLlmCredentials() error {
+	if err := llmadapter.ReloadCredentials(); err != nil {
+		global.GVA_LOG.Warn("重新加载LLM凭证失败", zap.Error(err))
+		return fmt.Errorf("凭证已保存，但重新加载失败，原有凭证继续生效: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/gaia-x/server/service/llmadapter"
+)
+
+func TestLlmCredentialService(t *testing.T) {
+	db := newUsageTestDB(t)
+	if err := db.AutoMigrate(&ai.AiLlmCredential{}); err != nil {
+		t.Fatalf("迁移凭证表失败: %v", err)
+	}
+	global.GVA_DB = db
+
+	// 使用临时配置目录，配置文件中的密文直接导入
+	_, decrypt, err := llmadapter.InitRSAKeyManager()
+	if err != nil {
+		t.Fatalf("初始化RSA密钥管理器失败: %v", err)
+	}
+	encrypted, err := encryptSecrets("claude", nil, map[string]string{"api_key": "file-key"})
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	dir := t.TempDir()
+	content := "environments:\n  test:\n    credentials:\n      - name: file\n        api_key: \"" + encrypted["api_key"] + "\"\n        enabled: true\n        weight: 1\n        models: [claude-3-5-sonnet]\n"
+	if err := os.WriteFile(filepath.Join(dir, "claude.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	oldPath, oldEnv := llmadapter.LLMConfigPath, llmadapter.ENV
+	llmadapter.LLMConfigPath, llmadapter.ENV = dir, "test"
+	t.Cleanup(func() { llmadapter.LLMConfigPath, llmadapter.ENV = oldPath, oldEnv })
+
+	service := &LlmCredentialService{}
+	res, err := service.ImportLlmCredentials()
+	if err != nil || res.Imported != 1 {
+		t.Fatalf("导入结果 = %+v, %v", res, err)
+	}
+	if res, _ = service.ImportLlmCredentials(); res.Imported != 0 || res.Skipped != 1 {
+		t.Errorf("重复导入应跳过已存在的凭证: %+v", res)
+	}
+
+	// 敏感字段加密保存，不在JSON中返回
+	if _, err := service.CreateLlmCredential(aiReq.CreateLlmCredentialReq{
+		Env: "test", Vendor: "claude", Name: "db", Weight: 1, Models: []string{"claude-3-5-sonnet"},
+		Secrets: map[string]string{"region": "x"},
+	}); err == nil {
+		t.Errorf("非敏感字段不能作为Secrets保存")
+	}
+	cred, err := service.CreateLlmCredential(aiReq.CreateLlmCredentialReq{
+		Env: "test", Vendor: "claude", Name: "db", Enabled: true, Weight: 1, Models: []string{"claude-3-5-sonnet"},
+		Secrets: map[string]string{"api_key": "db-key"},
+	})
+	if err != nil {
+		t.Fatalf("创建凭证失败: %v", err)
+	}
+	if cred.Secrets["api_key"] == "db-key" || strings.Join(cred.SecretFields, ",") != "api_key" {
+		t.Errorf("敏感字段 = %+v, %v", cred.Secrets, cred.SecretFields)
+	}
+	if plain, err := decrypt(cred.Secrets["api_key"]); err != nil || plain != "db-key" {
+		t.Errorf("解密后 = %q, %v", plain, err)
+	}
+
+	// 未传入的敏感字段保持不变
+	if err := service.UpdateLlmCredential(aiReq.UpdateLlmCredentialReq{ID: cred.ID, Enabled: true, Weight: 3, Models: []string{"claude-3-5-sonnet"}}); err != nil {
+		t.Fatalf("更新凭证失败: %v", err)
+	}
+	var updated ai.AiLlmCredential
+	db.First(&updated, cred.ID)
+	if updated.Weight != 3 || updated.Secrets["api_key"] != cred.Secrets["api_key"] {
+		t.Errorf("更新后 = %+v", updated)
+	}
+
+	// 数据库中的凭证与配置文件一起生效
+	models := llmadapter.ListModels()
+	if len(models) != 1 || models[0].Vendor != "claude" {
+		t.Errorf("模型列表 = %+v", models)
+	}
+	if err := service.DisableLlmCredential(cred.ID); err != nil {
+		t.Fatalf("停用凭证失败: %v", err)
+	}
+	list, total, err := service.GetLlmCredentialList(aiReq.LlmCredentialSearch{Vendor: "claude"})
+	if err != nil || total != 2 || list[0].Name != "db" || list[0].Enabled {
+		t.Errorf("凭证列表 = %+v, %d, %v", list, total, err)
+	}
+
+	data, err := LlmCredentialSource{}.Credentials("claude")
+	if err != nil || !strings.Contains(string(data), "name: db") || strings.Contains(string(data), "db-key") {
+		t.Errorf("凭证来源 = %s, %v", data, err)
+	}
+	if data, _ := (LlmCredentialSource{}).Credentials("openai"); data != nil {
+		t.Errorf("没有凭证的供应商应返回nil: %s", data)
+	}
+}
+
+func TestRotateLlmCredentials(t *testing.T) {
+	db := newUsageTestDB(t)
+	if err := db.AutoMigrate(&ai.AiLlmCredential{}); err != nil {
+		t.Fatalf("迁移凭证表失败: %v", err)
+	}
+	global.GVA_DB = db
+
+	keyDir := t.TempDir()
+	if _, err := llmadapter.AddKey(keyDir); err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	oldKeyring, err := llmadapter.LoadKeyring(keyDir)
+	if err != nil {
+		t.Fatalf("加载密钥失败: %v", err)
+	}
+	oldCipher, _ := oldKeyring.Encrypt("sk-db")
+	db.Create(&ai.AiLlmCredential{Env: "test", Vendor: "claude", Name: "a", Secrets: map[string]string{"api_key": oldCipher}})
+	db.Create(&ai.AiLlmCredential{Env: "test", Vendor: "claude", Name: "b", Secrets: map[string]string{"api_key": "YOUR_API_KEY_HERE"}})
+
+	if _, err := llmadapter.AddKey(keyDir); err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	keyring, err := llmadapter.LoadKeyring(keyDir)
+	if err != nil {
+		t.Fatalf("加载密钥失败: %v", err)
+	}
+	result, err := (&LlmCredentialService{}).RotateLlmCredentials(keyring)
+	if err != nil || result.Rotated != 1 || len(result.Skipped) != 1 || result.Skipped[0] != "claude/test/b.api_key" {
+		t.Fatalf("轮换结果 = %+v, %v", result, err)
+	}
+
+	var cred ai.AiLlmCredential
+	db.Where("name = ?", "a").First(&cred)
+	if !keyring.IsCurrent(cred.Secrets["api_key"]) {
+		t.Errorf("密文未使用新密钥: %s", cred.Secrets["api_key"])
+	}
+	if plain, err := keyring.Decrypt(cred.Secrets["api_key"]); err != nil || plain != "sk-db" {
+		t.Errorf("解密后 = %q, %v", plain, err)
+	}
+}
@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"errors"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiRes "github.com/flipped-aurora/gin-vue-admin/server/model/ai/response"
+	"github.com/gaia-x/server/service/llmadapter"
+)
+
+// ErrModelNotFound 模型未被启用的凭证配置，或调用方无权调用
+var ErrModelNotFound = errors.New("模型不存在")
+
+// ModelService 可调用模型查询服务
+type ModelService struct{}
+
+// ListModels 返回当前环境启用凭证配置的模型，apiKey 不为nil时只返回该密钥允许调用的模型
+func (s *ModelService) ListModels(apiKey *ai.AiApiKey) aiRes.ModelListRes {
+	return listModels(llmadapter.DefaultCredentialStore(), apiKey, time.Now())
+}
+
+// GetModel 返回指定模型的信息
+func (s *ModelService) GetModel(apiKey *ai.AiApiKey, id string) (aiRes.ModelRes, error) {
+	for _, model := range s.ListModels(apiKey).Data {
+		if model.ID == id {
+			return model, nil
+		}
+	}
+	return aiRes.ModelRes{}, ErrModelNotFound
+}
+
+// listModels 从凭证缓存中列出模型并附加 at 时生效的价格
+func listModels(store *llmadapter.CredentialStore, apiKey *ai.AiApiKey, at time.Time) aiRes.ModelListRes {
+	created := store.LoadedAt().Unix()
+	res := aiRes.ModelListRes{Object: "list", Data: []aiRes.ModelRes{}}
+	for _, info := range store.Models() {
+		if apiKey != nil && !apiKey.AllowModel(info.ID) {
+			continue
+		}
+		res.Data = append(res.Data, aiRes.ModelRes{
+			ID:            info.ID,
+			Object:        "model",
+			Created:       created,
+			OwnedBy:       info.Vendor,
+			Vendors:       info.Vendors,
+			ContextWindow: info.ContextWindow,
+			Capabilities:  info.Capabilities,
+			Pricing:       modelPricing(info, at),
+		})
+	}
+	return res
+}
+
+// modelPricing 按供应商顺序查找第一个配置了价格的供应商
+func modelPricing(info llmadapter.ModelInfo, at time.Time) *aiRes.ModelPricing {
+	for _, vendor := range info.Vendors {
+		price, ok := FindModelPrice(vendor, info.ID, at)
+		if !ok {
+			continue
+		}
+		return &aiRes.ModelPricing{
+			Vendor:      vendor,
+			Currency:    price.Currency,
+			Input:       price.InputPrice,
+			CachedInput: price.CachedInputPrice,
+			Output:      price.OutputPrice,
+		}
+	}
+	return nil
+}
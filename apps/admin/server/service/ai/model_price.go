@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x"
+	"go.uber.org/zap"
+)
+
+// priceCacheTTL 模型价格缓存的有效期，多实例部署时其他实例修改价格后最多延迟这么久生效
+const priceCacheTTL = time.Minute
+
+// priceCache 模型价格缓存，避免每次调用都查询价格表
+var priceCache struct {
+	sync.RWMutex
+	prices   []ai.AiModelPrice
+	loadedAt time.Time
+}
+
+// ModelPriceService 模型价格服务
+type ModelPriceService struct{}
+
+// CreateModelPrice 创建模型价格
+func (s *ModelPriceService) CreateModelPrice(req aiReq.CreateModelPriceReq) (price ai.AiModelPrice, err error) {
+	price = ai.AiModelPrice{
+		Vendor:           req.Vendor,
+		Model:            req.Model,
+		EffectiveFrom:    req.EffectiveFrom,
+		Currency:         normalizeCurrency(req.Currency),
+		InputPrice:       req.InputPrice,
+		CachedInputPrice: req.CachedInputPrice,
+		OutputPrice:      req.OutputPrice,
+		Description:      req.Description,
+	}
+	if err = global.GVA_DB.Create(&price).Error; err != nil {
+		return
+	}
+	invalidatePriceCache()
+	return
+}
+
+// UpdateModelPrice 更新模型价格，供应商、模型和生效时间不可修改
+// 调价应新增一条生效时间更晚的价格，以免改变历史记录的费用口径
+func (s *ModelPriceService) UpdateModelPrice(req aiReq.UpdateModelPriceReq) error {
+	result := global.GVA_DB.Model(&ai.AiModelPrice{}).Where("id = ?", req.ID).
+		Select("currency", "input_price", "cached_input_price", "output_price", "description").
+		Updates(ai.AiModelPrice{
+			Currency:         normalizeCurrency(req.Currency),
+			InputPrice:       req.InputPrice,
+			CachedInputPrice: req.CachedInputPrice,
+			OutputPrice:      req.OutputPrice,
+			Description:      req.Description,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("价格不存在")
+	}
+	invalidatePriceCache()
+	return nil
+}
+
+// DeleteModelPrice 删除模型价格，直接物理删除以便重新创建相同生效时间的价格
+func (s *ModelPriceService) DeleteModelPrice(id uint) error {
+	if err := global.GVA_DB.Unscoped().Delete(&ai.AiModelPrice{}, id).Error; err != nil {
+		return err
+	}
+	invalidatePriceCache()
+	return nil
+}
+
+// GetModelPriceList 分页获取模型价格列表
+func (s *ModelPriceService) GetModelPriceList(info aiReq.ModelPriceSearch) (list []ai.AiModelPrice, total int64, err error) {
+	db := global.GVA_DB.Model(&ai.AiModelPrice{})
+	if info.Vendor != "" {
+		db = db.Where("vendor = ?", info.Vendor)
+	}
+	if info.Model != "" {
+		db = db.Where("model LIKE ?", "%"+info.Model+"%")
+	}
+
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("vendor, model, effective_from desc").Find(&list).Error
+	return list, total, err
+}
+
+// FindModelPrice 查找供应商和模型在 at 时生效的价格，没有配置价格时返回false
+// 指定供应商的价格优先于供应商为空的价格，同一优先级取生效时间最晚的一条
+func FindModelPrice(vendor, model string, at time.Time) (ai.AiModelPrice, bool) {
+	var found ai.AiModelPrice
+	ok := false
+	for _, price := range loadPrices() {
+		if price.Model != model || (price.Vendor != vendor && price.Vendor != "") || price.EffectiveFrom.After(at) {
+			continue
+		}
+		if !ok || pricePreferred(price, found) {
+			found, ok = price, true
+		}
+	}
+	return found, ok
+}
+
+// pricePreferred 判断价格 a 是否优先于 b
+func pricePreferred(a, b ai.AiModelPrice) bool {
+	if (a.Vendor != "") != (b.Vendor != "") {
+		return a.Vendor != ""
+	}
+	return a.EffectiveFrom.After(b.EffectiveFrom)
+}
+
+// applyModelPrice 按模型价格计算用量记录的费用
+func applyModelPrice(record *gaia_x.LlmUsageRecord) {
+	price, ok := FindModelPrice(record.Vendor, record.ModelName, record.RequestTime)
+	if !ok {
+		return
+	}
+	record.Cost = price.Cost(record.PromptTokens, record.CachedTokens, record.CompletionTokens)
+	record.Currency = price.Currency
+	record.PriceID = price.ID
+}
+
+// loadPrices 获取缓存的价格，缓存过期时重新加载；加载失败时沿用旧的缓存
+func loadPrices() []ai.AiModelPrice {
+	priceCache.RLock()
+	prices, loadedAt := priceCache.prices, priceCache.loadedAt
+	priceCache.RUnlock()
+	if !loadedAt.IsZero() && time.Since(loadedAt) < priceCacheTTL {
+		return prices
+	}
+
+	priceCache.Lock()
+	defer priceCache.Unlock()
+	if !priceCache.loadedAt.IsZero() && time.Since(priceCache.loadedAt) < priceCacheTTL {
+		return priceCache.prices
+	}
+	var loaded []ai.AiModelPrice
+	if err := global.GVA_DB.Find(&loaded).Error; err != nil {
+		global.GVA_LOG.Error("加载模型价格失败", zap.Error(err))
+		return priceCache.prices
+	}
+	priceCache.prices, priceCache.loadedAt = loaded, time.Now()
+	return loaded
+}
+
+// invalidatePriceCache 价格变更后清空缓存
+func invalidatePriceCache() {
+	priceCache.Lock()
+	priceCache.loadedAt = time.Time{}
+	priceCache.Unlock()
+}
+
+// normalizeCurrency 币种统一为大写，未指定时使用默认币种
+func normalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return ai.DefaultPriceCurrency
+	}
+	return currency
+}
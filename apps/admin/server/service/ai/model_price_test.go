@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x"
+)
+
+func TestFindModelPrice(t *testing.T) {
+	db := newUsageTestDB(t)
+	if err := db.AutoMigrate(&ai.AiModelPrice{}); err != nil {
+		t.Fatalf("迁移价格表失败: %v", err)
+	}
+	global.GVA_DB = db
+	invalidatePriceCache()
+	t.Cleanup(invalidatePriceCache)
+
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	service := &ModelPriceService{}
+	for _, req := range []aiReq.CreateModelPriceReq{
+		{Model: "claude-3-5-sonnet", EffectiveFrom: day(1), InputPrice: 3, OutputPrice: 15},
+		{Vendor: "bedrock", Model: "claude-3-5-sonnet", EffectiveFrom: day(1), InputPrice: 3.3, OutputPrice: 16.5},
+		{Vendor: "bedrock", Model: "claude-3-5-sonnet", EffectiveFrom: day(10), InputPrice: 2, OutputPrice: 10},
+		{Vendor: "deepseek", Model: "deepseek-chat", EffectiveFrom: day(1), Currency: "cny", InputPrice: 2, CachedInputPrice: 0.5, OutputPrice: 8},
+	} {
+		if _, err := service.CreateModelPrice(req); err != nil {
+			t.Fatalf("创建价格失败: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name, vendor, model string
+		at                  time.Time
+		wantInput           float64
+		wantFound           bool
+	}{
+		{"指定供应商优先", "bedrock", "claude-3-5-sonnet", day(5), 3.3, true},
+		{"使用最新生效的价格", "bedrock", "claude-3-5-sonnet", day(10), 2, true},
+		{"其他供应商使用通用价格", "claude", "claude-3-5-sonnet", day(20), 3, true},
+		{"生效前没有价格", "bedrock", "claude-3-5-sonnet", time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), 0, false},
+		{"未配置的模型", "openai", "gpt-4o", day(5), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, ok := FindModelPrice(tt.vendor, tt.model, tt.at)
+			if ok != tt.wantFound || price.InputPrice != tt.wantInput {
+				t.Fatalf("期望 %v/%v，实际为 %v/%+v", tt.wantFound, tt.wantInput, ok, price)
+			}
+		})
+	}
+
+	record := gaia_x.LlmUsageRecord{Vendor: "deepseek", ModelName: "deepseek-chat", RequestTime: day(2),
+		PromptTokens: 1000000, CachedTokens: 500000, CompletionTokens: 100000}
+	applyModelPrice(&record)
+	if math.Abs(record.Cost-(1+0.25+0.8)) > 1e-9 || record.Currency != "CNY" || record.PriceID == 0 {
+		t.Fatalf("费用计算不正确: %+v", record)
+	}
+
+	// 修改价格后立即生效
+	price, _ := FindModelPrice("deepseek", "deepseek-chat", day(2))
+	if err := service.UpdateModelPrice(aiReq.UpdateModelPriceReq{ID: price.ID, Currency: "CNY", InputPrice: 4}); err != nil {
+		t.Fatalf("更新价格失败: %v", err)
+	}
+	if price, _ := FindModelPrice("deepseek", "deepseek-chat", day(2)); price.InputPrice != 4 {
+		t.Fatalf("更新后的价格未生效: %+v", price)
+	}
+}
@@ -0,0 +1,184 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/gaia-x/server/service/llmadapter"
+	"go.uber.org/zap"
+)
+
+// routeCacheTTL 逻辑模型路由缓存的有效期，多实例部署时其他实例修改路由后最多延迟这么久生效
+const routeCacheTTL = time.Minute
+
+// routeCache 启用的逻辑模型路由缓存，避免每次调用都查询路由表
+var routeCache struct {
+	sync.RWMutex
+	routes   []ai.AiModelRoute
+	loadedAt time.Time
+}
+
+// ModelRouteService 逻辑模型路由服务
+type ModelRouteService struct{}
+
+// CreateModelRoute 创建逻辑模型路由
+func (s *ModelRouteService) CreateModelRoute(req aiReq.CreateModelRouteReq) (route ai.AiModelRoute, err error) {
+	if err = validateRouteTargets(req.Targets); err != nil {
+		return
+	}
+	route = ai.AiModelRoute{
+		Name:        strings.TrimSpace(req.Name),
+		AuthorityId: req.AuthorityId,
+		Targets:     req.Targets,
+		Enabled:     req.Enabled,
+		Description: req.Description,
+	}
+	if err = global.GVA_DB.Create(&route).Error; err != nil {
+		return
+	}
+	invalidateRouteCache()
+	return
+}
+
+// UpdateModelRoute 更新逻辑模型路由的目标、状态和备注，名称和角色不可修改
+func (s *ModelRouteService) UpdateModelRoute(req aiReq.UpdateModelRouteReq) error {
+	if err := validateRouteTargets(req.Targets); err != nil {
+		return err
+	}
+	result := global.GVA_DB.Model(&ai.AiModelRoute{}).Where("id = ?", req.ID).
+		Select("targets", "enabled", "description").
+		Updates(ai.AiModelRoute{
+			Targets:     req.Targets,
+			Enabled:     req.Enabled,
+			Description: req.Description,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("路由不存在")
+	}
+	invalidateRouteCache()
+	return nil
+}
+
+// DeleteModelRoute 删除逻辑模型路由，直接物理删除以便重新创建同名路由
+func (s *ModelRouteService) DeleteModelRoute(id uint) error {
+	if err := global.GVA_DB.Unscoped().Delete(&ai.AiModelRoute{}, id).Error; err != nil {
+		return err
+	}
+	invalidateRouteCache()
+	return nil
+}
+
+// GetModelRouteList 分页获取逻辑模型路由列表
+func (s *ModelRouteService) GetModelRouteList(info aiReq.ModelRouteSearch) (list []ai.AiModelRoute, total int64, err error) {
+	db := global.GVA_DB.Model(&ai.AiModelRoute{})
+	if info.Name != "" {
+		db = db.Where("name LIKE ?", "%"+info.Name+"%")
+	}
+	if info.AuthorityId != nil {
+		db = db.Where("authority_id = ?", *info.AuthorityId)
+	}
+
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Scopes(info.Paginate()).Order("name, authority_id").Find(&list).Error
+	return list, total, err
+}
+
+// FindModelRoute 查找角色调用逻辑模型 name 时使用的路由，没有启用的路由时返回false
+// 指定角色的路由优先于所有角色通用的路由
+func FindModelRoute(authorityId uint, name string) (ai.AiModelRoute, bool) {
+	var found ai.AiModelRoute
+	ok := false
+	for _, route := range loadRoutes() {
+		if route.Name != name || (route.AuthorityId != authorityId && route.AuthorityId != 0) {
+			continue
+		}
+		if !ok || route.AuthorityId != 0 {
+			found, ok = route, true
+		}
+	}
+	return found, ok
+}
+
+// routeChatRequest 按逻辑模型路由改写请求的供应商和模型
+// 命中路由时忽略请求中的 provider，按路由选出首选目标，其余目标作为本次请求的备用目标；
+// 未命中路由且未指定供应商时，由 llmadapter 按凭证配置的模型选择供应商，没有凭证配置该模型时使用配置文件中的默认供应商
+func routeChatRequest(caller aiReq.ChatCaller, req llmadapter.ChatRequest) llmadapter.ChatRequest {
+	route, ok := FindModelRoute(caller.AuthorityId, req.Model)
+	if !ok {
+		if req.Provider == "" {
+			if _, configured := llmadapter.LookupModel(req.Model); !configured {
+				req.Provider = global.GVA_CONFIG.AI.Provider
+			}
+		}
+		return req
+	}
+
+	plan := route.Plan(rand.Intn)
+	req.Provider, req.Model = plan[0].Vendor, plan[0].Model
+	req.Fallbacks = make([]llmadapter.FallbackTarget, 0, len(plan)-1)
+	for _, target := range plan[1:] {
+		req.Fallbacks = append(req.Fallbacks, llmadapter.FallbackTarget{Provider: target.Vendor, Model: target.Model})
+	}
+	return req
+}
+
+// validateRouteTargets 校验路由目标的供应商已注册
+func validateRouteTargets(targets []ai.ModelRouteTarget) error {
+	if len(targets) == 0 {
+		return errors.New("路由目标不能为空")
+	}
+	for _, target := range targets {
+		if _, err := llmadapter.GetProvider(target.Vendor); err != nil {
+			return err
+		}
+		if target.Model == "" {
+			return fmt.Errorf("供应商 %s 的目标模型不能为空", target.Vendor)
+		}
+	}
+	return nil
+}
+
+// loadRoutes 获取缓存的启用路由，缓存过期时重新加载；加载失败时沿用旧的缓存
+func loadRoutes() []ai.AiModelRoute {
+	routeCache.RLock()
+	routes, loadedAt := routeCache.routes, routeCache.loadedAt
+	routeCache.RUnlock()
+	if !loadedAt.IsZero() && time.Since(loadedAt) < routeCacheTTL {
+		return routes
+	}
+
+	routeCache.Lock()
+	defer routeCache.Unlock()
+	if !routeCache.loadedAt.IsZero() && time.Since(routeCache.loadedAt) < routeCacheTTL {
+		return routeCache.routes
+	}
+	if global.GVA_DB == nil {
+		return nil
+	}
+	var loaded []ai.AiModelRoute
+	if err := global.GVA_DB.Where("enabled = ?", true).Find(&loaded).Error; err != nil {
+		global.GVA_LOG.Error("加载模型路由失败", zap.Error(err))
+		return routeCache.routes
+	}
+	routeCache.routes, routeCache.loadedAt = loaded, time.Now()
+	return loaded
+}
+
+// invalidateRouteCache 路由变更后清空缓存
+func invalidateRouteCache() {
+	routeCache.Lock()
+	routeCache.loadedAt = time.Time{}
+	routeCache.Unlock()
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/gaia-x/server/service/llmadapter"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestRouteChatRequest(t *testing.T) {
+	db := newUsageTestDB(t)
+	if err := db.AutoMigrate(&ai.AiModelRoute{}); err != nil {
+		t.Fatalf("迁移路由表失败: %v", err)
+	}
+	global.GVA_DB = db
+	invalidateRouteCache()
+	t.Cleanup(invalidateRouteCache)
+
+	service := &ModelRouteService{}
+	if _, err := service.CreateModelRoute(aiReq.CreateModelRouteReq{
+		Name:    "default-chat",
+		Targets: []ai.ModelRouteTarget{{Vendor: "not-exists", Model: "m"}},
+		Enabled: true,
+	}); !errors.Is(err, llmadapter.ErrUnsupportedProvider) {
+		t.Fatalf("未注册的供应商应创建失败，实际为 %v", err)
+	}
+	for _, req := range []aiReq.CreateModelRouteReq{
+		{Name: "default-chat", Enabled: true, Targets: []ai.ModelRouteTarget{
+			{Vendor: "bedrock", Model: "us.anthropic.claude-3-7-sonnet-20250219-v1:0", Weight: 1},
+			{Vendor: "claude", Model: "claude-3-7-sonnet-20250219"},
+		}},
+		{Name: "default-chat", AuthorityId: 9528, Enabled: true, Targets: []ai.ModelRouteTarget{
+			{Vendor: "deepseek", Model: "deepseek-chat", Weight: 1},
+		}},
+		{Name: "fast", Enabled: false, Targets: []ai.ModelRouteTarget{{Vendor: "openai", Model: "gpt-4o-mini"}}},
+	} {
+		if _, err := service.CreateModelRoute(req); err != nil {
+			t.Fatalf("创建路由失败: %v", err)
+		}
+	}
+
+	request := func(model, provider string) llmadapter.ChatRequest {
+		return llmadapter.ChatRequest{Provider: provider, ChatCompletionRequest: openai.ChatCompletionRequest{Model: model}}
+	}
+
+	// 命中通用路由，忽略请求的供应商
+	got := routeChatRequest(aiReq.ChatCaller{AuthorityId: 888}, request("default-chat", "openai"))
+	if got.Provider != "bedrock" || got.Model != "us.anthropic.claude-3-7-sonnet-20250219-v1:0" {
+		t.Errorf("通用路由 = %s/%s", got.Provider, got.Model)
+	}
+	if len(got.Fallbacks) != 1 || got.Fallbacks[0] != (llmadapter.FallbackTarget{Provider: "claude", Model: "claude-3-7-sonnet-20250219"}) {
+		t.Errorf("备用目标 = %+v", got.Fallbacks)
+	}
+
+	// 指定角色的路由优先
+	got = routeChatRequest(aiReq.ChatCaller{AuthorityId: 9528}, request("default-chat", ""))
+	if got.Provider != "deepseek" || got.Model != "deepseek-chat" || len(got.Fallbacks) != 0 {
+		t.Errorf("角色路由 = %+v", got)
+	}
+
+	// 未启用的路由和普通模型不改写
+	global.GVA_CONFIG.AI.Provider = "azure"
+	t.Cleanup(func() { global.GVA_CONFIG.AI.Provider = "" })
+	got = routeChatRequest(aiReq.ChatCaller{}, request("fast", "openai"))
+	if got.Provider != "openai" || got.Model != "fast" || got.Fallbacks != nil {
+		t.Errorf("未启用的路由 = %+v", got)
+	}
+	got = routeChatRequest(aiReq.ChatCaller{}, request("not-configured-model", ""))
+	if got.Provider != "azure" {
+		t.Errorf("没有凭证配置的模型应使用默认供应商，实际为 %q", got.Provider)
+	}
+}
@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/gaia-x/server/service/llmadapter"
+)
+
+func TestListModels(t *testing.T) {
+	db := newUsageTestDB(t)
+	if err := db.AutoMigrate(&ai.AiModelPrice{}); err != nil {
+		t.Fatalf("迁移价格表失败: %v", err)
+	}
+	global.GVA_DB = db
+	invalidatePriceCache()
+	t.Cleanup(invalidatePriceCache)
+
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := new(ModelPriceService).CreateModelPrice(aiReq.CreateModelPriceReq{
+		Vendor: "openai", Model: "gpt-4o", EffectiveFrom: at, InputPrice: 2.5, OutputPrice: 10,
+	}); err != nil {
+		t.Fatalf("创建价格失败: %v", err)
+	}
+
+	oldEnv := llmadapter.ENV
+	llmadapter.ENV = "test"
+	t.Cleanup(func() { llmadapter.ENV = oldEnv })
+
+	dir := t.TempDir()
+	for file, content := range map[string]string{
+		"azure.yaml":  "environments:\n  test:\n    credentials:\n      - name: a\n        api_key: k\n        enabled: true\n        models: [gpt-4o, gpt-35-turbo]\n",
+		"openai.yaml": "environments:\n  test:\n    credentials:\n      - name: a\n        api_key: k\n        enabled: true\n        models: [gpt-4o]\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatalf("写入配置文件失败: %v", err)
+		}
+	}
+	store := llmadapter.NewCredentialStore(dir, func(s string) (string, error) { return s, nil })
+
+	res := listModels(store, nil, at)
+	if res.Object != "list" || len(res.Data) != 2 {
+		t.Fatalf("模型列表 = %+v", res)
+	}
+	gpt4o := res.Data[1]
+	if gpt4o.ID != "gpt-4o" || gpt4o.OwnedBy != "azure" || gpt4o.ContextWindow != 128000 || !gpt4o.Capabilities.Vision {
+		t.Errorf("gpt-4o = %+v", gpt4o)
+	}
+	// azure 没有配置价格，使用 openai 的价格
+	if gpt4o.Pricing == nil || gpt4o.Pricing.Vendor != "openai" || gpt4o.Pricing.Input != 2.5 {
+		t.Errorf("gpt-4o 价格 = %+v", gpt4o.Pricing)
+	}
+	if res.Data[0].Pricing != nil {
+		t.Errorf("未配置价格的模型不应返回价格: %+v", res.Data[0].Pricing)
+	}
+
+	// 访问密钥限制了可调用的模型
+	res = listModels(store, &ai.AiApiKey{Models: []string{"gpt-35-turbo"}}, at)
+	if len(res.Data) != 1 || res.Data[0].ID != "gpt-35-turbo" {
+		t.Errorf("密钥限制后的模型列表 = %+v", res.Data)
+	}
+}
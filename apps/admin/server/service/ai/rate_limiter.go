@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"context"
+	"time"
+
+	"github.com/gaia-x/server/service/llmadapter"
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript Redis令牌桶脚本
+// KEYS[1] 限流键；ARGV[1] 每秒产生的令牌数(同时也是桶的容量)；ARGV[2] 调用方的当前时间(毫秒)
+// 当前时间由调用方传入而不是在脚本中调用 TIME：Redis 5 以前的版本在调用 TIME 后不允许写入(除非开启效果复制)。
+// 写入使用 HMSET 以兼容 Redis 4 以前的版本；各实例的时钟需要同步，时间回退时不补充令牌
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local limit = tonumber(ARGV[1])
+local now = tonumber(ARGV[2])
+
+local bucket = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = limit
+	ts = now
+end
+
+tokens = math.min(limit, tokens + math.max(0, now - ts) * limit / 1000)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HMSET', key, 'tokens', tokens, 'ts', math.max(now, ts))
+redis.call('PEXPIRE', key, 2000)
+return allowed
+`)
+
+// RedisRateLimiter 基于Redis的凭证QPS限流器，多个实例共享同一个令牌桶
+type RedisRateLimiter struct {
+	client redis.UniversalClient
+}
+
+// NewRedisRateLimiter 创建基于Redis的凭证QPS限流器
+func NewRedisRateLimiter(client redis.UniversalClient) *RedisRateLimiter {
+	return &RedisRateLimiter{client: client}
+}
+
+// Allow 实现 llmadapter.RateLimiter 接口
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string, limit int) (bool, error) {
+	allowed, err := tokenBucketScript.Run(ctx, l.client, []string{key}, limit, time.Now().UnixMilli()).Int()
+	if err != nil {
+		return false, err
+	}
+	return allowed == 1, nil
+}
+
+var _ llmadapter.RateLimiter = (*RedisRateLimiter)(nil)
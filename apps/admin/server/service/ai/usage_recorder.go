@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 用量记录的默认配置
+const (
+	defaultUsageBufferSize    = 4096
+	defaultUsageBatchSize     = 100
+	defaultUsageFlushInterval = time.Second
+)
+
+// UsageRecorder 异步批量写入LLM调用用量记录
+// Record 只把记录放入缓冲区，不会阻塞聊天请求；后台协程凑满一批或到达写入间隔时批量写入数据库
+type UsageRecorder struct {
+	db        *gorm.DB
+	records   chan gaia_x.LlmUsageRecord
+	batchSize int
+	interval  time.Duration
+	quit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewUsageRecorder 创建用量记录器并启动后台写入协程，未配置的字段使用默认值
+func NewUsageRecorder(db *gorm.DB, conf config.UsageConf) *UsageRecorder {
+	bufferSize := defaultUsageBufferSize
+	if conf.BufferSize > 0 {
+		bufferSize = conf.BufferSize
+	}
+	batchSize := defaultUsageBatchSize
+	if conf.BatchSize > 0 {
+		batchSize = conf.BatchSize
+	}
+	interval := defaultUsageFlushInterval
+	if conf.FlushInterval > 0 {
+		interval = time.Duration(conf.FlushInterval) * time.Millisecond
+	}
+	r := &UsageRecorder{
+		db:        db,
+		records:   make(chan gaia_x.LlmUsageRecord, bufferSize),
+		batchSize: batchSize,
+		interval:  interval,
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go r.run()
+	return r
+}
+
+// Record 提交一条用量记录，缓冲区已满时丢弃并返回false
+func (r *UsageRecorder) Record(record gaia_x.LlmUsageRecord) bool {
+	select {
+	case r.records <- record:
+		return true
+	default:
+		global.GVA_LOG.Warn("用量记录缓冲区已满，丢弃记录",
+			zap.String("requestId", record.RequestID), zap.Uint("userId", record.UserID), zap.String("model", record.ModelName))
+		return false
+	}
+}
+
+// Close 停止后台协程，返回前写入缓冲区中剩余的记录
+func (r *UsageRecorder) Close() {
+	r.closeOnce.Do(func() {
+		close(r.quit)
+	})
+	<-r.done
+}
+
+// run 后台写入协程
+func (r *UsageRecorder) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	batch := make([]gaia_x.LlmUsageRecord, 0, r.batchSize)
+	for {
+		select {
+		case record := <-r.records:
+			batch = append(batch, record)
+			if len(batch) >= r.batchSize {
+				batch = r.flush(batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(batch)
+		case <-r.quit:
+			for {
+				select {
+				case record := <-r.records:
+					batch = append(batch, record)
+					if len(batch) >= r.batchSize {
+						batch = r.flush(batch)
+					}
+				default:
+					r.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush 批量写入记录，返回清空后的批次以便复用
+// 写入失败时只记录日志，不重试，避免数据库异常时缓冲区无限堆积
+func (r *UsageRecorder) flush(batch []gaia_x.LlmUsageRecord) []gaia_x.LlmUsageRecord {
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := r.db.CreateInBatches(batch, r.batchSize).Error; err != nil {
+		global.GVA_LOG.Error("写入用量记录失败", zap.Int("count", len(batch)), zap.Error(err))
+	}
+	return batch[:0]
+}
+
+// usageRecorder 全局用量记录器，未启动时不记录用量
+var usageRecorder atomic.Pointer[UsageRecorder]
+
+// StartUsageRecorder 启动全局用量记录器，已启动时先停止原记录器
+func StartUsageRecorder(db *gorm.DB, conf config.UsageConf) {
+	if old := usageRecorder.Swap(NewUsageRecorder(db, conf)); old != nil {
+		old.Close()
+	}
+}
+
+// StopUsageRecorder 停止全局用量记录器并写入剩余记录
+func StopUsageRecorder() {
+	if r := usageRecorder.Swap(nil); r != nil {
+		r.Close()
+	}
+}
+
+// recordUsage 通过全局用量记录器提交一条记录
+func recordUsage(record gaia_x.LlmUsageRecord) {
+	if r := usageRecorder.Load(); r != nil {
+		r.Record(record)
+	}
+}
@@ -0,0 +1,134 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	aiReq "github.com/flipped-aurora/gin-vue-admin/server/model/ai/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x"
+	"github.com/gaia-x/server/service/llmadapter"
+	"github.com/glebarez/sqlite"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newUsageTestDB 创建内存数据库并迁移用量记录表
+func newUsageTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	global.GVA_LOG = zap.NewNop()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&gaia_x.LlmUsageRecord{}); err != nil {
+		t.Fatalf("迁移用量记录表失败: %v", err)
+	}
+	return db
+}
+
+func TestUsageRecorder(t *testing.T) {
+	t.Run("凑满一批时写入", func(t *testing.T) {
+		db := newUsageTestDB(t)
+		recorder := NewUsageRecorder(db, config.UsageConf{BatchSize: 2, FlushInterval: 60000})
+		defer recorder.Close()
+
+		recorder.Record(gaia_x.LlmUsageRecord{RequestID: "r1"})
+		recorder.Record(gaia_x.LlmUsageRecord{RequestID: "r2"})
+		deadline := time.Now().Add(2 * time.Second)
+		var count int64
+		for time.Now().Before(deadline) {
+			db.Model(&gaia_x.LlmUsageRecord{}).Count(&count)
+			if count == 2 {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("期望写入2条记录，实际为%d", count)
+	})
+
+	t.Run("关闭时写入剩余记录", func(t *testing.T) {
+		db := newUsageTestDB(t)
+		recorder := NewUsageRecorder(db, config.UsageConf{BatchSize: 100, FlushInterval: 60000})
+		for i := 0; i < 5; i++ {
+			recorder.Record(gaia_x.LlmUsageRecord{RequestID: fmt.Sprintf("r%d", i)})
+		}
+		recorder.Close()
+
+		var count int64
+		db.Model(&gaia_x.LlmUsageRecord{}).Count(&count)
+		if count != 5 {
+			t.Fatalf("期望写入5条记录，实际为%d", count)
+		}
+	})
+
+	t.Run("缓冲区已满时丢弃", func(t *testing.T) {
+		global.GVA_LOG = zap.NewNop()
+		recorder := &UsageRecorder{records: make(chan gaia_x.LlmUsageRecord, 1)}
+		if !recorder.Record(gaia_x.LlmUsageRecord{}) {
+			t.Fatal("缓冲区未满时应写入成功")
+		}
+		if recorder.Record(gaia_x.LlmUsageRecord{}) {
+			t.Fatal("缓冲区已满时应丢弃记录")
+		}
+	})
+}
+
+func TestNewUsageRecord(t *testing.T) {
+	caller := aiReq.ChatCaller{RequestID: "req-1", UserID: 1, AuthorityId: 888, ApiKeyID: 3}
+	req := llmadapter.ChatRequest{Provider: "openai", ChatCompletionRequest: openai.ChatCompletionRequest{Model: "gpt-4o", Stream: true}}
+	start := time.Now().Add(-time.Second)
+
+	t.Run("成功", func(t *testing.T) {
+		info := &llmadapter.CallInfo{
+			Provider:         "azure",
+			Model:            "gpt-4o",
+			Credential:       "azure-1",
+			Attempts:         2,
+			Fallback:         true,
+			Usage:            openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, CompletionTokensDetails: &openai.CompletionTokensDetails{ReasoningTokens: 2}},
+			TimeToFirstToken: 300 * time.Millisecond,
+		}
+		record := newUsageRecord(caller, req, info, start, nil)
+		if record.Status != gaia_x.LlmUsageStatusSuccess || record.Vendor != "azure" || record.Credential != "azure-1" {
+			t.Fatalf("记录不正确: %+v", record)
+		}
+		if record.TotalTokens != 15 || record.ReasoningTokens != 2 || record.FirstTokenMs != 300 || record.LatencyMs < 1000 {
+			t.Fatalf("用量或耗时不正确: %+v", record)
+		}
+		if record.RequestID != "req-1" || record.ApiKeyID != 3 || record.Day != start.Format(time.DateOnly) {
+			t.Fatalf("调用方或日期不正确: %+v", record)
+		}
+	})
+
+	t.Run("上游错误", func(t *testing.T) {
+		err := &llmadapter.UpstreamError{
+			Kind:       llmadapter.ErrorKindRateLimit,
+			Provider:   "openai",
+			Model:      "gpt-4o",
+			Credential: "openai-2",
+			Attempts:   3,
+			Err:        errors.New("429 too many requests"),
+		}
+		record := newUsageRecord(caller, req, &llmadapter.CallInfo{}, start, fmt.Errorf("调用失败: %w", err))
+		if record.Status != gaia_x.LlmUsageStatusError || record.ErrorKind != "rate_limit" {
+			t.Fatalf("状态或错误分类不正确: %+v", record)
+		}
+		if record.Credential != "openai-2" || record.Attempts != 3 || record.ModelName != "gpt-4o" {
+			t.Fatalf("凭证或尝试次数不正确: %+v", record)
+		}
+	})
+
+	t.Run("客户端断开", func(t *testing.T) {
+		record := newUsageRecord(caller, req, &llmadapter.CallInfo{}, start, context.Canceled)
+		if record.Status != gaia_x.LlmUsageStatusCanceled || record.Vendor != "openai" || record.ModelName != "gpt-4o" {
+			t.Fatalf("记录不正确: %+v", record)
+		}
+	})
+}
@@ -4,4 +4,5 @@ type ServiceGroup struct {
 	GaiaXUserService
 	GaiaXVersionService
 	GaiaXUsageReportService
+	GaiaXLlmUsageService
 }
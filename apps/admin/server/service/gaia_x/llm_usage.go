@@ -0,0 +1,140 @@
+package gaia_x
+
+import (
+	"fmt"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/gaia_x/response"
+	"gorm.io/gorm"
+)
+
+type GaiaXLlmUsageService struct{}
+
+// llmUsageGroupColumns 分组方式对应的列
+var llmUsageGroupColumns = map[string]string{
+	request.LlmUsageGroupByUser:      "user_id",
+	request.LlmUsageGroupByAuthority: "authority_id",
+	request.LlmUsageGroupByModel:     "model",
+	request.LlmUsageGroupByDay:       "day",
+	request.LlmUsageGroupByVendor:    "vendor",
+}
+
+// GetLlmUsageSummary 获取LLM用量汇总
+// @function: GetLlmUsageSummary
+// @description: 按用户、角色、模型或天汇总调用次数、Token数和平均耗时
+// @param: req request.GetLlmUsageSummaryReq
+// @return: res response.GetLlmUsageSummaryRes, err error
+func (s *GaiaXLlmUsageService) GetLlmUsageSummary(req request.GetLlmUsageSummaryReq) (res response.GetLlmUsageSummaryRes, err error) {
+	column, ok := llmUsageGroupColumns[req.GroupBy]
+	if !ok {
+		return res, fmt.Errorf("不支持的分组方式: %s", req.GroupBy)
+	}
+
+	res.GroupBy = req.GroupBy
+	res.List = []response.LlmUsageSummaryItem{}
+	err = filterLlmUsage(global.GVA_DB.Model(&gaia_x.LlmUsageRecord{}), req.LlmUsageFilter).
+		Select(column+" AS group_key, COUNT(*) AS requests, "+
+			"SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS success_requests, "+
+			"SUM(CASE WHEN status <> ? THEN 1 ELSE 0 END) AS failed_requests, "+
+			"SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens, "+
+			"SUM(reasoning_tokens) AS reasoning_tokens, SUM(total_tokens) AS total_tokens, AVG(latency_ms) AS avg_latency_ms",
+			gaia_x.LlmUsageStatusSuccess, gaia_x.LlmUsageStatusSuccess).
+		Group(column).
+		Order(column).
+		Scan(&res.List).Error
+	return
+}
+
+// GetLlmCostReport 获取LLM费用报表
+// @function: GetLlmCostReport
+// @description: 按用户、角色、模型、天或供应商汇总Token数和费用，不同币种分别汇总
+// @param: req request.GetLlmCostReportReq
+// @return: res response.GetLlmCostReportRes, err error
+func (s *GaiaXLlmUsageService) GetLlmCostReport(req request.GetLlmCostReportReq) (res response.GetLlmCostReportRes, err error) {
+	column, ok := llmUsageGroupColumns[req.GroupBy]
+	if !ok {
+		return res, fmt.Errorf("不支持的分组方式: %s", req.GroupBy)
+	}
+
+	res.GroupBy = req.GroupBy
+	res.List = []response.LlmCostReportItem{}
+	err = filterLlmUsage(global.GVA_DB.Model(&gaia_x.LlmUsageRecord{}), req.LlmUsageFilter).
+		Select(column + " AS group_key, currency, COUNT(*) AS requests, " +
+			"SUM(prompt_tokens) AS prompt_tokens, SUM(cached_tokens) AS cached_tokens, " +
+			"SUM(completion_tokens) AS completion_tokens, SUM(reasoning_tokens) AS reasoning_tokens, " +
+			"SUM(total_tokens) AS total_tokens, SUM(cost) AS cost").
+		Group(column + ", currency").
+		Order(column + ", currency").
+		Scan(&res.List).Error
+	return
+}
+
+// GetLlmUsageRecordList 获取LLM用量记录列表
+// @function: GetLlmUsageRecordList
+// @description: 分页获取LLM用量记录，按请求时间倒序
+// @param: req request.GetLlmUsageRecordListReq
+// @return: res response.GetLlmUsageRecordListRes, err error
+func (s *GaiaXLlmUsageService) GetLlmUsageRecordList(req request.GetLlmUsageRecordListReq) (res response.GetLlmUsageRecordListRes, err error) {
+	query := filterLlmUsage(global.GVA_DB.Model(&gaia_x.LlmUsageRecord{}), req.LlmUsageFilter)
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.RequestID != "" {
+		query = query.Where("request_id = ?", req.RequestID)
+	}
+
+	var total int64
+	if err = query.Count(&total).Error; err != nil {
+		return
+	}
+
+	// 设置默认值
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	var records []gaia_x.LlmUsageRecord
+	offset := (req.Page - 1) * req.PageSize
+	if err = query.Order("request_time desc").Offset(offset).Limit(req.PageSize).Find(&records).Error; err != nil {
+		return
+	}
+
+	res = response.GetLlmUsageRecordListRes{
+		List:     records,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}
+	return
+}
+
+// filterLlmUsage 添加用量记录的筛选条件
+func filterLlmUsage(query *gorm.DB, filter request.LlmUsageFilter) *gorm.DB {
+	if !filter.StartTime.IsZero() {
+		query = query.Where("request_time >= ?", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		query = query.Where("request_time <= ?", filter.EndTime)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.AuthorityId != 0 {
+		query = query.Where("authority_id = ?", filter.AuthorityId)
+	}
+	if filter.ApiKeyID != 0 {
+		query = query.Where("api_key_id = ?", filter.ApiKeyID)
+	}
+	if filter.Vendor != "" {
+		query = query.Where("vendor = ?", filter.Vendor)
+	}
+	if filter.Model != "" {
+		query = query.Where("model = ?", filter.Model)
+	}
+	return query
+}
@@ -46,7 +46,7 @@ func main() {
 
 	fmt.Println("加密结果:")
 	fmt.Println(encryptedKey)
-	fmt.Printf("\n密钥文件存储在: 私钥=%s, 公钥=%s\n",
-		llmadapter.DefaultPrivateKeyPath,
-		llmadapter.DefaultPublicKeyPath)
+	if keyring, err := llmadapter.DefaultKeyring(); err == nil {
+		fmt.Printf("\n使用的密钥: %s (密钥目录: %s)\n", keyring.Primary(), llmadapter.KeyDir())
+	}
 }
@@ -0,0 +1,294 @@
+// llmctl LLM供应商凭证配置的命令行工具
+//
+// 使用方法(llmadapter是独立的模块，需在 apps/admin/server/service/llmadapter 目录下):
+//
+//	go run ./cmd/llmctl <子命令> [参数]
+//
+// 子命令:
+//
+//	validate       校验配置文件，与服务启动时的校验相同，有错误时退出码为1
+//	print          打印凭证配置，敏感字段只显示密钥ID
+//	decrypt-check  检查所有敏感字段能否使用现有密钥解密，不输出明文
+//	probe          使用指定凭证发送一次最小的请求
+//	list-models    列出当前环境可调用的模型
+//
+// 所有子命令都支持 -dir(配置目录)、-env(运行环境) 和 -key-dir(密钥目录)；
+// 只读取配置目录中的文件，不包含数据库中的凭证
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gaia-x/server/service/llmadapter"
+	"gopkg.in/yaml.v2"
+)
+
+// commands 子命令 -> 说明和执行函数，执行函数返回退出码
+var commands = map[string]struct {
+	usage string
+	run   func(args []string) int
+}{
+	"validate":      {"校验配置文件", validate},
+	"print":         {"打印凭证配置(敏感字段已隐藏)", printConfig},
+	"decrypt-check": {"检查敏感字段能否解密", decryptCheck},
+	"probe":         {"使用指定凭证发送一次请求", probe},
+	"list-models":   {"列出当前环境可调用的模型", listModels},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知的子命令: %s\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	os.Exit(command.run(os.Args[2:]))
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "使用方法: llmctl <子命令> [参数]\n\n子命令:")
+	for _, name := range []string{"validate", "print", "decrypt-check", "probe", "list-models"} {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr, "\n使用 llmctl <子命令> -h 查看子命令的参数")
+}
+
+// options 所有子命令共用的参数
+type options struct {
+	dir    string
+	env    string
+	keyDir string
+}
+
+// newFlagSet 创建子命令的参数集合并注册共用参数
+func newFlagSet(name string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet("llmctl "+name, flag.ExitOnError)
+	opts := &options{}
+	fs.StringVar(&opts.dir, "dir", llmadapter.LLMConfigPath, "凭证配置目录")
+	fs.StringVar(&opts.env, "env", llmadapter.ENV, "运行环境，如 development、production")
+	fs.StringVar(&opts.keyDir, "key-dir", "", "密钥目录，为空时按 LLM_PRIVATE_KEYS、LLM_KEY_DIR、默认目录的顺序查找")
+	return fs, opts
+}
+
+// apply 应用共用参数
+func (o *options) apply() {
+	llmadapter.LLMConfigPath = o.dir
+	llmadapter.SetENV(o.env)
+	if o.keyDir != "" {
+		llmadapter.SetKeyDir(o.keyDir)
+	}
+}
+
+// readConfigs 读取配置目录中的凭证，vendor 为空时读取所有供应商，env 为空时包含所有环境
+func readConfigs(dir, vendor, env string) ([]llmadapter.CredentialConfig, error) {
+	vendors := llmadapter.CredentialVendors()
+	if vendor != "" {
+		vendors = []string{vendor}
+	}
+	var configs []llmadapter.CredentialConfig
+	for _, v := range vendors {
+		vendorConfigs, err := llmadapter.ReadCredentialFile(dir, v)
+		if err != nil {
+			return nil, err
+		}
+		for _, config := range vendorConfigs {
+			if env == "" || config.Env == env {
+				configs = append(configs, config)
+			}
+		}
+	}
+	return configs, nil
+}
+
+func validate(args []string) int {
+	fs, opts := newFlagSet("validate")
+	_ = fs.Parse(args)
+	opts.apply()
+
+	issues := llmadapter.ValidateCredentials(opts.dir, nil, opts.env, nil)
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if llmadapter.HasErrors(issues) {
+		fmt.Printf("\n环境 %s 的凭证配置有错误\n", opts.env)
+		return 1
+	}
+	fmt.Printf("环境 %s 的凭证配置校验通过\n", opts.env)
+	return 0
+}
+
+func printConfig(args []string) int {
+	fs, opts := newFlagSet("print")
+	vendor := fs.String("vendor", "", "只打印指定供应商")
+	all := fs.Bool("all", false, "打印所有环境，默认只打印 -env 指定的环境")
+	format := fs.String("format", "yaml", "输出格式(yaml/json)")
+	_ = fs.Parse(args)
+	opts.apply()
+
+	env := opts.env
+	if *all {
+		env = ""
+	}
+	configs, err := readConfigs(opts.dir, *vendor, env)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	output := make(map[string][]map[string]any)
+	for _, config := range configs {
+		for field, value := range config.Secrets {
+			config.Secrets[field] = llmadapter.MaskSecret(value)
+		}
+		fields := config.Fields()
+		fields["env"] = config.Env
+		output[config.Vendor] = append(output[config.Vendor], fields)
+	}
+
+	var data []byte
+	switch *format {
+	case "yaml":
+		data, err = yaml.Marshal(output)
+	case "json":
+		data, err = json.MarshalIndent(output, "", "  ")
+	default:
+		err = fmt.Errorf("不支持的输出格式: %s", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(string(data))
+	return 0
+}
+
+func decryptCheck(args []string) int {
+	fs, opts := newFlagSet("decrypt-check")
+	all := fs.Bool("all", false, "检查所有环境，默认只检查 -env 指定的环境")
+	_ = fs.Parse(args)
+	opts.apply()
+
+	keyring, err := llmadapter.DefaultKeyring()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("当前密钥: %s，全部密钥: %s\n\n", keyring.Primary(), strings.Join(keyring.KeyIDs(), ", "))
+
+	env := opts.env
+	if *all {
+		env = ""
+	}
+	configs, err := readConfigs(opts.dir, "", env)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	failed, stale := 0, 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, config := range configs {
+		for _, field := range llmadapter.SecretFields(config.Vendor) {
+			value, ok := config.Secrets[field]
+			if !ok {
+				continue
+			}
+			name := fmt.Sprintf("%s/%s/%s.%s", config.Vendor, config.Env, config.Name, field)
+			status := "OK"
+			switch _, err := keyring.Decrypt(value); {
+			case strings.HasPrefix(value, "YOUR_"):
+				status = "占位符"
+			case err != nil:
+				status = "失败: " + err.Error()
+				if config.Enabled {
+					failed++
+				}
+			case !keyring.IsCurrent(value):
+				status = "OK(未使用当前密钥，需要轮换)"
+				stale++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, llmadapter.MaskSecret(value), status)
+		}
+	}
+	_ = w.Flush()
+
+	fmt.Printf("\n启用的凭证中 %d 个字段无法解密，%d 个字段未使用当前密钥\n", failed, stale)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func probe(args []string) int {
+	fs, opts := newFlagSet("probe")
+	vendor := fs.String("vendor", "", "供应商(必填)")
+	name := fs.String("name", "", "凭证名称(必填)")
+	model := fs.String("model", "", "测试使用的模型，为空时使用凭证配置的第一个模型")
+	timeout := fs.Duration("timeout", 30*time.Second, "超时时间")
+	_ = fs.Parse(args)
+	opts.apply()
+
+	if *vendor == "" || *name == "" {
+		fs.Usage()
+		return 2
+	}
+	configs, err := readConfigs(opts.dir, *vendor, opts.env)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	for _, config := range configs {
+		if config.Name != *name {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		start := time.Now()
+		if err := llmadapter.TestCredential(ctx, config, *model); err != nil {
+			fmt.Printf("凭证 %s/%s 请求失败(%s): %v\n", *vendor, *name, time.Since(start).Round(time.Millisecond), err)
+			return 1
+		}
+		fmt.Printf("凭证 %s/%s 可用，耗时 %s\n", *vendor, *name, time.Since(start).Round(time.Millisecond))
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "环境 %s 中没有 %s 凭证 %s\n", opts.env, *vendor, *name)
+	return 1
+}
+
+func listModels(args []string) int {
+	fs, opts := newFlagSet("list-models")
+	_ = fs.Parse(args)
+	opts.apply()
+
+	models := llmadapter.ListModels()
+	if len(models) == 0 {
+		fmt.Printf("环境 %s 中没有启用的凭证配置了 models\n", opts.env)
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "模型\t供应商\t上下文窗口\t能力")
+	for _, m := range models {
+		var capabilities []string
+		for name, ok := range map[string]bool{"tools": m.Capabilities.Tools, "vision": m.Capabilities.Vision, "reasoning": m.Capabilities.Reasoning} {
+			if ok {
+				capabilities = append(capabilities, name)
+			}
+		}
+		sort.Strings(capabilities)
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", m.ID, strings.Join(m.Vendors, ","), m.ContextWindow, strings.Join(capabilities, ","))
+	}
+	_ = w.Flush()
+	return 0
+}
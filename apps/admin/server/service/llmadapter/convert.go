@@ -0,0 +1,553 @@
+package llmadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// newRequestConfig 根据请求构造供应商的基础配置
+func newRequestConfig(vendor string, req ChatRequest) (*Config, error) {
+	if req.Model == "" {
+		return nil, invalidRequestf("未指定模型名称")
+	}
+	if len(req.Messages) == 0 {
+		return nil, invalidRequestf("消息列表不能为空")
+	}
+
+	temperature := req.Temperature
+	topP := req.TopP
+	vendorOptions, _ := requestVendorOptions(vendor, req.VendorOptions)
+	return &Config{
+		Vendor:           vendor,
+		Model:            req.Model,
+		MaxTokens:        req.MaxTokens,
+		Temperature:      &temperature,
+		TopP:             &topP,
+		Stop:             req.Stop,
+		Thinking:         req.Thinking,
+		Seed:             req.Seed,
+		PresencePenalty:  req.PresencePenalty,
+		FrequencyPenalty: req.FrequencyPenalty,
+		LogitBias:        req.LogitBias,
+		ResponseFormat:   req.ResponseFormat,
+		VendorOptional:   vendorOptions,
+	}, nil
+}
+
+// toSchemaMessages 将OpenAI格式的消息转换为eino的消息格式
+// 保留 name、assistant 消息的工具调用、tool 消息的 tool_call_id 以及图片等多模态内容，
+// tool 消息的函数名称按 tool_call_id 从之前的工具调用中补全；
+// 只包含文本的多段内容合并为一段文本，兼容不支持多模态消息的供应商
+func toSchemaMessages(messages []openai.ChatCompletionMessage) ([]*schema.Message, error) {
+	schemaMessages := make([]*schema.Message, len(messages))
+	// toolNames 工具调用ID -> 函数名称
+	toolNames := make(map[string]string)
+
+	for i, msg := range messages {
+		schemaMsg := &schema.Message{
+			Role:       schema.RoleType(msg.Role),
+			Content:    msg.Content,
+			Name:       msg.Name,
+			ToolCallID: msg.ToolCallID,
+		}
+
+		if len(msg.MultiContent) > 0 {
+			parts, err := toSchemaMessageParts(msg.MultiContent)
+			if err != nil {
+				return nil, fmt.Errorf("转换第%d条消息失败: %w", i+1, err)
+			}
+			if text, ok := joinTextParts(parts); ok {
+				schemaMsg.Content = text
+			} else {
+				schemaMsg.Content = ""
+				schemaMsg.MultiContent = parts
+			}
+		}
+
+		for _, toolCall := range msg.ToolCalls {
+			toolNames[toolCall.ID] = toolCall.Function.Name
+			schemaMsg.ToolCalls = append(schemaMsg.ToolCalls, schema.ToolCall{
+				Index: toolCall.Index,
+				ID:    toolCall.ID,
+				Type:  string(toolCall.Type),
+				Function: schema.FunctionCall{
+					Name:      toolCall.Function.Name,
+					Arguments: toolCall.Function.Arguments,
+				},
+			})
+		}
+
+		if msg.Role == openai.ChatMessageRoleTool {
+			schemaMsg.ToolName = toolNames[msg.ToolCallID]
+			if schemaMsg.ToolName == "" {
+				schemaMsg.ToolName = msg.Name
+			}
+		}
+
+		schemaMessages[i] = schemaMsg
+	}
+	return schemaMessages, nil
+}
+
+// chatMessagePartTypeFile OpenAI的文件内容类型，go-openai 未定义该常量
+const chatMessagePartTypeFile openai.ChatMessagePartType = "file"
+
+// toSchemaMessageParts 转换多模态消息内容，目前支持文本和图片(image_url)
+// go-openai 的 ChatMessagePart 没有 file 字段，解析请求时文件数据已经丢失，无法转换为eino的文件内容，
+// 因此文件内容(file)被忽略，并通过 UnsupportedParams 中的 ParamFileContent 告知客户端
+func toSchemaMessageParts(parts []openai.ChatMessagePart) ([]schema.ChatMessagePart, error) {
+	converted := make([]schema.ChatMessagePart, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			converted = append(converted, schema.ChatMessagePart{Type: schema.ChatMessagePartTypeText, Text: part.Text})
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL == nil || part.ImageURL.URL == "" {
+				return nil, invalidRequestf("图片内容缺少url")
+			}
+			mimeType, _, _ := parseDataURL(part.ImageURL.URL)
+			converted = append(converted, schema.ChatMessagePart{
+				Type: schema.ChatMessagePartTypeImageURL,
+				ImageURL: &schema.ChatMessageImageURL{
+					URL:      part.ImageURL.URL,
+					Detail:   schema.ImageURLDetail(part.ImageURL.Detail),
+					MIMEType: mimeType,
+				},
+			})
+		case chatMessagePartTypeFile:
+			continue
+		default:
+			return nil, invalidRequestf("不支持的消息内容类型: %s", part.Type)
+		}
+	}
+	return converted, nil
+}
+
+// joinTextParts 多段内容全部是文本时按换行合并
+func joinTextParts(parts []schema.ChatMessagePart) (string, bool) {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != schema.ChatMessagePartTypeText {
+			return "", false
+		}
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n"), true
+}
+
+// parseDataURL 解析 data:<mime>;base64,<data> 格式的URL，返回MIME类型和base64数据
+func parseDataURL(rawURL string) (mimeType, data string, ok bool) {
+	rest, found := strings.CutPrefix(rawURL, "data:")
+	if !found {
+		return "", "", false
+	}
+	header, data, found := strings.Cut(rest, ",")
+	if !found {
+		return "", "", false
+	}
+	mimeType, params, _ := strings.Cut(header, ";")
+	if !strings.Contains(";"+params+";", ";base64;") {
+		return "", "", false
+	}
+	return mimeType, data, true
+}
+
+// toUsage 将eino的Token使用情况转换为OpenAI格式
+func toUsage(meta *schema.ResponseMeta) openai.Usage {
+	if meta == nil || meta.Usage == nil {
+		return openai.Usage{}
+	}
+	return openai.Usage{
+		PromptTokens:     meta.Usage.PromptTokens,
+		CompletionTokens: meta.Usage.CompletionTokens,
+		TotalTokens:      meta.Usage.TotalTokens,
+	}
+}
+
+// toChatCompletionResponse 将eino的响应消息转换为OpenAI格式的完整响应
+// 推理内容放在 reasoning_content 中，供应商未返回推理Token数时按推理内容估算
+func toChatCompletionResponse(vendor, model string, msg *schema.Message) *openai.ChatCompletionResponse {
+	reasoning := reasoningContent(msg)
+	choice := openai.ChatCompletionChoice{
+		Index: 0,
+		Message: openai.ChatCompletionMessage{
+			Role:             string(msg.Role),
+			Content:          msg.Content,
+			ReasoningContent: reasoning,
+		},
+		FinishReason: openai.FinishReasonStop, // 默认值，供应商返回了完成原因时以供应商为准
+	}
+	if len(msg.ToolCalls) > 0 {
+		choice.Message.ToolCalls = convertToolCalls(msg.ToolCalls)
+		choice.FinishReason = openai.FinishReasonToolCalls
+	}
+	if msg.ResponseMeta != nil && msg.ResponseMeta.FinishReason != "" {
+		choice.FinishReason = openai.FinishReason(msg.ResponseMeta.FinishReason)
+	}
+
+	return &openai.ChatCompletionResponse{
+		ID:      fmt.Sprintf("%s-%d", vendor, time.Now().UnixNano()),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []openai.ChatCompletionChoice{choice},
+		Usage:   withReasoningTokens(toUsage(msg.ResponseMeta), reasoning),
+	}
+}
+
+// toChatCompletionStream 将eino的消息流转换为OpenAI格式的增量响应流
+// 转换在单独的goroutine中进行，转换结束、ctx取消或下游关闭读取端时，
+// 关闭上游流并调用cancel释放上游请求
+func toChatCompletionStream(ctx context.Context, cancel context.CancelFunc, vendor, model string, streamReader *schema.StreamReader[*schema.Message]) *schema.StreamReader[*openai.ChatCompletionStreamResponse] {
+	resultReader, resultWriter := schema.Pipe[*openai.ChatCompletionStreamResponse](10)
+
+	go func() {
+		defer func() {
+			if panicErr := recover(); panicErr != nil {
+				fmt.Printf("%s Stream处理发生异常: %v\n", vendor, panicErr)
+				_ = resultWriter.Send(nil, fmt.Errorf("%s Stream处理发生异常: %v", vendor, panicErr))
+			}
+			cancel()
+			streamReader.Close()
+			resultWriter.Close()
+		}()
+
+		// 同一次调用的所有分片使用相同的ID
+		uniqueID := fmt.Sprintf("%s-stream-%d", vendor, time.Now().UnixNano())
+		created := time.Now().Unix()
+
+		for {
+			// 调用方已取消，不再继续读取上游
+			if ctx.Err() != nil {
+				_ = resultWriter.Send(nil, ctx.Err())
+				return
+			}
+
+			message, err := streamReader.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					err = ctxErr
+				}
+				_ = resultWriter.Send(nil, fmt.Errorf("从%s接收流数据失败: %w", vendor, err))
+				return
+			}
+
+			streamResp := &openai.ChatCompletionStreamResponse{
+				ID:      uniqueID,
+				Object:  "chat.completion.chunk",
+				Created: created,
+				Model:   model,
+				Choices: []openai.ChatCompletionStreamChoice{
+					{
+						Index: 0,
+						Delta: openai.ChatCompletionStreamChoiceDelta{
+							Role:             string(message.Role),
+							Content:          message.Content,
+							ReasoningContent: reasoningContent(message),
+							ToolCalls:        convertToolCalls(message.ToolCalls),
+						},
+					},
+				},
+			}
+
+			// 最后一个分片携带完成原因
+			if message.ResponseMeta != nil && message.ResponseMeta.FinishReason != "" {
+				streamResp.Choices[0].FinishReason = openai.FinishReason(message.ResponseMeta.FinishReason)
+			}
+			// 供应商在分片中返回了Token用量
+			if message.ResponseMeta != nil && message.ResponseMeta.Usage != nil {
+				usage := toUsage(message.ResponseMeta)
+				streamResp.Usage = &usage
+			}
+
+			if closed := resultWriter.Send(streamResp, nil); closed {
+				return
+			}
+		}
+	}()
+
+	return resultReader
+}
+
+// streamOutput 流式响应的输出选项
+type streamOutput struct {
+	includeUsage  bool // 在 [DONE] 之前输出用量分片，即请求设置了 stream_options.include_usage
+	hideReasoning bool // 不输出推理内容，即请求设置了 hide_reasoning
+}
+
+// newStreamOutput 根据请求生成流式响应的输出选项
+func newStreamOutput(req ChatRequest) streamOutput {
+	return streamOutput{
+		includeUsage:  req.StreamOptions != nil && req.StreamOptions.IncludeUsage,
+		hideReasoning: req.HideReasoning,
+	}
+}
+
+// writeStream 将OpenAI格式的增量响应流以SSE格式写入writer，每个分片写入后立即刷新，结束时写入 [DONE] 标记
+// 供应商在分片中返回的用量不直接输出，汇总后在 output.includeUsage 为true时于 [DONE] 之前单独输出一个 choices 为空的用量分片；
+// output.hideReasoning 为true时去掉分片中的推理内容，只包含推理内容的分片不输出，推理Token仍计入用量；
+// ctx 取消(如客户端断开)时立即停止写入并关闭流，上游请求随之中止；
+// ctx 携带 CallInfo 时记录响应ID和分片中汇总的Token用量
+func writeStream(ctx context.Context, streamReader *schema.StreamReader[*openai.ChatCompletionStreamResponse], writer *sseWriter, output streamOutput) error {
+	defer streamReader.Close()
+
+	info := callInfoFromContext(ctx)
+	if info == nil {
+		info = &CallInfo{}
+	}
+
+	var last openai.ChatCompletionStreamResponse
+	// reasoning 已输出的推理内容，供应商未返回推理Token数时用于估算
+	var reasoning strings.Builder
+	// roleSent 是否已经输出过角色
+	var roleSent bool
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		response, err := streamReader.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("接收流式响应失败: %w", err)
+		}
+		info.ResponseID = response.ID
+		last = *response
+		if response.Usage != nil {
+			info.Usage = mergeUsage(info.Usage, *response.Usage)
+			chunk := *response
+			chunk.Usage = nil
+			response = &chunk
+		}
+		for _, choice := range response.Choices {
+			reasoning.WriteString(choice.Delta.ReasoningContent)
+		}
+		if output.hideReasoning {
+			response = withoutReasoning(response, roleSent)
+		}
+		roleSent = roleSent || hasRole(response)
+		// 只包含用量(或隐藏的推理内容)的分片
+		if len(response.Choices) == 0 {
+			continue
+		}
+
+		data, err := json.Marshal(response)
+		if err != nil {
+			return fmt.Errorf("序列化流式响应失败: %w", err)
+		}
+		if err := writer.writeData(data); err != nil {
+			return fmt.Errorf("写入流式响应失败: %w", err)
+		}
+	}
+
+	info.Usage = withReasoningTokens(info.Usage, reasoning.String())
+	if output.includeUsage {
+		usage := info.Usage
+		data, err := json.Marshal(openai.ChatCompletionStreamResponse{
+			ID:      last.ID,
+			Object:  "chat.completion.chunk",
+			Created: last.Created,
+			Model:   last.Model,
+			Choices: []openai.ChatCompletionStreamChoice{},
+			Usage:   &usage,
+		})
+		if err != nil {
+			return fmt.Errorf("序列化用量分片失败: %w", err)
+		}
+		if err := writer.writeData(data); err != nil {
+			return fmt.Errorf("写入用量分片失败: %w", err)
+		}
+	}
+
+	// 添加结束标记
+	if err := writer.writeData([]byte("[DONE]")); err != nil {
+		return fmt.Errorf("写入流式响应结束标记失败: %w", err)
+	}
+
+	return nil
+}
+
+// mergeUsage 合并流式分片中的Token用量
+// 部分供应商分多次返回用量(如Claude先返回输入Token，结束时返回输出Token)，且数值是累计值，因此逐项取最大值
+func mergeUsage(total, chunk openai.Usage) openai.Usage {
+	total.PromptTokens = max(total.PromptTokens, chunk.PromptTokens)
+	total.CompletionTokens = max(total.CompletionTokens, chunk.CompletionTokens)
+	total.TotalTokens = max(total.TotalTokens, chunk.TotalTokens, total.PromptTokens+total.CompletionTokens)
+	if chunk.PromptTokensDetails != nil && (total.PromptTokensDetails == nil || chunk.PromptTokensDetails.CachedTokens > total.PromptTokensDetails.CachedTokens) {
+		total.PromptTokensDetails = &openai.PromptTokensDetails{CachedTokens: chunk.PromptTokensDetails.CachedTokens}
+	}
+	if reasoningTokens(chunk) > reasoningTokens(total) {
+		total.CompletionTokensDetails = &openai.CompletionTokensDetails{ReasoningTokens: reasoningTokens(chunk)}
+	}
+	return total
+}
+
+// addUsage 累加多次调用的Token用量
+func addUsage(total, usage openai.Usage) openai.Usage {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+	if usage.PromptTokensDetails != nil {
+		if total.PromptTokensDetails == nil {
+			total.PromptTokensDetails = &openai.PromptTokensDetails{}
+		}
+		total.PromptTokensDetails.CachedTokens += usage.PromptTokensDetails.CachedTokens
+	}
+	if usage.CompletionTokensDetails != nil {
+		if total.CompletionTokensDetails == nil {
+			total.CompletionTokensDetails = &openai.CompletionTokensDetails{}
+		}
+		total.CompletionTokensDetails.ReasoningTokens += usage.CompletionTokensDetails.ReasoningTokens
+	}
+	return total
+}
+
+// convertToolCalls 将eino的工具调用转换为OpenAI格式
+func convertToolCalls(toolCalls []schema.ToolCall) []openai.ToolCall {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+	converted := make([]openai.ToolCall, len(toolCalls))
+	for i, tc := range toolCalls {
+		converted[i] = openai.ToolCall{
+			Index: tc.Index,
+			ID:    tc.ID,
+			Type:  openai.ToolType(tc.Type),
+			Function: openai.FunctionCall{
+				Name:      tc.Function.Name,
+				Arguments: tc.Function.Arguments,
+			},
+		}
+	}
+	return converted
+}
+
+// getRequiredFields 从参数对象中提取required字段
+func getRequiredFields(paramsObj map[string]interface{}) []string {
+	if required, ok := paramsObj["required"].([]interface{}); ok {
+		result := make([]string, 0, len(required))
+		for _, r := range required {
+			if s, ok := r.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// tool_choice 的取值，指定函数时按 toolChoiceRequired 处理
+const (
+	toolChoiceNone     = "none"
+	toolChoiceAuto     = "auto"
+	toolChoiceRequired = "required"
+)
+
+// parseToolChoice 解析OpenAI的 tool_choice，返回调用方式以及指定的函数名称
+// "none" 禁止调用、"auto" 由模型决定、"required"(或Gemini的 "any")必须调用；
+// 指定函数时返回 "required" 和函数名称，无法识别时返回空
+func parseToolChoice(toolChoice any) (choice string, function string) {
+	switch v := toolChoice.(type) {
+	case nil:
+		return "", ""
+	case string:
+		switch v {
+		case toolChoiceNone, toolChoiceAuto, toolChoiceRequired:
+			return v, ""
+		case "any":
+			return toolChoiceRequired, ""
+		}
+		return "", ""
+	default:
+		// 请求JSON解析后为map，直接调用时可能是 openai.ToolChoice
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", ""
+		}
+		var named openai.ToolChoice
+		if err := json.Unmarshal(data, &named); err != nil || named.Function.Name == "" {
+			return "", ""
+		}
+		return toolChoiceRequired, named.Function.Name
+	}
+}
+
+// convertToolInfos 转换工具信息并过滤同名工具
+// 参数的JSON Schema中的 $ref 替换为所指向的Schema，各属性原样保留，包括嵌套对象的 properties、数组的 items、enum 和 required 等
+func convertToolInfos(reqTools []openai.Tool) ([]*schema.ToolInfo, error) {
+	tools := make([]*schema.ToolInfo, 0, len(reqTools))
+	toolNameMap := make(map[string]bool) // 用于记录已存在的工具名称
+
+	for _, tool := range reqTools {
+		if tool.Function == nil {
+			continue
+		}
+
+		// 检查工具名称是否已存在，如果存在则跳过
+		if _, exists := toolNameMap[tool.Function.Name]; exists {
+			currentLogger().Warn("跳过重复的工具名称", zap.String("tool", tool.Function.Name))
+			continue
+		}
+		toolNameMap[tool.Function.Name] = true
+
+		params, err := toJSONMap(tool.Function.Parameters)
+		if err != nil {
+			return nil, invalidRequestf("工具 %s 的参数格式不正确: %v", tool.Function.Name, err)
+		}
+		if params, err = inlineSchemaRefs(params); err != nil {
+			return nil, fmt.Errorf("工具 %s 的参数: %w", tool.Function.Name, err)
+		}
+		properties, err := schemaProperties(params)
+		if err != nil {
+			return nil, fmt.Errorf("工具 %s 的参数: %w", tool.Function.Name, err)
+		}
+
+		tools = append(tools, &schema.ToolInfo{
+			Name: tool.Function.Name,
+			Desc: tool.Function.Description,
+			ParamsOneOf: schema.NewParamsOneOfByOpenAPIV3(&openapi3.Schema{
+				Type:       openapi3.TypeObject,
+				Properties: properties,
+				Required:   getRequiredFields(params),
+			}),
+		})
+	}
+
+	return tools, nil
+}
+
+// schemaProperties 将对象类型的JSON Schema的属性转换为 openapi3 的属性
+// 属性的JSON Schema作为 openapi3.Schema 的扩展字段保存，序列化时原样输出，不受 openapi3 与JSON Schema差异的影响(如 type 为数组)
+func schemaProperties(jsonSchema map[string]any) (map[string]*openapi3.SchemaRef, error) {
+	properties := make(map[string]*openapi3.SchemaRef)
+	props, ok := jsonSchema["properties"].(map[string]any)
+	if !ok {
+		if _, exists := jsonSchema["properties"]; exists {
+			return nil, invalidRequestf("JSON Schema的 properties 格式不正确")
+		}
+		return properties, nil
+	}
+	for name, value := range props {
+		property, ok := value.(map[string]any)
+		if !ok {
+			return nil, invalidRequestf("JSON Schema的属性 %s 格式不正确", name)
+		}
+		properties[name] = &openapi3.SchemaRef{Value: &openapi3.Schema{Extensions: property}}
+	}
+	return properties, nil
+}
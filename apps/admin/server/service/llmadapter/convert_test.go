@@ -0,0 +1,159 @@
+package llmadapter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// testImageURL 1x1 PNG图片的data URL
+const testImageURL = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mP8z8BQDwAEhQGAhKmMIQAAAABJRU5ErkJggg=="
+
+// toolHistoryMessages 包含图片输入、工具调用和工具结果的ReAct对话历史
+func toolHistoryMessages() []openai.ChatCompletionMessage {
+	return []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "你是天气助手"},
+		{Role: openai.ChatMessageRoleUser, Name: "alice", MultiContent: []openai.ChatMessagePart{
+			{Type: openai.ChatMessagePartTypeText, Text: "这张照片是在哪个城市拍的？那里天气怎么样"},
+			{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{URL: testImageURL, Detail: openai.ImageURLDetailLow}},
+		}},
+		{Role: openai.ChatMessageRoleAssistant, ToolCalls: []openai.ToolCall{
+			{ID: "call_1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "get_weather", Arguments: `{"city":"北京"}`}},
+		}},
+		{Role: openai.ChatMessageRoleTool, ToolCallID: "call_1", Content: `{"weather":"晴"}`},
+	}
+}
+
+// TestToSchemaMessages 测试工具调用、工具结果、name和多模态内容的转换
+func TestToSchemaMessages(t *testing.T) {
+	messages, err := toSchemaMessages(toolHistoryMessages())
+	if !assert.NoError(t, err) || !assert.Len(t, messages, 4) {
+		return
+	}
+
+	assert.Equal(t, &schema.Message{Role: schema.System, Content: "你是天气助手"}, messages[0])
+
+	user := messages[1]
+	assert.Equal(t, "alice", user.Name)
+	assert.Empty(t, user.Content)
+	assert.Equal(t, []schema.ChatMessagePart{
+		{Type: schema.ChatMessagePartTypeText, Text: "这张照片是在哪个城市拍的？那里天气怎么样"},
+		{Type: schema.ChatMessagePartTypeImageURL, ImageURL: &schema.ChatMessageImageURL{
+			URL: testImageURL, Detail: schema.ImageURLDetailLow, MIMEType: "image/png",
+		}},
+	}, user.MultiContent)
+
+	assert.Equal(t, []schema.ToolCall{{
+		ID:       "call_1",
+		Type:     "function",
+		Function: schema.FunctionCall{Name: "get_weather", Arguments: `{"city":"北京"}`},
+	}}, messages[2].ToolCalls)
+
+	tool := messages[3]
+	assert.Equal(t, schema.Tool, tool.Role)
+	assert.Equal(t, "call_1", tool.ToolCallID)
+	assert.Equal(t, "get_weather", tool.ToolName)
+	assert.Equal(t, `{"weather":"晴"}`, tool.Content)
+
+	// 只包含文本的多段内容合并为一段文本
+	messages, err = toSchemaMessages([]openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, MultiContent: []openai.ChatMessagePart{
+		{Type: openai.ChatMessagePartTypeText, Text: "第一段"},
+		{Type: openai.ChatMessagePartTypeText, Text: "第二段"},
+	}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "第一段\n第二段", messages[0].Content)
+	assert.Empty(t, messages[0].MultiContent)
+
+	// 不支持的内容类型和缺少url的图片返回错误，不静默丢弃
+	_, err = toSchemaMessages([]openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, MultiContent: []openai.ChatMessagePart{{Type: "input_audio"}}}})
+	assert.ErrorContains(t, err, "不支持的消息内容类型: input_audio")
+	_, err = toSchemaMessages([]openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, MultiContent: []openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeImageURL}}}})
+	assert.ErrorContains(t, err, "图片内容缺少url")
+
+	// 文件内容被忽略，由 UnsupportedParams 告知客户端
+	messages, err = toSchemaMessages([]openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, MultiContent: []openai.ChatMessagePart{
+		{Type: openai.ChatMessagePartTypeText, Text: "总结这份文件"},
+		{Type: "file"},
+	}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "总结这份文件", messages[0].Content)
+	assert.Empty(t, messages[0].MultiContent)
+}
+
+// TestParseDataURL 测试data URL解析
+func TestParseDataURL(t *testing.T) {
+	mimeType, data, ok := parseDataURL("data:image/jpeg;base64,/9j/4AAQ")
+	assert.True(t, ok)
+	assert.Equal(t, "image/jpeg", mimeType)
+	assert.Equal(t, "/9j/4AAQ", data)
+
+	_, _, ok = parseDataURL("https://example.com/cat.png")
+	assert.False(t, ok)
+	_, _, ok = parseDataURL("data:text/plain,hello")
+	assert.False(t, ok)
+}
+
+// TestConvertToolInfos 工具参数保留嵌套的对象、数组、枚举和 required，$ref 替换为所指向的Schema
+func TestConvertToolInfos(t *testing.T) {
+	params := `{
+		"type": "object",
+		"properties": {
+			"city": {"type": "string", "enum": ["北京", "上海"]},
+			"days": {"type": ["integer", "null"]},
+			"options": {
+				"type": "object",
+				"properties": {"unit": {"$ref": "#/$defs/unit"}},
+				"required": ["unit"]
+			},
+			"hours": {"type": "array", "items": {"type": "integer", "minimum": 0}}
+		},
+		"required": ["city"],
+		"$defs": {"unit": {"type": "string", "enum": ["celsius", "fahrenheit"]}}
+	}`
+	tools, err := convertToolInfos([]openai.Tool{
+		{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "get_weather", Description: "查询天气", Parameters: params}},
+		{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "get_weather", Parameters: `{"type": "object"}`}},
+		{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "now"}},
+	})
+	if !assert.NoError(t, err) || !assert.Len(t, tools, 2) {
+		return
+	}
+
+	assert.Equal(t, "查询天气", tools[0].Desc)
+	openAPISchema, err := tools[0].ToOpenAPIV3()
+	if !assert.NoError(t, err) {
+		return
+	}
+	data, err := json.Marshal(openAPISchema)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"city": {"type": "string", "enum": ["北京", "上海"]},
+			"days": {"type": ["integer", "null"]},
+			"options": {
+				"type": "object",
+				"properties": {"unit": {"type": "string", "enum": ["celsius", "fahrenheit"]}},
+				"required": ["unit"]
+			},
+			"hours": {"type": "array", "items": {"type": "integer", "minimum": 0}}
+		},
+		"required": ["city"]
+	}`, string(data))
+
+	// 没有参数的工具
+	openAPISchema, err = tools[1].ToOpenAPIV3()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "object", openAPISchema.Type)
+		assert.Empty(t, openAPISchema.Properties)
+	}
+
+	// 格式不正确的参数返回请求参数错误
+	_, err = convertToolInfos([]openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "bad", Parameters: `{"properties": {"a": 1}}`}}})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	_, err = convertToolInfos([]openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "bad", Parameters: `{"properties": {"a": {"$ref": "#/$defs/missing"}}}`}}})
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
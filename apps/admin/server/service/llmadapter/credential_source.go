@@ -0,0 +1,310 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v2"
+)
+
+// CredentialSource 配置文件以外的凭证来源，如数据库
+// 配置文件作为只读来源继续生效，同一环境中与配置文件同名的凭证以 CredentialSource 为准
+type CredentialSource interface {
+	// Credentials 返回供应商所有环境的凭证配置，格式与配置文件相同(可由 MarshalCredentials 生成)
+	// 没有该供应商的凭证时返回nil
+	Credentials(vendor string) ([]byte, error)
+}
+
+// CredentialConfig 与存储方式无关的凭证配置，用于在配置文件和其他来源之间转换
+type CredentialConfig struct {
+	Env    string // 运行环境
+	Vendor string // 供应商
+	CredentialBase
+	Region  string            // 地域，只有部分供应商使用
+	BaseURL string            // 接口地址，对应各供应商的 base_url、endpoint、api_endpoint 或 host
+	Proxy   string            // 代理地址
+	Secrets map[string]string // 敏感字段，如 api_key，值为加密后的内容
+	Options map[string]any    // 其他供应商字段，如 deployment_id、timeout
+}
+
+// baseURLFields 接口地址在各供应商配置中的字段名，未列出的供应商为 base_url
+var baseURLFields = map[string]string{
+	"azure":  "endpoint",
+	"gemini": "api_endpoint",
+	"ollama": "host",
+}
+
+// secretFields 各供应商加密保存的字段，与凭证的 decrypted 方法解密的字段一致，未列出的供应商为 api_key
+var secretFields = map[string][]string{
+	"bedrock": {"access_key", "secret_access_key"},
+}
+
+// SecretFields 返回供应商加密保存的字段名
+func SecretFields(vendor string) []string {
+	if fields, ok := secretFields[vendor]; ok {
+		return append([]string(nil), fields...)
+	}
+	return []string{"api_key"}
+}
+
+// CredentialVendors 返回所有支持凭证配置的供应商(按字母排序)
+func CredentialVendors() []string {
+	vendors := make([]string, 0, len(credentialLoaders))
+	for vendor := range credentialLoaders {
+		vendors = append(vendors, vendor)
+	}
+	sort.Strings(vendors)
+	return vendors
+}
+
+// baseURLField 返回供应商接口地址的字段名
+func baseURLField(vendor string) string {
+	if field, ok := baseURLFields[vendor]; ok {
+		return field
+	}
+	return "base_url"
+}
+
+// ReadCredentialFile 读取配置目录中供应商配置文件的所有凭证(含未启用和其他环境的凭证)，敏感字段保持加密
+// 配置文件不存在时返回空列表
+func ReadCredentialFile(dir, vendor string) ([]CredentialConfig, error) {
+	loader, ok := credentialLoaders[vendor]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, vendor)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, loader.file))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取%s配置文件失败: %v", loader.label, err)
+	}
+	return parseCredentialConfigs(vendor, data)
+}
+
+// parseCredentialConfigs 将配置文件格式的内容转换为凭证配置(按环境名称排序)
+func parseCredentialConfigs(vendor string, data []byte) ([]CredentialConfig, error) {
+	loader := credentialLoaders[vendor]
+	var file credentialFile[map[string]any]
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析%s配置文件失败: %v", loader.label, err)
+	}
+
+	envs := make([]string, 0, len(file.Environments))
+	for env := range file.Environments {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	var configs []CredentialConfig
+	for _, env := range envs {
+		for _, fields := range file.Environments[env].Credentials {
+			config, err := credentialConfigFromFields(vendor, env, fields)
+			if err != nil {
+				return nil, fmt.Errorf("环境 %s 中%s凭证格式错误: %v", env, loader.label, err)
+			}
+			configs = append(configs, config)
+		}
+	}
+	return configs, nil
+}
+
+// MarshalCredentials 将同一供应商的凭证配置转换为配置文件格式
+func MarshalCredentials(configs []CredentialConfig) ([]byte, error) {
+	environments := make(map[string]map[string][]map[string]any)
+	for _, config := range configs {
+		env, ok := environments[config.Env]
+		if !ok {
+			env = map[string][]map[string]any{"credentials": {}}
+			environments[config.Env] = env
+		}
+		env["credentials"] = append(env["credentials"], config.Fields())
+	}
+	return yaml.Marshal(map[string]any{"environments": environments})
+}
+
+// credentialConfigFromFields 将配置文件中的一个凭证转换为 CredentialConfig
+func credentialConfigFromFields(vendor, env string, fields map[string]any) (CredentialConfig, error) {
+	fields, _ = normalizeYAML(fields).(map[string]any)
+
+	// 通用字段沿用配置文件的解析规则
+	var base struct {
+		CredentialBase `yaml:",inline"`
+	}
+	raw, err := yaml.Marshal(fields)
+	if err != nil {
+		return CredentialConfig{}, err
+	}
+	if err := yaml.Unmarshal(raw, &base); err != nil {
+		return CredentialConfig{}, err
+	}
+
+	config := CredentialConfig{
+		Env:            env,
+		Vendor:         vendor,
+		CredentialBase: base.CredentialBase,
+		Secrets:        make(map[string]string),
+		Options:        make(map[string]any),
+	}
+	urlField := baseURLField(vendor)
+	secrets := SecretFields(vendor)
+	for key, value := range fields {
+		switch key {
+		case "name", "enabled", "weight", "qps_limit", "description", "models":
+		case "region":
+			config.Region = fmt.Sprint(value)
+		case "proxy":
+			config.Proxy = fmt.Sprint(value)
+		case urlField:
+			config.BaseURL = fmt.Sprint(value)
+		default:
+			if containsString(secrets, key) {
+				if value != nil && value != "" {
+					config.Secrets[key] = fmt.Sprint(value)
+				}
+				continue
+			}
+			config.Options[key] = value
+		}
+	}
+	return config, nil
+}
+
+// Fields 转换为配置文件中的一个凭证(字段名 -> 值)
+func (c CredentialConfig) Fields() map[string]any {
+	fields := make(map[string]any, len(c.Options)+len(c.Secrets)+9)
+	for key, value := range c.Options {
+		fields[key] = value
+	}
+	for key, value := range c.Secrets {
+		fields[key] = value
+	}
+	if c.Region != "" {
+		fields["region"] = c.Region
+	}
+	if c.Proxy != "" {
+		fields["proxy"] = c.Proxy
+	}
+	if c.BaseURL != "" {
+		fields[baseURLField(c.Vendor)] = c.BaseURL
+	}
+	fields["name"] = c.Name
+	fields["enabled"] = c.Enabled
+	fields["weight"] = c.Weight
+	fields["qps_limit"] = c.QPSLimit
+	fields["description"] = c.Description
+	fields["models"] = c.Models
+	return fields
+}
+
+// normalizeYAML 将 yaml.v2 解析出的 map[interface{}]interface{} 转换为 map[string]any，便于JSON序列化
+func normalizeYAML(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(value)
+		}
+		return m
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[key] = normalizeYAML(value)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, value := range v {
+			s[i] = normalizeYAML(value)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	defaultSourceMu sync.Mutex
+	defaultSource   CredentialSource
+)
+
+// SetCredentialSource 设置默认凭证缓存的额外凭证来源并立即重新加载
+// 来源中的凭证变化后需要调用 ReloadCredentials
+func SetCredentialSource(source CredentialSource) error {
+	defaultSourceMu.Lock()
+	defaultSource = source
+	defaultSourceMu.Unlock()
+	return DefaultCredentialStore().SetSource(source)
+}
+
+// ReloadCredentials 重新加载默认凭证缓存
+func ReloadCredentials() error {
+	return DefaultCredentialStore().Reload()
+}
+
+// credentialOverride 测试凭证时代替凭证缓存的凭证
+type credentialOverride struct {
+	vendor string
+	creds  any // []T
+}
+
+type credentialOverrideKey struct{}
+
+// TestCredential 使用给定的凭证配置调用一次供应商，用于在保存或启用前检查凭证是否可用
+// 凭证不需要启用，也不需要属于当前环境；model 为空时使用凭证配置的第一个模型
+func TestCredential(ctx context.Context, config CredentialConfig, model string) error {
+	loader, ok := credentialLoaders[config.Vendor]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedProvider, config.Vendor)
+	}
+	if model == "" {
+		if len(config.Models) == 0 {
+			return fmt.Errorf("凭证 %s 未配置模型，请指定测试使用的模型", config.Name)
+		}
+		model = config.Models[0]
+	}
+
+	env := currentEnv()
+	config.Env, config.Enabled, config.Models = env, true, []string{model}
+	data, err := MarshalCredentials([]CredentialConfig{config})
+	if err != nil {
+		return err
+	}
+	decrypt, err := defaultDecrypt()
+	if err != nil {
+		return err
+	}
+	environments, err := loader.parse(data, LLMConfigPath, env, decrypt)
+	if err != nil {
+		return err
+	}
+	provider, err := GetProvider(config.Vendor)
+	if err != nil {
+		return err
+	}
+
+	ctx = context.WithValue(ctx, credentialOverrideKey{}, credentialOverride{
+		vendor: config.Vendor,
+		creds:  loader.credentials(environments, env),
+	})
+	_, err = provider.Generate(ctx, ChatRequest{Provider: config.Vendor, ChatCompletionRequest: openai.ChatCompletionRequest{
+		Model:     model,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "ping"}},
+		MaxTokens: 1,
+	}})
+	return err
+}
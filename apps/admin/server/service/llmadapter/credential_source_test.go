@@ -0,0 +1,145 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// staticSource 测试用的凭证来源
+type staticSource map[string][]CredentialConfig
+
+func (s staticSource) Credentials(vendor string) ([]byte, error) {
+	configs, ok := s[vendor]
+	if !ok {
+		return nil, nil
+	}
+	return MarshalCredentials(configs)
+}
+
+// TestReadCredentialFile 配置文件中的凭证按字段分类，敏感字段保持加密
+func TestReadCredentialFile(t *testing.T) {
+	dir := t.TempDir()
+	content := `environments:
+  production:
+    credentials:
+      - name: east
+        api_key: enc:key
+        endpoint: https://east.openai.azure.com
+        deployment_id: gpt-4o
+        proxy: http://127.0.0.1:7890
+        enabled: true
+        weight: 2
+        qps_limit: 10
+        models: ["gpt-4o"]
+  development:
+    credentials:
+      - name: dev
+        api_key: YOUR_API_KEY_HERE
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "azure.yaml"), []byte(content), 0644))
+
+	configs, err := ReadCredentialFile(dir, "azure")
+	if !assert.NoError(t, err) || !assert.Len(t, configs, 2) {
+		return
+	}
+	assert.Equal(t, "development", configs[0].Env)
+	east := configs[1]
+	assert.Equal(t, CredentialBase{Name: "east", Enabled: true, Weight: 2, QPSLimit: 10, Models: []string{"gpt-4o"}}, east.CredentialBase)
+	assert.Equal(t, "https://east.openai.azure.com", east.BaseURL)
+	assert.Equal(t, "http://127.0.0.1:7890", east.Proxy)
+	assert.Equal(t, map[string]string{"api_key": "enc:key"}, east.Secrets)
+	assert.Equal(t, map[string]any{"deployment_id": "gpt-4o"}, east.Options)
+
+	// 转换回配置文件格式后解析结果一致
+	data, err := MarshalCredentials(configs)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var calls int32
+	environments, err := parseCredentials[AzureCredential]("Azure", data, "", "production", testDecrypt(&calls))
+	if !assert.NoError(t, err) || !assert.Len(t, environments["production"], 1) {
+		return
+	}
+	cred := environments["production"][0]
+	assert.Equal(t, "key", cred.ApiKey)
+	assert.Equal(t, "https://east.openai.azure.com", cred.Endpoint)
+	assert.Equal(t, "gpt-4o", cred.DeploymentId)
+	assert.Equal(t, "http://127.0.0.1:7890", cred.Proxy)
+
+	configs, err = ReadCredentialFile(dir, "claude")
+	assert.NoError(t, err)
+	assert.Empty(t, configs, "配置文件不存在时返回空列表")
+}
+
+// TestCredentialStoreSource 配置文件与凭证来源合并，同名凭证以来源为准
+func TestCredentialStoreSource(t *testing.T) {
+	dir := t.TempDir()
+	writeAzureCredentials(t, dir, "a", "b")
+
+	var calls int32
+	store := NewCredentialStore(dir, testDecrypt(&calls))
+	env := currentEnv()
+	source := staticSource{
+		"azure": {
+			{Env: env, Vendor: "azure", CredentialBase: CredentialBase{Name: "b", Enabled: true, Weight: 5}, Secrets: map[string]string{"api_key": "enc:db-b"}},
+			{Env: env, Vendor: "azure", CredentialBase: CredentialBase{Name: "c", Enabled: true, Weight: 1}, Secrets: map[string]string{"api_key": "enc:db-c"}},
+			{Env: env, Vendor: "azure", CredentialBase: CredentialBase{Name: "off", Enabled: false}},
+		},
+		// 没有配置文件的供应商只使用来源中的凭证
+		"claude": {
+			{Env: env, Vendor: "claude", CredentialBase: CredentialBase{Name: "db", Enabled: true, Weight: 1}, Secrets: map[string]string{"api_key": "enc:claude"}},
+		},
+	}
+	if !assert.NoError(t, store.SetSource(source)) {
+		return
+	}
+
+	creds, err := credentialsFrom[AzureCredential](store, "azure")
+	if !assert.NoError(t, err) {
+		return
+	}
+	got := make([]string, len(creds))
+	for i, cred := range creds {
+		got[i] = fmt.Sprintf("%s:%s:%d", cred.Name, cred.ApiKey, cred.Weight)
+	}
+	assert.Equal(t, []string{"a:key-a:1", "b:db-b:5", "c:db-c:1"}, got)
+
+	claude, err := credentialsFrom[ClaudeCredential](store, "claude")
+	if assert.NoError(t, err) && assert.Len(t, claude, 1) {
+		assert.Equal(t, "claude", claude[0].APIKey)
+	}
+
+	// 来源中的凭证无效时保留原有凭证
+	source["azure"][1].Secrets = map[string]string{"api_key": "invalid"}
+	assert.Error(t, store.Reload())
+	creds, _ = credentialsFrom[AzureCredential](store, "azure")
+	assert.Len(t, creds, 3)
+}
+
+// TestTestCredential 测试未启用的凭证时直接使用给定的配置，不经过凭证缓存
+func TestTestCredential(t *testing.T) {
+	server := newOpenAITestServer(t)
+	useTestCredentials(t, map[string]string{})
+
+	config := CredentialConfig{
+		Env:            "other",
+		Vendor:         "openai_compatible",
+		CredentialBase: CredentialBase{Name: "vllm", Models: []string{"Qwen/Qwen2.5-7B-Instruct"}},
+		BaseURL:        server.URL + "/v1",
+		Secrets:        map[string]string{"api_key": encryptTestKey(t, "vllm-key")},
+	}
+	if !assert.NoError(t, TestCredential(context.Background(), config, "")) {
+		return
+	}
+	req := server.lastRequest(t)
+	assert.Equal(t, "Bearer vllm-key", req.Authorization)
+	assert.Equal(t, "Qwen/Qwen2.5-7B-Instruct", req.Body["model"])
+
+	config.Models = nil
+	assert.Error(t, TestCredential(context.Background(), config, ""), "未配置模型时需要指定测试模型")
+}
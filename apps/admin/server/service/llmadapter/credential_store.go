@@ -0,0 +1,689 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+// DecryptFunc 解密凭证中加密字段的函数
+type DecryptFunc func(string) (string, error)
+
+// CredentialBase 各供应商凭证的通用字段
+// 以内联方式嵌入各供应商的凭证结构，yaml中与凭证的其他字段写在同一层级
+type CredentialBase struct {
+	Name        string   `yaml:"name"`        // 凭证名称，同一环境内唯一
+	Enabled     bool     `yaml:"enabled"`     // 是否启用
+	Weight      int      `yaml:"weight"`      // 负载均衡权重
+	QPSLimit    int      `yaml:"qps_limit"`   // QPS限制
+	Description string   `yaml:"description"` // 描述
+	Models      []string `yaml:"models"`      // 支持的模型列表
+}
+
+// credentialBase 返回凭证的通用字段，供泛型代码访问
+func (b CredentialBase) credentialBase() CredentialBase {
+	return b
+}
+
+// credential 供应商凭证需要实现的约束
+// decrypted 返回解密敏感字段后的凭证副本，加载配置时对每个启用的凭证调用一次
+type credential[T any] interface {
+	credentialBase() CredentialBase
+	decrypted(decrypt DecryptFunc) (T, error)
+}
+
+// dirCredential 凭证中包含相对于配置目录的文件路径时实现
+// resolved 返回将相对路径转换为 dir 下路径后的凭证副本，在 decrypted 之前调用
+type dirCredential[T any] interface {
+	resolved(dir string) T
+}
+
+// credentialFile 供应商配置文件结构
+type credentialFile[T any] struct {
+	Environments map[string]struct {
+		Credentials []T `yaml:"credentials"`
+	} `yaml:"environments"`
+}
+
+// credentialLoader 描述如何加载某个供应商的配置文件
+type credentialLoader struct {
+	// file 配置文件名，位于配置目录下
+	file string
+	// label 供应商的显示名称，用于错误信息
+	label string
+	// strict 严格解析配置文件，存在未知字段(如拼写错误)时返回错误
+	strict func(data []byte) error
+	// parse 解析配置文件并解密 env 环境启用的凭证，返回 map[string][]T (环境 -> 启用的凭证)
+	// dir 为加载该配置的配置目录，用于解析凭证中的相对路径
+	parse func(data []byte, dir, env string, decrypt DecryptFunc) (any, error)
+	// bases 返回 parse 结果中 env 环境凭证的通用字段
+	bases func(environments any, env string) []CredentialBase
+	// credentials 返回 parse 结果中 env 环境的凭证 []T
+	credentials func(environments any, env string) any
+	// merge 合并两个 parse 结果，override 中的同名凭证覆盖 base
+	merge func(base, override any, env string) any
+}
+
+// credentialLoaders 已注册的供应商配置 vendor -> loader，只在 init() 中写入
+var credentialLoaders = make(map[string]credentialLoader)
+
+// registerCredentials 注册供应商的凭证配置文件
+// 一般与 Register 一起在供应商实现文件的 init() 中调用
+func registerCredentials[T credential[T]](vendor, file, label string) {
+	if _, exists := credentialLoaders[vendor]; exists {
+		panic("llmadapter: 供应商 " + vendor + " 的凭证配置重复注册")
+	}
+	credentialLoaders[vendor] = credentialLoader{
+		file:  file,
+		label: label,
+		strict: func(data []byte) error {
+			var file credentialFile[T]
+			return yaml.UnmarshalStrict(data, &file)
+		},
+		parse: func(data []byte, dir, env string, decrypt DecryptFunc) (any, error) {
+			return parseCredentials[T](label, data, dir, env, decrypt)
+		},
+		bases: func(environments any, env string) []CredentialBase {
+			creds, _ := environments.(map[string][]T)
+			bases := make([]CredentialBase, len(creds[env]))
+			for i, cred := range creds[env] {
+				bases[i] = cred.credentialBase()
+			}
+			return bases
+		},
+		credentials: func(environments any, env string) any {
+			creds, _ := environments.(map[string][]T)
+			return creds[env]
+		},
+		merge: func(base, override any, env string) any {
+			return mergeCredentials(base.(map[string][]T), override.(map[string][]T), env)
+		},
+	}
+}
+
+// parseCredentials 解析配置文件、校验所有环境的凭证，并解密 env 环境中启用的凭证
+// 结果只保留 env 环境启用的凭证；其他环境和未启用的凭证不解密，允许其中保留占位内容。
+// 凭证中的相对路径相对于 dir，见 dirCredential
+func parseCredentials[T credential[T]](label string, data []byte, dir, env string, decrypt DecryptFunc) (map[string][]T, error) {
+	var file credentialFile[T]
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析%s配置文件失败: %v", label, err)
+	}
+
+	environments := make(map[string][]T, 1)
+	for envName, envConfig := range file.Environments {
+		names := make(map[string]bool)
+		enabled := make([]T, 0, len(envConfig.Credentials))
+		for i, cred := range envConfig.Credentials {
+			base := cred.credentialBase()
+			if base.Name == "" {
+				return nil, fmt.Errorf("环境 %s 的第 %d 个凭证缺少名称", envName, i+1)
+			}
+			if names[base.Name] {
+				return nil, fmt.Errorf("环境 %s 中凭证名称 %s 重复", envName, base.Name)
+			}
+			names[base.Name] = true
+			if base.Weight < 0 {
+				return nil, fmt.Errorf("环境 %s 中凭证 %s 的权重不能为负数", envName, base.Name)
+			}
+			if envName != env || !base.Enabled {
+				continue
+			}
+
+			if withDir, ok := any(cred).(dirCredential[T]); ok {
+				cred = withDir.resolved(dir)
+			}
+			decryptedCred, err := cred.decrypted(decrypt)
+			if err != nil {
+				return nil, fmt.Errorf("环境 %s 中凭证 %s %v", envName, base.Name, err)
+			}
+			enabled = append(enabled, decryptedCred)
+		}
+		if envName == env {
+			environments[env] = enabled
+		}
+	}
+	return environments, nil
+}
+
+// mergeCredentials 合并配置文件和 CredentialSource 中 env 环境的凭证
+// 同名凭证使用 override 中的配置并保持在 base 中的位置；两者都没有 env 环境时结果中也没有
+func mergeCredentials[T credential[T]](base, override map[string][]T, env string) map[string][]T {
+	baseCreds, inBase := base[env]
+	overrideCreds, inOverride := override[env]
+	if !inBase && !inOverride {
+		return map[string][]T{}
+	}
+
+	overrides := make(map[string]T, len(overrideCreds))
+	for _, cred := range overrideCreds {
+		overrides[cred.credentialBase().Name] = cred
+	}
+	merged := make([]T, 0, len(baseCreds)+len(overrideCreds))
+	for _, cred := range baseCreds {
+		name := cred.credentialBase().Name
+		if replacement, ok := overrides[name]; ok {
+			merged = append(merged, replacement)
+			delete(overrides, name)
+			continue
+		}
+		merged = append(merged, cred)
+	}
+	for _, cred := range overrideCreds {
+		if _, ok := overrides[cred.credentialBase().Name]; ok {
+			merged = append(merged, cred)
+		}
+	}
+	return map[string][]T{env: merged}
+}
+
+// vendorCredentials 单个供应商的凭证快照
+type vendorCredentials struct {
+	// raw、sourceRaw 配置文件和 source 中的原始内容，拒绝修改时用于比较凭证的变化
+	raw, sourceRaw []byte
+	// environments map[string][]T 运行环境 -> 启用且已解密的凭证
+	environments any
+	// err 读取或解析失败的原因，非nil时 environments 为空
+	err error
+}
+
+// credentialSnapshot 所有供应商凭证的不可变快照
+// 快照创建后不再修改，请求之间通过原子指针共享
+type credentialSnapshot struct {
+	// env 加载时的运行环境，快照只包含该环境的凭证
+	env      string
+	vendors  map[string]*vendorCredentials
+	loadedAt time.Time
+}
+
+// CredentialStore 缓存各供应商的凭证配置
+// 启动时一次性读取并解密所有配置文件，请求时直接读取内存中的快照；
+// Watch 后配置目录发生变化时重新加载，校验通过才替换快照，
+// 修改后的配置文件无效时保留该供应商原有的凭证，并在日志中输出凭证的变化
+type CredentialStore struct {
+	dir     string
+	decrypt DecryptFunc
+	// source 配置文件以外的凭证来源，修改时持有 reloadMu
+	source CredentialSource
+
+	snapshot atomic.Pointer[credentialSnapshot]
+
+	// reloadMu 保证同一时间只有一次重新加载
+	reloadMu sync.Mutex
+
+	watchMu sync.Mutex
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// NewCredentialStore 创建凭证缓存并立即加载配置目录下的所有供应商配置
+// decrypt 为nil时使用 InitRSAKeyManager 返回的解密函数
+// 单个供应商的配置无效不会影响其他供应商，错误在获取该供应商的凭证时返回
+func NewCredentialStore(dir string, decrypt DecryptFunc) *CredentialStore {
+	s := &CredentialStore{dir: dir, decrypt: decrypt}
+	s.snapshot.Store(s.load())
+	return s
+}
+
+// Dir 返回配置目录
+func (s *CredentialStore) Dir() string {
+	return s.dir
+}
+
+// LoadedAt 返回当前快照的加载时间
+func (s *CredentialStore) LoadedAt() time.Time {
+	return s.snapshot.Load().loadedAt
+}
+
+// SetSource 设置配置文件以外的凭证来源并重新加载，source 为nil时只使用配置文件
+func (s *CredentialStore) SetSource(source CredentialSource) error {
+	s.reloadMu.Lock()
+	s.source = source
+	s.reloadMu.Unlock()
+	return s.Reload()
+}
+
+// defaultDecrypt 返回 InitRSAKeyManager 的解密函数
+func defaultDecrypt() (DecryptFunc, error) {
+	_, decrypt, err := InitRSAKeyManager()
+	if err != nil {
+		return nil, fmt.Errorf("初始化RSA密钥管理器失败: %v", err)
+	}
+	return decrypt, nil
+}
+
+// load 读取配置目录和 source，生成新的快照
+func (s *CredentialStore) load() *credentialSnapshot {
+	decrypt := s.decrypt
+	var decryptErr error
+	if decrypt == nil {
+		decrypt, decryptErr = defaultDecrypt()
+	}
+
+	snapshot := &credentialSnapshot{
+		env:      currentEnv(),
+		vendors:  make(map[string]*vendorCredentials, len(credentialLoaders)),
+		loadedAt: time.Now(),
+	}
+	for vendor, loader := range credentialLoaders {
+		entry := &vendorCredentials{}
+		snapshot.vendors[vendor] = entry
+
+		var sourceRaw []byte
+		if s.source != nil {
+			var err error
+			if sourceRaw, err = s.source.Credentials(vendor); err != nil {
+				entry.err = fmt.Errorf("读取%s凭证失败: %v", loader.label, err)
+				continue
+			}
+		}
+
+		raw, err := os.ReadFile(filepath.Join(s.dir, loader.file))
+		if err != nil && (!os.IsNotExist(err) || sourceRaw == nil) {
+			entry.err = fmt.Errorf("读取%s配置文件失败: %v", loader.label, err)
+			continue
+		}
+		entry.raw, entry.sourceRaw = raw, sourceRaw
+
+		if decryptErr != nil {
+			entry.err = decryptErr
+			continue
+		}
+		entry.environments, entry.err = s.parse(loader, raw, sourceRaw, snapshot.env, decrypt)
+	}
+	return snapshot
+}
+
+// parse 分别解析配置文件和 source 中的凭证后合并，同名凭证以 source 为准
+func (s *CredentialStore) parse(loader credentialLoader, raw, sourceRaw []byte, env string, decrypt DecryptFunc) (any, error) {
+	environments, err := loader.parse(raw, s.dir, env, decrypt)
+	if err != nil || sourceRaw == nil {
+		return environments, err
+	}
+	sourceEnvironments, err := loader.parse(sourceRaw, s.dir, env, decrypt)
+	if err != nil {
+		return nil, fmt.Errorf("%s凭证来源: %v", loader.label, err)
+	}
+	return loader.merge(environments, sourceEnvironments, env), nil
+}
+
+// Reload 重新加载配置目录
+// 原本有效、修改后无效的供应商保留原有凭证，并返回被拒绝的供应商及原因；
+// 被拒绝的修改记录一条警告日志，包含错误原因和凭证的变化(见 credentialChanges)
+func (s *CredentialStore) Reload() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	current := s.snapshot.Load()
+	next := s.load()
+
+	var rejected []string
+	for vendor, entry := range next.vendors {
+		old, ok := current.vendors[vendor]
+		if entry.err == nil || !ok || old.err != nil {
+			continue
+		}
+		// 修改后的配置无效，保留原有凭证
+		next.vendors[vendor] = old
+		rejected = append(rejected, fmt.Sprintf("%s: %v", vendor, entry.err))
+		fields := []zap.Field{
+			zap.String("vendor", vendor),
+			zap.String("file", credentialLoaders[vendor].file),
+			zap.Error(entry.err),
+		}
+		// 修改后的配置无法解析时只能输出错误原因
+		if changes, err := credentialChanges(vendor, old, entry); err == nil {
+			fields = append(fields, zap.Strings("changes", changes))
+		}
+		currentLogger().Warn("拒绝无效的凭证配置修改，保留原有配置", fields...)
+	}
+
+	s.snapshot.Store(next)
+
+	if len(rejected) > 0 {
+		sort.Strings(rejected)
+		return fmt.Errorf("部分凭证配置无效，已保留原有配置: %s", strings.Join(rejected, "; "))
+	}
+	return nil
+}
+
+// Watch 监听配置目录，文件变化后自动重新加载
+// 编辑器保存文件时可能产生多个事件，合并 debounce 时间内的事件后只加载一次
+func (s *CredentialStore) Watch(debounce time.Duration) error {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+
+	if s.watcher != nil {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建配置目录监听失败: %v", err)
+	}
+	if err := watcher.Add(s.dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("监听配置目录 %s 失败: %v", s.dir, err)
+	}
+
+	s.watcher = watcher
+	s.done = make(chan struct{})
+	go s.watch(watcher, s.done, debounce)
+	return nil
+}
+
+// watch 处理文件变化事件
+func (s *CredentialStore) watch(watcher *fsnotify.Watcher, done chan struct{}, debounce time.Duration) {
+	files := make(map[string]bool, len(credentialLoaders))
+	for _, loader := range credentialLoaders {
+		files[loader.file] = true
+	}
+
+	var timer *time.Timer
+	var reload <-chan time.Time
+	for {
+		select {
+		case <-done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !files[filepath.Base(event.Name)] || event.Op == fsnotify.Chmod {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(debounce)
+			} else {
+				timer.Reset(debounce)
+			}
+			reload = timer.C
+		case <-reload:
+			reload = nil
+			if err := s.Reload(); err != nil {
+				currentLogger().Warn("重新加载LLM凭证配置失败", zap.Error(err))
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			currentLogger().Warn("监听LLM凭证配置目录出错", zap.Error(err))
+		}
+	}
+}
+
+// Close 停止监听配置目录
+func (s *CredentialStore) Close() error {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+
+	if s.watcher == nil {
+		return nil
+	}
+	close(s.done)
+	err := s.watcher.Close()
+	s.watcher = nil
+	return err
+}
+
+var (
+	defaultStoreMu sync.Mutex
+	defaultStore   *CredentialStore
+)
+
+// DefaultCredentialStore 返回 LLMConfigPath 对应的凭证缓存
+// 首次调用时加载配置并开始监听目录；LLMConfigPath 或 ENV 变化后重新创建
+func DefaultCredentialStore() *CredentialStore {
+	defaultStoreMu.Lock()
+	defer defaultStoreMu.Unlock()
+
+	if defaultStore != nil && defaultStore.dir == LLMConfigPath && defaultStore.snapshot.Load().env == currentEnv() {
+		return defaultStore
+	}
+	if defaultStore != nil {
+		_ = defaultStore.Close()
+	}
+
+	defaultStore = NewCredentialStore(LLMConfigPath, nil)
+	defaultSourceMu.Lock()
+	source := defaultSource
+	defaultSourceMu.Unlock()
+	if source != nil {
+		if err := defaultStore.SetSource(source); err != nil {
+			currentLogger().Warn("加载LLM凭证来源失败", zap.Error(err))
+		}
+	}
+	if err := defaultStore.Watch(200 * time.Millisecond); err != nil {
+		currentLogger().Warn("LLM凭证配置热加载未启用", zap.Error(err))
+	}
+	return defaultStore
+}
+
+// currentEnv 返回当前运行环境，未设置时为 development
+func currentEnv() string {
+	if ENV == "" {
+		return "development"
+	}
+	return ENV
+}
+
+// enabledCredentials 返回供应商在当前环境(ENV)下启用的凭证
+// 返回的是快照中凭证的副本，调用方可以自由修改；TestCredential 调用时返回被测试的凭证
+func enabledCredentials[T any](ctx context.Context, vendor string) ([]T, error) {
+	if override, ok := ctx.Value(credentialOverrideKey{}).(credentialOverride); ok && override.vendor == vendor {
+		creds, _ := override.creds.([]T)
+		return append([]T(nil), creds...), nil
+	}
+	return credentialsFrom[T](DefaultCredentialStore(), vendor)
+}
+
+// credentialsFrom 从指定的凭证缓存中获取供应商在当前环境下启用的凭证
+func credentialsFrom[T any](store *CredentialStore, vendor string) ([]T, error) {
+	snapshot := store.snapshot.Load()
+	env := snapshot.env
+
+	entry, ok := snapshot.vendors[vendor]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, vendor)
+	}
+	if entry.err != nil {
+		return nil, entry.err
+	}
+
+	environments, ok := entry.environments.(map[string][]T)
+	if !ok {
+		return nil, fmt.Errorf("供应商 %s 的凭证类型不匹配", vendor)
+	}
+	creds, ok := environments[env]
+	if !ok {
+		return nil, fmt.Errorf("未找到环境 %s 的配置", env)
+	}
+	if len(creds) == 0 {
+		return nil, fmt.Errorf("环境 %s 中没有启用的配置", env)
+	}
+	return append([]T(nil), creds...), nil
+}
+
+// selectCredential 在支持 model 且仍有QPS余量的凭证中按权重随机选择一个
+// 选中的凭证QPS已用尽时从候选中移除并重新选择，全部不可用时返回 *NoCapacityError；
+// 限流器出错(如Redis不可用)时不阻塞请求，直接使用选中的凭证；
+// 熔断冷却中的凭证不参与选择，重试时跳过本次请求中已失败的凭证(见 callWithFailover)
+func selectCredential[T credential[T]](ctx context.Context, vendor, model string, creds []T) (T, error) {
+	attempt := attemptFromContext(ctx, vendor)
+
+	// 优先选择本次请求中尚未失败过的凭证；都失败过时再从非认证失败的凭证中选择
+	candidates := make([]T, 0, len(creds))
+	retried := make([]T, 0)
+	total := 0
+	for _, cred := range creds {
+		base := cred.credentialBase()
+		if !supportsModel(base, model) {
+			continue
+		}
+		total++
+		if !breaker.available(breakerKey(vendor, base.Name)) {
+			continue
+		}
+		if attempt != nil {
+			if kind, failed := attempt.failed[base.Name]; failed {
+				if kind != ErrorKindAuth {
+					retried = append(retried, cred)
+				}
+				continue
+			}
+		}
+		candidates = append(candidates, cred)
+	}
+
+	cred, ok := pickAllowed(ctx, vendor, candidates)
+	if !ok {
+		cred, ok = pickAllowed(ctx, vendor, retried)
+	}
+	if !ok {
+		var zero T
+		return zero, &NoCapacityError{Vendor: vendor, Model: model, Candidates: total}
+	}
+	if attempt != nil {
+		attempt.selected = cred.credentialBase().Name
+	}
+	return cred, nil
+}
+
+// pickAllowed 按权重选择仍有QPS余量的凭证，限流器出错时按 SetRateLimitFailClosed 的设置放行或跳过该凭证
+func pickAllowed[T credential[T]](ctx context.Context, vendor string, candidates []T) (T, bool) {
+	limiter := currentRateLimiter()
+	for len(candidates) > 0 {
+		i := pickWeighted(candidates)
+		cred := candidates[i]
+		base := cred.credentialBase()
+		if base.QPSLimit <= 0 {
+			return cred, true
+		}
+
+		allowed, err := limiter.Allow(ctx, rateLimitKey(vendor, base.Name), base.QPSLimit)
+		if err != nil {
+			failClosed := rateLimitFailClosed.Load()
+			currentLogger().Warn("凭证限流检查失败",
+				zap.String("provider", vendor),
+				zap.String("credential", base.Name),
+				zap.Bool("failClosed", failClosed),
+				errorField(err))
+			allowed = !failClosed
+		}
+		if allowed {
+			return cred, true
+		}
+		candidates = append(candidates[:i], candidates[i+1:]...)
+	}
+
+	var zero T
+	return zero, false
+}
+
+// pickWeighted 根据权重随机选择一个凭证，返回其下标
+func pickWeighted[T credential[T]](creds []T) int {
+	if len(creds) == 1 {
+		return 0
+	}
+
+	// 计算总权重
+	totalWeight := 0
+	for _, cred := range creds {
+		totalWeight += cred.credentialBase().Weight
+	}
+	if totalWeight <= 0 {
+		return rand.Intn(len(creds))
+	}
+
+	// 生成一个随机数,范围是[0, totalWeight)
+	randomNum := rand.Intn(totalWeight)
+	currentWeight := 0
+	for i, cred := range creds {
+		currentWeight += cred.credentialBase().Weight
+		if randomNum < currentWeight {
+			return i
+		}
+	}
+	return len(creds) - 1
+}
+
+// credentialChanges 比较修改前后的凭证配置，按运行环境列出新增和删除的凭证，以及 enabled、weight、qps_limit、models 的变化
+// 只比较这些字段，不输出密钥、地址等其他配置内容(其中可能包含误填的明文密钥)
+func credentialChanges(vendor string, old, next *vendorCredentials) ([]string, error) {
+	oldConfigs, err := vendorCredentialConfigs(vendor, old)
+	if err != nil {
+		return nil, err
+	}
+	newConfigs, err := vendorCredentialConfigs(vendor, next)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ env, name string }
+	oldByKey := make(map[key]CredentialBase, len(oldConfigs))
+	for _, config := range oldConfigs {
+		oldByKey[key{config.Env, config.Name}] = config.CredentialBase
+	}
+	var changes []string
+	for _, config := range newConfigs {
+		k := key{config.Env, config.Name}
+		before, ok := oldByKey[k]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s: 新增凭证 %s", config.Env, config.Name))
+			continue
+		}
+		delete(oldByKey, k)
+		after := config.CredentialBase
+		prefix := config.Env + "/" + config.Name
+		if before.Enabled != after.Enabled {
+			changes = append(changes, fmt.Sprintf("%s: enabled %v -> %v", prefix, before.Enabled, after.Enabled))
+		}
+		if before.Weight != after.Weight {
+			changes = append(changes, fmt.Sprintf("%s: weight %d -> %d", prefix, before.Weight, after.Weight))
+		}
+		if before.QPSLimit != after.QPSLimit {
+			changes = append(changes, fmt.Sprintf("%s: qps_limit %d -> %d", prefix, before.QPSLimit, after.QPSLimit))
+		}
+		if !slices.Equal(before.Models, after.Models) {
+			changes = append(changes, fmt.Sprintf("%s: models %v -> %v", prefix, before.Models, after.Models))
+		}
+	}
+	for k := range oldByKey {
+		changes = append(changes, fmt.Sprintf("%s: 删除凭证 %s", k.env, k.name))
+	}
+	sort.Strings(changes)
+	return changes, nil
+}
+
+// vendorCredentialConfigs 解析快照中供应商的配置文件和 source 内容，同一环境的同名凭证以 source 为准
+func vendorCredentialConfigs(vendor string, entry *vendorCredentials) ([]CredentialConfig, error) {
+	configs, err := parseCredentialConfigs(vendor, entry.raw)
+	if err != nil || entry.sourceRaw == nil {
+		return configs, err
+	}
+	sourceConfigs, err := parseCredentialConfigs(vendor, entry.sourceRaw)
+	if err != nil {
+		return nil, err
+	}
+	configs = slices.DeleteFunc(configs, func(config CredentialConfig) bool {
+		return slices.ContainsFunc(sourceConfigs, func(source CredentialConfig) bool {
+			return source.Env == config.Env && source.Name == config.Name
+		})
+	})
+	return append(configs, sourceConfigs...), nil
+}
@@ -0,0 +1,242 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testDecrypt 测试用的解密函数，去掉 enc: 前缀并统计调用次数
+func testDecrypt(calls *int32) DecryptFunc {
+	return func(s string) (string, error) {
+		atomic.AddInt32(calls, 1)
+		if !strings.HasPrefix(s, "enc:") {
+			return "", fmt.Errorf("无效的数据: %s", s)
+		}
+		return strings.TrimPrefix(s, "enc:"), nil
+	}
+}
+
+// writeAzureCredentials 在配置目录中写入包含指定凭证的azure.yaml
+func writeAzureCredentials(t *testing.T, dir string, creds ...string) {
+	t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "environments:\n  %s:\n    credentials:\n", currentEnv())
+	for _, name := range creds {
+		fmt.Fprintf(&b, "      - name: %s\n        api_key: enc:key-%s\n        enabled: true\n        weight: 1\n", name, name)
+	}
+	// 其他环境的凭证不解密，允许保留占位内容
+	b.WriteString("  other:\n    credentials:\n      - name: placeholder\n        api_key: YOUR_API_KEY_HERE\n        enabled: true\n")
+	if err := os.WriteFile(filepath.Join(dir, "azure.yaml"), []byte(b.String()), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+}
+
+// credentialNames 返回凭证缓存中azure的凭证名称
+func credentialNames(t *testing.T, store *CredentialStore) []string {
+	t.Helper()
+	creds, err := credentialsFrom[AzureCredential](store, "azure")
+	if err != nil {
+		t.Fatalf("获取凭证失败: %v", err)
+	}
+	names := make([]string, len(creds))
+	for i, cred := range creds {
+		names[i] = cred.Name
+	}
+	return names
+}
+
+// TestCredentialStoreLoad 加载时解密一次，之后的请求直接读取快照
+func TestCredentialStoreLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeAzureCredentials(t, dir, "a", "b")
+
+	var calls int32
+	store := NewCredentialStore(dir, testDecrypt(&calls))
+
+	for i := 0; i < 10; i++ {
+		creds, err := credentialsFrom[AzureCredential](store, "azure")
+		assert.NoError(t, err)
+		assert.Len(t, creds, 2)
+		assert.Equal(t, "key-a", creds[0].ApiKey, "凭证应已解密")
+		// 修改返回的副本不影响快照
+		creds[0].ApiKey = "modified"
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "每个启用的凭证只解密一次")
+
+	// 目录中没有的供应商配置返回读取错误
+	_, err := credentialsFrom[BedrockCredential](store, "bedrock")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "读取Bedrock配置文件失败")
+
+	// 未注册的供应商
+	_, err = credentialsFrom[AzureCredential](store, "unknown")
+	assert.ErrorIs(t, err, ErrUnsupportedProvider)
+}
+
+// TestCredentialStoreValidation 无效配置在加载时报错
+func TestCredentialStoreValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "YAML格式错误",
+			content: "environments:\n  - invalid\n    name: x\n",
+			wantErr: "解析Azure配置文件失败",
+		},
+		{
+			name:    "凭证名称重复",
+			content: "environments:\n  development:\n    credentials:\n      - name: a\n      - name: a\n",
+			wantErr: "重复",
+		},
+		{
+			name:    "权重为负数",
+			content: "environments:\n  development:\n    credentials:\n      - name: a\n        weight: -1\n",
+			wantErr: "权重",
+		},
+		{
+			name:    "解密失败",
+			content: fmt.Sprintf("environments:\n  %s:\n    credentials:\n      - name: a\n        api_key: plain\n        enabled: true\n", currentEnv()),
+			wantErr: "解密失败",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "azure.yaml"), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("写入配置文件失败: %v", err)
+			}
+			var calls int32
+			store := NewCredentialStore(dir, testDecrypt(&calls))
+			_, err := credentialsFrom[AzureCredential](store, "azure")
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+// TestCredentialStoreRejectInvalidReload 修改后的配置无效时保留原有凭证
+func TestCredentialStoreRejectInvalidReload(t *testing.T) {
+	dir := t.TempDir()
+	writeAzureCredentials(t, dir, "a")
+
+	var calls int32
+	store := NewCredentialStore(dir, testDecrypt(&calls))
+	assert.Equal(t, []string{"a"}, credentialNames(t, store))
+
+	// 写入无效配置，拒绝修改
+	if err := os.WriteFile(filepath.Join(dir, "azure.yaml"), []byte("environments: [invalid"), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	err := store.Reload()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "azure")
+	assert.Equal(t, []string{"a"}, credentialNames(t, store), "无效修改后应保留原有凭证")
+
+	// 删除配置文件同样保留原有凭证
+	if err := os.Remove(filepath.Join(dir, "azure.yaml")); err != nil {
+		t.Fatalf("删除配置文件失败: %v", err)
+	}
+	assert.Error(t, store.Reload())
+	assert.Equal(t, []string{"a"}, credentialNames(t, store))
+
+	// 配置可以解析但无效时，日志中输出凭证的变化，不输出密钥
+	writeAzureCredentials(t, dir, "a")
+	assert.NoError(t, store.Reload())
+	logs := withObservedLogger(t)
+	config := fmt.Sprintf("environments:\n  %s:\n    credentials:\n"+
+		"      - name: a\n        api_key: enc:key-a\n        enabled: true\n        weight: 3\n        models: [gpt-4o]\n"+
+		"      - name: b\n        api_key: sk-plain\n        enabled: true\n", currentEnv())
+	if err := os.WriteFile(filepath.Join(dir, "azure.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+	assert.Error(t, store.Reload())
+	entries := logs.FilterMessage("拒绝无效的凭证配置修改，保留原有配置").All()
+	if assert.Len(t, entries, 1) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "azure", fields["vendor"])
+		assert.Equal(t, []any{
+			currentEnv() + "/a: models [] -> [gpt-4o]",
+			currentEnv() + "/a: weight 1 -> 3",
+			currentEnv() + ": 新增凭证 b",
+			"other: 删除凭证 placeholder",
+		}, fields["changes"])
+		assert.NotContains(t, fmt.Sprint(fields["changes"]), "sk-plain")
+	}
+
+	// 修正后正常替换
+	writeAzureCredentials(t, dir, "b", "c")
+	assert.NoError(t, store.Reload())
+	assert.Equal(t, []string{"b", "c"}, credentialNames(t, store))
+}
+
+// TestCredentialStoreWatch 监听目录，文件变化后自动加载新凭证
+func TestCredentialStoreWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeAzureCredentials(t, dir, "a")
+
+	var calls int32
+	store := NewCredentialStore(dir, testDecrypt(&calls))
+	if err := store.Watch(10 * time.Millisecond); err != nil {
+		t.Fatalf("监听配置目录失败: %v", err)
+	}
+	defer store.Close()
+
+	writeAzureCredentials(t, dir, "b")
+	assert.Eventually(t, func() bool {
+		creds, err := credentialsFrom[AzureCredential](store, "azure")
+		return err == nil && len(creds) == 1 && creds[0].Name == "b"
+	}, 5*time.Second, 10*time.Millisecond, "修改配置文件后应自动加载新凭证")
+}
+
+// TestCredentialStoreConcurrentReload 并发读取与重新加载，使用 go test -race 检测数据竞争
+func TestCredentialStoreConcurrentReload(t *testing.T) {
+	dir := t.TempDir()
+	writeAzureCredentials(t, dir, "a", "b")
+
+	var calls int32
+	store := NewCredentialStore(dir, testDecrypt(&calls))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				creds, err := credentialsFrom[AzureCredential](store, "azure")
+				if err != nil {
+					t.Errorf("获取凭证失败: %v", err)
+					return
+				}
+				selected, err := selectCredential(context.Background(), "azure", "gpt-4o", creds)
+				if err != nil {
+					t.Errorf("选择凭证失败: %v", err)
+					return
+				}
+				selected.ApiKey = "modified"
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		if err := store.Reload(); err != nil {
+			t.Errorf("重新加载失败: %v", err)
+		}
+	}
+	wg.Wait()
+
+	creds, err := credentialsFrom[AzureCredential](store, "azure")
+	assert.NoError(t, err)
+	for _, cred := range creds {
+		assert.True(t, strings.HasPrefix(cred.ApiKey, "key-"), "快照中的凭证不应被修改")
+	}
+}
@@ -0,0 +1,157 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/sashabaranov/go-openai"
+)
+
+// einoProvider 基于eino ChatModel组件的通用Provider实现
+// bedrock、azure、openai、claude、deepseek 等供应商只需提供创建 ChatModel 的方法即可复用
+type einoProvider struct {
+	// vendor 供应商名称，用于生成响应ID和错误信息
+	vendor string
+	// newChatModel 根据配置选择凭证并创建对应的 ChatModel
+	newChatModel func(ctx context.Context, conf *Config) (model.ChatModel, error)
+	// params 支持的OpenAI标准请求参数，见 ParamsProvider
+	params []string
+	// structured response_format 为 json_schema 时的实现方式，默认由供应商原生支持
+	structured structuredOutputMode
+}
+
+// SupportedParams 实现 ParamsProvider 接口
+func (p *einoProvider) SupportedParams() []string {
+	return p.params
+}
+
+// forcedToolBinder 支持强制调用工具的ChatModel，eino的OpenAI、Claude组件均已实现
+type forcedToolBinder interface {
+	BindForcedTools(tools []*schema.ToolInfo) error
+}
+
+// prepare 创建ChatModel、绑定工具并转换消息，同时返回选中凭证配置的超时时间
+// response_format 为 json_schema 时按 structured 的方式处理；CreateChatCompletion 对这类请求只使用非流式调用，
+// 因此 structuredOutputTool 方式只在 Generate 中将输出工具的调用转换为回复内容
+func (p *einoProvider) prepare(ctx context.Context, req ChatRequest) (model.ChatModel, []*schema.Message, time.Duration, error) {
+	conf, err := newRequestConfig(p.vendor, req)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	// 先转换消息，消息不合法时不占用凭证
+	messages, err := toSchemaMessages(req.Messages)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	// 转换工具并过滤同名工具，按 tool_choice 只保留指定的函数
+	var tools []*schema.ToolInfo
+	if len(req.Tools) > 0 {
+		if tools, err = convertToolInfos(req.Tools); err != nil {
+			return nil, nil, 0, fmt.Errorf("转换工具信息失败: %w", err)
+		}
+	}
+	choice, function := parseToolChoice(req.ToolChoice)
+	if function != "" {
+		tools = slices.DeleteFunc(tools, func(tool *schema.ToolInfo) bool { return tool.Name != function })
+		if len(tools) == 0 {
+			return nil, nil, 0, invalidRequestf("tool_choice 指定的函数 %s 不在 tools 中", function)
+		}
+	}
+
+	// 不支持 json_schema 的供应商改为强制调用输出工具，或在系统提示词中给出Schema
+	if isJSONSchemaFormat(req.ResponseFormat) {
+		switch p.structured {
+		case structuredOutputTool:
+			outputTool, err := structuredOutputToolInfo(req.ResponseFormat)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			if slices.ContainsFunc(tools, func(tool *schema.ToolInfo) bool { return tool.Name == structuredOutputToolName }) {
+				return nil, nil, 0, invalidRequestf("工具名称 %s 已被 json_schema 占用", structuredOutputToolName)
+			}
+			if choice == toolChoiceNone {
+				tools = nil
+			}
+			// Claude开启推理时不允许强制调用工具，由模型自行调用，未调用时由 generateStructured 要求修正
+			tools, choice = append(tools, outputTool), toolChoiceRequired
+			if req.Thinking.enabled() {
+				choice = toolChoiceAuto
+			}
+		case structuredOutputPrompt:
+			prompt, err := structuredSystemPrompt(req.ResponseFormat)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			messages = append([]*schema.Message{schema.SystemMessage(prompt)}, messages...)
+		}
+	}
+
+	chatModel, err := p.newChatModel(ctx, conf)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if err = bindTools(chatModel, tools, choice); err != nil {
+		return nil, nil, 0, fmt.Errorf("绑定工具调用失败: %v", err)
+	}
+
+	return chatModel, messages, conf.Timeout, nil
+}
+
+// bindTools 按 tool_choice 为ChatModel绑定工具
+// "none" 时不绑定工具，模型不会调用工具；"required" 时ChatModel支持则强制调用工具；其他情况由模型决定是否调用
+func bindTools(chatModel model.ChatModel, tools []*schema.ToolInfo, choice string) error {
+	if len(tools) == 0 || choice == toolChoiceNone {
+		return nil
+	}
+	if forced, ok := chatModel.(forcedToolBinder); ok && choice == toolChoiceRequired {
+		return forced.BindForcedTools(tools)
+	}
+	return chatModel.BindTools(tools)
+}
+
+// Generate 实现 Provider 接口
+func (p *einoProvider) Generate(ctx context.Context, req ChatRequest) (*openai.ChatCompletionResponse, error) {
+	chatModel, messages, timeout, err := p.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withRequestTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := chatModel.Generate(ctx, messages)
+	if err != nil {
+		return nil, fmt.Errorf("调用Generate方法失败: %w", err)
+	}
+	if p.structured == structuredOutputTool && isJSONSchemaFormat(req.ResponseFormat) {
+		takeStructuredOutput(resp)
+	}
+
+	return toChatCompletionResponse(p.vendor, req.Model, resp), nil
+}
+
+// Stream 实现 Provider 接口
+func (p *einoProvider) Stream(ctx context.Context, req ChatRequest) (*schema.StreamReader[*openai.ChatCompletionStreamResponse], error) {
+	chatModel, messages, timeout, err := p.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// cancel 由转换协程在流结束时调用
+	ctx, cancel := withRequestTimeout(ctx, timeout)
+
+	streamReader, err := chatModel.Stream(ctx, messages)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("调用Stream方法失败: %w", err)
+	}
+
+	return toChatCompletionStream(ctx, cancel, p.vendor, req.Model, streamReader), nil
+}
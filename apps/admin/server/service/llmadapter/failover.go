@@ -0,0 +1,624 @@
+package llmadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/cloudwego/eino/schema"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+)
+
+// ErrorKind 上游错误的分类，决定是否重试以及是否切换到备用供应商
+type ErrorKind int
+
+const (
+	// ErrorKindUnknown 无法识别的错误(如凭证解密失败)，不重试
+	ErrorKindUnknown ErrorKind = iota
+	// ErrorKindRateLimit 供应商限流(429、throttling)，换凭证重试
+	ErrorKindRateLimit
+	// ErrorKindAuth 认证失败(401、403)，换凭证重试，不再使用该凭证
+	ErrorKindAuth
+	// ErrorKindContextLength 上下文超出模型限制，不重试，可切换到备用模型
+	ErrorKindContextLength
+	// ErrorKindServer 供应商服务端错误(5xx、overloaded)，重试
+	ErrorKindServer
+	// ErrorKindNetwork 网络错误或单次请求超时，重试
+	ErrorKindNetwork
+	// ErrorKindNoCapacity 没有可用的凭证，切换到备用供应商
+	ErrorKindNoCapacity
+	// ErrorKindInvalidRequest 其他4xx请求错误和请求参数校验失败(ErrInvalidRequest)，不重试
+	ErrorKindInvalidRequest
+	// ErrorKindCanceled 调用方取消了请求，不重试
+	ErrorKindCanceled
+)
+
+// String 返回错误分类的名称
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindRateLimit:
+		return "rate_limit"
+	case ErrorKindAuth:
+		return "auth"
+	case ErrorKindContextLength:
+		return "context_length"
+	case ErrorKindServer:
+		return "server"
+	case ErrorKindNetwork:
+		return "network"
+	case ErrorKindNoCapacity:
+		return "no_capacity"
+	case ErrorKindInvalidRequest:
+		return "invalid_request"
+	case ErrorKindCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// Retryable 是否可以在同一供应商上换凭证重试
+func (k ErrorKind) Retryable() bool {
+	switch k {
+	case ErrorKindRateLimit, ErrorKindAuth, ErrorKindServer, ErrorKindNetwork:
+		return true
+	}
+	return false
+}
+
+// failover 同一供应商重试失败后是否切换到备用供应商/模型
+func (k ErrorKind) failover() bool {
+	return k.Retryable() || k == ErrorKindNoCapacity || k == ErrorKindContextLength
+}
+
+// tripsBreaker 是否计入凭证的连续失败次数
+func (k ErrorKind) tripsBreaker() bool {
+	return k.Retryable()
+}
+
+// UpstreamError 重试和故障转移结束后返回的错误，记录最后一次失败的分类和位置
+// Error() 与原始错误一致，可通过 errors.Is/errors.As 访问原始错误
+type UpstreamError struct {
+	Kind       ErrorKind
+	Provider   string
+	Model      string
+	Credential string // 最后一次失败使用的凭证，选择凭证前失败时为空
+	Attempts   int    // 所有供应商累计的尝试次数
+	Err        error
+}
+
+// Error 实现 error 接口
+func (e *UpstreamError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// statusCodePattern 从错误信息中提取HTTP状态码
+var statusCodePattern = regexp.MustCompile(`(?i)(?:status(?:\s*code)?[:=\s]+|http\s+|error,\s*status code:\s*)([45]\d\d)\b`)
+
+// ClassifyError 对上游错误进行分类
+// 优先根据各SDK错误类型中的HTTP状态码判断，无法获取状态码时根据错误信息判断
+func ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorKindUnknown
+	}
+
+	var upstreamErr *UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.Kind
+	}
+	if errors.Is(err, ErrNoCapacity) {
+		return ErrorKindNoCapacity
+	}
+	if errors.Is(err, ErrInvalidRequest) {
+		return ErrorKindInvalidRequest
+	}
+	if errors.Is(err, context.Canceled) {
+		return ErrorKindCanceled
+	}
+
+	message := strings.ToLower(err.Error())
+	// 上下文超限一般以400返回，需要先于状态码判断
+	if isContextLengthMessage(message) {
+		return ErrorKindContextLength
+	}
+
+	if code := statusCode(err); code != 0 {
+		return classifyStatusCode(code, message)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrorKindNetwork
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorKindNetwork
+	}
+
+	switch {
+	case containsAny(message, "rate limit", "ratelimit", "too many requests", "throttl", "quota exceeded", "resource_exhausted", "resource exhausted"):
+		return ErrorKindRateLimit
+	case containsAny(message, "unauthorized", "invalid api key", "invalid_api_key", "incorrect api key", "permission denied", "permission_denied", "access denied", "accessdenied", "forbidden", "unrecognizedclient", "security token"):
+		return ErrorKindAuth
+	case containsAny(message, "overloaded", "internal server error", "service unavailable", "bad gateway", "gateway timeout", "internal error", "server error", "serviceunavailable"):
+		return ErrorKindServer
+	case containsAny(message, "connection refused", "connection reset", "no such host", "i/o timeout", "tls handshake", "broken pipe", "eof", "timeout"):
+		return ErrorKindNetwork
+	}
+	return ErrorKindUnknown
+}
+
+// statusCode 从各SDK的错误类型中获取HTTP状态码，获取不到时返回0
+func statusCode(err error) int {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode != 0 {
+		return apiErr.HTTPStatusCode
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode != 0 {
+		return reqErr.HTTPStatusCode
+	}
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) && anthropicErr.StatusCode != 0 {
+		return anthropicErr.StatusCode
+	}
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) && googleErr.Code != 0 {
+		return googleErr.Code
+	}
+	var httpCoder interface{ HTTPCode() int }
+	if errors.As(err, &httpCoder) && httpCoder.HTTPCode() > 0 {
+		return httpCoder.HTTPCode()
+	}
+
+	if match := statusCodePattern.FindStringSubmatch(err.Error()); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return code
+	}
+	return 0
+}
+
+// classifyStatusCode 根据HTTP状态码分类
+func classifyStatusCode(code int, message string) ErrorKind {
+	switch {
+	case code == 429:
+		return ErrorKindRateLimit
+	case code == 401 || code == 403:
+		return ErrorKindAuth
+	case code == 408:
+		return ErrorKindNetwork
+	case code == 413:
+		return ErrorKindContextLength
+	case code >= 500:
+		return ErrorKindServer
+	case code >= 400:
+		// 部分供应商限流时返回400，例如Bedrock的ThrottlingException
+		if containsAny(message, "throttl", "rate limit") {
+			return ErrorKindRateLimit
+		}
+		return ErrorKindInvalidRequest
+	}
+	return ErrorKindUnknown
+}
+
+// isContextLengthMessage 错误信息是否表示上下文超出模型限制
+func isContextLengthMessage(message string) bool {
+	return containsAny(message,
+		"context_length_exceeded", "context length", "maximum context", "context window",
+		"too many tokens", "prompt is too long", "input is too long", "exceeds the maximum number of tokens",
+		"input length", "token limit")
+}
+
+// containsAny 字符串是否包含任意一个子串
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// FallbackTarget 备用的供应商和模型
+type FallbackTarget struct {
+	Provider string // 供应商名称
+	Model    string // 模型名称，为空时沿用请求的模型
+}
+
+// FallbackRule 故障转移规则
+// 请求的供应商(及模型)与规则匹配，且在该供应商上重试失败后，依次尝试 Targets
+type FallbackRule struct {
+	Provider string // 匹配的供应商
+	Model    string // 匹配的模型，为空时匹配该供应商的所有模型
+	Targets  []FallbackTarget
+}
+
+// RetryPolicy 重试与故障转移策略
+type RetryPolicy struct {
+	// MaxAttempts 每个供应商最多尝试的次数(含首次)，每次尝试优先使用未失败过的凭证
+	MaxAttempts int
+	// BaseDelay 首次重试前的等待时间，之后按指数增长并加入随机抖动
+	BaseDelay time.Duration
+	// MaxDelay 单次等待时间的上限
+	MaxDelay time.Duration
+	// BreakerThreshold 凭证连续失败多少次后进入冷却，0表示不启用熔断
+	BreakerThreshold int
+	// BreakerCooldown 凭证冷却时间，冷却期间不会被选中，冷却结束后允许再次尝试
+	BreakerCooldown time.Duration
+	// Fallbacks 故障转移规则，按顺序匹配第一条
+	Fallbacks []FallbackRule
+}
+
+// DefaultRetryPolicy 默认的重试策略，不包含故障转移规则
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:      3,
+		BaseDelay:        200 * time.Millisecond,
+		MaxDelay:         2 * time.Second,
+		BreakerThreshold: 3,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// retryPolicy 当前使用的重试策略
+var retryPolicy atomic.Pointer[RetryPolicy]
+
+func init() {
+	policy := DefaultRetryPolicy()
+	retryPolicy.Store(&policy)
+}
+
+// SetRetryPolicy 设置重试与故障转移策略
+func SetRetryPolicy(policy RetryPolicy) {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 1
+	}
+	retryPolicy.Store(&policy)
+}
+
+// currentRetryPolicy 返回当前使用的重试策略
+func currentRetryPolicy() RetryPolicy {
+	return *retryPolicy.Load()
+}
+
+// targets 返回请求依次尝试的供应商和模型，第一个为请求本身
+// 请求指定了 Fallbacks 时按请求的备用目标切换，不再匹配故障转移规则
+func (p RetryPolicy) targets(req ChatRequest) []FallbackTarget {
+	provider, model := req.Provider, req.Model
+	targets := []FallbackTarget{{Provider: provider, Model: model}}
+	if len(req.Fallbacks) > 0 {
+		for _, target := range req.Fallbacks {
+			if target.Model == "" {
+				target.Model = model
+			}
+			targets = append(targets, target)
+		}
+		return targets
+	}
+	for _, rule := range p.Fallbacks {
+		if rule.Provider != provider || (rule.Model != "" && rule.Model != model) {
+			continue
+		}
+		for _, target := range rule.Targets {
+			if target.Model == "" {
+				target.Model = model
+			}
+			targets = append(targets, target)
+		}
+		break
+	}
+	return targets
+}
+
+// backoff 第 retry 次重试前的等待时间(retry从1开始)
+// 以 BaseDelay 为基数指数增长，不超过 MaxDelay，并在 [d/2, d) 之间随机抖动
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay << (retry - 1)
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// circuitBreaker 凭证熔断器，连续失败的凭证在冷却期内不会被选中
+type circuitBreaker struct {
+	mu     sync.Mutex
+	states map[string]*breakerState
+}
+
+// breakerState 单个凭证的熔断状态
+type breakerState struct {
+	failures  int
+	openUntil time.Time
+}
+
+// breaker 所有供应商共享的凭证熔断器
+var breaker = &circuitBreaker{states: make(map[string]*breakerState)}
+
+// breakerKey 凭证的熔断键
+func breakerKey(vendor, name string) string {
+	return vendor + "/" + name
+}
+
+// available 凭证当前是否可用(未熔断或冷却已结束)
+func (b *circuitBreaker) available(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.states[key]
+	return !ok || time.Now().After(state.openUntil)
+}
+
+// success 调用成功，清除失败记录
+func (b *circuitBreaker) success(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.states, key)
+}
+
+// failure 记录一次失败，连续失败达到阈值后进入冷却
+// 冷却结束后的首次尝试再次失败会立即重新冷却
+func (b *circuitBreaker) failure(key string, threshold int, cooldown time.Duration) {
+	if threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.states[key]
+	if !ok {
+		state = &breakerState{}
+		b.states[key] = state
+	}
+	state.failures++
+	if state.failures >= threshold {
+		state.openUntil = time.Now().Add(cooldown)
+	}
+}
+
+// attemptKey 上下文中保存 callAttempt 的键
+type attemptKey struct{}
+
+// callAttempt 同一供应商上多次尝试之间共享的状态
+// 由 selectCredential 读取已失败的凭证并记录本次选中的凭证
+type callAttempt struct {
+	vendor   string
+	failed   map[string]ErrorKind // 凭证名称 -> 最近一次失败的分类
+	selected string               // 本次尝试选中的凭证
+}
+
+// withAttempt 将尝试状态保存到上下文
+func withAttempt(ctx context.Context, attempt *callAttempt) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// attemptFromContext 获取当前供应商的尝试状态，不存在时返回nil
+func attemptFromContext(ctx context.Context, vendor string) *callAttempt {
+	attempt, _ := ctx.Value(attemptKey{}).(*callAttempt)
+	if attempt == nil || attempt.vendor != vendor {
+		return nil
+	}
+	return attempt
+}
+
+// CallInfo 记录一次调用最终使用的供应商、模型和凭证，以及响应的Token用量
+type CallInfo struct {
+	Provider         string        // 最终处理请求的供应商
+	Model            string        // 最终使用的模型
+	Credential       string        // 最终使用的凭证名称
+	Attempts         int           // 累计尝试次数
+	Fallback         bool          // 是否切换到了备用供应商/模型
+	ResponseID       string        // 响应ID
+	Usage            openai.Usage  // Token用量，流式响应时从分片中汇总
+	TimeToFirstToken time.Duration // 流式响应从发起调用到收到第一个分片的时间
+}
+
+// callInfoKey 上下文中保存 CallInfo 的键
+type callInfoKey struct{}
+
+// WithCallInfo 返回携带 CallInfo 的上下文，CreateChatCompletion 返回后可从中读取调用详情
+func WithCallInfo(ctx context.Context) (context.Context, *CallInfo) {
+	info := &CallInfo{}
+	return context.WithValue(ctx, callInfoKey{}, info), info
+}
+
+// callInfoFromContext 获取上下文中的 CallInfo，不存在时返回nil
+func callInfoFromContext(ctx context.Context) *CallInfo {
+	info, _ := ctx.Value(callInfoKey{}).(*CallInfo)
+	return info
+}
+
+// ErrModelNotAllowed 请求的模型不在 WithModelFilter 允许的范围内
+var ErrModelNotAllowed = errors.New("不允许调用该模型")
+
+// modelFilterKey 上下文中保存模型过滤函数的键
+type modelFilterKey struct{}
+
+// WithModelFilter 返回限制可调用模型的上下文，如访问密钥只允许调用部分模型
+// 请求的模型不被允许时 CreateChatCompletion 返回包装了 ErrModelNotAllowed 的错误；故障转移时跳过不被允许的备用模型
+func WithModelFilter(ctx context.Context, allow func(model string) bool) context.Context {
+	return context.WithValue(ctx, modelFilterKey{}, allow)
+}
+
+// modelAllowed 判断上下文是否允许调用 model，没有设置过滤函数时允许所有模型
+func modelAllowed(ctx context.Context, model string) bool {
+	allow, ok := ctx.Value(modelFilterKey{}).(func(model string) bool)
+	return !ok || allow == nil || allow(model)
+}
+
+// callWithFailover 按重试策略调用供应商
+// 可重试的错误在同一供应商上换凭证重试，重试用尽或没有可用凭证时依次切换到备用供应商/模型；
+// 不可重试的错误(如参数错误)直接返回
+func callWithFailover[R any](ctx context.Context, req ChatRequest, call func(ctx context.Context, provider Provider, req ChatRequest) (R, error)) (R, error) {
+	var zero R
+	policy := currentRetryPolicy()
+	info := callInfoFromContext(ctx)
+
+	var lastErr *UpstreamError
+	attempts := 0
+	for i, target := range policy.targets(req) {
+		if !modelAllowed(ctx, target.Model) {
+			if i == 0 {
+				return zero, fmt.Errorf("%w: %s", ErrModelNotAllowed, target.Model)
+			}
+			continue
+		}
+		provider, err := GetProvider(target.Provider)
+		if err != nil {
+			if i == 0 {
+				return zero, err
+			}
+			currentLogger().Warn("备用供应商不可用，跳过", zap.String("provider", target.Provider), errorField(err))
+			continue
+		}
+
+		targetReq := req
+		targetReq.Provider = target.Provider
+		targetReq.Model = target.Model
+		attempt := &callAttempt{vendor: target.Provider, failed: make(map[string]ErrorKind)}
+
+		for n := 0; n < policy.MaxAttempts; n++ {
+			if n > 0 {
+				if err := sleepContext(ctx, policy.backoff(n)); err != nil {
+					return zero, err
+				}
+			}
+
+			attempt.selected = ""
+			attempts++
+			result, err := call(withAttempt(ctx, attempt), provider, targetReq)
+			if err == nil {
+				if attempt.selected != "" {
+					breaker.success(breakerKey(target.Provider, attempt.selected))
+				}
+				if info != nil {
+					*info = CallInfo{
+						Provider:   target.Provider,
+						Model:      target.Model,
+						Credential: attempt.selected,
+						Attempts:   attempts,
+						Fallback:   i > 0,
+					}
+				}
+				return result, nil
+			}
+
+			// 调用方已取消，不再重试
+			if ctx.Err() != nil {
+				return zero, err
+			}
+
+			kind := ClassifyError(err)
+			lastErr = &UpstreamError{
+				Kind:       kind,
+				Provider:   target.Provider,
+				Model:      target.Model,
+				Credential: attempt.selected,
+				Attempts:   attempts,
+				Err:        err,
+			}
+			if attempt.selected != "" {
+				attempt.failed[attempt.selected] = kind
+				if kind.tripsBreaker() {
+					breaker.failure(breakerKey(target.Provider, attempt.selected), policy.BreakerThreshold, policy.BreakerCooldown)
+				}
+			}
+			if !kind.Retryable() {
+				break
+			}
+			currentLogger().Warn("调用上游失败，准备重试",
+				zap.String("provider", target.Provider),
+				zap.String("credential", attempt.selected),
+				zap.String("kind", kind.String()),
+				errorField(err))
+		}
+
+		if !lastErr.Kind.failover() {
+			break
+		}
+	}
+
+	if info != nil && lastErr != nil {
+		info.Provider = lastErr.Provider
+		info.Model = lastErr.Model
+		info.Credential = lastErr.Credential
+		info.Attempts = attempts
+	}
+	if lastErr == nil {
+		return zero, fmt.Errorf("%w: %s", ErrUnsupportedProvider, req.Provider)
+	}
+	return zero, lastErr
+}
+
+// sleepContext 等待指定时间，ctx取消时提前返回ctx的错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// peekStream 读取流的第一个分片
+// 部分SDK在读取第一个分片时才真正发起请求，先读取第一个分片可以让连接阶段的错误参与重试；
+// 成功时返回包含第一个分片的完整流
+func peekStream[T any](streamReader *schema.StreamReader[T]) (*schema.StreamReader[T], error) {
+	first, err := streamReader.Recv()
+	if errors.Is(err, io.EOF) {
+		streamReader.Close()
+		return schema.StreamReaderFromArray([]T{}), nil
+	}
+	if err != nil {
+		streamReader.Close()
+		return nil, err
+	}
+
+	resultReader, resultWriter := schema.Pipe[T](1)
+	go func() {
+		defer func() {
+			streamReader.Close()
+			resultWriter.Close()
+		}()
+
+		if closed := resultWriter.Send(first, nil); closed {
+			return
+		}
+		for {
+			chunk, err := streamReader.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if closed := resultWriter.Send(chunk, err); closed || err != nil {
+				return
+			}
+		}
+	}()
+	return resultReader, nil
+}
@@ -0,0 +1,400 @@
+package llmadapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/cloudwego/eino/schema"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi"
+)
+
+// withRetryPolicy 在测试期间替换重试策略
+func withRetryPolicy(t *testing.T, policy RetryPolicy) {
+	t.Helper()
+	SetRetryPolicy(policy)
+	t.Cleanup(func() { SetRetryPolicy(DefaultRetryPolicy()) })
+}
+
+// testRetryPolicy 测试使用的重试策略，等待时间很短
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:      3,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         5 * time.Millisecond,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+	}
+}
+
+// credentialProvider 从固定的凭证列表中选择凭证，按凭证名称返回预设的错误
+type credentialProvider struct {
+	vendor string
+	creds  []AzureCredential
+
+	mu    sync.Mutex
+	errs  map[string]error // 凭证名称 -> 调用返回的错误
+	calls []string         // 按顺序记录使用的凭证
+}
+
+func (p *credentialProvider) call(ctx context.Context, model string) (string, error) {
+	cred, err := selectCredential(ctx, p.vendor, model, p.creds)
+	if err != nil {
+		return "", err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, cred.Name)
+	return cred.Name, p.errs[cred.Name]
+}
+
+func (p *credentialProvider) Generate(ctx context.Context, req ChatRequest) (*openai.ChatCompletionResponse, error) {
+	name, err := p.call(ctx, req.Model)
+	if err != nil {
+		return nil, err
+	}
+	return &openai.ChatCompletionResponse{
+		Model:   req.Model,
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: name}}},
+	}, nil
+}
+
+func (p *credentialProvider) Stream(ctx context.Context, req ChatRequest) (*schema.StreamReader[*openai.ChatCompletionStreamResponse], error) {
+	name, err := p.call(ctx, req.Model)
+	if err != nil {
+		// 模拟在读取第一个分片时才返回连接错误的SDK
+		reader, writer := schema.Pipe[*openai.ChatCompletionStreamResponse](1)
+		writer.Send(nil, err)
+		writer.Close()
+		return reader, nil
+	}
+	return schema.StreamReaderFromArray([]*openai.ChatCompletionStreamResponse{
+		{Model: req.Model, Choices: []openai.ChatCompletionStreamChoice{{Delta: openai.ChatCompletionStreamChoiceDelta{Content: name}}}},
+	}), nil
+}
+
+func (p *credentialProvider) usedCredentials() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+// registerCredentialProvider 注册测试供应商，vendor 需要在测试之间唯一
+func registerCredentialProvider(vendor string, errs map[string]error, names ...string) *credentialProvider {
+	provider := &credentialProvider{vendor: vendor, errs: errs}
+	for _, name := range names {
+		provider.creds = append(provider.creds, testCredential(name, 1, 0))
+	}
+	Register(vendor, func() Provider { return provider })
+	return provider
+}
+
+// testChatRequest 构造测试用的请求
+func testChatRequest(provider string) ChatRequest {
+	return ChatRequest{Provider: provider, ChatCompletionRequest: openai.ChatCompletionRequest{
+		Model:    "test-model",
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}},
+	}}
+}
+
+// timeoutError 模拟网络超时
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+// anthropicError 构造 anthropic SDK 返回的错误
+func anthropicError(code int) error {
+	request, _ := http.NewRequest(http.MethodPost, "https://api.anthropic.com/v1/messages", nil)
+	return &anthropic.Error{StatusCode: code, Request: request, Response: &http.Response{StatusCode: code}}
+}
+
+// TestClassifyError 测试上游错误分类
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorKind
+	}{
+		{"nil", nil, ErrorKindUnknown},
+		{"openai 429", &openai.APIError{HTTPStatusCode: 429, Message: "Rate limit reached"}, ErrorKindRateLimit},
+		{"openai 401", fmt.Errorf("调用Generate方法失败: %w", &openai.APIError{HTTPStatusCode: 401, Message: "Incorrect API key"}), ErrorKindAuth},
+		{"openai context length", &openai.APIError{HTTPStatusCode: 400, Code: "context_length_exceeded", Message: "This model's maximum context length is 8192 tokens"}, ErrorKindContextLength},
+		{"openai 400", &openai.APIError{HTTPStatusCode: 400, Message: "Invalid value for 'temperature'"}, ErrorKindInvalidRequest},
+		{"openai 503", &openai.RequestError{HTTPStatusCode: 503, Err: errors.New("service unavailable")}, ErrorKindServer},
+		{"anthropic 529", anthropicError(529), ErrorKindServer},
+		{"gemini 403", &googleapi.Error{Code: 403, Message: "permission denied"}, ErrorKindAuth},
+		{"message status", errors.New("error, status code: 502, message: bad gateway"), ErrorKindServer},
+		{"bedrock throttling", errors.New("ThrottlingException: Too many requests, please wait before trying again"), ErrorKindRateLimit},
+		{"claude prompt too long", errors.New("prompt is too long: 210000 tokens > 200000 maximum"), ErrorKindContextLength},
+		{"overloaded", errors.New("overloaded_error: Overloaded"), ErrorKindServer},
+		{"net timeout", fmt.Errorf("发送消息失败: %w", timeoutError{}), ErrorKindNetwork},
+		{"unexpected eof", io.ErrUnexpectedEOF, ErrorKindNetwork},
+		{"deadline", context.DeadlineExceeded, ErrorKindNetwork},
+		{"canceled", fmt.Errorf("wrapped: %w", context.Canceled), ErrorKindCanceled},
+		{"no capacity", &NoCapacityError{Vendor: "azure", Model: "gpt-4o"}, ErrorKindNoCapacity},
+		{"upstream", &UpstreamError{Kind: ErrorKindAuth, Err: errors.New("x")}, ErrorKindAuth},
+		{"unknown", errors.New("获取Azure配置失败: 未找到环境 test 的配置"), ErrorKindUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyError(tt.err), "%v", tt.err)
+		})
+	}
+}
+
+// TestRetryPolicyBackoff 等待时间按指数增长，带抖动且不超过上限
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	for i := 0; i < 50; i++ {
+		d1 := policy.backoff(1)
+		assert.True(t, d1 >= 50*time.Millisecond && d1 < 100*time.Millisecond, "第1次重试: %v", d1)
+		d2 := policy.backoff(2)
+		assert.True(t, d2 >= 100*time.Millisecond && d2 < 200*time.Millisecond, "第2次重试: %v", d2)
+		d5 := policy.backoff(5)
+		assert.True(t, d5 >= 150*time.Millisecond && d5 < 300*time.Millisecond, "超过上限: %v", d5)
+	}
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.backoff(1))
+}
+
+// TestFailoverNextCredential 可重试的错误换下一个凭证重试，认证失败的凭证不再使用
+func TestFailoverNextCredential(t *testing.T) {
+	withRetryPolicy(t, testRetryPolicy())
+	logs := withObservedLogger(t)
+
+	provider := registerCredentialProvider("fake-failover-cred", map[string]error{
+		"bad-key":   &openai.APIError{HTTPStatusCode: 401, Message: "Incorrect API key"},
+		"throttled": &openai.APIError{HTTPStatusCode: 429, Message: "Rate limit reached"},
+	}, "bad-key", "throttled", "good")
+
+	ctx, info := WithCallInfo(context.Background())
+	resp, err := CreateChatCompletion(ctx, testChatRequest("fake-failover-cred"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "good", resp.Choices[0].Message.Content)
+	assert.Equal(t, "good", info.Credential)
+	assert.Equal(t, "fake-failover-cred", info.Provider)
+	assert.False(t, info.Fallback)
+
+	used := provider.usedCredentials()
+	assert.Equal(t, "good", used[len(used)-1])
+	assert.Equal(t, len(used), info.Attempts)
+	seen := make(map[string]int)
+	for _, name := range used {
+		seen[name]++
+	}
+	for name, count := range seen {
+		assert.Equal(t, 1, count, "凭证 %s 在一次请求中不应重复使用", name)
+	}
+
+	// 每次重试记录一条警告日志，包含供应商、凭证和错误分类
+	retries := logs.FilterMessage("调用上游失败，准备重试").All()
+	if assert.Len(t, retries, len(used)-1) {
+		for i, entry := range retries {
+			fields := entry.ContextMap()
+			assert.Equal(t, zap.WarnLevel, entry.Level)
+			assert.Equal(t, "fake-failover-cred", fields["provider"])
+			assert.Equal(t, used[i], fields["credential"])
+			assert.Contains(t, []any{"auth", "rate_limit"}, fields["kind"])
+		}
+	}
+}
+
+// TestFailoverNotRetryable 参数错误不重试
+func TestFailoverNotRetryable(t *testing.T) {
+	withRetryPolicy(t, testRetryPolicy())
+
+	badRequest := &openai.APIError{HTTPStatusCode: 400, Message: "Invalid value for 'temperature'"}
+	provider := registerCredentialProvider("fake-failover-400", map[string]error{
+		"a": badRequest, "b": badRequest,
+	}, "a", "b")
+
+	_, err := CreateChatCompletion(context.Background(), testChatRequest("fake-failover-400"), nil)
+	var upstreamErr *UpstreamError
+	if assert.ErrorAs(t, err, &upstreamErr) {
+		assert.Equal(t, ErrorKindInvalidRequest, upstreamErr.Kind)
+		assert.Equal(t, 1, upstreamErr.Attempts)
+	}
+	var apiErr *openai.APIError
+	assert.ErrorAs(t, err, &apiErr, "应能获取原始错误")
+	assert.Len(t, provider.usedCredentials(), 1)
+}
+
+// TestFailoverCircuitBreaker 连续失败的凭证进入冷却，冷却期间不再被选中
+func TestFailoverCircuitBreaker(t *testing.T) {
+	policy := testRetryPolicy()
+	policy.MaxAttempts = 1
+	withRetryPolicy(t, policy)
+
+	serverErr := &openai.APIError{HTTPStatusCode: 500, Message: "internal server error"}
+	provider := registerCredentialProvider("fake-failover-breaker", map[string]error{"broken": serverErr}, "broken")
+
+	for i := 0; i < policy.BreakerThreshold; i++ {
+		_, err := CreateChatCompletion(context.Background(), testChatRequest("fake-failover-breaker"), nil)
+		assert.Equal(t, ErrorKindServer, ClassifyError(err))
+	}
+	assert.Len(t, provider.usedCredentials(), policy.BreakerThreshold)
+
+	// 熔断后不再调用上游
+	_, err := CreateChatCompletion(context.Background(), testChatRequest("fake-failover-breaker"), nil)
+	assert.ErrorIs(t, err, ErrNoCapacity)
+	assert.Len(t, provider.usedCredentials(), policy.BreakerThreshold)
+
+	// 冷却结束后允许再次尝试，成功后清除失败记录
+	breaker.mu.Lock()
+	breaker.states[breakerKey("fake-failover-breaker", "broken")].openUntil = time.Now().Add(-time.Second)
+	breaker.mu.Unlock()
+	provider.mu.Lock()
+	provider.errs = nil
+	provider.mu.Unlock()
+
+	_, err = CreateChatCompletion(context.Background(), testChatRequest("fake-failover-breaker"), nil)
+	assert.NoError(t, err)
+	assert.True(t, breaker.available(breakerKey("fake-failover-breaker", "broken")))
+}
+
+// TestFailoverFallbackChain 主供应商重试失败后依次切换到备用供应商和模型
+func TestFailoverFallbackChain(t *testing.T) {
+	policy := testRetryPolicy()
+	policy.BreakerThreshold = 0
+	policy.Fallbacks = []FallbackRule{
+		{Provider: "fake-fallback-primary", Model: "other-model", Targets: []FallbackTarget{{Provider: "not-matched"}}},
+		{Provider: "fake-fallback-primary", Targets: []FallbackTarget{
+			{Provider: "not-exists"},
+			{Provider: "fake-fallback-second"},
+			{Provider: "fake-fallback-third", Model: "backup-model"},
+		}},
+	}
+	withRetryPolicy(t, policy)
+
+	overloaded := errors.New("overloaded_error: Overloaded")
+	primary := registerCredentialProvider("fake-fallback-primary", map[string]error{"p": overloaded}, "p")
+	second := registerCredentialProvider("fake-fallback-second", map[string]error{
+		"s": &openai.APIError{HTTPStatusCode: 400, Code: "context_length_exceeded", Message: "maximum context length"},
+	}, "s")
+	third := registerCredentialProvider("fake-fallback-third", nil, "t")
+
+	ctx, info := WithCallInfo(context.Background())
+	resp, err := CreateChatCompletion(ctx, testChatRequest("fake-fallback-primary"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "backup-model", resp.Model)
+	assert.Equal(t, []string{"p", "p", "p"}, primary.usedCredentials(), "服务端错误在主供应商上重试")
+	assert.Equal(t, []string{"s"}, second.usedCredentials(), "上下文超限不重试，直接切换")
+	assert.Equal(t, []string{"t"}, third.usedCredentials())
+	assert.Equal(t, CallInfo{Provider: "fake-fallback-third", Model: "backup-model", Credential: "t", Attempts: 5, Fallback: true}, *info)
+}
+
+// TestFailoverRequestFallbacks 请求指定的备用目标代替故障转移规则
+func TestFailoverRequestFallbacks(t *testing.T) {
+	policy := testRetryPolicy()
+	policy.BreakerThreshold = 0
+	policy.Fallbacks = []FallbackRule{
+		{Provider: "fake-route-primary", Targets: []FallbackTarget{{Provider: "fake-route-rule"}}},
+	}
+	withRetryPolicy(t, policy)
+
+	primary := registerCredentialProvider("fake-route-primary", map[string]error{"p": errors.New("overloaded_error: Overloaded")}, "p")
+	rule := registerCredentialProvider("fake-route-rule", nil, "r")
+	route := registerCredentialProvider("fake-route-target", nil, "t")
+
+	req := testChatRequest("fake-route-primary")
+	req.Fallbacks = []FallbackTarget{{Provider: "fake-route-target", Model: "route-model"}}
+	resp, err := CreateChatCompletion(context.Background(), req, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "route-model", resp.Model)
+	assert.Len(t, primary.usedCredentials(), 3)
+	assert.Empty(t, rule.usedCredentials(), "请求指定了备用目标时不匹配故障转移规则")
+	assert.Equal(t, []string{"t"}, route.usedCredentials())
+}
+
+// TestFailoverModelFilter 请求的模型不被允许时不调用供应商，故障转移时跳过不被允许的备用模型
+func TestFailoverModelFilter(t *testing.T) {
+	policy := testRetryPolicy()
+	policy.BreakerThreshold = 0
+	withRetryPolicy(t, policy)
+
+	primary := registerCredentialProvider("fake-filter-primary", map[string]error{"p": errors.New("overloaded_error: Overloaded")}, "p")
+	denied := registerCredentialProvider("fake-filter-denied", nil, "d")
+	allowed := registerCredentialProvider("fake-filter-allowed", nil, "a")
+
+	ctx := WithModelFilter(context.Background(), func(model string) bool { return model != "denied-model" })
+	req := testChatRequest("fake-filter-primary")
+	req.Fallbacks = []FallbackTarget{
+		{Provider: "fake-filter-denied", Model: "denied-model"},
+		{Provider: "fake-filter-allowed", Model: "allowed-model"},
+	}
+	resp, err := CreateChatCompletion(ctx, req, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "allowed-model", resp.Model)
+	assert.Len(t, primary.usedCredentials(), 3)
+	assert.Empty(t, denied.usedCredentials(), "不被允许的备用模型不调用")
+	assert.Equal(t, []string{"a"}, allowed.usedCredentials())
+
+	req.Model = "denied-model"
+	_, err = CreateChatCompletion(ctx, req, nil)
+	assert.ErrorIs(t, err, ErrModelNotAllowed)
+	assert.Len(t, primary.usedCredentials(), 3, "请求的模型不被允许时不调用供应商")
+}
+
+// TestFailoverContextCanceled 调用方取消后不再重试
+func TestFailoverContextCanceled(t *testing.T) {
+	policy := testRetryPolicy()
+	policy.BaseDelay = time.Second
+	policy.MaxDelay = time.Second
+	withRetryPolicy(t, policy)
+
+	registerCredentialProvider("fake-failover-cancel", map[string]error{
+		"a": &openai.APIError{HTTPStatusCode: 503, Message: "service unavailable"},
+	}, "a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := CreateChatCompletion(ctx, testChatRequest("fake-failover-cancel"), nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+// TestFailoverStream 流式请求在第一个分片返回错误时换凭证重试
+func TestFailoverStream(t *testing.T) {
+	withRetryPolicy(t, testRetryPolicy())
+
+	provider := registerCredentialProvider("fake-failover-stream", map[string]error{
+		"reset": errors.New("read tcp 127.0.0.1:1234: connection reset by peer"),
+	}, "reset", "ok")
+
+	buffer := &bytes.Buffer{}
+	req := testChatRequest("fake-failover-stream")
+	req.Stream = true
+	for i := 0; i < 5; i++ {
+		buffer.Reset()
+		_, err := CreateChatCompletion(context.Background(), req, buffer)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, buffer.String(), `"content":"ok"`)
+		assert.NotContains(t, buffer.String(), "reset")
+	}
+	assert.Contains(t, provider.usedCredentials(), "ok")
+}
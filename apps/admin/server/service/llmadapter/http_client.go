@@ -0,0 +1,116 @@
+package llmadapter
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// HTTPClientConfig 凭证的HTTP客户端配置
+// 以内联方式嵌入各供应商的凭证结构，yaml中与凭证的其他字段写在同一层级
+type HTTPClientConfig struct {
+	Timeout             int    `yaml:"timeout"`                 // 请求超时时间(秒)，0表示不限制
+	Proxy               string `yaml:"proxy"`                   // 代理地址，例如 http://127.0.0.1:7890
+	InsecureSkipVerify  bool   `yaml:"insecure_skip_verify"`    // 跳过TLS证书校验，仅用于测试环境
+	CACertFile          string `yaml:"ca_cert_file"`            // 自定义CA证书文件(PEM)
+	MaxIdleConns        int    `yaml:"max_idle_conns"`          // 连接池最大空闲连接数，默认100
+	MaxIdleConnsPerHost int    `yaml:"max_idle_conns_per_host"` // 每个主机最大空闲连接数，默认10
+	IdleConnTimeout     int    `yaml:"idle_conn_timeout"`       // 空闲连接超时时间(秒)，默认90
+}
+
+// httpClientKey 客户端缓存的键
+// 同一供应商的同名凭证在配置不变时复用同一个客户端(及其连接池)，配置变化后重新创建
+type httpClientKey struct {
+	vendor     string
+	credential string
+	config     HTTPClientConfig
+}
+
+// httpClients 已创建的凭证HTTP客户端 httpClientKey -> *http.Client
+var httpClients sync.Map
+
+// credentialHTTPClient 获取凭证专用的HTTP客户端
+// 每个凭证拥有独立的 Transport 和连接池，代理、超时与TLS设置互不影响，也不会修改 http.DefaultClient
+func credentialHTTPClient(vendor, credential string, conf HTTPClientConfig) (*http.Client, error) {
+	key := httpClientKey{vendor: vendor, credential: credential, config: conf}
+	if client, ok := httpClients.Load(key); ok {
+		return client.(*http.Client), nil
+	}
+
+	client, err := newHTTPClient(conf)
+	if err != nil {
+		return nil, fmt.Errorf("创建凭证 %s 的HTTP客户端失败: %w", credential, err)
+	}
+
+	// 并发创建时以先存入的为准，保证同一凭证只有一个连接池
+	actual, _ := httpClients.LoadOrStore(key, client)
+	return actual.(*http.Client), nil
+}
+
+// newHTTPClient 根据配置创建新的HTTP客户端
+func newHTTPClient(conf HTTPClientConfig) (*http.Client, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	if conf.Proxy != "" {
+		proxyURL, err := url.Parse(conf.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("代理地址格式错误: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if conf.MaxIdleConns > 0 {
+		transport.MaxIdleConns = conf.MaxIdleConns
+	}
+	if conf.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = conf.MaxIdleConnsPerHost
+	}
+	if conf.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = time.Duration(conf.IdleConnTimeout) * time.Second
+	}
+
+	if conf.InsecureSkipVerify || conf.CACertFile != "" {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: conf.InsecureSkipVerify,
+		}
+		if conf.CACertFile != "" {
+			pem, err := os.ReadFile(conf.CACertFile)
+			if err != nil {
+				return nil, fmt.Errorf("读取CA证书失败: %v", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil || pool == nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("CA证书 %s 中没有有效的PEM证书", conf.CACertFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(conf.Timeout) * time.Second,
+	}, nil
+}
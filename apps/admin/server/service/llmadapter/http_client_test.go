@@ -0,0 +1,133 @@
+package llmadapter
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestCredentialHTTPClientIsolation 多个凭证并发获取HTTP客户端，互不共享状态且不修改 http.DefaultClient
+// 使用 go test -race 运行可检测数据竞争
+func TestCredentialHTTPClientIsolation(t *testing.T) {
+	defaultClient := http.DefaultClient
+	defaultTimeout := http.DefaultClient.Timeout
+	defaultTransport := http.DefaultClient.Transport
+
+	const credentials = 8
+	const callsPerCredential = 20
+
+	confs := make([]HTTPClientConfig, credentials)
+	for i := range confs {
+		confs[i] = HTTPClientConfig{
+			Timeout:             i + 1,
+			Proxy:               fmt.Sprintf("http://127.0.0.1:%d", 8000+i),
+			MaxIdleConnsPerHost: i + 1,
+		}
+	}
+
+	clients := make([][]*http.Client, credentials)
+	var wg sync.WaitGroup
+	for i := 0; i < credentials; i++ {
+		clients[i] = make([]*http.Client, callsPerCredential)
+		for j := 0; j < callsPerCredential; j++ {
+			wg.Add(1)
+			go func(i, j int) {
+				defer wg.Done()
+				client, err := credentialHTTPClient("race-test", fmt.Sprintf("cred-%d", i), confs[i])
+				if err != nil {
+					t.Errorf("创建HTTP客户端失败: %v", err)
+					return
+				}
+				clients[i][j] = client
+			}(i, j)
+		}
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	seen := make(map[*http.Client]int)
+	for i := 0; i < credentials; i++ {
+		client := clients[i][0]
+		// 同一凭证多次获取应复用同一客户端
+		for j := 1; j < callsPerCredential; j++ {
+			if clients[i][j] != client {
+				t.Fatalf("凭证 cred-%d 未复用缓存的HTTP客户端", i)
+			}
+		}
+		// 不同凭证的客户端互不相同
+		if other, ok := seen[client]; ok {
+			t.Fatalf("凭证 cred-%d 与 cred-%d 共用了HTTP客户端", i, other)
+		}
+		seen[client] = i
+
+		if client == http.DefaultClient {
+			t.Fatalf("凭证 cred-%d 使用了 http.DefaultClient", i)
+		}
+		if want := time.Duration(i+1) * time.Second; client.Timeout != want {
+			t.Errorf("凭证 cred-%d 超时时间 = %v, 期望 %v", i, client.Timeout, want)
+		}
+
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("凭证 cred-%d 的Transport类型 = %T", i, client.Transport)
+		}
+		if transport == http.DefaultTransport {
+			t.Fatalf("凭证 cred-%d 使用了 http.DefaultTransport", i)
+		}
+		if transport.MaxIdleConnsPerHost != i+1 {
+			t.Errorf("凭证 cred-%d MaxIdleConnsPerHost = %d, 期望 %d", i, transport.MaxIdleConnsPerHost, i+1)
+		}
+		proxyURL, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "example.com"}})
+		if err != nil {
+			t.Fatalf("获取代理失败: %v", err)
+		}
+		if proxyURL == nil || proxyURL.String() != confs[i].Proxy {
+			t.Errorf("凭证 cred-%d 代理 = %v, 期望 %s", i, proxyURL, confs[i].Proxy)
+		}
+	}
+
+	if http.DefaultClient != defaultClient || http.DefaultClient.Timeout != defaultTimeout || http.DefaultClient.Transport != defaultTransport {
+		t.Fatal("http.DefaultClient 被修改")
+	}
+}
+
+// TestCredentialHTTPClientConfigChange 凭证配置变化后重新创建客户端
+func TestCredentialHTTPClientConfigChange(t *testing.T) {
+	first, err := credentialHTTPClient("change-test", "cred", HTTPClientConfig{Timeout: 10})
+	if err != nil {
+		t.Fatalf("创建HTTP客户端失败: %v", err)
+	}
+	second, err := credentialHTTPClient("change-test", "cred", HTTPClientConfig{Timeout: 20})
+	if err != nil {
+		t.Fatalf("创建HTTP客户端失败: %v", err)
+	}
+	if first == second {
+		t.Fatal("配置变化后仍返回旧的HTTP客户端")
+	}
+	if second.Timeout != 20*time.Second {
+		t.Errorf("超时时间 = %v, 期望 20s", second.Timeout)
+	}
+}
+
+// TestCredentialHTTPClientInvalidConfig 无效配置返回错误
+func TestCredentialHTTPClientInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		conf HTTPClientConfig
+	}{
+		{name: "无效代理地址", conf: HTTPClientConfig{Proxy: "://bad proxy"}},
+		{name: "CA证书不存在", conf: HTTPClientConfig{CACertFile: "/nonexistent/ca.pem"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := credentialHTTPClient("invalid-test", tt.name, tt.conf); err == nil {
+				t.Fatal("期望返回错误")
+			}
+		})
+	}
+}
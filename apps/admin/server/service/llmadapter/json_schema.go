@@ -0,0 +1,365 @@
+package llmadapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSchemaDepth JSON Schema和被校验的JSON的最大嵌套层数，避免循环引用导致无限递归
+const maxSchemaDepth = 128
+
+// validateJSONSchema 按JSON Schema校验 value(encoding/json 解析得到的值)，返回所有不符合的位置和原因，
+// 位置的格式如 $.items[0].price
+// 支持常用的关键字：type、enum、const、properties、required、additionalProperties、items、minItems、maxItems、
+// minLength、maxLength、pattern、minimum、maximum、exclusiveMinimum、exclusiveMaximum、anyOf、oneOf、allOf，
+// 以及指向Schema内部的 $ref(如 #/$defs/item)；其他关键字忽略
+func validateJSONSchema(schema map[string]any, value any) []string {
+	v := &schemaValidator{root: schema}
+	v.validate(schema, value, "$", 0)
+	return v.errors
+}
+
+// schemaValidator 校验过程的状态
+type schemaValidator struct {
+	root   map[string]any // 根Schema，用于解析 $ref
+	errors []string
+}
+
+// fail 记录一个不符合的位置和原因
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	v.errors = append(v.errors, path+": "+fmt.Sprintf(format, args...))
+}
+
+// validate 按 schema 校验 path 位置的 value
+func (v *schemaValidator) validate(schema map[string]any, value any, path string, depth int) {
+	if depth > maxSchemaDepth {
+		v.fail(path, "JSON Schema嵌套过深或存在循环引用")
+		return
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := v.resolve(ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		v.validate(target, value, path, depth+1)
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return matchesType(t, value) }) {
+		v.fail(path, "类型应为 %s，实际为 %s", strings.Join(types, "|"), jsonType(value))
+		// 类型不符时不再检查其他关键字，避免重复报错
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(item any) bool { return jsonEqual(item, value) }) {
+		v.fail(path, "取值应为 %s 之一", compactJSON(enum))
+	}
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		v.fail(path, "取值应为 %s", compactJSON(constant))
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, value, path, depth)
+	case []any:
+		v.validateArray(schema, value, path, depth)
+	case string:
+		v.validateString(schema, value, path)
+	case float64:
+		v.validateNumber(schema, value, path)
+	}
+
+	if allOf, ok := schema["allOf"].([]any); ok {
+		for _, item := range allOf {
+			if sub, ok := item.(map[string]any); ok {
+				v.validate(sub, value, path, depth+1)
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok && v.countMatches(anyOf, value, depth) == 0 {
+		v.fail(path, "不符合 anyOf 中的任何一个Schema")
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		if n := v.countMatches(oneOf, value, depth); n != 1 {
+			v.fail(path, "应恰好符合 oneOf 中的一个Schema，实际符合 %d 个", n)
+		}
+	}
+}
+
+// validateObject 校验对象的 required、properties 和 additionalProperties
+func (v *schemaValidator) validateObject(schema map[string]any, value map[string]any, path string, depth int) {
+	for _, name := range getRequiredFields(schema) {
+		if _, ok := value[name]; !ok {
+			v.fail(path, "缺少必填字段 %s", name)
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, ok := properties[name].(map[string]any); ok {
+			v.validate(property, value[name], path+"."+name, depth+1)
+			continue
+		}
+		if _, ok := properties[name]; ok {
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(path, "不允许的字段 %s", name)
+			}
+		case map[string]any:
+			v.validate(additional, value[name], path+"."+name, depth+1)
+		}
+	}
+}
+
+// validateArray 校验数组的 items、minItems 和 maxItems
+func (v *schemaValidator) validateArray(schema map[string]any, value []any, path string, depth int) {
+	if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(value)) < n {
+		v.fail(path, "元素个数不能少于 %v，实际为 %d", n, len(value))
+	}
+	if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(value)) > n {
+		v.fail(path, "元素个数不能多于 %v，实际为 %d", n, len(value))
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		for i, item := range value {
+			v.validate(items, item, fmt.Sprintf("%s[%d]", path, i), depth+1)
+		}
+	}
+}
+
+// validateString 校验字符串的 minLength、maxLength 和 pattern，长度按字符计算
+func (v *schemaValidator) validateString(schema map[string]any, value string, path string) {
+	length := utf8.RuneCountInString(value)
+	if n, ok := schemaNumber(schema, "minLength"); ok && float64(length) < n {
+		v.fail(path, "长度不能小于 %v，实际为 %d", n, length)
+	}
+	if n, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > n {
+		v.fail(path, "长度不能大于 %v，实际为 %d", n, length)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.fail(path, "无法解析 pattern %q: %v", pattern, err)
+		} else if !re.MatchString(value) {
+			v.fail(path, "不匹配 pattern %q", pattern)
+		}
+	}
+}
+
+// validateNumber 校验数值的 minimum、maximum、exclusiveMinimum 和 exclusiveMaximum
+func (v *schemaValidator) validateNumber(schema map[string]any, value float64, path string) {
+	if n, ok := schemaNumber(schema, "minimum"); ok && value < n {
+		v.fail(path, "不能小于 %v，实际为 %v", n, value)
+	}
+	if n, ok := schemaNumber(schema, "maximum"); ok && value > n {
+		v.fail(path, "不能大于 %v，实际为 %v", n, value)
+	}
+	if n, ok := schemaNumber(schema, "exclusiveMinimum"); ok && value <= n {
+		v.fail(path, "应大于 %v，实际为 %v", n, value)
+	}
+	if n, ok := schemaNumber(schema, "exclusiveMaximum"); ok && value >= n {
+		v.fail(path, "应小于 %v，实际为 %v", n, value)
+	}
+}
+
+// countMatches 返回 value 符合 schemas 中几个Schema，用于 anyOf 和 oneOf
+func (v *schemaValidator) countMatches(schemas []any, value any, depth int) int {
+	matches := 0
+	for _, item := range schemas {
+		sub, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		branch := &schemaValidator{root: v.root}
+		branch.validate(sub, value, "$", depth+1)
+		if len(branch.errors) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// resolve 解析指向根Schema内部的 $ref，如 #、#/$defs/item、#/definitions/item
+func (v *schemaValidator) resolve(ref string) (map[string]any, error) {
+	if ref != "#" && !strings.HasPrefix(ref, "#/") {
+		return nil, invalidRequestf("不支持的 $ref: %s，只支持指向Schema内部的引用", ref)
+	}
+	var current any = v.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case map[string]any:
+			current = node[token]
+		case []any:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, invalidRequestf("找不到 $ref 指向的Schema: %s", ref)
+			}
+			current = node[index]
+		default:
+			current = nil
+		}
+	}
+	target, ok := current.(map[string]any)
+	if !ok {
+		return nil, invalidRequestf("找不到 $ref 指向的Schema: %s", ref)
+	}
+	return target, nil
+}
+
+// inlineSchemaRefs 返回将 $ref 替换为所指向Schema后的副本，并去掉根上的 $defs 和 definitions
+// 用于不支持 $ref 的供应商(如Claude的工具参数、Gemini的 response_schema)；循环引用返回错误
+func inlineSchemaRefs(schema map[string]any) (map[string]any, error) {
+	v := &schemaValidator{root: schema}
+	result := make(map[string]any, len(schema))
+	for key, value := range schema {
+		if key == "$defs" || key == "definitions" {
+			continue
+		}
+		inlined, err := v.inline(value, 1)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = inlined
+	}
+	if ref, ok := result["$ref"].(string); ok {
+		delete(result, "$ref")
+		target, err := v.resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		inlined, err := v.inline(target, 1)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range inlined.(map[string]any) {
+			if _, ok := result[key]; !ok {
+				result[key] = value
+			}
+		}
+	}
+	return result, nil
+}
+
+// inline 递归替换 value 中的 $ref
+func (v *schemaValidator) inline(value any, depth int) (any, error) {
+	if depth > maxSchemaDepth {
+		return nil, invalidRequestf("JSON Schema嵌套过深或存在循环引用")
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		if ref, ok := value["$ref"].(string); ok {
+			target, err := v.resolve(ref)
+			if err != nil {
+				return nil, err
+			}
+			return v.inline(target, depth+1)
+		}
+		result := make(map[string]any, len(value))
+		for key, item := range value {
+			inlined, err := v.inline(item, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = inlined
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(value))
+		for i, item := range value {
+			inlined, err := v.inline(item, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = inlined
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+// schemaTypes 返回Schema的 type，支持 "string" 和 ["string", "null"] 两种形式
+func schemaTypes(value any) []string {
+	switch t := value.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// matchesType 判断 value 是否为JSON Schema的类型 name，integer 要求是没有小数部分的数值
+func matchesType(name string, value any) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonType(value) == name
+	}
+}
+
+// jsonType 返回 value 的JSON类型名称
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// schemaNumber 返回Schema中数值类型的关键字
+func schemaNumber(schema map[string]any, key string) (float64, bool) {
+	n, ok := schema[key].(float64)
+	return n, ok
+}
+
+// jsonEqual 按JSON序列化结果比较两个值是否相等，用于 enum 和 const
+func jsonEqual(a, b any) bool {
+	return compactJSON(a) == compactJSON(b)
+}
+
+// compactJSON 返回 value 的JSON文本，用于比较和错误信息
+func compactJSON(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
@@ -0,0 +1,107 @@
+package llmadapter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidateJSONSchema 测试JSON Schema校验支持的关键字和错误位置
+func TestValidateJSONSchema(t *testing.T) {
+	schema := map[string]any{}
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 2, "maxLength": 4, "pattern": "^[a-z]+$"},
+			"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+			"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}, "maxItems": 2},
+			"level": {"enum": ["low", "high"]},
+			"version": {"const": 1},
+			"note": {"type": ["string", "null"]},
+			"id": {"anyOf": [{"type": "string"}, {"type": "integer"}]},
+			"score": {"oneOf": [{"type": "number", "minimum": 0}, {"type": "number", "maximum": 10}]}
+		},
+		"required": ["name", "age"],
+		"additionalProperties": false,
+		"$defs": {"tag": {"type": "object", "properties": {"key": {"type": "string"}}, "required": ["key"]}}
+	}`), &schema)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{name: "合法", value: `{"name": "ab", "age": 3, "tags": [{"key": "x"}], "level": "low", "version": 1, "note": null, "id": "a", "score": 20}`},
+		{name: "缺少字段和多余字段", value: `{"name": "ab", "extra": 1}`, want: []string{"$: 缺少必填字段 age", "$: 不允许的字段 extra"}},
+		{name: "类型", value: `{"name": 1, "age": 1.5, "note": 2}`, want: []string{
+			"$.age: 类型应为 integer，实际为 number",
+			"$.name: 类型应为 string，实际为 number",
+			"$.note: 类型应为 string|null，实际为 number",
+		}},
+		{name: "字符串", value: `{"name": "ABCDE", "age": 1}`, want: []string{
+			"$.name: 长度不能大于 4，实际为 5",
+			`$.name: 不匹配 pattern "^[a-z]+$"`,
+		}},
+		{name: "数值", value: `{"name": "ab", "age": 150}`, want: []string{"$.age: 应小于 150，实际为 150"}},
+		{name: "数组和引用", value: `{"name": "ab", "age": 1, "tags": [{"key": "x"}, {}, {"key": 1}]}`, want: []string{
+			"$.tags: 元素个数不能多于 2，实际为 3",
+			"$.tags[1]: 缺少必填字段 key",
+			"$.tags[2].key: 类型应为 string，实际为 number",
+		}},
+		{name: "枚举和常量", value: `{"name": "ab", "age": 1, "level": "mid", "version": 2}`, want: []string{
+			`$.level: 取值应为 ["low","high"] 之一`,
+			"$.version: 取值应为 1",
+		}},
+		{name: "组合", value: `{"name": "ab", "age": 1, "id": true, "score": 5}`, want: []string{
+			"$.id: 不符合 anyOf 中的任何一个Schema",
+			"$.score: 应恰好符合 oneOf 中的一个Schema，实际符合 2 个",
+		}},
+		{name: "根类型", value: `[]`, want: []string{"$: 类型应为 object，实际为 array"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value any
+			if !assert.NoError(t, json.Unmarshal([]byte(tt.value), &value)) {
+				return
+			}
+			assert.Equal(t, tt.want, validateJSONSchema(schema, value))
+		})
+	}
+
+	// 无法解析的引用
+	assert.Equal(t, []string{"$: 找不到 $ref 指向的Schema: #/$defs/missing"}, validateJSONSchema(map[string]any{"$ref": "#/$defs/missing"}, "x"))
+	assert.Len(t, validateJSONSchema(map[string]any{"$ref": "https://example.com/schema.json"}, "x"), 1)
+}
+
+// TestInlineSchemaRefs 测试展开 $ref 和检测循环引用
+func TestInlineSchemaRefs(t *testing.T) {
+	var schema map[string]any
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {"items": {"type": "array", "items": {"$ref": "#/definitions/item"}}},
+		"definitions": {"item": {"type": "object", "properties": {"price": {"$ref": "#/definitions/price"}}}, "price": {"type": "number"}}
+	}`), &schema)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	inlined, err := inlineSchemaRefs(schema)
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{
+			"type": "object",
+			"properties": {"items": {"type": "array", "items": {"type": "object", "properties": {"price": {"type": "number"}}}}}
+		}`, compactJSON(inlined))
+	}
+	// 原Schema不变
+	assert.Contains(t, schema, "definitions")
+
+	_, err = inlineSchemaRefs(map[string]any{
+		"$ref":  "#/$defs/node",
+		"$defs": map[string]any{"node": map[string]any{"type": "object", "properties": map[string]any{"next": map[string]any{"$ref": "#/$defs/node"}}}},
+	})
+	assert.ErrorContains(t, err, "循环引用")
+}
@@ -0,0 +1,489 @@
+package llmadapter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// KeyDirEnv 指定密钥目录的环境变量，优先于 SetKeyDir
+	KeyDirEnv = "LLM_KEY_DIR"
+	// PrivateKeysEnv 直接提供PEM格式私钥的环境变量，设置后不再读取密钥目录
+	// 可以包含多个PEM块，每块通过 Key-Id 头指定密钥ID，第一块为当前密钥；只有一块且没有 Key-Id 时密钥ID为 env
+	PrivateKeysEnv = "LLM_PRIVATE_KEYS"
+
+	// envelopePrefix 信封加密密文的前缀，格式为 v2:<密钥ID>:<RSA加密的数据密钥>:<AES-GCM的nonce和密文>
+	// 没有前缀的密文为直接使用RSA-OAEP加密的旧格式
+	envelopePrefix = "v2:"
+	// primaryKeyFile 密钥目录中记录当前密钥ID的文件
+	primaryKeyFile = "primary"
+	// legacyKeyID 旧版 private_key.pem 的密钥ID
+	legacyKeyID = "default"
+)
+
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Keyring 按密钥ID管理的一组RSA密钥
+// 使用当前密钥(primary)做信封加密：随机生成AES-256数据密钥加密内容，再用RSA公钥加密数据密钥，
+// 密文中带有密钥ID，轮换密钥后旧密钥加密的内容仍可解密
+type Keyring struct {
+	dir     string // 密钥目录，来自环境变量时为空
+	keys    map[string]*rsa.PrivateKey
+	primary string
+}
+
+// LoadKeyring 读取密钥目录中的所有密钥
+// 目录中每个 <密钥ID>.pem 为一个私钥，primary 文件记录当前密钥ID；
+// 旧版的 private_key.pem 作为密钥 default 加载，public_key.pem 被忽略。
+// 没有任何密钥时返回 ErrKeyNotFound，不会自动生成
+func LoadKeyring(dir string) (*Keyring, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKeyNotFound, err)
+	}
+
+	k := &Keyring{dir: dir, keys: make(map[string]*rsa.PrivateKey)}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".pem" || name == "public_key.pem" {
+			continue
+		}
+		id := strings.TrimSuffix(name, ".pem")
+		if name == "private_key.pem" {
+			id = legacyKeyID
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥 %s 失败: %v", name, err)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("%w: %s 不是PEM格式", ErrInvalidKey, name)
+		}
+		key, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := k.add(id, key); err != nil {
+			return nil, err
+		}
+	}
+	if len(k.keys) == 0 {
+		return nil, fmt.Errorf("%w: 密钥目录 %s 中没有私钥", ErrKeyNotFound, dir)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, primaryKeyFile))
+	switch {
+	case err == nil:
+		k.primary = strings.TrimSpace(string(data))
+	case os.IsNotExist(err) && len(k.keys) == 1:
+		for id := range k.keys {
+			k.primary = id
+		}
+	case os.IsNotExist(err):
+		return nil, fmt.Errorf("%w: 密钥目录 %s 中有多个密钥，需要在 %s 文件中指定当前密钥", ErrInvalidKey, dir, primaryKeyFile)
+	default:
+		return nil, fmt.Errorf("读取当前密钥ID失败: %v", err)
+	}
+	if _, ok := k.keys[k.primary]; !ok {
+		return nil, fmt.Errorf("%w: 当前密钥 %s 不存在", ErrKeyNotFound, k.primary)
+	}
+	return k, nil
+}
+
+// ParseKeyring 从PEM内容创建密钥环，格式见 PrivateKeysEnv
+func ParseKeyring(data []byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]*rsa.PrivateKey)}
+	var blocks []*pem.Block
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("%w: 没有PEM格式的私钥", ErrKeyNotFound)
+	}
+
+	for i, block := range blocks {
+		id := block.Headers["Key-Id"]
+		if id == "" {
+			if len(blocks) > 1 {
+				return nil, fmt.Errorf("%w: 第%d个私钥缺少 Key-Id", ErrInvalidKey, i+1)
+			}
+			id = "env"
+		}
+		key, err := parsePrivateKey(block)
+		if err != nil {
+			return nil, err
+		}
+		if err := k.add(id, key); err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			k.primary = id
+		}
+	}
+	return k, nil
+}
+
+// parsePrivateKey 解析PKCS#1或PKCS#8格式的RSA私钥
+func parsePrivateKey(block *pem.Block) (*rsa.PrivateKey, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		return key, nil
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+		}
+		key, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: 不是RSA私钥", ErrInvalidKey)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("%w: 不支持的PEM类型 %s", ErrInvalidKey, block.Type)
+	}
+}
+
+func (k *Keyring) add(id string, key *rsa.PrivateKey) error {
+	if !keyIDPattern.MatchString(id) {
+		return fmt.Errorf("%w: 密钥ID %q 只能包含字母、数字、_、. 和 -", ErrInvalidKey, id)
+	}
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("%w: 密钥ID %s 重复", ErrInvalidKey, id)
+	}
+	k.keys[id] = key
+	return nil
+}
+
+// Dir 返回密钥目录，密钥来自环境变量时为空
+func (k *Keyring) Dir() string {
+	return k.dir
+}
+
+// Primary 返回当前用于加密的密钥ID
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// KeyIDs 返回所有密钥ID(按字母排序)
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt 使用当前密钥做信封加密，内容长度不受RSA密钥长度限制
+func (k *Keyring) Encrypt(data string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("生成数据密钥失败: %v", err)
+	}
+	wrapped, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &k.keys[k.primary].PublicKey, dataKey, []byte(k.primary))
+	if err != nil {
+		return "", fmt.Errorf("加密数据密钥失败: %v", err)
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成nonce失败: %v", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(data), []byte(k.primary))
+
+	return envelopePrefix + k.primary + ":" +
+		base64.StdEncoding.EncodeToString(wrapped) + ":" +
+		base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 或旧版 EncryptWithPublicKey 生成的密文
+// 旧格式的密文没有密钥ID，依次尝试所有密钥
+func (k *Keyring) Decrypt(encryptedData string) (string, error) {
+	if !strings.HasPrefix(encryptedData, envelopePrefix) {
+		var lastErr error
+		for _, id := range k.KeyIDs() {
+			plain, err := DecryptWithPrivateKey(k.keys[id], encryptedData)
+			if err == nil {
+				return plain, nil
+			}
+			lastErr = err
+		}
+		return "", lastErr
+	}
+
+	parts := strings.Split(strings.TrimPrefix(encryptedData, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: 密文格式不正确", ErrInvalidData)
+	}
+	id := parts[0]
+	key, ok := k.keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w: 密钥 %s", ErrKeyNotFound, id)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("%w: Base64解码失败: %v", ErrInvalidData, err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("%w: Base64解码失败: %v", ErrInvalidData, err)
+	}
+
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, wrapped, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("解密数据密钥失败: %v", err)
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("%w: 密文长度不正确", ErrInvalidData)
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %v", err)
+	}
+	return string(plain), nil
+}
+
+// IsCurrent 判断密文是否已经使用当前密钥和信封格式加密，不需要轮换
+func (k *Keyring) IsCurrent(encryptedData string) bool {
+	return strings.HasPrefix(encryptedData, envelopePrefix+k.primary+":")
+}
+
+// Reencrypt 使用当前密钥重新加密，已经是当前密钥的密文原样返回
+func (k *Keyring) Reencrypt(encryptedData string) (string, error) {
+	if k.IsCurrent(encryptedData) {
+		return encryptedData, nil
+	}
+	plain, err := k.Decrypt(encryptedData)
+	if err != nil {
+		return "", err
+	}
+	return k.Encrypt(plain)
+}
+
+func newGCM(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// AddKey 在密钥目录中生成一个新密钥并设为当前密钥，返回新密钥ID
+// 原有密钥保留在目录中用于解密，所有密文重新加密后才可以删除
+func AddKey(dir string) (string, error) {
+	keyPair, err := GenerateRSAKeyPair()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("创建密钥目录失败: %v", err)
+	}
+
+	// 密钥ID为生成时间，同一秒内生成多个密钥时加序号
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(keyPair.PrivateKey)})
+	base := time.Now().UTC().Format("20060102T150405Z")
+	id := base
+	for i := 1; ; i++ {
+		err = writeFileExclusive(filepath.Join(dir, id+".pem"), data, 0600)
+		if !os.IsExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+	if err != nil {
+		return "", fmt.Errorf("保存密钥失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, primaryKeyFile), []byte(id+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("保存当前密钥ID失败: %v", err)
+	}
+	return id, nil
+}
+
+// writeFileExclusive 创建新文件并写入，文件已存在时返回错误，避免覆盖已有密钥
+func writeFileExclusive(path string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var (
+	keyringMu      sync.Mutex
+	defaultKeyring *Keyring
+	configuredDir  string
+)
+
+// SetKeyDir 设置默认密钥环的密钥目录，环境变量 LLM_KEY_DIR 和 LLM_PRIVATE_KEYS 优先
+// dir 为空时使用 DefaultRSAKeysDir
+func SetKeyDir(dir string) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	configuredDir = dir
+	defaultKeyring = nil
+}
+
+// KeyDir 返回默认密钥环使用的密钥目录
+func KeyDir() string {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	return keyDir()
+}
+
+func keyDir() string {
+	if dir := os.Getenv(KeyDirEnv); dir != "" {
+		return dir
+	}
+	if configuredDir != "" {
+		return configuredDir
+	}
+	return DefaultRSAKeysDir
+}
+
+// DefaultKeyring 返回默认密钥环，首次调用时加载
+// 依次使用环境变量 LLM_PRIVATE_KEYS、LLM_KEY_DIR、SetKeyDir 设置的目录和 DefaultRSAKeysDir
+func DefaultKeyring() (*Keyring, error) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	if defaultKeyring != nil {
+		return defaultKeyring, nil
+	}
+
+	var k *Keyring
+	var err error
+	if keys := os.Getenv(PrivateKeysEnv); keys != "" {
+		k, err = ParseKeyring([]byte(keys))
+	} else {
+		k, err = LoadKeyring(keyDir())
+	}
+	if err != nil {
+		return nil, err
+	}
+	defaultKeyring = k
+	return k, nil
+}
+
+// ReloadKeyring 丢弃已加载的默认密钥环，下次使用时重新读取，用于新增或轮换密钥后
+func ReloadKeyring() {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	defaultKeyring = nil
+}
+
+// RotateResult 重新加密敏感字段的结果
+type RotateResult struct {
+	Rotated int      // 重新加密的字段数
+	Skipped []string // 无法解密而保持不变的字段，如占位符 YOUR_API_KEY_HERE
+}
+
+// RotateCredentialFiles 使用密钥环的当前密钥重新加密配置目录中所有凭证(含未启用和其他环境)的敏感字段
+// 只替换配置文件中的密文，注释和格式保持不变；无法解密的值保持不变并记录在 Skipped 中
+func RotateCredentialFiles(dir string, keyring *Keyring) (RotateResult, error) {
+	var result RotateResult
+	for _, vendor := range CredentialVendors() {
+		configs, err := ReadCredentialFile(dir, vendor)
+		if err != nil {
+			return result, err
+		}
+		if len(configs) == 0 {
+			continue
+		}
+
+		replacements := make(map[string]string)
+		for _, config := range configs {
+			for _, field := range SecretFields(vendor) {
+				value, ok := config.Secrets[field]
+				if !ok || keyring.IsCurrent(value) {
+					continue
+				}
+				if _, done := replacements[value]; done {
+					continue
+				}
+				rotated, err := keyring.Reencrypt(value)
+				if err != nil {
+					result.Skipped = append(result.Skipped, fmt.Sprintf("%s/%s/%s.%s", vendor, config.Env, config.Name, field))
+					continue
+				}
+				replacements[value] = rotated
+			}
+		}
+		if len(replacements) == 0 {
+			continue
+		}
+
+		path := filepath.Join(dir, credentialLoaders[vendor].file)
+		info, err := os.Stat(path)
+		if err != nil {
+			return result, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return result, err
+		}
+		content := string(data)
+		for old, rotated := range replacements {
+			content = strings.ReplaceAll(content, old, rotated)
+		}
+		if err := writeFileAtomic(path, []byte(content), info.Mode().Perm()); err != nil {
+			return result, fmt.Errorf("写入%s配置文件失败: %v", credentialLoaders[vendor].label, err)
+		}
+		result.Rotated += len(replacements)
+	}
+	return result, nil
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再重命名，避免热加载读到写了一半的配置
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,225 @@
+package llmadapter
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeLegacyKey 在目录中写入旧版的 private_key.pem/public_key.pem，返回密钥对
+func writeLegacyKey(t *testing.T, dir string) *RSAKeyPair {
+	t.Helper()
+	keyPair, err := GenerateRSAKeyPair()
+	if err != nil {
+		t.Fatalf("生成RSA密钥对失败: %v", err)
+	}
+	if err := SaveRSAKeyPair(keyPair, filepath.Join(dir, "private_key.pem"), filepath.Join(dir, "public_key.pem")); err != nil {
+		t.Fatalf("保存RSA密钥对失败: %v", err)
+	}
+	return keyPair
+}
+
+// TestKeyringRotation 新增密钥后旧密钥和旧格式的密文仍可解密，新密文使用新密钥
+func TestKeyringRotation(t *testing.T) {
+	dir := t.TempDir()
+	legacy := writeLegacyKey(t, dir)
+	legacyCipher, err := EncryptWithPublicKey(legacy.PublicKey, "legacy-secret")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	keyring, err := LoadKeyring(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "default", keyring.Primary())
+	oldCipher, err := keyring.Encrypt("old-secret")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, strings.HasPrefix(oldCipher, "v2:default:"))
+	assert.False(t, keyring.IsCurrent(legacyCipher))
+
+	id, err := AddKey(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	keyring, err = LoadKeyring(dir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, id, keyring.Primary())
+	assert.Equal(t, []string{id, "default"}, keyring.KeyIDs())
+
+	for cipher, want := range map[string]string{legacyCipher: "legacy-secret", oldCipher: "old-secret"} {
+		plain, err := keyring.Decrypt(cipher)
+		assert.NoError(t, err)
+		assert.Equal(t, want, plain)
+
+		rotated, err := keyring.Reencrypt(cipher)
+		assert.NoError(t, err)
+		assert.True(t, keyring.IsCurrent(rotated))
+		plain, _ = keyring.Decrypt(rotated)
+		assert.Equal(t, want, plain)
+	}
+
+	// 信封加密的内容长度不受RSA密钥长度限制，且不能被篡改
+	long := strings.Repeat("service-account-json", 100)
+	cipher, err := keyring.Encrypt(long)
+	if assert.NoError(t, err) {
+		plain, err := keyring.Decrypt(cipher)
+		assert.NoError(t, err)
+		assert.Equal(t, long, plain)
+	}
+	tampered := strings.Replace(cipher, "v2:"+id+":", "v2:default:", 1)
+	_, err = keyring.Decrypt(tampered)
+	assert.Error(t, err)
+}
+
+// TestLoadKeyringErrors 密钥缺失或无法确定当前密钥时返回错误，不会生成新密钥
+func TestLoadKeyringErrors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := LoadKeyring(dir)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries, "不应自动生成密钥")
+
+	_, err = LoadKeyring(filepath.Join(dir, "missing"))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	writeLegacyKey(t, dir)
+	keyPair, _ := GenerateRSAKeyPair()
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(keyPair.PrivateKey)})
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "k2.pem"), data, 0600))
+	_, err = LoadKeyring(dir)
+	assert.ErrorIs(t, err, ErrInvalidKey, "多个密钥时需要 primary 文件")
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "primary"), []byte("k3\n"), 0600))
+	_, err = LoadKeyring(dir)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+// TestParseKeyring 环境变量中的多个私钥按 Key-Id 区分，第一个为当前密钥
+func TestParseKeyring(t *testing.T) {
+	var data []byte
+	for _, id := range []string{"k2", "k1"} {
+		keyPair, _ := GenerateRSAKeyPair()
+		data = append(data, pem.EncodeToMemory(&pem.Block{
+			Type:    "RSA PRIVATE KEY",
+			Headers: map[string]string{"Key-Id": id},
+			Bytes:   x509.MarshalPKCS1PrivateKey(keyPair.PrivateKey),
+		})...)
+	}
+	keyring, err := ParseKeyring(data)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "k2", keyring.Primary())
+		assert.Equal(t, []string{"k1", "k2"}, keyring.KeyIDs())
+	}
+
+	_, err = ParseKeyring([]byte("not a key"))
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+// TestDefaultKeyring 依次使用 LLM_PRIVATE_KEYS、LLM_KEY_DIR 和 SetKeyDir 设置的目录
+func TestDefaultKeyring(t *testing.T) {
+	t.Cleanup(func() { SetKeyDir("") })
+	configured, fromEnv := t.TempDir(), t.TempDir()
+	writeLegacyKey(t, configured)
+	_, err := AddKey(fromEnv)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	SetKeyDir(configured)
+	keyring, err := DefaultKeyring()
+	if assert.NoError(t, err) {
+		assert.Equal(t, configured, keyring.Dir())
+	}
+
+	t.Setenv(KeyDirEnv, fromEnv)
+	ReloadKeyring()
+	keyring, err = DefaultKeyring()
+	if assert.NoError(t, err) {
+		assert.Equal(t, fromEnv, keyring.Dir())
+	}
+
+	keyPair, _ := GenerateRSAKeyPair()
+	t.Setenv(PrivateKeysEnv, string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(keyPair.PrivateKey)})))
+	ReloadKeyring()
+	keyring, err = DefaultKeyring()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "env", keyring.Primary())
+		assert.Empty(t, keyring.Dir())
+	}
+
+	t.Setenv(PrivateKeysEnv, "")
+	t.Setenv(KeyDirEnv, t.TempDir())
+	ReloadKeyring()
+	_, _, err = InitRSAKeyManager()
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	ReloadKeyring()
+}
+
+// TestRotateCredentialFiles 只替换配置文件中的密文，保留注释，占位符等无法解密的值保持不变
+func TestRotateCredentialFiles(t *testing.T) {
+	keyDir, dir := t.TempDir(), t.TempDir()
+	legacy := writeLegacyKey(t, keyDir)
+	oldCipher, _ := EncryptWithPublicKey(legacy.PublicKey, "sk-azure")
+
+	content := `environments:
+  production:
+    credentials:
+      # 主账号
+      - name: east
+        api_key: "` + oldCipher + `"
+        enabled: true
+      - name: west
+        api_key: "` + oldCipher + `"
+        enabled: true
+  development:
+    credentials:
+      - name: dev
+        api_key: "YOUR_API_KEY_HERE"
+`
+	path := filepath.Join(dir, "azure.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0640))
+
+	_, err := AddKey(keyDir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	keyring, err := LoadKeyring(keyDir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	result, err := RotateCredentialFiles(dir, keyring)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, result.Rotated)
+	assert.Equal(t, []string{"azure/development/dev.api_key"}, result.Skipped)
+
+	data, _ := os.ReadFile(path)
+	assert.Contains(t, string(data), "# 主账号")
+	assert.Contains(t, string(data), "YOUR_API_KEY_HERE")
+	assert.NotContains(t, string(data), oldCipher)
+	info, _ := os.Stat(path)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	configs, err := ReadCredentialFile(dir, "azure")
+	if assert.NoError(t, err) && assert.Len(t, configs, 3) {
+		plain, err := keyring.Decrypt(configs[1].Secrets["api_key"])
+		assert.NoError(t, err)
+		assert.Equal(t, "sk-azure", plain)
+	}
+
+	// 再次轮换时没有需要重新加密的字段
+	result, err = RotateCredentialFiles(dir, keyring)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Rotated)
+}
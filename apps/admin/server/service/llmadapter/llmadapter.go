@@ -19,12 +19,16 @@
 package llmadapter
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"github.com/cloudwego/eino/schema"
 	"github.com/sashabaranov/go-openai"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
+	"time"
 )
 
 // 配置文件路径常量
@@ -119,81 +123,232 @@ type Config struct {
 	//代理URl
 	ProxyURL string `yaml:"proxy_url" json:"proxy_url"`
 
-	// 厂商可选配置参数
+	// Timeout 单次请求的超时时间，由选中凭证的timeout字段决定，0表示不限制
+	Timeout time.Duration `yaml:"-" json:"-"`
+
+	// Thinking 请求的推理(思考)设置，支持的供应商映射为各自的参数
+	Thinking *ThinkingOptions `yaml:"-" json:"-"`
+
+	// Seed、PresencePenalty、FrequencyPenalty、LogitBias、ResponseFormat 请求中的OpenAI标准参数，
+	// 支持的供应商映射为各自的参数，VendorOptional 中设置了同名参数时以 VendorOptional 为准
+	Seed             *int                                 `yaml:"-" json:"-"`
+	PresencePenalty  float32                              `yaml:"-" json:"-"`
+	FrequencyPenalty float32                              `yaml:"-" json:"-"`
+	LogitBias        map[string]int                       `yaml:"-" json:"-"`
+	ResponseFormat   *openai.ChatCompletionResponseFormat `yaml:"-" json:"-"`
+
+	// 厂商可选配置参数，来自请求的 vendor_options(只保留对当前供应商生效的部分，见 requestVendorOptions)
 	VendorOptional *VendorOptional `yaml:"vendor_optional,omitempty" json:"vendor_optional,omitempty"`
 }
 
 // CreateChatCompletion 创建聊天完成
-// 统一的聊天接口，根据req.Stream参数决定是否使用流式响应
-// 如果writer为nil，则返回普通响应；如果writer不为nil，则写入流式响应
+// 统一的聊天接口，根据req.Provider从已注册的供应商中查找实现，根据req.Stream参数决定是否使用流式响应
 //
 // 参数:
+//   - ctx: 调用方上下文，取消后(如客户端断开连接)上游请求和流式转换都会停止
 //   - req: ChatRequest类型，包含完成请求的所有参数，如模型、消息历史、温度等
-//   - req.Provider 指定使用的LLM供应商，例如 "bedrock"、"azure" 等
+//   - req.Provider 指定使用的LLM供应商，例如 "bedrock"、"azure" 等，取值见 Providers()
 //   - req.Stream 指定是否使用流式响应
 //   - writer: io.Writer类型，用于接收流式响应的输出
-//     当req.Stream=true且writer不为nil时使用流式响应
+//     当req.Stream=true且writer不为nil时使用流式响应，writer 实现了 http.Flusher 时每个分片写入后立即刷新
 //
 // 返回值:
-//   - *ChatResponse: 非流式响应的返回结果，包含AI生成的完整回复
+//   - *openai.ChatCompletionResponse: 非流式响应的返回结果，包含AI生成的完整回复
 //   - error: 操作过程中遇到的任何错误
 //
 // 错误:
-//   - 当提供的供应商不受支持时返回错误
+//   - 当提供的供应商未注册时返回包装了 ErrUnsupportedProvider 的错误
 //   - 当供应商的特定操作失败时返回相应错误
+//   - 按 SetRetryPolicy 设置的策略重试和故障转移后仍失败时返回 *UpstreamError，记录错误分类和最后使用的凭证
 //
 // 注意事项:
-//   - 流式响应模式下 *ChatResponse 将返回 nil
-//   - 当前支持 "bedrock" 供应商的流式响应，其他供应商正在开发中
-//   - 如未指定供应商，默认使用 "bedrock"
-func CreateChatCompletion(req ChatRequest, writer io.Writer) (*openai.ChatCompletionResponse, error) {
-	// 获取供应商
-	provider := req.Provider
-	if provider == "" {
-		// 如果没有提供供应商，可以从配置中获取默认供应商
-		// TODO: 从配置中获取默认供应商
-		provider = "bedrock" // 暂时默认使用bedrock
-	}
-
-	// 如果是流式响应且writer不为nil
-	if req.Stream && writer != nil {
-		var err error
-		switch provider {
-		case "bedrock":
-			err = BedrockStreamChatCompletionToChat(req, writer)
-		case "azure":
-			err = AzureStreamChatCompletionToChat(req, writer)
-		case "deepseek":
-			err = DeepSeekStreamChatCompletionToChat(req, writer)
-		case "openai":
-			//TODO 未实际测试通过 缺少KEY
-			err = OpenAIStreamChatCompletionToChat(req, writer)
-		case "claude":
-			//TODO 未实际测试通过 缺少KEY
-			err = ClaudeStreamChatCompletionToChat(req, writer)
-			// TODO: 在此处添加其他供应商的流式调用实现
-		default:
-			err = errors.New("不支持的AI供应商: " + provider)
-		}
+//   - 流式响应模式下返回的响应为 nil
+//   - 需要调用详情(最终使用的供应商、凭证、Token用量等)时，通过 WithCallInfo 获取上下文后传入
+//   - 如未指定供应商，使用当前环境中启用凭证的 models 配置了该模型的供应商(见 ListModels)
+//   - req.Fallbacks 不为空时按其中的顺序故障转移，代替 RetryPolicy 中的规则；通过 WithModelFilter 限制可调用的模型时跳过不允许的备用模型
+//   - 流式响应等待上游输出时按 SetStreamHeartbeat 设置的间隔发送 ": ping" 心跳；请求设置了 stream_options.include_usage 时在 [DONE] 之前输出用量分片
+//   - 推理模型的推理内容在 reasoning_content 中返回(流式为增量)，推理Token数记录在 usage.completion_tokens_details.reasoning_tokens；
+//     req.Thinking 设置推理预算(见 ThinkingOptions)，req.HideReasoning 为true时不返回推理内容
+//   - 请求中的 seed、presence_penalty、frequency_penalty、logit_bias、response_format、tool_choice 映射为各供应商的参数，
+//     req.VendorOptions 设置供应商特有的参数；供应商不支持而被忽略的参数见 UnsupportedParams，可通过 WithUnsupportedParamsHandler 获取
+//   - 非流式请求的 n 大于1时，供应商不支持 n 参数(见 MultiChoiceProvider)则分别调用 n 次后合并结果；流式请求不支持 n 大于1
+//   - response_format 为 json_schema 时所有供应商都可用(Claude、Bedrock通过强制调用工具实现，DeepSeek通过提示词实现)，
+//     输出不符合Schema时自动要求模型修正一次，仍不符合时返回 *StructuredOutputError；流式请求生成完整输出并校验后再输出
+func CreateChatCompletion(ctx context.Context, req ChatRequest, writer io.Writer) (*openai.ChatCompletionResponse, error) {
+	// 获取供应商，未指定时使用凭证配置了该模型的供应商
+	name, err := providerName(req)
+	if err != nil {
 		return nil, err
 	}
 
+	req.Provider = name
+	start := time.Now()
+	if req.N > maxChoices {
+		return nil, invalidRequestf("n 不能超过 %d", maxChoices)
+	}
+	if !modelAllowed(ctx, req.Model) {
+		return nil, fmt.Errorf("%w: %s", ErrModelNotAllowed, req.Model)
+	}
+	notifyUnsupportedParams(ctx, req)
+
 	// 非流式响应
-	switch provider {
-	case "bedrock":
-		return BedrockCreateChatCompletionToChat(req)
-	case "azure":
-		return AzureCreateChatCompletionToChat(req)
-	case "deepseek":
-		return DeepSeekCreateChatCompletionToChat(req)
-	case "openai":
-		//TODO 未实际测试通过 缺少KEY
-		return OpenAICreateChatCompletionToChat(req)
-	case "claude":
-		//TODO 未实际测试通过 缺少KEY
-		return ClaudeCreateChatCompletionToChat(req)
-		// TODO: 在此处添加其他供应商的非流式调用实现
-	default:
-		return nil, errors.New("不支持的AI供应商: " + provider)
+	if !req.Stream || writer == nil {
+		var resp *openai.ChatCompletionResponse
+		var err error
+		// json_schema 需要逐个校验候选回复，因此也分别调用
+		if req.N > 1 && (!supportsMultipleChoices(name) || isJSONSchemaFormat(req.ResponseFormat)) {
+			resp, err = generateChoices(ctx, req)
+		} else {
+			resp, err = generate(ctx, req)
+		}
+		if info := callInfoFromContext(ctx); info != nil && resp != nil {
+			info.ResponseID = resp.ID
+			info.Usage = resp.Usage
+		}
+		if resp != nil && req.HideReasoning {
+			hideReasoning(resp)
+		}
+		return resp, err
+	}
+
+	if req.N > 1 {
+		return nil, invalidRequestf("流式响应不支持 n 大于1")
+	}
+
+	// 等待上游期间(包括重试和收到第一个分片之前)定期发送心跳
+	sse := newSSEWriter(writer)
+	stopHeartbeat := sse.startHeartbeat(time.Duration(streamHeartbeat.Load()))
+	defer stopHeartbeat()
+
+	// json_schema 需要完整的输出才能校验，非流式生成后再按分片输出
+	if isJSONSchemaFormat(req.ResponseFormat) {
+		resp, err := generate(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("调用%s聊天接口失败: %w", name, err)
+		}
+		if info := callInfoFromContext(ctx); info != nil {
+			info.TimeToFirstToken = time.Since(start)
+		}
+		if req.HideReasoning {
+			hideReasoning(resp)
+		}
+		return nil, writeStream(ctx, responseChunks(resp), sse, newStreamOutput(req))
+	}
+
+	// 流式响应，只在收到第一个分片之前重试，开始输出后不再切换凭证或供应商
+	streamReader, err := callWithFailover(ctx, req, func(ctx context.Context, provider Provider, req ChatRequest) (*schema.StreamReader[*openai.ChatCompletionStreamResponse], error) {
+		streamReader, err := provider.Stream(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return peekStream(streamReader)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("调用%s流式聊天接口失败: %w", name, err)
+	}
+	if info := callInfoFromContext(ctx); info != nil {
+		info.TimeToFirstToken = time.Since(start)
+	}
+	return nil, writeStream(ctx, streamReader, sse, newStreamOutput(req))
+}
+
+// providerName 返回请求使用的供应商，未指定时使用凭证配置了该模型的供应商
+func providerName(req ChatRequest) (string, error) {
+	if req.Provider != "" {
+		return req.Provider, nil
+	}
+	model, ok := LookupModel(req.Model)
+	if !ok {
+		return "", fmt.Errorf("%w: 未指定供应商，且没有启用的凭证配置模型 %s", ErrUnsupportedProvider, req.Model)
+	}
+	return model.Vendor, nil
+}
+
+// maxChoices 一次请求最多生成的候选回复数，分别调用时避免单个请求占用过多上游配额
+const maxChoices = 10
+
+// supportsMultipleChoices 判断供应商是否原生支持 n 参数
+func supportsMultipleChoices(name string) bool {
+	provider, err := GetProvider(name)
+	if err != nil {
+		return false
+	}
+	multi, ok := provider.(MultiChoiceProvider)
+	return ok && multi.SupportsMultipleChoices()
+}
+
+// generateChoices 并发调用 req.N 次非流式接口，将各次的回复合并为一个包含 n 个候选回复的响应
+// 每次调用独立重试和故障转移，任意一次失败时取消其余调用并返回该错误；
+// CallInfo 的用量为所有成功调用之和，供应商、模型和凭证取自第一次调用
+func generateChoices(ctx context.Context, req ChatRequest) (*openai.ChatCompletionResponse, error) {
+	n := req.N
+	req.N = 0
+	callCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses := make([]*openai.ChatCompletionResponse, n)
+	infos := make([]*CallInfo, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		var infoCtx context.Context
+		infoCtx, infos[i] = WithCallInfo(callCtx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = generate(infoCtx, req)
+			if errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 优先返回引起取消的错误，而不是被取消的调用返回的错误
+	failed := -1
+	for i, err := range errs {
+		if err != nil && (failed < 0 || ClassifyError(errs[failed]) == ErrorKindCanceled) {
+			failed = i
+		}
+	}
+
+	var merged *openai.ChatCompletionResponse
+	var usage openai.Usage
+	for _, resp := range responses {
+		if resp == nil {
+			continue
+		}
+		usage = addUsage(usage, resp.Usage)
+		if failed >= 0 {
+			continue
+		}
+		if merged == nil {
+			first := *resp
+			first.Choices = nil
+			merged = &first
+		}
+		for _, choice := range resp.Choices {
+			choice.Index = len(merged.Choices)
+			merged.Choices = append(merged.Choices, choice)
+		}
+	}
+
+	if info := callInfoFromContext(ctx); info != nil {
+		source := 0
+		if failed >= 0 {
+			source = failed
+		}
+		*info = *infos[source]
+		info.Attempts, info.Fallback = 0, false
+		for _, callInfo := range infos {
+			info.Attempts += callInfo.Attempts
+			info.Fallback = info.Fallback || callInfo.Fallback
+		}
+		info.Usage = usage
+	}
+	if failed >= 0 {
+		return nil, errs[failed]
 	}
+	merged.Usage = usage
+	return merged, nil
 }
@@ -0,0 +1,41 @@
+package llmadapter
+
+import (
+	"sync/atomic"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// maxLogErrorLength 日志中错误信息的最大长度(字符)
+// 上游返回的错误可能包含请求内容或供应商的内部信息，超出部分截断
+const maxLogErrorLength = 200
+
+// logger 适配器输出警告和错误的日志，默认不输出
+var logger atomic.Pointer[zap.Logger]
+
+func init() {
+	logger.Store(zap.NewNop())
+}
+
+// SetLogger 设置适配器的日志，服务启动时设置为系统日志；l 为nil时不输出日志
+func SetLogger(l *zap.Logger) {
+	if l == nil {
+		l = zap.NewNop()
+	}
+	logger.Store(l)
+}
+
+// currentLogger 返回当前使用的日志
+func currentLogger() *zap.Logger {
+	return logger.Load()
+}
+
+// errorField 截断后的错误信息，用于记录可能包含上游返回内容的错误
+func errorField(err error) zap.Field {
+	msg := err.Error()
+	if utf8.RuneCountInString(msg) > maxLogErrorLength {
+		msg = string([]rune(msg)[:maxLogErrorLength]) + "..."
+	}
+	return zap.String("error", msg)
+}
@@ -0,0 +1,29 @@
+package llmadapter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// withObservedLogger 测试期间将适配器的日志替换为可检查的日志，结束后恢复
+func withObservedLogger(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.DebugLevel)
+	old := currentLogger()
+	SetLogger(zap.New(core))
+	t.Cleanup(func() { SetLogger(old) })
+	return logs
+}
+
+// TestErrorField 测试日志中的错误信息超出长度时截断
+func TestErrorField(t *testing.T) {
+	assert.Equal(t, "bad request", errorField(errors.New("bad request")).String)
+
+	long := errorField(errors.New(strings.Repeat("密", maxLogErrorLength+10))).String
+	assert.Equal(t, strings.Repeat("密", maxLogErrorLength)+"...", long)
+}
@@ -0,0 +1,88 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+
+	einoopenai "github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// defaultArkRegion 火山方舟默认地域
+const defaultArkRegion = "cn-beijing"
+
+// ArkCredential 定义火山引擎方舟(豆包)的凭证配置结构
+// 通过方舟的OpenAI兼容接口(/api/v3)调用，模型名称可以是模型ID或推理接入点ID(ep-xxx)
+type ArkCredential struct {
+	CredentialBase   `yaml:",inline"` // 名称、启用、权重、QPS限制、描述、模型列表
+	APIKey           string           `yaml:"api_key"`  // 方舟API Key
+	Region           string           `yaml:"region"`   // 地域，默认 cn-beijing
+	BaseURL          string           `yaml:"base_url"` // 接口基础URL，设置后忽略region
+	HTTPClientConfig `yaml:",inline"` // HTTP客户端设置(超时、代理、TLS、连接池)
+}
+
+// decrypted 返回解密敏感字段后的凭证副本
+func (c ArkCredential) decrypted(decrypt DecryptFunc) (ArkCredential, error) {
+	var err error
+	if c.APIKey, err = decrypt(c.APIKey); err != nil {
+		return c, fmt.Errorf("解密API密钥失败: %v", err)
+	}
+	return c, nil
+}
+
+// getArkConfig 获取火山方舟配置
+func (c *Config) getArkConfig(ctx context.Context) (*einoopenai.ChatModelConfig, error) {
+	// 从凭证缓存中获取当前环境启用的凭证(已解密)
+	creds, err := enabledCredentials[ArkCredential](ctx, "ark")
+	if err != nil {
+		return nil, err
+	}
+
+	// 在支持请求模型且仍有QPS余量的凭证中按权重选择
+	selectedCred, err := selectCredential(ctx, "ark", c.Model, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	// 请求级别指定的地域优先于凭证配置
+	region := selectedCred.Region
+	if c.VendorOptional != nil && c.VendorOptional.ArkConfig != nil && c.VendorOptional.ArkConfig.Region != "" {
+		region = c.VendorOptional.ArkConfig.Region
+	}
+	baseURL := arkBaseURL(region)
+	if selectedCred.BaseURL != "" {
+		baseURL = selectedCred.BaseURL
+	}
+	return c.openAICompatibleConfig("ark", selectedCred.Name, baseURL, selectedCred.APIKey, selectedCred.HTTPClientConfig)
+}
+
+// arkBaseURL 返回指定地域的方舟接口地址
+func arkBaseURL(region string) string {
+	if region == "" {
+		region = defaultArkRegion
+	}
+	return fmt.Sprintf("https://ark.%s.volces.com/api/v3", region)
+}
+
+func init() {
+	registerCredentials[ArkCredential]("ark", "ark.yaml", "火山方舟")
+	Register("ark", func() Provider {
+		return &einoProvider{vendor: "ark", newChatModel: newArkChatModel, params: openAIParams}
+	})
+}
+
+// newArkChatModel 根据配置创建火山方舟聊天模型
+func newArkChatModel(ctx context.Context, conf *Config) (model.ChatModel, error) {
+	// 获取火山方舟配置
+	arkConf, err := conf.getArkConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取火山方舟配置失败: %w", err)
+	}
+
+	// 创建聊天模型
+	chatModel, err := einoopenai.NewChatModel(ctx, arkConf)
+	if err != nil {
+		return nil, fmt.Errorf("创建聊天模型失败: %v", err)
+	}
+	return chatModel, nil
+}
@@ -221,18 +221,22 @@ func TestAzureCreateChatCompletion(t *testing.T) {
 				Temperature: 0.7,
 				TopP:        1.0,
 			},
-			expectedError: true,
-			errorContains: "model",
+			expectedError:  true,
+			errorContains:  "model",
+			skipOnAPIError: true,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// 调用被测试的函数
-			resp, err := AzureCreateChatCompletion(tc.request)
+			resp, err := mustGetProvider(t, "azure").Generate(context.Background(), ChatRequest{ChatCompletionRequest: tc.request})
 
 			// 检查错误情况
 			if tc.expectedError {
+				if tc.skipOnAPIError {
+					skipIfNotConfigured(t, err)
+				}
 				assert.Error(t, err, "应该返回错误")
 				if tc.errorContains != "" {
 					assert.Contains(t, err.Error(), tc.errorContains, "错误信息应包含预期内容")
@@ -309,7 +313,7 @@ func TestAzureStreamChatCompletion(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// 调用被测试的函数
-			streamReader, err := AzureStreamChatCompletion(tc.request)
+			streamReader, err := mustGetProvider(t, "azure").Stream(context.Background(), ChatRequest{ChatCompletionRequest: tc.request})
 
 			// 检查结果
 			if err != nil {
@@ -393,16 +397,10 @@ func TestAzureConfigurationErrors(t *testing.T) {
 	}
 	defer os.RemoveAll(tmpDir)
 
-	// 保存当前工作目录
-	currentDir, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("无法获取当前工作目录: %v", err)
-	}
+	// 保存当前配置目录，测试结束后恢复
+	originalConfigPath := LLMConfigPath
 	defer func() {
-		// 恢复工作目录
-		if err := os.Chdir(currentDir); err != nil {
-			t.Logf("无法恢复工作目录: %v", err)
-		}
+		LLMConfigPath = originalConfigPath
 	}()
 
 	// 测试异常情况1: 未找到配置文件
@@ -413,10 +411,8 @@ func TestAzureConfigurationErrors(t *testing.T) {
 			t.Fatalf("无法创建配置目录: %v", err)
 		}
 
-		// 更改工作目录
-		if err := os.Chdir(filepath.Join(tmpDir, "config1")); err != nil {
-			t.Fatalf("无法更改工作目录: %v", err)
-		}
+		// 指向临时配置目录
+		LLMConfigPath = configDir
 
 		// 测试请求
 		req := openai.ChatCompletionRequest{
@@ -433,7 +429,7 @@ func TestAzureConfigurationErrors(t *testing.T) {
 		}
 
 		// 调用被测试的函数
-		_, err := AzureCreateChatCompletion(req)
+		_, err := mustGetProvider(t, "azure").Generate(context.Background(), ChatRequest{ChatCompletionRequest: req})
 
 		// 验证
 		assert.Error(t, err, "应该返回错误")
@@ -460,10 +456,8 @@ environments:
 			t.Fatalf("无法创建测试配置文件: %v", err)
 		}
 
-		// 更改工作目录
-		if err := os.Chdir(filepath.Join(tmpDir, "config2")); err != nil {
-			t.Fatalf("无法更改工作目录: %v", err)
-		}
+		// 指向临时配置目录
+		LLMConfigPath = configDir
 
 		// 测试请求
 		req := openai.ChatCompletionRequest{
@@ -480,7 +474,7 @@ environments:
 		}
 
 		// 调用被测试的函数
-		_, err := AzureCreateChatCompletion(req)
+		_, err := mustGetProvider(t, "azure").Generate(context.Background(), ChatRequest{ChatCompletionRequest: req})
 
 		// 验证
 		assert.Error(t, err, "应该返回错误")
@@ -514,10 +508,8 @@ environments:
 			t.Fatalf("无法创建测试配置文件: %v", err)
 		}
 
-		// 更改工作目录
-		if err := os.Chdir(filepath.Join(tmpDir, "config3")); err != nil {
-			t.Fatalf("无法更改工作目录: %v", err)
-		}
+		// 指向临时配置目录
+		LLMConfigPath = configDir
 
 		// 设置环境变量为不存在的环境
 		os.Setenv("ENV", "development")
@@ -538,7 +530,7 @@ environments:
 		}
 
 		// 调用被测试的函数
-		_, err := AzureCreateChatCompletion(req)
+		_, err := mustGetProvider(t, "azure").Generate(context.Background(), ChatRequest{ChatCompletionRequest: req})
 
 		// 验证
 		assert.Error(t, err, "应该返回错误")
@@ -572,10 +564,8 @@ environments:
 			t.Fatalf("无法创建测试配置文件: %v", err)
 		}
 
-		// 更改工作目录
-		if err := os.Chdir(filepath.Join(tmpDir, "config4")); err != nil {
-			t.Fatalf("无法更改工作目录: %v", err)
-		}
+		// 指向临时配置目录
+		LLMConfigPath = configDir
 
 		// 测试请求
 		req := openai.ChatCompletionRequest{
@@ -592,7 +582,7 @@ environments:
 		}
 
 		// 调用被测试的函数
-		_, err := AzureCreateChatCompletion(req)
+		_, err := mustGetProvider(t, "azure").Generate(context.Background(), ChatRequest{ChatCompletionRequest: req})
 
 		// 验证
 		assert.Error(t, err, "应该返回错误")
@@ -630,7 +620,7 @@ func TestAzureCreateChatCompletionEdgeCases(t *testing.T) {
 				TopP:        1.0,
 			},
 			expectedError: true,
-			errorContains: "model",
+			errorContains: "模型",
 		},
 		{
 			name: "极大温度测试",
@@ -678,8 +668,9 @@ func TestAzureCreateChatCompletionEdgeCases(t *testing.T) {
 				Temperature: 0.7,
 				TopP:        1.0,
 			},
-			expectedError: true,
-			errorContains: "token",
+			expectedError:  true,
+			errorContains:  "token",
+			skipOnAPIError: true, // 由API校验，凭证未配置时无法验证
 		},
 		{
 			name: "非常长的文本测试",
@@ -713,10 +704,13 @@ func TestAzureCreateChatCompletionEdgeCases(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// 调用被测试的函数
-			resp, err := AzureCreateChatCompletion(tc.request)
+			resp, err := mustGetProvider(t, "azure").Generate(context.Background(), ChatRequest{ChatCompletionRequest: tc.request})
 
 			// 检查错误情况
 			if tc.expectedError {
+				if tc.skipOnAPIError {
+					skipIfNotConfigured(t, err)
+				}
 				assert.Error(t, err, "应该返回错误")
 				if tc.errorContains != "" {
 					assert.Contains(t, err.Error(), tc.errorContains, "错误信息应包含预期内容")
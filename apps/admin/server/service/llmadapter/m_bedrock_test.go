@@ -20,9 +20,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"github.com/sashabaranov/go-openai"
 	"io"
 	"os"
-	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -41,13 +41,13 @@ func TestBedrockCreateChatCompletion(t *testing.T) {
 	// 准备测试用例
 	testCases := []struct {
 		name    string
-		request ChatCompletionRequest
+		request openai.ChatCompletionRequest
 	}{
 		{
 			name: "基本聊天完成测试",
-			request: ChatCompletionRequest{
+			request: openai.ChatCompletionRequest{
 				Model: "anthropic.claude-3-5-sonnet-20240620-v1:0",
-				Messages: []ChatMessage{
+				Messages: []openai.ChatCompletionMessage{
 					{
 						Role:    "system",
 						Content: "你是一个有帮助的助手。",
@@ -67,7 +67,7 @@ func TestBedrockCreateChatCompletion(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// 调用被测试的函数
-			resp, err := BedrockCreateChatCompletion(tc.request)
+			resp, err := mustGetProvider(t, "bedrock").Generate(context.Background(), ChatRequest{ChatCompletionRequest: tc.request})
 
 			// 检查结果
 			if err != nil {
@@ -105,13 +105,13 @@ func TestBedrockStreamChatCompletion(t *testing.T) {
 	// 准备测试用例
 	testCases := []struct {
 		name    string
-		request ChatCompletionRequest
+		request openai.ChatCompletionRequest
 	}{
 		{
 			name: "基本流式聊天完成测试",
-			request: ChatCompletionRequest{
+			request: openai.ChatCompletionRequest{
 				Model: "anthropic.claude-3-5-sonnet-20240620-v1:0",
-				Messages: []ChatMessage{
+				Messages: []openai.ChatCompletionMessage{
 					{
 						Role:    "system",
 						Content: "你是一个有帮助的助手。",
@@ -131,7 +131,7 @@ func TestBedrockStreamChatCompletion(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// 调用被测试的函数
-			streamReader, err := BedrockStreamChatCompletion(tc.request)
+			streamReader, err := mustGetProvider(t, "bedrock").Stream(context.Background(), ChatRequest{ChatCompletionRequest: tc.request})
 
 			// 检查结果
 			if err != nil {
@@ -226,19 +226,21 @@ func TestBedrockStreamChatCompletionToChat(t *testing.T) {
 		{
 			name: "基本流式聊天完成测试",
 			request: ChatRequest{
-				Model: "anthropic.claude-3-5-sonnet-20240620-v1:0", // 根据实际可用的Bedrock模型调整
-				Messages: []ChatMessage{
-					{
-						Role:    "system",
-						Content: "你是一个有帮助的助手。",
-					},
-					{
-						Role:    "user",
-						Content: "简单介绍一下自然语言处理。",
+				ChatCompletionRequest: openai.ChatCompletionRequest{
+					Model: "anthropic.claude-3-5-sonnet-20240620-v1:0", // 根据实际可用的Bedrock模型调整
+					Messages: []openai.ChatCompletionMessage{
+						{
+							Role:    "system",
+							Content: "你是一个有帮助的助手。",
+						},
+						{
+							Role:    "user",
+							Content: "简单介绍一下自然语言处理。",
+						},
 					},
+					MaxTokens:   100,
+					Temperature: 0.7,
 				},
-				MaxTokens:   100,
-				Temperature: 0.7,
 			},
 		},
 	}
@@ -249,7 +251,7 @@ func TestBedrockStreamChatCompletionToChat(t *testing.T) {
 			buffer := new(bytes.Buffer)
 
 			// 调用被测试的函数
-			err := BedrockStreamChatCompletionToChat(tc.request, buffer)
+			err := streamToWriter(t, "bedrock", tc.request, buffer)
 
 			// 检查结果
 			if err != nil {
@@ -314,41 +316,32 @@ func TestBedrockStreamChatCompletionToChat(t *testing.T) {
 func TestBedrockConfigurationErrors(t *testing.T) {
 	// 测试异常情况：未找到配置文件
 	t.Run("配置文件不存在时的错误处理", func(t *testing.T) {
-		// 原文件路径和临时文件路径
-		configPath := filepath.Join(LLMConfigPath, "bedrock.yaml")
-		tempPath := filepath.Join(LLMConfigPath, "bedrock.yaml.temp")
-
-		// 检查配置文件是否存在
-		if _, err := os.Stat(configPath); err == nil {
-			// 重命名配置文件
-			err := os.Rename(configPath, tempPath)
-			if err != nil {
-				t.Fatalf("无法重命名配置文件: %v", err)
-			}
-			defer os.Rename(tempPath, configPath) // 测试完成后恢复
-
-			// 创建请求
-			req := ChatCompletionRequest{
-				Model: "anthropic.claude-3-opus-20240229",
-				Messages: []ChatMessage{
-					{
-						Role:    "user",
-						Content: "你好",
-					},
+		// 凭证配置在加载后缓存，指向不包含bedrock.yaml的临时配置目录
+		originalConfigPath := LLMConfigPath
+		defer func() {
+			LLMConfigPath = originalConfigPath
+		}()
+		LLMConfigPath = t.TempDir()
+
+		// 创建请求
+		req := openai.ChatCompletionRequest{
+			Model: "anthropic.claude-3-opus-20240229",
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    "user",
+					Content: "你好",
 				},
-				MaxTokens:   50,
-				Temperature: 0.7,
-				TopP:        1.0,
-			}
+			},
+			MaxTokens:   50,
+			Temperature: 0.7,
+			TopP:        1.0,
+		}
 
-			// 调用函数
-			_, err = BedrockCreateChatCompletion(req)
+		// 调用函数
+		_, err := mustGetProvider(t, "bedrock").Generate(context.Background(), ChatRequest{ChatCompletionRequest: req})
 
-			// 验证错误
-			assert.Error(t, err, "应该返回错误")
-			assert.Contains(t, err.Error(), "配置文件", "错误信息应包含配置文件相关内容")
-		} else {
-			t.Skip("配置文件不存在，跳过此测试")
-		}
+		// 验证错误
+		assert.Error(t, err, "应该返回错误")
+		assert.Contains(t, err.Error(), "配置文件", "错误信息应包含配置文件相关内容")
 	})
 }
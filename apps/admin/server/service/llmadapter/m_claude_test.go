@@ -18,7 +18,12 @@ package llmadapter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/sashabaranov/go-openai"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -42,19 +47,21 @@ func TestClaudeStreamChatCompletionToChat(t *testing.T) {
 		{
 			name: "基本流式聊天完成测试",
 			request: ChatRequest{
-				Model: "claude-3-sonnet-20240229", // 根据实际可用的Claude模型调整
-				Messages: []ChatMessage{
-					{
-						Role:    "system",
-						Content: "你是一个有帮助的助手。",
-					},
-					{
-						Role:    "user",
-						Content: "简单介绍一下自然语言处理。",
+				ChatCompletionRequest: openai.ChatCompletionRequest{
+					Model: "claude-3-sonnet-20240229", // 根据实际可用的Claude模型调整
+					Messages: []openai.ChatCompletionMessage{
+						{
+							Role:    "system",
+							Content: "你是一个有帮助的助手。",
+						},
+						{
+							Role:    "user",
+							Content: "简单介绍一下自然语言处理。",
+						},
 					},
+					MaxTokens:   100,
+					Temperature: 0.7,
 				},
-				MaxTokens:   100,
-				Temperature: 0.7,
 			},
 		},
 	}
@@ -65,7 +72,7 @@ func TestClaudeStreamChatCompletionToChat(t *testing.T) {
 			buffer := new(bytes.Buffer)
 
 			// 调用被测试的函数
-			err := ClaudeStreamChatCompletionToChat(tc.request, buffer)
+			err := streamToWriter(t, "claude", tc.request, buffer)
 
 			// 检查结果
 			if err != nil {
@@ -142,19 +149,21 @@ func TestClaudeCreateChatCompletionToChat(t *testing.T) {
 		{
 			name: "基本非流式聊天完成测试",
 			request: ChatRequest{
-				Model: "claude-3-sonnet-20240229", // 根据实际可用的Claude模型调整
-				Messages: []ChatMessage{
-					{
-						Role:    "system",
-						Content: "你是一个有帮助的助手。",
-					},
-					{
-						Role:    "user",
-						Content: "简单介绍一下自然语言处理。",
+				ChatCompletionRequest: openai.ChatCompletionRequest{
+					Model: "claude-3-sonnet-20240229", // 根据实际可用的Claude模型调整
+					Messages: []openai.ChatCompletionMessage{
+						{
+							Role:    "system",
+							Content: "你是一个有帮助的助手。",
+						},
+						{
+							Role:    "user",
+							Content: "简单介绍一下自然语言处理。",
+						},
 					},
+					MaxTokens:   100,
+					Temperature: 0.7,
 				},
-				MaxTokens:   100,
-				Temperature: 0.7,
 			},
 		},
 	}
@@ -162,7 +171,7 @@ func TestClaudeCreateChatCompletionToChat(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// 调用被测试的函数
-			response, err := ClaudeCreateChatCompletionToChat(tc.request)
+			response, err := mustGetProvider(t, "claude").Generate(context.Background(), tc.request)
 
 			// 检查结果
 			if err != nil {
@@ -201,3 +210,73 @@ func TestClaudeCreateChatCompletionToChat(t *testing.T) {
 		})
 	}
 }
+
+// TestClaudeToolHistory 测试Claude将工具调用、工具结果和图片转换为对应的内容块
+func TestClaudeToolHistory(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-3-5-sonnet-20241022",
+			"content": [{"type": "text", "text": "北京今天晴"}],
+			"stop_reason": "end_turn", "stop_sequence": null,
+			"usage": {"input_tokens": 20, "output_tokens": 5}
+		}`)
+	}))
+	defer server.Close()
+
+	useTestCredentials(t, map[string]string{"claude.yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: mock-claude
+        api_key: "%s"
+        base_url: %s
+        enabled: true
+        weight: 1
+`, encryptTestKey(t, "claude-key"), server.URL)})
+
+	req := weatherToolRequest("claude", "claude-3-5-sonnet-20241022")
+	req.MaxTokens = 1024
+	req.Messages = toolHistoryMessages()
+	resp, err := CreateChatCompletion(context.Background(), req, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "北京今天晴", resp.Choices[0].Message.Content)
+
+	type contentBlock struct {
+		Type      string         `json:"type"`
+		Text      string         `json:"text"`
+		ID        string         `json:"id"`
+		Name      string         `json:"name"`
+		Input     map[string]any `json:"input"`
+		ToolUseID string         `json:"tool_use_id"`
+		Source    map[string]any `json:"source"`
+	}
+	var messages []struct {
+		Role    string         `json:"role"`
+		Content []contentBlock `json:"content"`
+	}
+	data, _ := json.Marshal(body["messages"])
+	if !assert.NoError(t, json.Unmarshal(data, &messages)) || !assert.Len(t, messages, 3) {
+		return
+	}
+	assert.Contains(t, fmt.Sprint(body["system"]), "你是天气助手")
+
+	if assert.Len(t, messages[0].Content, 2) {
+		assert.Equal(t, "text", messages[0].Content[0].Type)
+		assert.Equal(t, "image", messages[0].Content[1].Type)
+		assert.Equal(t, "image/png", messages[0].Content[1].Source["media_type"])
+	}
+	assert.Equal(t, "assistant", messages[1].Role)
+	assert.Equal(t, []contentBlock{{Type: "tool_use", ID: "call_1", Name: "get_weather", Input: map[string]any{"city": "北京"}}}, messages[1].Content)
+	assert.Equal(t, "user", messages[2].Role)
+	if assert.Len(t, messages[2].Content, 1) {
+		assert.Equal(t, "tool_result", messages[2].Content[0].Type)
+		assert.Equal(t, "call_1", messages[2].Content[0].ToolUseID)
+	}
+}
@@ -18,108 +18,68 @@ package llmadapter
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
-	"github.com/sashabaranov/go-openai"
-	"io"
-	"math/rand"
-	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/cloudwego/eino-ext/components/model/deepseek"
-	"github.com/cloudwego/eino/schema"
-	"gopkg.in/yaml.v2"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/sashabaranov/go-openai"
 )
 
 // DeepSeekCredential 定义了DeepSeek模型的凭证配置
 type DeepSeekCredential struct {
-	Name        string   `yaml:"name"`
-	APIKey      string   `yaml:"api_key"`
-	BaseURL     string   `yaml:"base_url"`
-	Enabled     bool     `yaml:"enabled"`
-	Weight      int      `yaml:"weight"`
-	QPSLimit    int      `yaml:"qps_limit"`
-	Description string   `yaml:"description"`
-	Models      []string `yaml:"models"`
-	Timeout     int      `yaml:"timeout"`
-	Proxy       string   `yaml:"proxy"`
-}
-
-// 定义配置文件结构
-var deepseekConfig struct {
-	Environments map[string]struct {
-		Credentials []DeepSeekCredential `yaml:"credentials"`
-	} `yaml:"environments"`
+	CredentialBase   `yaml:",inline"` // 名称、启用、权重、QPS限制、描述、模型列表
+	APIKey           string           `yaml:"api_key"`
+	BaseURL          string           `yaml:"base_url"`
+	HTTPClientConfig `yaml:",inline"` // HTTP客户端设置，只支持超时
 }
 
-// getDeepSeekConfig 获取DeepSeek配置
-func (c *Config) getDeepSeekConfig() (*deepseek.ChatModelConfig, error) {
-	// 使用统一定义的环境变量
-	env := ENV
-	if env == "" {
-		env = "development"
-	}
-
-	// 读取DeepSeek配置文件
-	yamlFile, err := os.ReadFile(filepath.Join(LLMConfigPath, "deepseek.yaml"))
-	if err != nil {
-		return nil, fmt.Errorf("读取DeepSeek配置文件失败: %v", err)
+// decrypted 返回解密敏感字段后的凭证副本
+// DeepSeek组件不支持注入HTTP客户端，配置了超时以外的HTTP客户端设置时拒绝加载，避免误以为代理等设置已经生效
+func (c DeepSeekCredential) decrypted(decrypt DecryptFunc) (DeepSeekCredential, error) {
+	var unsupported []string
+	for field, set := range map[string]bool{
+		"proxy":                   c.Proxy != "",
+		"insecure_skip_verify":    c.InsecureSkipVerify,
+		"ca_cert_file":            c.CACertFile != "",
+		"max_idle_conns":          c.MaxIdleConns != 0,
+		"max_idle_conns_per_host": c.MaxIdleConnsPerHost != 0,
+		"idle_conn_timeout":       c.IdleConnTimeout != 0,
+	} {
+		if set {
+			unsupported = append(unsupported, field)
+		}
 	}
-
-	err = yaml.Unmarshal(yamlFile, &deepseekConfig)
-	if err != nil {
-		return nil, fmt.Errorf("解析DeepSeek配置文件失败: %v", err)
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return c, fmt.Errorf("配置了DeepSeek不支持的HTTP客户端设置 %s，DeepSeek只支持 timeout", strings.Join(unsupported, "、"))
 	}
 
-	// 获取指定环境的配置
-	envConfig, ok := deepseekConfig.Environments[env]
-	if !ok {
-		return nil, fmt.Errorf("未找到环境 %s 的配置", env)
+	var err error
+	if c.APIKey, err = decrypt(c.APIKey); err != nil {
+		return c, fmt.Errorf("解密API密钥失败: %v", err)
 	}
+	return c, nil
+}
 
-	// 存储启用的配置
-	var enabledCredentials []DeepSeekCredential
-
-	// 遍历该环境下的所有凭证配置
-	for _, cred := range envConfig.Credentials {
-		// 只添加启用的配置
-		if cred.Enabled {
-			enabledCredentials = append(enabledCredentials, cred)
-		}
+// getDeepSeekConfig 获取DeepSeek配置
+func (c *Config) getDeepSeekConfig(ctx context.Context) (*deepseek.ChatModelConfig, error) {
+	// 从凭证缓存中获取当前环境启用的凭证(已解密)
+	creds, err := enabledCredentials[DeepSeekCredential](ctx, "deepseek")
+	if err != nil {
+		return nil, err
 	}
 
-	// 如果没有启用的配置,返回错误
-	if len(enabledCredentials) == 0 {
-		return nil, fmt.Errorf("环境 %s 中没有启用的配置", env)
+	// 在支持请求模型且仍有QPS余量的凭证中按权重选择
+	selectedCred, err := selectCredential(ctx, "deepseek", c.Model, creds)
+	if err != nil {
+		return nil, err
 	}
 
-	// 根据权重选择配置
-	var selectedCred DeepSeekCredential
-	if len(enabledCredentials) > 1 {
-		// 计算总权重
-		totalWeight := 0
-		for _, cred := range enabledCredentials {
-			totalWeight += cred.Weight
-		}
-
-		// 生成一个随机数,范围是[0, totalWeight)
-		randomNum := rand.Intn(totalWeight)
-
-		// 根据权重选择配置
-		currentWeight := 0
-		for _, cred := range enabledCredentials {
-			currentWeight += cred.Weight
-			if randomNum < currentWeight {
-				selectedCred = cred
-				break
-			}
-		}
-	} else {
-		// 如果只有一个配置,直接使用
-		selectedCred = enabledCredentials[0]
-	}
+	// 凭证配置的超时时间作为单次请求的截止时间
+	c.Timeout = time.Duration(selectedCred.Timeout) * time.Second
 
 	// 确保DeepSeek配置存在
 	if c.VendorOptional == nil {
@@ -129,18 +89,8 @@ func (c *Config) getDeepSeekConfig() (*deepseek.ChatModelConfig, error) {
 		c.VendorOptional.DeepSeekConfig = &DeepSeekConfig{}
 	}
 
-	// 处理API密钥解密
-	_, decryptFunc, err := InitRSAKeyManager()
-	if err != nil {
-		return nil, fmt.Errorf("初始化RSA密钥管理器失败: %v", err)
-	}
-
-	apiKey, err := decryptFunc(selectedCred.APIKey)
-	if err != nil {
-		return nil, fmt.Errorf("解密API密钥失败: %v", err)
-	}
-
 	// 设置超时
+	// DeepSeek组件不支持注入HTTP客户端，请求时自行创建客户端，因此凭证中只支持超时设置(见 decrypted)
 	var timeout time.Duration
 	if selectedCred.Timeout > 0 {
 		timeout = time.Duration(selectedCred.Timeout) * time.Second
@@ -148,7 +98,7 @@ func (c *Config) getDeepSeekConfig() (*deepseek.ChatModelConfig, error) {
 
 	// 创建DeepSeek聊天模型配置
 	deepseekConf := &deepseek.ChatModelConfig{
-		APIKey:           apiKey,
+		APIKey:           selectedCred.APIKey,
 		Model:            c.Model,
 		Timeout:          timeout,
 		MaxTokens:        c.MaxTokens,
@@ -159,6 +109,14 @@ func (c *Config) getDeepSeekConfig() (*deepseek.ChatModelConfig, error) {
 		FrequencyPenalty: c.VendorOptional.DeepSeekConfig.FrequencyPenalty,
 	}
 
+	// vendor_options 未设置时使用请求中的标准参数
+	if deepseekConf.PresencePenalty == 0 {
+		deepseekConf.PresencePenalty = c.PresencePenalty
+	}
+	if deepseekConf.FrequencyPenalty == 0 {
+		deepseekConf.FrequencyPenalty = c.FrequencyPenalty
+	}
+
 	// 如果有自定义BaseURL，则设置
 	if selectedCred.BaseURL != "" {
 		deepseekConf.BaseURL = selectedCred.BaseURL
@@ -168,11 +126,23 @@ func (c *Config) getDeepSeekConfig() (*deepseek.ChatModelConfig, error) {
 	if c.VendorOptional.DeepSeekConfig.ResponseFormatType != "" {
 		deepseekConf.ResponseFormatType = deepseek.ResponseFormatType(
 			c.VendorOptional.DeepSeekConfig.ResponseFormatType)
+	} else if c.ResponseFormat != nil {
+		// DeepSeek只支持 json_object，json_schema 使用 json_object 并在系统提示词中给出Schema(见 structuredOutputPrompt)
+		switch c.ResponseFormat.Type {
+		case openai.ChatCompletionResponseFormatTypeJSONObject, openai.ChatCompletionResponseFormatTypeJSONSchema:
+			deepseekConf.ResponseFormatType = deepseek.ResponseFormatTypeJSONObject
+		case openai.ChatCompletionResponseFormatTypeText:
+			deepseekConf.ResponseFormatType = deepseek.ResponseFormatTypeText
+		}
 	}
 
 	return deepseekConf, nil
 }
 
+// deepSeekParams DeepSeek支持的OpenAI标准参数
+// 响应格式只支持 json_object，请求 json_schema 时使用 json_object 并在系统提示词中给出Schema；DeepSeek组件不支持工具调用
+var deepSeekParams = []string{ParamPresencePenalty, ParamFrequencyPenalty, ParamResponseFormat, ParamJSONSchema}
+
 // dereferenceFloat32OrDefault 返回指针值或默认值
 func dereferenceFloat32OrDefault(ptr *float32, defaultValue float32) float32 {
 	if ptr == nil {
@@ -181,328 +151,25 @@ func dereferenceFloat32OrDefault(ptr *float32, defaultValue float32) float32 {
 	return *ptr
 }
 
-// DeepSeekCreateChatCompletion 使用DeepSeek服务创建聊天完成
-func DeepSeekCreateChatCompletion(req ChatCompletionRequest) (*openai.ChatCompletionResponse, error) {
-	// 创建DeepSeek配置
-	conf := &Config{
-		Vendor:      "deepseek",
-		Model:       req.Model,
-		MaxTokens:   req.MaxTokens,
-		Temperature: &req.Temperature,
-		TopP:        &req.TopP,
-		Stop:        req.Stop,
-	}
-
-	// 获取DeepSeek配置
-	deepseekConf, err := conf.getDeepSeekConfig()
-	if err != nil {
-		return nil, fmt.Errorf("获取DeepSeek配置失败: %v", err)
-	}
-
-	// 创建上下文
-	ctx := context.Background()
-
-	// 创建聊天模型
-	chatModel, err := deepseek.NewChatModel(ctx, deepseekConf)
-	if err != nil {
-		return nil, fmt.Errorf("创建聊天模型失败: %v", err)
-	}
-
-	// 转换消息格式
-	schemaMessages := make([]*schema.Message, len(req.Messages))
-	for i, msg := range req.Messages {
-		role := schema.RoleType(msg.Role)
-		schemaMessages[i] = &schema.Message{
-			Role:    role,
-			Content: msg.Content,
-		}
-	}
-
-	// 调用Generate方法获取响应
-	resp, err := chatModel.Generate(ctx, schemaMessages)
-	if err != nil {
-		return nil, fmt.Errorf("调用Generate方法失败: %v", err)
-	}
-
-	// 构造ChatCompletionChoice
-	choices := []openai.ChatCompletionChoice{
-		{
-			Index: 0,
-			Message: openai.ChatCompletionMessage{
-				Role:    string(resp.Role),
-				Content: resp.Content,
-			},
-			FinishReason: openai.FinishReason(resp.ResponseMeta.FinishReason),
-		},
-	}
-
-	// 生成唯一ID
-	uniqueID := fmt.Sprintf("deepseek-%d", time.Now().UnixNano())
-
-	// 获取Token使用情况
-	var usage openai.Usage
-	if resp.ResponseMeta != nil && resp.ResponseMeta.Usage != nil {
-		usage = openai.Usage{
-			PromptTokens:     resp.ResponseMeta.Usage.PromptTokens,
-			CompletionTokens: resp.ResponseMeta.Usage.CompletionTokens,
-			TotalTokens:      resp.ResponseMeta.Usage.TotalTokens,
-		}
-	}
-
-	// 构造并返回响应
-	return &openai.ChatCompletionResponse{
-		ID:      uniqueID,
-		Object:  "chat.completion",
-		Created: time.Now().Unix(),
-		Model:   req.Model,
-		Choices: choices,
-		Usage:   usage,
-	}, nil
+func init() {
+	registerCredentials[DeepSeekCredential]("deepseek", "deepseek.yaml", "DeepSeek")
+	Register("deepseek", func() Provider {
+		return &einoProvider{vendor: "deepseek", newChatModel: newDeepSeekChatModel, params: deepSeekParams, structured: structuredOutputPrompt}
+	})
 }
 
-// DeepSeekCreateChatCompletionToChat 使用DeepSeek服务创建聊天完成并转换到Chat接口格式
-func DeepSeekCreateChatCompletionToChat(req ChatRequest) (*openai.ChatCompletionResponse, error) {
-	// 准备请求参数
-	model := req.Model
-	if model == "" {
-		// 如果没有指定模型，返回错误
-		return nil, fmt.Errorf("未指定模型名称")
-	}
-
-	temperature := float32(req.Temperature)
-	maxTokens := req.MaxTokens
-
-	// 转换消息格式
-	messages := make([]ChatMessage, 0, len(req.Messages))
-	for _, msg := range req.Messages {
-		messages = append(messages, ChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
-
-	// 创建DeepSeek请求
-	deepseekReq := ChatCompletionRequest{
-		Model:       model,
-		Messages:    messages,
-		Temperature: temperature,
-		MaxTokens:   maxTokens,
-	}
-
-	// 调用DeepSeek服务
-	resp, err := DeepSeekCreateChatCompletion(deepseekReq)
-	if err != nil {
-		return nil, fmt.Errorf("调用DeepSeek聊天接口失败: %w", err)
-	}
-
-	return &openai.ChatCompletionResponse{
-		ID:      resp.ID,
-		Object:  resp.Object,
-		Created: resp.Created,
-		Model:   resp.Model,
-		Choices: resp.Choices,
-		Usage: openai.Usage{
-			PromptTokens:     resp.Usage.PromptTokens,
-			CompletionTokens: resp.Usage.CompletionTokens,
-			TotalTokens:      resp.Usage.TotalTokens,
-		},
-	}, nil
-}
-
-// DeepSeekStreamChatCompletion 使用DeepSeek服务创建流式聊天完成
-func DeepSeekStreamChatCompletion(req ChatCompletionRequest) (*schema.StreamReader[*ChatCompletionStreamResponse], error) {
-	// 创建DeepSeek配置
-	conf := &Config{
-		Vendor:      "deepseek",
-		Model:       req.Model,
-		MaxTokens:   req.MaxTokens,
-		Temperature: &req.Temperature,
-		TopP:        &req.TopP,
-		Stop:        req.Stop,
-	}
-
+// newDeepSeekChatModel 根据配置创建DeepSeek聊天模型
+func newDeepSeekChatModel(ctx context.Context, conf *Config) (model.ChatModel, error) {
 	// 获取DeepSeek配置
-	deepseekConf, err := conf.getDeepSeekConfig()
+	deepseekConf, err := conf.getDeepSeekConfig(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("获取DeepSeek配置失败: %v", err)
+		return nil, fmt.Errorf("获取DeepSeek配置失败: %w", err)
 	}
 
-	// 创建上下文
-	ctx := context.Background()
-
 	// 创建聊天模型
 	chatModel, err := deepseek.NewChatModel(ctx, deepseekConf)
 	if err != nil {
 		return nil, fmt.Errorf("创建聊天模型失败: %v", err)
 	}
-
-	// 转换消息格式
-	schemaMessages := make([]*schema.Message, len(req.Messages))
-	for i, msg := range req.Messages {
-		role := schema.RoleType(msg.Role)
-		schemaMessages[i] = &schema.Message{
-			Role:    role,
-			Content: msg.Content,
-		}
-	}
-
-	// 调用Stream方法获取流式响应
-	streamReader, err := chatModel.Stream(ctx, schemaMessages)
-	if err != nil {
-		return nil, fmt.Errorf("调用Stream方法失败: %v", err)
-	}
-
-	// 创建结果通道
-	resultReader, resultWriter := schema.Pipe[*ChatCompletionStreamResponse](10)
-
-	// 启动goroutine处理流式数据
-	go func() {
-		defer func() {
-			if panicErr := recover(); panicErr != nil {
-				fmt.Printf("DeepSeek Stream处理发生异常: %v\n", panicErr)
-			}
-			streamReader.Close()
-			resultWriter.Close()
-		}()
-
-		// 生成唯一ID
-		uniqueID := fmt.Sprintf("deepseek-stream-%d", time.Now().UnixNano())
-		created := time.Now().Unix()
-
-		for {
-			// 从流中接收消息
-			message, err := streamReader.Recv()
-			if errors.Is(err, io.EOF) {
-				// 流结束
-				break
-			}
-			if err != nil {
-				// 处理错误
-				_ = resultWriter.Send(nil, fmt.Errorf("从DeepSeek接收流数据失败: %v", err))
-				return
-			}
-
-			// 获取推理内容
-			reasoningContent := ""
-			if reason, ok := deepseek.GetReasoningContent(message); ok {
-				reasoningContent = reason
-			}
-
-			// 构造流式响应
-			streamResp := &ChatCompletionStreamResponse{
-				ID:      uniqueID,
-				Object:  "chat.completion.chunk",
-				Created: created,
-				Model:   req.Model,
-				Choices: []ChatCompletionStreamChoice{
-					{
-						Index: 0,
-						Delta: ChatCompletionStreamDelta{
-							Role:             string(message.Role),
-							Content:          message.Content,
-							ReasoningContent: reasoningContent,
-						},
-						FinishReason: "",
-					},
-				},
-			}
-
-			// 如果是最后一条消息，设置完成原因
-			if message.ResponseMeta != nil && message.ResponseMeta.FinishReason != "" {
-				streamResp.Choices[0].FinishReason = message.ResponseMeta.FinishReason
-			}
-
-			// 发送流式响应
-			closed := resultWriter.Send(streamResp, nil)
-			if closed {
-				return
-			}
-		}
-	}()
-
-	return resultReader, nil
-}
-
-// DeepSeekStreamChatCompletionToChat 使用DeepSeek服务创建流式聊天完成并转换为聊天流格式
-func DeepSeekStreamChatCompletionToChat(req ChatRequest, writer io.Writer) error {
-	// 创建ChatCompletionRequest
-	chatReq := ChatCompletionRequest{
-		Model:       req.Model,
-		Temperature: float32(req.Temperature),
-		MaxTokens:   req.MaxTokens,
-		Stream:      true,
-	}
-
-	// 转换消息格式
-	chatReq.Messages = make([]ChatMessage, len(req.Messages))
-	for i, msg := range req.Messages {
-		chatReq.Messages[i] = ChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
-	}
-
-	// 调用DeepSeek流式聊天API
-	streamReader, err := DeepSeekStreamChatCompletion(chatReq)
-	if err != nil {
-		return fmt.Errorf("调用DeepSeek流式聊天接口失败: %w", err)
-	}
-
-	// 处理流式响应
-	for {
-		response, err := streamReader.Recv()
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("接收DeepSeek流式响应失败: %w", err)
-		}
-
-		// 转换响应格式
-		choices := make([]StreamChoice, 0, len(response.Choices))
-		for _, choice := range response.Choices {
-			choices = append(choices, StreamChoice{
-				Index: choice.Index,
-				Delta: StreamChoiceDelta{
-					Role:    choice.Delta.Role,
-					Content: choice.Delta.Content,
-				},
-				FinishReason: choice.FinishReason,
-			})
-		}
-
-		streamResp := StreamResponse{
-			ID:      response.ID,
-			Object:  response.Object,
-			Created: response.Created,
-			Model:   response.Model,
-			Choices: choices,
-		}
-
-		// 将响应写入writer
-		data, err := json.Marshal(streamResp)
-		if err != nil {
-			return fmt.Errorf("序列化流式响应失败: %w", err)
-		}
-
-		// 添加data:前缀
-		if _, err := writer.Write([]byte("data: ")); err != nil {
-			return fmt.Errorf("写入流式响应前缀失败: %w", err)
-		}
-
-		if _, err := writer.Write(data); err != nil {
-			return fmt.Errorf("写入流式响应失败: %w", err)
-		}
-
-		if _, err := writer.Write([]byte("\n\n")); err != nil {
-			return fmt.Errorf("写入流式响应分隔符失败: %w", err)
-		}
-	}
-
-	// 添加结束标记
-	if _, err := writer.Write([]byte("data: [DONE]\n\n")); err != nil {
-		return fmt.Errorf("写入流式响应结束标记失败: %w", err)
-	}
-
-	return nil
+	return chatModel, nil
 }
@@ -17,9 +17,14 @@
 package llmadapter
 
 import (
+	"context"
+	"fmt"
+	"github.com/sashabaranov/go-openai"
 	"os"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 // 定义测试所需的结构
@@ -65,7 +70,7 @@ func TestGetDeepSeekConfig(t *testing.T) {
 
 		// 由于配置文件可能不存在，使用Mock替代或跳过实际调用
 		// 这里仅测试函数调用不会崩溃
-		_, err := conf.getDeepSeekConfig()
+		_, err := conf.getDeepSeekConfig(context.Background())
 		if err != nil {
 			// 如果配置文件不存在，会返回错误，这里只是确保函数能被调用
 			t.Logf("获取配置返回错误(可能是配置文件不存在): %v", err)
@@ -77,13 +82,13 @@ func TestGetDeepSeekConfig(t *testing.T) {
 func TestDeepSeekCreateChatCompletion(t *testing.T) {
 	t.Run("测试创建聊天完成请求", func(t *testing.T) {
 		// 准备测试请求
-		req := ChatCompletionRequest{
+		req := openai.ChatCompletionRequest{
 			Model:       "deepseek-chat",
 			MaxTokens:   1000,
 			Temperature: 0.7,
 			TopP:        0.9,
 			Stop:        []string{"stop"},
-			Messages: []ChatMessage{
+			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    "user",
 					Content: "你好，DeepSeek!",
@@ -100,7 +105,7 @@ func TestDeepSeekCreateChatCompletion(t *testing.T) {
 		t.Log("【开始调用】DeepSeekCreateChatCompletion...")
 
 		// 调用DeepSeek API
-		resp, err := DeepSeekCreateChatCompletion(req)
+		resp, err := mustGetProvider(t, "deepseek").Generate(context.Background(), ChatRequest{ChatCompletionRequest: req})
 
 		// 如果有错误
 		if err != nil {
@@ -139,13 +144,13 @@ func TestDeepSeekCreateChatCompletion(t *testing.T) {
 func TestDeepSeekStreamChatCompletion(t *testing.T) {
 	t.Run("测试创建流式聊天完成请求", func(t *testing.T) {
 		// 准备测试请求
-		req := ChatCompletionRequest{
+		req := openai.ChatCompletionRequest{
 			Model:       "deepseek-chat",
 			MaxTokens:   1000,
 			Temperature: 0.7,
 			TopP:        0.9,
 			Stop:        []string{"stop"},
-			Messages: []ChatMessage{
+			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    "user",
 					Content: "你好，DeepSeek!",
@@ -162,7 +167,7 @@ func TestDeepSeekStreamChatCompletion(t *testing.T) {
 		t.Log("【开始流式调用】DeepSeekStreamChatCompletion...")
 
 		// 调用DeepSeek流式API
-		streamReader, err := DeepSeekStreamChatCompletion(req)
+		streamReader, err := mustGetProvider(t, "deepseek").Stream(context.Background(), ChatRequest{ChatCompletionRequest: req})
 
 		// 如果有错误
 		if err != nil {
@@ -317,3 +322,24 @@ func mockStreamReceive() []*mockMessage {
 		},
 	}
 }
+
+// TestDeepSeekUnsupportedHTTPClientConfig DeepSeek凭证只支持超时，配置了代理等HTTP客户端设置时拒绝加载
+func TestDeepSeekUnsupportedHTTPClientConfig(t *testing.T) {
+	useTestCredentials(t, map[string]string{"deepseek.yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: proxy
+        api_key: "%s"
+        enabled: true
+        weight: 1
+        timeout: 30
+        proxy: http://127.0.0.1:7890
+        ca_cert_file: ca.pem
+`, encryptTestKey(t, "deepseek-key"))})
+
+	conf := &Config{Vendor: "deepseek", Model: "deepseek-chat"}
+	_, err := conf.getDeepSeekConfig(context.Background())
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "不支持的HTTP客户端设置 ca_cert_file、proxy")
+	}
+}
@@ -18,193 +18,139 @@ package llmadapter
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"math/rand"
+	"maps"
+	"mime"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
+	"path"
 	"runtime/debug"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudwego/eino-ext/components/model/gemini"
 	"github.com/cloudwego/eino/schema"
-	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/generative-ai-go/genai"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+	"google.golang.org/api/googleapi/transport"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
-	"gopkg.in/yaml.v2"
 )
 
 // GeminiCredential 定义Google Gemini服务的凭证配置结构
 type GeminiCredential struct {
-	Name                string                 `yaml:"name"`
-	APIKey              string                 `yaml:"api_key"`               // Gemini API 密钥
-	APIEndpoint         string                 `yaml:"api_endpoint"`          // API端点URL，可选
-	Enabled             bool                   `yaml:"enabled"`               // 是否启用
-	Weight              int                    `yaml:"weight"`                // 权重
-	QPSLimit            int                    `yaml:"qps_limit"`             // QPS限制
-	Description         string                 `yaml:"description"`           // 描述
-	Models              []string               `yaml:"models"`                // 支持的模型列表
-	Timeout             int                    `yaml:"timeout"`               // 超时时间
-	Proxy               string                 `yaml:"proxy"`                 // 代理设置
-	SafetySettings      map[string]interface{} `yaml:"safety_settings"`       // 安全设置
-	GenerationConfig    map[string]interface{} `yaml:"generation_config"`     // 生成配置
-	EnableCodeExecution bool                   `yaml:"enable_code_execution"` // 允许模型执行代码
+	CredentialBase            `yaml:",inline"`       // 名称、启用、权重、QPS限制、描述、模型列表
+	APIKey                    string                 `yaml:"api_key"`      // Gemini API 密钥
+	APIEndpoint               string                 `yaml:"api_endpoint"` // API端点URL，可选
+	HTTPClientConfig          `yaml:",inline"`       // HTTP客户端设置(超时、代理、TLS、连接池)
+	SafetySettings            map[string]interface{} `yaml:"safety_settings"`              // 安全设置
+	GenerationConfig          map[string]interface{} `yaml:"generation_config"`            // 生成配置
+	EnableCodeExecution       bool                   `yaml:"enable_code_execution"`        // 所有请求都允许模型执行代码
+	AllowRequestCodeExecution bool                   `yaml:"allow_request_code_execution"` // 允许请求通过 vendor_options 启用代码执行，未允许时请求中的设置无效
 }
 
-// 配置文件结构定义
-var geminiConfig struct {
-	Environments map[string]struct {
-		Credentials []GeminiCredential `yaml:"credentials"`
-	} `yaml:"environments"`
-}
-
-// getGeminiConfig 获取Gemini配置
-func (c *Config) getGeminiConfig() (*gemini.Config, error) {
-	// 使用统一定义的环境变量
-	env := ENV
-	if env == "" {
-		env = "development"
-	}
-
-	// 读取Gemini配置文件
-	yamlFile, err := os.ReadFile(filepath.Join(LLMConfigPath, "gemini.yaml"))
-	if err != nil {
-		return nil, fmt.Errorf("读取Gemini配置文件失败: %v", err)
-	}
-
-	err = yaml.Unmarshal(yamlFile, &geminiConfig)
-	if err != nil {
-		fmt.Printf("解析Gemini配置文件失败: %v", err)
-		// 抛出异常
-		return nil, err
+// decrypted 返回解密敏感字段后的凭证副本
+func (c GeminiCredential) decrypted(decrypt DecryptFunc) (GeminiCredential, error) {
+	var err error
+	if c.APIKey, err = decrypt(c.APIKey); err != nil {
+		return c, fmt.Errorf("解密API密钥失败: %v", err)
 	}
+	return c, nil
+}
 
-	// 获取指定环境的配置
-	envConfig, ok := geminiConfig.Environments[env]
-	if !ok {
-		return nil, fmt.Errorf("未找到环境 %s 的配置", env)
-	}
+// geminiClientKey Gemini客户端缓存的键，与 httpClientKey 一样在凭证配置不变时复用同一个客户端
+type geminiClientKey struct {
+	credential string
+	apiKey     string
+	endpoint   string
+	config     HTTPClientConfig
+}
 
-	// 存储启用的配置
-	var enabledCredentials []GeminiCredential
+// geminiClients 已创建的Gemini客户端 geminiClientKey -> *genai.Client
+// genai.Client 内部持有多个服务客户端和连接，按凭证缓存复用，避免每次请求创建后未关闭导致连接和协程累积
+var geminiClients sync.Map
 
-	// 遍历该环境下的所有凭证配置
-	for _, cred := range envConfig.Credentials {
-		// 只添加启用的配置
-		if cred.Enabled {
-			enabledCredentials = append(enabledCredentials, cred)
-		}
+// geminiClient 获取凭证的Gemini客户端，客户端在凭证配置不变时复用
+func geminiClient(cred GeminiCredential) (*genai.Client, error) {
+	key := geminiClientKey{credential: cred.Name, apiKey: cred.APIKey, endpoint: cred.APIEndpoint, config: cred.HTTPClientConfig}
+	if client, ok := geminiClients.Load(key); ok {
+		return client.(*genai.Client), nil
 	}
 
-	// 如果没有启用的配置,返回错误
-	if len(enabledCredentials) == 0 {
-		return nil, fmt.Errorf("环境 %s 中没有启用的配置", env)
+	options := []option.ClientOption{
+		option.WithAPIKey(cred.APIKey),
 	}
 
-	// 根据权重选择配置
-	var selectedCred GeminiCredential
-	if len(enabledCredentials) > 1 {
-		// 计算总权重
-		totalWeight := 0
-		for _, cred := range enabledCredentials {
-			totalWeight += cred.Weight
-		}
-
-		// 生成一个随机数,范围是[0, totalWeight)
-		randomNum := rand.Intn(totalWeight)
-
-		// 根据权重选择配置
-		currentWeight := 0
-
-		for _, cred := range enabledCredentials {
-			currentWeight += cred.Weight
-			if randomNum < currentWeight {
-				selectedCred = cred
-				break
-			}
-		}
-	} else {
-		// 如果只有一个配置,直接使用
-		selectedCred = enabledCredentials[0]
+	// 如果设置了自定义APIEndpoint
+	if cred.APIEndpoint != "" {
+		options = append(options, option.WithEndpoint(cred.APIEndpoint))
 	}
 
-	// 解密凭证
-	_, decryptFunc, err := InitRSAKeyManager()
+	// 每个凭证使用独立缓存的HTTP客户端(代理、超时、TLS、连接池)
+	// 指定 WithHTTPClient 后客户端库不再附加API密钥，需要在 Transport 上携带
+	httpClient, err := credentialHTTPClient("gemini", cred.Name, cred.HTTPClientConfig)
 	if err != nil {
-		return nil, fmt.Errorf("初始化RSA密钥管理器失败: %v", err)
+		return nil, err
 	}
+	options = append(options, option.WithHTTPClient(&http.Client{
+		Transport: &transport.APIKey{Key: cred.APIKey, Transport: httpClient.Transport},
+		Timeout:   httpClient.Timeout,
+	}))
 
-	// API密钥解密
-	selectedCred.APIKey, err = decryptFunc(selectedCred.APIKey)
+	// 客户端在多个请求之间复用，不使用请求上下文创建；单次请求的超时由调用方根据 c.Timeout 设置在请求上下文中
+	client, err := genai.NewClient(context.Background(), options...)
 	if err != nil {
-		return nil, fmt.Errorf("解密API密钥失败: %v", err)
+		return nil, fmt.Errorf("创建Gemini客户端失败: %w", err)
 	}
 
-	// 创建Gemini客户端选项
-	options := []option.ClientOption{
-		option.WithAPIKey(selectedCred.APIKey),
-	}
-
-	// 如果设置了自定义APIEndpoint
-	if selectedCred.APIEndpoint != "" {
-		options = append(options, option.WithEndpoint(selectedCred.APIEndpoint))
+	// 并发创建时以先存入的为准，关闭多创建的客户端
+	actual, loaded := geminiClients.LoadOrStore(key, client)
+	if loaded {
+		_ = client.Close()
 	}
+	return actual.(*genai.Client), nil
+}
 
-	// 如果设置了代理
-	if selectedCred.Proxy != "" {
-		// 创建HTTP客户端
-		httpClient := &http.Client{
-			Transport: &http.Transport{
-				Proxy: func(req *http.Request) (*url.URL, error) {
-					return url.Parse(selectedCred.Proxy)
-				},
-			},
-		}
-		options = append(options, option.WithHTTPClient(httpClient))
+// getGeminiConfig 获取Gemini配置
+func (c *Config) getGeminiConfig(ctx context.Context) (*gemini.Config, error) {
+	// 从凭证缓存中获取当前环境启用的凭证(已解密)
+	creds, err := enabledCredentials[GeminiCredential](ctx, "gemini")
+	if err != nil {
+		return nil, err
 	}
 
-	// 创建Gemini客户端
-	client, err := genai.NewClient(context.Background(), options...)
+	// 在支持请求模型且仍有QPS余量的凭证中按权重选择
+	selectedCred, err := selectCredential(ctx, "gemini", c.Model, creds)
 	if err != nil {
-		return nil, fmt.Errorf("创建Gemini客户端失败: %v", err)
+		return nil, err
 	}
 
-	// 添加客户端检查和超时设置
-	if selectedCred.Timeout > 0 {
-		// 如果配置了超时时间，创建带超时的上下文
-		timeoutCtx, cancel := context.WithTimeout(context.Background(), time.Duration(selectedCred.Timeout)*time.Second)
-		defer cancel()
+	// 凭证配置的超时时间作为单次请求的截止时间
+	c.Timeout = time.Duration(selectedCred.Timeout) * time.Second
 
-		// 重新创建客户端，带超时上下文
-		client, err = genai.NewClient(timeoutCtx, options...)
-		if err != nil {
-			return nil, fmt.Errorf("创建带超时的Gemini客户端失败: %v", err)
-		}
+	// 每个凭证复用缓存的Gemini客户端，不在每次请求时创建
+	client, err := geminiClient(selectedCred)
+	if err != nil {
+		return nil, err
 	}
 
-	// 验证支持的模型
-	if len(selectedCred.Models) > 0 {
-		modelSupported := false
-		for _, supportedModel := range selectedCred.Models {
-			if supportedModel == c.Model {
-				modelSupported = true
-				break
-			}
-		}
-
-		if !modelSupported {
-			fmt.Printf("警告: 请求的模型 %s 不在配置支持的模型列表中: %v\n", c.Model, selectedCred.Models)
+	// 转换SafetySettings，请求 vendor_options 中的设置按类别覆盖凭证的设置
+	settings := maps.Clone(selectedCred.SafetySettings)
+	if c.VendorOptional != nil && c.VendorOptional.GeminiConfig != nil && len(c.VendorOptional.GeminiConfig.SafetySettings) > 0 {
+		if settings == nil {
+			settings = make(map[string]interface{})
 		}
+		maps.Copy(settings, c.VendorOptional.GeminiConfig.SafetySettings)
 	}
-
-	// 转换SafetySettings
 	var safetySettings []*genai.SafetySetting
-	if selectedCred.SafetySettings != nil {
-		for category, threshold := range selectedCred.SafetySettings {
+	if settings != nil {
+		for category, threshold := range settings {
 			// 将字符串类型的安全类别转换为genai.HarmCategory
 			var harmCategory genai.HarmCategory
 			switch category {
@@ -271,31 +217,9 @@ func (c *Config) getGeminiConfig() (*gemini.Config, error) {
 			geminiConf.TopK = c.VendorOptional.GeminiConfig.TopK
 		}
 
-		// 设置是否启用代码执行
-		geminiConf.EnableCodeExecution = c.VendorOptional.GeminiConfig.EnableCodeExecution || selectedCred.EnableCodeExecution
-
-		// 设置ResponseSchema(如果有)
-		if c.VendorOptional.GeminiConfig.ResponseSchema != nil {
-			// 将map[string]interface{}转换为openapi3.Schema
-			schemaJSON, err := json.Marshal(c.VendorOptional.GeminiConfig.ResponseSchema)
-			if err != nil {
-				return nil, fmt.Errorf("序列化ResponseSchema失败: %v", err)
-			}
-
-			var schema openapi3.Schema
-			if err := json.Unmarshal(schemaJSON, &schema); err != nil {
-				return nil, fmt.Errorf("解析ResponseSchema失败: %v", err)
-			}
-
-			geminiConf.ResponseSchema = &schema
-		}
-
-		// 处理GenerationConfig中的其他配置项
-		if selectedCred.GenerationConfig != nil {
-			// 这里可以根据需要从GenerationConfig中提取其他配置项
-			// 比如，后续可能会支持更多的生成选项
-			fmt.Printf("提示: Gemini凭证中包含GenerationConfig，但当前版本暂未完全支持\n")
-		}
+		// 设置是否启用代码执行，请求只能在凭证允许时启用
+		geminiConf.EnableCodeExecution = selectedCred.EnableCodeExecution ||
+			selectedCred.AllowRequestCodeExecution && c.VendorOptional.GeminiConfig.EnableCodeExecution
 	} else {
 		// 如果没有设置VendorOptional，确保初始化
 		if c.VendorOptional == nil {
@@ -311,39 +235,45 @@ func (c *Config) getGeminiConfig() (*gemini.Config, error) {
 	return geminiConf, nil
 }
 
-// GeminiCreateChatCompletion 使用Google Gemini服务创建聊天完成
-func GeminiCreateChatCompletion(req ChatCompletionRequest) (*ChatCompletionResponse, error) {
-	// 创建Gemini配置
-	conf := &Config{
-		Vendor:      "gemini",
-		Model:       req.Model,
-		MaxTokens:   req.MaxTokens,
-		Temperature: &req.Temperature,
-		TopP:        &req.TopP,
-		Stop:        req.Stop,
-	}
+func init() {
+	registerCredentials[GeminiCredential]("gemini", "gemini.yaml", "Gemini")
+	Register("gemini", func() Provider {
+		return &geminiProvider{}
+	})
+}
 
-	// 获取Gemini配置
-	geminiConf, err := conf.getGeminiConfig()
+// geminiProvider 基于Google Gemini SDK的Provider实现
+// Gemini直接使用genai客户端，而不是eino的ChatModel，因此单独实现
+type geminiProvider struct{}
+
+// SupportedParams 实现 ParamsProvider 接口
+// genai的生成配置没有seed、惩罚系数和logit_bias；json_schema 转换为Gemini的 response_schema
+func (p *geminiProvider) SupportedParams() []string {
+	return []string{ParamResponseFormat, ParamJSONSchema, ParamToolChoice}
+}
+
+// startChat 根据请求创建生成模型和会话，返回会话、需要发送的最后一条消息以及选中凭证配置的超时时间
+func (p *geminiProvider) startChat(ctx context.Context, req ChatRequest) (*genai.ChatSession, []genai.Part, time.Duration, error) {
+	conf, err := newRequestConfig("gemini", req)
 	if err != nil {
-		return nil, fmt.Errorf("获取Gemini配置失败: %v", err)
+		return nil, nil, 0, err
 	}
 
-	// 创建上下文
-	ctx := context.Background()
+	// 转换消息格式，系统消息作为系统指令，工具调用和工具结果转换为函数调用
+	system, history, lastParts, err := geminiContents(req.Messages)
+	if err != nil {
+		return nil, nil, 0, err
+	}
 
-	// 转换消息格式
-	schemaMessages := make([]*schema.Message, len(req.Messages))
-	for i, msg := range req.Messages {
-		role := schema.RoleType(msg.Role)
-		schemaMessages[i] = &schema.Message{
-			Role:    role,
-			Content: msg.Content,
-		}
+	// 获取Gemini配置
+	geminiConf, err := conf.getGeminiConfig(ctx)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("获取Gemini配置失败: %w", err)
 	}
 
 	// 创建生成模型
 	model := geminiConf.Client.GenerativeModel(req.Model)
+	model.SystemInstruction = system
 
 	// 设置参数
 	if req.MaxTokens > 0 {
@@ -358,97 +288,97 @@ func GeminiCreateChatCompletion(req ChatCompletionRequest) (*ChatCompletionRespo
 	if geminiConf.TopK != nil {
 		model.SetTopK(*geminiConf.TopK)
 	}
+	if len(req.Stop) > 0 {
+		model.StopSequences = req.Stop
+	}
 
 	// 设置安全级别
 	if len(geminiConf.SafetySettings) > 0 {
 		model.SafetySettings = geminiConf.SafetySettings
 	}
 
-	// 如果配置了ResponseSchema，设置结构化输出格式
-	if geminiConf.ResponseSchema != nil {
-		// 请求JSON格式响应
+	// 请求 response_format 为JSON时要求返回JSON
+	if req.ResponseFormat != nil && req.ResponseFormat.Type != openai.ChatCompletionResponseFormatTypeText {
 		model.ResponseMIMEType = "application/json"
+	}
 
-		// 创建Schema实例 - 使用vertexai/genai包的TypeObject常量
-		// 根据搜索结果，TypeObject = 6
-		schema := &genai.Schema{
-			Type:        6, // TypeObject = 6
-			Description: geminiConf.ResponseSchema.Description,
-			Properties:  make(map[string]*genai.Schema),
+	// 设置结构化输出格式，请求 response_format 为 json_schema 时使用请求的Schema，否则使用 vendor_options 中的 response_schema
+	// Gemini的Schema不支持 $ref，替换为所指向的Schema
+	responseSchema := conf.VendorOptional.GeminiConfig.ResponseSchema
+	if isJSONSchemaFormat(req.ResponseFormat) {
+		jsonSchema, err := responseJSONSchema(req.ResponseFormat)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if responseSchema, err = inlineSchemaRefs(jsonSchema); err != nil {
+			return nil, nil, 0, err
 		}
-
-		// 如果有需要，还可以进一步转换properties
-		// 注意：这里只是一个简化的转换示例
-
-		// 设置ResponseSchema
-		model.ResponseSchema = schema
+	}
+	if responseSchema != nil {
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = geminiSchema(responseSchema)
 	}
 
-	// 设置是否启用代码执行
-	if geminiConf.EnableCodeExecution {
-		// 注意：启用代码执行可能存在安全风险，应谨慎使用
-		fmt.Printf("警告：已为模型 %s 启用代码执行功能\n", req.Model)
+	// 转换工具定义和工具选择，启用代码执行时加入代码执行工具
+	model.Tools, err = geminiTools(req.Tools, geminiConf.EnableCodeExecution)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("转换工具信息失败: %w", err)
 	}
+	model.ToolConfig = geminiToolConfig(req.ToolChoice)
 
-	// 转换消息为Gemini格式
 	chat := model.StartChat()
-	for i := 0; i < len(schemaMessages)-1; i++ {
-		msg := schemaMessages[i]
-		parts := []genai.Part{genai.Text(msg.Content)}
-		content := &genai.Content{
-			Role:  toGeminiRole(msg.Role),
-			Parts: parts,
-		}
-		chat.History = append(chat.History, content)
+	chat.History = history
+
+	return chat, lastParts, conf.Timeout, nil
+}
+
+// Generate 实现 Provider 接口
+func (p *geminiProvider) Generate(ctx context.Context, req ChatRequest) (*openai.ChatCompletionResponse, error) {
+	chat, lastParts, timeout, err := p.startChat(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
+	ctx, cancel := withRequestTimeout(ctx, timeout)
+	defer cancel()
+
 	// 发送最后一条消息
-	lastMsg := schemaMessages[len(schemaMessages)-1]
-	resp, err := chat.SendMessage(ctx, genai.Text(lastMsg.Content))
+	resp, err := chat.SendMessage(ctx, lastParts...)
 	if err != nil {
-		return nil, fmt.Errorf("发送消息失败: %v", err)
+		return nil, fmt.Errorf("发送消息失败: %w", err)
 	}
 
-	// 解析响应
-	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+	// 解析响应，提示词被拦截时没有候选结果，以 content_filter 作为完成原因返回
+	if len(resp.Candidates) == 0 && !geminiPromptBlocked(resp) {
 		return nil, fmt.Errorf("Gemini返回的响应是空的")
 	}
-
-	content := ""
-	for _, part := range resp.Candidates[0].Content.Parts {
-		if text, ok := part.(genai.Text); ok {
-			content += string(text)
-		}
+	content, toolCalls, finishReason := geminiCandidate(resp, 0)
+	if finishReason == "" {
+		finishReason = openai.FinishReasonStop
 	}
 
 	// 构造ChatCompletionChoice
-	choices := []ChatCompletionChoice{
+	choices := []openai.ChatCompletionChoice{
 		{
 			Index: 0,
-			Message: ChatMessage{
-				Role:    "assistant",
-				Content: content,
+			Message: openai.ChatCompletionMessage{
+				Role:      openai.ChatMessageRoleAssistant,
+				Content:   content,
+				ToolCalls: toolCalls,
 			},
-			FinishReason: "stop", // 默认值
+			FinishReason: finishReason,
 		},
 	}
 
-	// 生成唯一ID
-	uniqueID := fmt.Sprintf("gemini-%d", time.Now().UnixNano())
-
 	// 获取Token使用情况
-	usage := ChatCompletionUsage{}
+	var usage openai.Usage
 	if resp.UsageMetadata != nil {
-		usage = ChatCompletionUsage{
-			PromptTokens:     int(resp.UsageMetadata.PromptTokenCount),
-			CompletionTokens: int(resp.UsageMetadata.CandidatesTokenCount),
-			TotalTokens:      int(resp.UsageMetadata.TotalTokenCount),
-		}
+		usage = *geminiUsage(resp.UsageMetadata)
 	}
 
 	// 构造并返回响应
-	return &ChatCompletionResponse{
-		ID:      uniqueID,
+	return &openai.ChatCompletionResponse{
+		ID:      fmt.Sprintf("gemini-%d", time.Now().UnixNano()),
 		Object:  "chat.completion",
 		Created: time.Now().Unix(),
 		Model:   req.Model,
@@ -457,165 +387,21 @@ func GeminiCreateChatCompletion(req ChatCompletionRequest) (*ChatCompletionRespo
 	}, nil
 }
 
-// GeminiCreateChatCompletionToChat 使用Google Gemini服务创建聊天完成接口
-func GeminiCreateChatCompletionToChat(req ChatRequest) (*ChatResponse, error) {
-	// 准备请求参数
-	model := req.Model
-	if model == "" {
-		// 如果没有指定模型，可以设置一个默认值或返回错误
-		return nil, fmt.Errorf("未指定模型名称")
-	}
-
-	temperature := float32(req.Temperature)
-	maxTokens := req.MaxTokens
-
-	// 转换消息格式
-	messages := make([]ChatMessage, 0, len(req.Messages))
-	for _, msg := range req.Messages {
-		messages = append(messages, ChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
-
-	// 创建Gemini请求
-	geminiReq := ChatCompletionRequest{
-		Model:       model,
-		Messages:    messages,
-		Temperature: temperature,
-		MaxTokens:   maxTokens,
-	}
-
-	// 调用Gemini服务
-	resp, err := GeminiCreateChatCompletion(geminiReq)
+// Stream 实现 Provider 接口
+func (p *geminiProvider) Stream(ctx context.Context, req ChatRequest) (*schema.StreamReader[*openai.ChatCompletionStreamResponse], error) {
+	chat, lastParts, timeout, err := p.startChat(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("调用Gemini聊天接口失败: %w", err)
-	}
-
-	// 转换响应格式
-	choices := make([]Choice, 0, len(resp.Choices))
-	for _, choice := range resp.Choices {
-		choices = append(choices, Choice{
-			Index: choice.Index,
-			Message: ChatMessage{
-				Role:    choice.Message.Role,
-				Content: choice.Message.Content,
-			},
-			FinishReason: choice.FinishReason,
-		})
-	}
-
-	return &ChatResponse{
-		ID:      resp.ID,
-		Object:  resp.Object,
-		Created: resp.Created,
-		Model:   resp.Model,
-		Choices: choices,
-		Usage: TokenUsage{
-			PromptTokens:     resp.Usage.PromptTokens,
-			CompletionTokens: resp.Usage.CompletionTokens,
-			TotalTokens:      resp.Usage.TotalTokens,
-		},
-	}, nil
-}
-
-// GeminiStreamChatCompletion 使用Google Gemini服务创建流式聊天完成
-func GeminiStreamChatCompletion(req ChatRequest) (*schema.StreamReader[*ChatCompletionStreamResponse], error) {
-	// 创建Gemini配置
-	conf := &Config{
-		Vendor:      "gemini",
-		Model:       req.Model,
-		MaxTokens:   req.MaxTokens,
-		Temperature: &req.Temperature,
-		TopP:        &req.TopP,
-		Stop:        req.Stop,
-	}
-
-	// 获取Gemini配置
-	geminiConf, err := conf.getGeminiConfig()
-	if err != nil {
-		return nil, fmt.Errorf("获取Gemini配置失败: %v", err)
-	}
-
-	// 创建上下文
-	ctx := context.Background()
-
-	// 转换消息格式
-	schemaMessages := make([]*schema.Message, len(req.Messages))
-	for i, msg := range req.Messages {
-		role := schema.RoleType(msg.Role)
-		schemaMessages[i] = &schema.Message{
-			Role:    role,
-			Content: msg.Content,
-		}
-	}
-
-	// 创建生成模型
-	model := geminiConf.Client.GenerativeModel(req.Model)
-
-	// 设置参数
-	if req.MaxTokens > 0 {
-		model.SetMaxOutputTokens(int32(req.MaxTokens))
-	}
-	if req.Temperature > 0 {
-		model.SetTemperature(req.Temperature)
-	}
-	if req.TopP > 0 {
-		model.SetTopP(req.TopP)
-	}
-	if geminiConf.TopK != nil {
-		model.SetTopK(*geminiConf.TopK)
-	}
-
-	// 设置安全级别
-	if len(geminiConf.SafetySettings) > 0 {
-		model.SafetySettings = geminiConf.SafetySettings
-	}
-
-	// 如果配置了ResponseSchema，设置结构化输出格式
-	if geminiConf.ResponseSchema != nil {
-		// 请求JSON格式响应
-		model.ResponseMIMEType = "application/json"
-
-		// 创建Schema实例 - 使用vertexai/genai包的TypeObject常量
-		// 根据搜索结果，TypeObject = 6
-		schema := &genai.Schema{
-			Type:        6, // TypeObject = 6
-			Description: geminiConf.ResponseSchema.Description,
-			Properties:  make(map[string]*genai.Schema),
-		}
-
-		// 如果有需要，还可以进一步转换properties
-		// 注意：这里只是一个简化的转换示例
-
-		// 设置ResponseSchema
-		model.ResponseSchema = schema
-	}
-
-	// 设置是否启用代码执行
-	if geminiConf.EnableCodeExecution {
-		// 注意：启用代码执行可能存在安全风险，应谨慎使用
-		fmt.Printf("警告：已为模型 %s 启用代码执行功能\n", req.Model)
+		return nil, err
 	}
 
-	// 转换消息为Gemini格式
-	chat := model.StartChat()
-	for i := 0; i < len(schemaMessages)-1; i++ {
-		msg := schemaMessages[i]
-		parts := []genai.Part{genai.Text(msg.Content)}
-		content := &genai.Content{
-			Role:  toGeminiRole(msg.Role),
-			Parts: parts,
-		}
-		chat.History = append(chat.History, content)
-	}
+	// cancel 由处理协程在流结束时调用
+	ctx, cancel := withRequestTimeout(ctx, timeout)
 
 	// 发送最后一条消息（流式）
-	lastMsg := schemaMessages[len(schemaMessages)-1]
-	streamIter := chat.SendMessageStream(ctx, genai.Text(lastMsg.Content))
+	streamIter := chat.SendMessageStream(ctx, lastParts...)
 
 	// 创建结果通道
-	resultReader, resultWriter := schema.Pipe[*ChatCompletionStreamResponse](10)
+	resultReader, resultWriter := schema.Pipe[*openai.ChatCompletionStreamResponse](10)
 
 	// 启动goroutine处理流式数据
 	go func() {
@@ -627,7 +413,8 @@ func GeminiStreamChatCompletion(req ChatRequest) (*schema.StreamReader[*ChatComp
 				// 发送错误信息给resultWriter
 				_ = resultWriter.Send(nil, fmt.Errorf("Gemini Stream处理发生异常: %v", panicErr))
 			}
-			// 只关闭resultWriter
+			// 释放上游请求并关闭resultWriter
+			cancel()
 			resultWriter.Close()
 		}()
 
@@ -635,50 +422,53 @@ func GeminiStreamChatCompletion(req ChatRequest) (*schema.StreamReader[*ChatComp
 		uniqueID := fmt.Sprintf("gemini-stream-%d", time.Now().UnixNano())
 		created := time.Now().Unix()
 
+		// toolIndex 本次响应中已输出的工具调用数量，用于设置工具调用的序号
+		toolIndex := 0
+		role := openai.ChatMessageRoleAssistant
 		for {
 			resp, err := streamIter.Next()
-			if errors.Is(err, io.EOF) {
+			if errors.Is(err, iterator.Done) || errors.Is(err, io.EOF) {
 				// 流结束
 				break
 			}
 			if err != nil {
-				// 处理错误
-				_ = resultWriter.Send(nil, fmt.Errorf("从Gemini接收流数据失败: %v", err))
+				// 调用方取消时返回ctx的错误
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					err = ctxErr
+				}
+				_ = resultWriter.Send(nil, fmt.Errorf("从Gemini接收流数据失败: %w", err))
 				return
 			}
 
-			if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			content, toolCalls, finishReason := geminiCandidate(resp, toolIndex)
+			toolIndex += len(toolCalls)
+			if content == "" && len(toolCalls) == 0 && finishReason == "" {
 				continue
 			}
 
-			content := ""
-			for _, part := range resp.Candidates[0].Content.Parts {
-				if text, ok := part.(genai.Text); ok {
-					content += string(text)
-				}
-			}
-
-			// 构造流式响应
-			streamResp := &ChatCompletionStreamResponse{
+			// 构造流式响应，只有第一个分片携带角色
+			streamResp := &openai.ChatCompletionStreamResponse{
 				ID:      uniqueID,
 				Object:  "chat.completion.chunk",
 				Created: created,
 				Model:   req.Model,
-				Choices: []ChatCompletionStreamChoice{
+				Choices: []openai.ChatCompletionStreamChoice{
 					{
 						Index: 0,
-						Delta: ChatCompletionStreamDelta{
-							Role:    "assistant",
-							Content: content,
+						Delta: openai.ChatCompletionStreamChoiceDelta{
+							Role:      role,
+							Content:   content,
+							ToolCalls: toolCalls,
 						},
-						FinishReason: "",
+						FinishReason: finishReason,
 					},
 				},
 			}
+			role = ""
 
-			// 如果是最后一条消息，设置完成原因
-			if resp.Candidates[0].FinishReason != genai.FinishReasonUnspecified {
-				streamResp.Choices[0].FinishReason = resp.Candidates[0].FinishReason.String()
+			// 最后一个分片携带完成原因和Token使用情况(Gemini每个分片返回的都是累计值)
+			if finishReason != "" && resp.UsageMetadata != nil {
+				streamResp.Usage = geminiUsage(resp.UsageMetadata)
 			}
 
 			// 发送流式响应
@@ -692,72 +482,387 @@ func GeminiStreamChatCompletion(req ChatRequest) (*schema.StreamReader[*ChatComp
 	return resultReader, nil
 }
 
-// GeminiStreamChatCompletionToChat 使用Google Gemini服务创建流式聊天完成并转换为聊天流格式
-func GeminiStreamChatCompletionToChat(req ChatRequest, writer io.Writer) error {
-	// 调用Gemini流式聊天API
-	streamReader, err := GeminiStreamChatCompletion(req)
-	if err != nil {
-		return fmt.Errorf("调用Gemini流式聊天接口失败: %w", err)
+// geminiContents 将OpenAI格式的消息转换为Gemini的系统指令、历史消息和最后一条需要发送的消息
+// system/developer 消息合并为系统指令；assistant 消息的 tool_calls 转换为函数调用，
+// tool 消息转换为函数结果；相邻的同角色消息合并为一条，满足Gemini角色交替的要求
+func geminiContents(messages []openai.ChatCompletionMessage) (*genai.Content, []*genai.Content, []genai.Part, error) {
+	var system *genai.Content
+	var contents []*genai.Content
+	// toolNames 工具调用ID -> 函数名称，Gemini的函数结果需要携带函数名称
+	toolNames := make(map[string]string)
+
+	for _, msg := range messages {
+		var role string
+		var parts []genai.Part
+
+		switch msg.Role {
+		case openai.ChatMessageRoleSystem, "developer":
+			if system == nil {
+				system = &genai.Content{}
+			}
+			parts, err := geminiMessageParts(msg)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			system.Parts = append(system.Parts, parts...)
+			continue
+		case openai.ChatMessageRoleTool:
+			name := toolNames[msg.ToolCallID]
+			if name == "" {
+				name = msg.Name
+			}
+			if name == "" {
+				return nil, nil, nil, invalidRequestf("未找到工具调用 %s 对应的函数名称", msg.ToolCallID)
+			}
+			role = "user"
+			parts = []genai.Part{genai.FunctionResponse{Name: name, Response: geminiFunctionResponse(msg.Content)}}
+		default:
+			role = toGeminiRole(schema.RoleType(msg.Role))
+			var err error
+			if parts, err = geminiMessageParts(msg); err != nil {
+				return nil, nil, nil, err
+			}
+			for _, toolCall := range msg.ToolCalls {
+				args := make(map[string]any)
+				if toolCall.Function.Arguments != "" {
+					if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+						return nil, nil, nil, invalidRequestf("解析工具调用 %s 的参数失败: %v", toolCall.Function.Name, err)
+					}
+				}
+				toolNames[toolCall.ID] = toolCall.Function.Name
+				parts = append(parts, genai.FunctionCall{Name: toolCall.Function.Name, Args: args})
+			}
+			if len(parts) == 0 {
+				parts = []genai.Part{genai.Text(msg.Content)}
+			}
+		}
+
+		if n := len(contents); n > 0 && contents[n-1].Role == role {
+			contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+			continue
+		}
+		contents = append(contents, &genai.Content{Role: role, Parts: parts})
 	}
-	// 注意：由于streamReader没有Close方法，我们不需要defer close
 
-	// 处理流式响应
-	for {
-		response, err := streamReader.Recv()
-		if errors.Is(err, io.EOF) {
-			break
+	// 只有系统消息时作为用户消息发送
+	if len(contents) == 0 {
+		if system == nil {
+			return nil, nil, nil, invalidRequestf("消息列表不能为空")
 		}
-		if err != nil {
-			return fmt.Errorf("接收Gemini流式响应失败: %w", err)
+		return nil, nil, system.Parts, nil
+	}
+
+	last := contents[len(contents)-1]
+	return system, contents[:len(contents)-1], last.Parts, nil
+}
+
+// geminiMessageParts 提取消息中的文本和图片内容，忽略空文本和文件内容
+// data URL 格式的图片作为内联数据发送，其他URL(如 gs:// 或 File API 地址)按扩展名推断MIME类型后引用
+func geminiMessageParts(msg openai.ChatCompletionMessage) ([]genai.Part, error) {
+	var parts []genai.Part
+	if msg.Content != "" {
+		parts = append(parts, genai.Text(msg.Content))
+	}
+	for _, part := range msg.MultiContent {
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			if part.Text != "" {
+				parts = append(parts, genai.Text(part.Text))
+			}
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL == nil || part.ImageURL.URL == "" {
+				return nil, invalidRequestf("图片内容缺少url")
+			}
+			imagePart, err := geminiImagePart(part.ImageURL.URL)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, imagePart)
+		case chatMessagePartTypeFile:
+			// 文件数据在解析请求时已经丢失，见 toSchemaMessageParts
+			continue
+		default:
+			return nil, invalidRequestf("不支持的消息内容类型: %s", part.Type)
 		}
+	}
+	return parts, nil
+}
 
-		// 转换响应格式
-		choices := make([]StreamChoice, 0, len(response.Choices))
-		for _, choice := range response.Choices {
-			choices = append(choices, StreamChoice{
-				Index: choice.Index,
-				Delta: StreamChoiceDelta{
-					Role:    choice.Delta.Role,
-					Content: choice.Delta.Content,
-				},
-				FinishReason: choice.FinishReason,
-			})
+// geminiImagePart 将图片URL转换为Gemini的内联数据或文件引用
+func geminiImagePart(imageURL string) (genai.Part, error) {
+	if mimeType, data, ok := parseDataURL(imageURL); ok {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, invalidRequestf("解析base64图片失败: %v", err)
 		}
+		return genai.Blob{MIMEType: mimeType, Data: decoded}, nil
+	}
+
+	parsed, err := url.Parse(imageURL)
+	if err != nil {
+		return nil, invalidRequestf("图片url格式不正确: %v", err)
+	}
+	mimeType := mime.TypeByExtension(path.Ext(parsed.Path))
+	if mimeType == "" {
+		return nil, invalidRequestf("无法识别图片 %s 的MIME类型", imageURL)
+	}
+	return genai.FileData{MIMEType: mimeType, URI: imageURL}, nil
+}
 
-		streamResp := StreamResponse{
-			ID:      response.ID,
-			Object:  response.Object,
-			Created: response.Created,
-			Model:   response.Model,
-			Choices: choices,
+// geminiFunctionResponse 将工具结果转换为Gemini函数结果
+// 结果是JSON对象时直接使用，否则放在 content 字段中
+func geminiFunctionResponse(content string) map[string]any {
+	var response map[string]any
+	if err := json.Unmarshal([]byte(content), &response); err == nil && response != nil {
+		return response
+	}
+	return map[string]any{"content": content}
+}
+
+// geminiTools 将OpenAI格式的工具定义转换为Gemini的函数声明，过滤同名工具
+func geminiTools(reqTools []openai.Tool, codeExecution bool) ([]*genai.Tool, error) {
+	var tools []*genai.Tool
+	if codeExecution {
+		tools = append(tools, &genai.Tool{CodeExecution: &genai.CodeExecution{}})
+	}
+
+	var declarations []*genai.FunctionDeclaration
+	toolNameMap := make(map[string]bool)
+	for _, tool := range reqTools {
+		if tool.Function == nil {
+			continue
+		}
+		if toolNameMap[tool.Function.Name] {
+			currentLogger().Warn("跳过重复的工具名称", zap.String("tool", tool.Function.Name))
+			continue
 		}
+		toolNameMap[tool.Function.Name] = true
 
-		// 将响应写入writer
-		data, err := json.Marshal(streamResp)
+		params, err := toJSONMap(tool.Function.Parameters)
 		if err != nil {
-			return fmt.Errorf("序列化流式响应失败: %w", err)
+			return nil, invalidRequestf("工具 %s 的参数格式不正确: %v", tool.Function.Name, err)
+		}
+		declaration := &genai.FunctionDeclaration{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
 		}
+		// Gemini不接受没有属性的object参数，无参数的函数不设置Parameters
+		if properties, _ := params["properties"].(map[string]any); len(properties) > 0 {
+			declaration.Parameters = geminiSchema(params)
+		}
+		declarations = append(declarations, declaration)
+	}
+	if len(declarations) > 0 {
+		tools = append(tools, &genai.Tool{FunctionDeclarations: declarations})
+	}
+	return tools, nil
+}
 
-		// 添加data:前缀
-		if _, err := writer.Write([]byte("data: ")); err != nil {
-			return fmt.Errorf("写入流式响应前缀失败: %w", err)
+// toJSONMap 将工具参数(JSON字符串、map或可序列化的结构体)转换为map
+func toJSONMap(value any) (map[string]any, error) {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case json.RawMessage:
+		data = v
+	case map[string]any:
+		return v, nil
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
 		}
+	}
 
-		if _, err := writer.Write(data); err != nil {
-			return fmt.Errorf("写入流式响应失败: %w", err)
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// geminiSchema 将JSON Schema转换为Gemini的Schema，只保留Gemini支持的字段
+func geminiSchema(jsonSchema map[string]any) *genai.Schema {
+	result := &genai.Schema{}
+
+	switch t := jsonSchema["type"].(type) {
+	case string:
+		result.Type = geminiSchemaType(t)
+	case []any:
+		// ["string", "null"] 形式的可空类型
+		for _, item := range t {
+			if s, _ := item.(string); s == "null" {
+				result.Nullable = true
+			} else if s != "" {
+				result.Type = geminiSchemaType(s)
+			}
 		}
+	}
+	if nullable, ok := jsonSchema["nullable"].(bool); ok {
+		result.Nullable = nullable
+	}
+	result.Description, _ = jsonSchema["description"].(string)
+	result.Format, _ = jsonSchema["format"].(string)
 
-		if _, err := writer.Write([]byte("\n\n")); err != nil {
-			return fmt.Errorf("写入流式响应分隔符失败: %w", err)
+	if enum, ok := jsonSchema["enum"].([]any); ok {
+		for _, value := range enum {
+			result.Enum = append(result.Enum, fmt.Sprint(value))
 		}
+		// Gemini只支持字符串枚举
+		result.Type = genai.TypeString
+	}
+	if items, ok := jsonSchema["items"].(map[string]any); ok {
+		result.Items = geminiSchema(items)
 	}
+	if properties, ok := jsonSchema["properties"].(map[string]any); ok {
+		result.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, value := range properties {
+			if property, ok := value.(map[string]any); ok {
+				result.Properties[name] = geminiSchema(property)
+			}
+		}
+		if result.Type == genai.TypeUnspecified {
+			result.Type = genai.TypeObject
+		}
+	}
+	result.Required = getRequiredFields(jsonSchema)
+
+	if result.Type == genai.TypeUnspecified {
+		result.Type = genai.TypeString
+	}
+	return result
+}
 
-	// 添加结束标记
-	if _, err := writer.Write([]byte("data: [DONE]\n\n")); err != nil {
-		return fmt.Errorf("写入流式响应结束标记失败: %w", err)
+// geminiSchemaType 将JSON Schema的类型名称转换为Gemini的类型
+func geminiSchemaType(name string) genai.Type {
+	switch name {
+	case "string":
+		return genai.TypeString
+	case "number":
+		return genai.TypeNumber
+	case "integer":
+		return genai.TypeInteger
+	case "boolean":
+		return genai.TypeBoolean
+	case "array":
+		return genai.TypeArray
+	case "object":
+		return genai.TypeObject
+	default:
+		return genai.TypeUnspecified
 	}
+}
+
+// geminiToolConfig 将OpenAI的 tool_choice 转换为Gemini的函数调用配置
+// "none" 禁止调用、"auto" 由模型决定、"required" 必须调用；指定函数时只允许调用该函数
+func geminiToolConfig(toolChoice any) *genai.ToolConfig {
+	var config *genai.FunctionCallingConfig
+	choice, function := parseToolChoice(toolChoice)
+	switch choice {
+	case toolChoiceNone:
+		config = &genai.FunctionCallingConfig{Mode: genai.FunctionCallingNone}
+	case toolChoiceAuto:
+		config = &genai.FunctionCallingConfig{Mode: genai.FunctionCallingAuto}
+	case toolChoiceRequired:
+		config = &genai.FunctionCallingConfig{Mode: genai.FunctionCallingAny}
+		if function != "" {
+			config.AllowedFunctionNames = []string{function}
+		}
+	default:
+		return nil
+	}
+	return &genai.ToolConfig{FunctionCallingConfig: config}
+}
+
+// geminiPromptBlocked 提示词是否被Gemini的安全策略拦截
+func geminiPromptBlocked(resp *genai.GenerateContentResponse) bool {
+	return resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != genai.BlockReasonUnspecified
+}
 
-	return nil
+// geminiCandidate 将Gemini的第一个候选结果转换为OpenAI格式的文本、工具调用和完成原因
+// toolIndex 为第一个工具调用的序号，流式响应中跨分片递增；未结束时完成原因为空
+func geminiCandidate(resp *genai.GenerateContentResponse, toolIndex int) (string, []openai.ToolCall, openai.FinishReason) {
+	if len(resp.Candidates) == 0 {
+		if geminiPromptBlocked(resp) {
+			return "", nil, openai.FinishReasonContentFilter
+		}
+		return "", nil, ""
+	}
+
+	candidate := resp.Candidates[0]
+	var content strings.Builder
+	var toolCalls []openai.ToolCall
+	if candidate.Content != nil {
+		for _, part := range candidate.Content.Parts {
+			switch v := part.(type) {
+			case genai.Text:
+				content.WriteString(string(v))
+			case genai.FunctionCall:
+				arguments, err := json.Marshal(v.Args)
+				if err != nil || v.Args == nil {
+					arguments = []byte("{}")
+				}
+				index := toolIndex + len(toolCalls)
+				toolCalls = append(toolCalls, openai.ToolCall{
+					Index: &index,
+					// Gemini的函数调用没有ID，生成一个供后续tool消息引用
+					ID:   fmt.Sprintf("call_%d_%d", time.Now().UnixNano(), index),
+					Type: openai.ToolTypeFunction,
+					Function: openai.FunctionCall{
+						Name:      v.Name,
+						Arguments: string(arguments),
+					},
+				})
+			case *genai.ExecutableCode:
+				// 代码执行的代码和结果以Markdown代码块输出
+				language := ""
+				if v.Language == genai.ExecutableCodePython {
+					language = "python"
+				}
+				fmt.Fprintf(&content, "\n```%s\n%s\n```\n", language, v.Code)
+			case *genai.CodeExecutionResult:
+				fmt.Fprintf(&content, "\n```\n%s\n```\n", v.Output)
+			}
+		}
+	}
+
+	return content.String(), toolCalls, geminiFinishReason(candidate.FinishReason, len(toolCalls) > 0)
+}
+
+// geminiFinishReason 将Gemini的完成原因转换为OpenAI的完成原因
+func geminiFinishReason(reason genai.FinishReason, hasToolCalls bool) openai.FinishReason {
+	switch reason {
+	case genai.FinishReasonUnspecified:
+		return ""
+	case genai.FinishReasonMaxTokens:
+		return openai.FinishReasonLength
+	case genai.FinishReasonSafety, genai.FinishReasonRecitation,
+		// 当前SDK没有定义的 BLOCKLIST、PROHIBITED_CONTENT、SPII
+		7, 8, 9:
+		return openai.FinishReasonContentFilter
+	}
+	if hasToolCalls {
+		return openai.FinishReasonToolCalls
+	}
+	return openai.FinishReasonStop
+}
+
+// geminiUsage 将Gemini的Token使用情况转换为OpenAI格式
+func geminiUsage(meta *genai.UsageMetadata) *openai.Usage {
+	usage := &openai.Usage{
+		PromptTokens:     int(meta.PromptTokenCount),
+		CompletionTokens: int(meta.CandidatesTokenCount),
+		TotalTokens:      int(meta.TotalTokenCount),
+	}
+	if meta.CachedContentTokenCount > 0 {
+		usage.PromptTokensDetails = &openai.PromptTokensDetails{CachedTokens: int(meta.CachedContentTokenCount)}
+	}
+	return usage
 }
 
 // 用于将schema.RoleType转换为Gemini的角色类型
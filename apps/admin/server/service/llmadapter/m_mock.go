@@ -0,0 +1,386 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v2"
+)
+
+// mockDefaultChunkSize 未配置 chunk_size 时流式响应每个分片的字符数
+const mockDefaultChunkSize = 4
+
+// MockCredential 定义内置模拟供应商的凭证配置结构
+// 模拟供应商不访问网络，按规则返回预设的回复、工具调用或错误，用于自动化测试和离线演示
+type MockCredential struct {
+	CredentialBase  `yaml:",inline"` // 名称、启用、权重、QPS限制、描述、模型列表
+	Fixture         string           `yaml:"fixture"`           // 规则文件路径，相对路径相对于加载该凭证的配置目录；文件中的规则排在 rules 之后
+	Rules           []MockRule       `yaml:"rules"`             // 回复规则，按顺序匹配，都不匹配时原样返回最后一条用户消息
+	ChunkSize       int              `yaml:"chunk_size"`        // 流式响应每个分片的字符数，0表示4
+	ChunkDelay      int              `yaml:"chunk_delay"`       // 流式分片之间的间隔(毫秒)
+	FirstTokenDelay int              `yaml:"first_token_delay"` // 返回第一个分片(非流式为整个响应)前的等待时间(毫秒)
+}
+
+// MockRule 模拟供应商的回复规则
+type MockRule struct {
+	Match            string         `yaml:"match"`             // 匹配最后一条用户消息的正则表达式，为空时匹配所有消息
+	Model            string         `yaml:"model"`             // 只匹配该模型的请求，为空时不限制
+	Reply            string         `yaml:"reply"`             // 回复内容，可以用 ${1}、${name} 引用 match 中的分组；为空且没有工具调用时原样返回用户消息
+	Reasoning        string         `yaml:"reasoning"`         // 推理内容，在回复之前输出；请求关闭了推理(thinking.type=disabled)时不输出
+	ToolCalls        []MockToolCall `yaml:"tool_calls"`        // 返回的工具调用
+	Error            *MockError     `yaml:"error"`             // 返回的错误，设置后不再返回回复内容
+	PromptTokens     int            `yaml:"prompt_tokens"`     // 输入Token数，0表示按消息长度估算
+	CompletionTokens int            `yaml:"completion_tokens"` // 输出Token数，0表示按回复和推理内容的长度估算
+	ReasoningTokens  int            `yaml:"reasoning_tokens"`  // 输出Token数中的推理Token数，0表示按推理内容的长度估算
+
+	pattern *regexp.Regexp
+}
+
+// MockToolCall 模拟的工具调用
+type MockToolCall struct {
+	Name      string `yaml:"name"`      // 函数名称
+	Arguments string `yaml:"arguments"` // JSON格式的参数，同样可以引用 match 中的分组
+}
+
+// MockError 模拟的上游错误
+type MockError struct {
+	Status      int    `yaml:"status"`       // HTTP状态码，如429、500，按上游错误参与重试和故障转移
+	Message     string `yaml:"message"`      // 错误信息
+	AfterChunks int    `yaml:"after_chunks"` // 大于0时模拟连接中断：流式响应发送指定数量的内容分片后断开，非流式直接返回网络错误
+}
+
+// mockFixture 规则文件的结构
+type mockFixture struct {
+	Rules []MockRule `yaml:"rules"`
+}
+
+// resolved 将相对路径的规则文件转换为配置目录 dir 下的路径
+func (c MockCredential) resolved(dir string) MockCredential {
+	if c.Fixture != "" && !filepath.IsAbs(c.Fixture) {
+		c.Fixture = filepath.Join(dir, c.Fixture)
+	}
+	return c
+}
+
+// decrypted 模拟供应商没有敏感字段，加载规则文件并编译所有规则的正则表达式
+func (c MockCredential) decrypted(DecryptFunc) (MockCredential, error) {
+	rules := append([]MockRule(nil), c.Rules...)
+	if c.Fixture != "" {
+		data, err := os.ReadFile(c.Fixture)
+		if err != nil {
+			return c, fmt.Errorf("读取模拟规则文件失败: %v", err)
+		}
+		var fixture mockFixture
+		if err := yaml.UnmarshalStrict(data, &fixture); err != nil {
+			return c, fmt.Errorf("解析模拟规则文件 %s 失败: %v", c.Fixture, err)
+		}
+		rules = append(rules, fixture.Rules...)
+	}
+
+	for i := range rules {
+		if rules[i].Match == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(rules[i].Match)
+		if err != nil {
+			return c, fmt.Errorf("第%d条模拟规则的正则表达式错误: %v", i+1, err)
+		}
+		rules[i].pattern = pattern
+	}
+	c.Rules = rules
+	return c, nil
+}
+
+// mockReply 一次调用按规则生成的结果
+type mockReply struct {
+	content   string
+	reasoning string
+	toolCalls []openai.ToolCall
+	err       *MockError
+	usage     openai.Usage
+}
+
+// reply 按规则生成请求的回复
+func (c MockCredential) reply(req ChatRequest) mockReply {
+	text := lastUserMessage(req.Messages)
+
+	var result mockReply
+	matched := false
+	for _, rule := range c.Rules {
+		if rule.Model != "" && rule.Model != req.Model {
+			continue
+		}
+		var submatch []int
+		if rule.pattern != nil {
+			if submatch = rule.pattern.FindStringSubmatchIndex(text); submatch == nil {
+				continue
+			}
+		}
+		expand := func(template string) string {
+			if submatch == nil {
+				return template
+			}
+			return string(rule.pattern.ExpandString(nil, template, text, submatch))
+		}
+
+		matched = true
+		result.err = rule.Error
+		result.content = expand(rule.Reply)
+		if !req.Thinking.disabled() {
+			result.reasoning = expand(rule.Reasoning)
+		}
+		for i, call := range rule.ToolCalls {
+			result.toolCalls = append(result.toolCalls, openai.ToolCall{
+				ID:       fmt.Sprintf("call_mock_%d", i),
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: call.Name, Arguments: expand(call.Arguments)},
+			})
+		}
+		if result.content == "" && len(result.toolCalls) == 0 {
+			result.content = text
+		}
+		result.usage = openai.Usage{PromptTokens: rule.PromptTokens, CompletionTokens: rule.CompletionTokens}
+		if result.reasoning != "" {
+			tokens := rule.ReasoningTokens
+			if tokens == 0 {
+				tokens = mockTokens(result.reasoning)
+			}
+			result.usage.CompletionTokensDetails = &openai.CompletionTokensDetails{ReasoningTokens: tokens}
+		}
+		break
+	}
+	if !matched {
+		result.content = text
+	}
+
+	// 未指定Token数时按字符数估算，保证同样的请求得到同样的用量
+	if result.usage.PromptTokens == 0 {
+		for _, msg := range req.Messages {
+			result.usage.PromptTokens += mockTokens(messageText(msg))
+		}
+	}
+	if result.usage.CompletionTokens == 0 {
+		result.usage.CompletionTokens = mockTokens(result.content) + reasoningTokens(result.usage)
+		for _, call := range result.toolCalls {
+			result.usage.CompletionTokens += mockTokens(call.Function.Name + call.Function.Arguments)
+		}
+	}
+	result.usage.TotalTokens = result.usage.PromptTokens + result.usage.CompletionTokens
+	return result
+}
+
+// asError 将规则中的错误转换为上游错误，status 错误使用OpenAI SDK的错误类型以便按状态码分类
+func (e *MockError) asError() error {
+	message := e.Message
+	if message == "" {
+		message = "模拟错误"
+	}
+	if e.Status > 0 {
+		return &openai.APIError{Type: "mock_error", Message: message, HTTPStatusCode: e.Status, HTTPStatus: http.StatusText(e.Status)}
+	}
+	return fmt.Errorf("%s: %w", message, io.ErrUnexpectedEOF)
+}
+
+// lastUserMessage 返回最后一条用户消息的文本
+func lastUserMessage(messages []openai.ChatCompletionMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == openai.ChatMessageRoleUser {
+			return messageText(messages[i])
+		}
+	}
+	return ""
+}
+
+// messageText 返回消息的文本内容，多段内容只保留文本部分
+func messageText(msg openai.ChatCompletionMessage) string {
+	if msg.Content != "" || len(msg.MultiContent) == 0 {
+		return msg.Content
+	}
+	parts := make([]string, 0, len(msg.MultiContent))
+	for _, part := range msg.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeText {
+			parts = append(parts, part.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// mockTokens 按每4个字符1个Token估算Token数
+func mockTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// splitRunes 将字符串按字符数切分
+func splitRunes(s string, size int) []string {
+	runes := []rune(s)
+	chunks := make([]string, 0, (len(runes)+size-1)/size)
+	for len(runes) > size {
+		chunks = append(chunks, string(runes[:size]))
+		runes = runes[size:]
+	}
+	if len(runes) > 0 {
+		chunks = append(chunks, string(runes))
+	}
+	return chunks
+}
+
+func init() {
+	secretFields["mock"] = []string{}
+	registerCredentials[MockCredential]("mock", "mock.yaml", "模拟供应商")
+	Register("mock", func() Provider { return mockProvider{} })
+}
+
+// mockProvider 内置的模拟供应商
+type mockProvider struct{}
+
+// prepare 校验请求并选择凭证，返回按规则生成的回复
+func (mockProvider) prepare(ctx context.Context, req ChatRequest) (MockCredential, mockReply, error) {
+	if _, err := newRequestConfig("mock", req); err != nil {
+		return MockCredential{}, mockReply{}, err
+	}
+
+	creds, err := enabledCredentials[MockCredential](ctx, "mock")
+	if err != nil {
+		return MockCredential{}, mockReply{}, err
+	}
+	cred, err := selectCredential(ctx, "mock", req.Model, creds)
+	if err != nil {
+		return MockCredential{}, mockReply{}, err
+	}
+	return cred, cred.reply(req), nil
+}
+
+// Generate 实现 Provider 接口
+func (p mockProvider) Generate(ctx context.Context, req ChatRequest) (*openai.ChatCompletionResponse, error) {
+	cred, reply, err := p.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := sleepContext(ctx, time.Duration(cred.FirstTokenDelay)*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if reply.err != nil {
+		return nil, reply.err.asError()
+	}
+
+	choice := openai.ChatCompletionChoice{
+		Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: reply.content, ReasoningContent: reply.reasoning},
+		FinishReason: openai.FinishReasonStop,
+	}
+	if len(reply.toolCalls) > 0 {
+		choice.Message.ToolCalls = reply.toolCalls
+		choice.FinishReason = openai.FinishReasonToolCalls
+	}
+	return &openai.ChatCompletionResponse{
+		ID:      fmt.Sprintf("mock-%d", time.Now().UnixNano()),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []openai.ChatCompletionChoice{choice},
+		Usage:   reply.usage,
+	}, nil
+}
+
+// Stream 实现 Provider 接口
+// 推理内容和回复内容依次按 chunk_size 切分，每个工具调用单独一个分片，最后一个分片包含完成原因和用量；
+// 状态码错误在建立连接时返回，after_chunks 错误在发送指定数量的内容分片后返回
+func (p mockProvider) Stream(ctx context.Context, req ChatRequest) (*schema.StreamReader[*openai.ChatCompletionStreamResponse], error) {
+	cred, reply, err := p.prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if reply.err != nil && reply.err.AfterChunks <= 0 {
+		if err := sleepContext(ctx, time.Duration(cred.FirstTokenDelay)*time.Millisecond); err != nil {
+			return nil, err
+		}
+		return nil, reply.err.asError()
+	}
+
+	chunkSize := cred.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = mockDefaultChunkSize
+	}
+	id := fmt.Sprintf("mock-stream-%d", time.Now().UnixNano())
+	created := time.Now().Unix()
+	chunk := func(delta openai.ChatCompletionStreamChoiceDelta) *openai.ChatCompletionStreamResponse {
+		return &openai.ChatCompletionStreamResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   req.Model,
+			Choices: []openai.ChatCompletionStreamChoice{{Delta: delta}},
+		}
+	}
+
+	var chunks []*openai.ChatCompletionStreamResponse
+	for _, reasoning := range splitRunes(reply.reasoning, chunkSize) {
+		delta := openai.ChatCompletionStreamChoiceDelta{ReasoningContent: reasoning}
+		if len(chunks) == 0 {
+			delta.Role = openai.ChatMessageRoleAssistant
+		}
+		chunks = append(chunks, chunk(delta))
+	}
+	for _, content := range splitRunes(reply.content, chunkSize) {
+		delta := openai.ChatCompletionStreamChoiceDelta{Content: content}
+		if len(chunks) == 0 {
+			delta.Role = openai.ChatMessageRoleAssistant
+		}
+		chunks = append(chunks, chunk(delta))
+	}
+	for i, call := range reply.toolCalls {
+		index := i
+		call.Index = &index
+		delta := openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{call}}
+		if len(chunks) == 0 {
+			delta.Role = openai.ChatMessageRoleAssistant
+		}
+		chunks = append(chunks, chunk(delta))
+	}
+	last := chunk(openai.ChatCompletionStreamChoiceDelta{})
+	last.Choices[0].FinishReason = openai.FinishReasonStop
+	if len(reply.toolCalls) > 0 {
+		last.Choices[0].FinishReason = openai.FinishReasonToolCalls
+	}
+	usage := reply.usage
+	last.Usage = &usage
+	chunks = append(chunks, last)
+
+	failAfter := -1
+	if reply.err != nil {
+		failAfter = min(reply.err.AfterChunks, len(chunks)-1)
+	}
+
+	reader, writer := schema.Pipe[*openai.ChatCompletionStreamResponse](len(chunks))
+	go func() {
+		defer writer.Close()
+		for i, resp := range chunks {
+			delay := cred.ChunkDelay
+			if i == 0 {
+				delay = cred.FirstTokenDelay
+			}
+			if err := sleepContext(ctx, time.Duration(delay)*time.Millisecond); err != nil {
+				writer.Send(nil, err)
+				return
+			}
+			if i == failAfter {
+				writer.Send(nil, reply.err.asError())
+				return
+			}
+			// 下游已关闭读取端
+			if writer.Send(resp, nil) {
+				return
+			}
+		}
+	}()
+	return reader, nil
+}
@@ -0,0 +1,267 @@
+package llmadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockChatRequest 构造调用模拟供应商的请求
+func mockChatRequest(content string, stream bool) ChatRequest {
+	return ChatRequest{Provider: "mock", ChatCompletionRequest: openai.ChatCompletionRequest{
+		Model:    "mock-model",
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: content}},
+		Stream:   stream,
+	}}
+}
+
+// streamContents 按顺序返回流式输出中各分片的内容
+func streamContents(t *testing.T, output string) []string {
+	t.Helper()
+	var contents []string
+	for _, line := range strings.Split(output, "\n\n") {
+		data := strings.TrimPrefix(line, "data: ")
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		var chunk openai.ChatCompletionStreamResponse
+		if !assert.NoError(t, json.Unmarshal([]byte(data), &chunk)) {
+			return nil
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			contents = append(contents, chunk.Choices[0].Delta.Content)
+		}
+	}
+	return contents
+}
+
+// TestMockProvider 测试模拟供应商按规则回复、工具调用和流式分片
+func TestMockProvider(t *testing.T) {
+	useTestCredentials(t, map[string]string{
+		"mock.yaml": `environments:
+  {{env}}:
+    credentials:
+      - name: mock
+        enabled: true
+        weight: 1
+        fixture: rules.yaml
+        chunk_size: 2
+        rules:
+          - match: "^repeat (\\d+)$"
+            reply: "第${1}次"
+            prompt_tokens: 10
+            completion_tokens: 5
+          - model: other-model
+            reply: "other"
+`,
+		"rules.yaml": `rules:
+  - match: "天气.*?(?P<city>北京|上海)|(?P<city>北京|上海).*?天气"
+    tool_calls:
+      - name: get_weather
+        arguments: '{"city":"${city}"}'
+`,
+	})
+
+	t.Run("正则规则", func(t *testing.T) {
+		ctx, info := WithCallInfo(context.Background())
+		resp, err := CreateChatCompletion(ctx, mockChatRequest("repeat 3", false), nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "第3次", resp.Choices[0].Message.Content)
+		assert.Equal(t, openai.FinishReasonStop, resp.Choices[0].FinishReason)
+		assert.Equal(t, openai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, resp.Usage)
+		assert.Equal(t, "mock", info.Credential)
+		assert.Equal(t, 15, info.Usage.TotalTokens)
+	})
+
+	t.Run("未匹配时原样返回", func(t *testing.T) {
+		resp, err := CreateChatCompletion(context.Background(), mockChatRequest("hello world!", false), nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "hello world!", resp.Choices[0].Message.Content)
+		assert.Equal(t, openai.Usage{PromptTokens: 3, CompletionTokens: 3, TotalTokens: 6}, resp.Usage)
+	})
+
+	t.Run("按模型匹配", func(t *testing.T) {
+		// 规则按顺序匹配，第一条规则优先
+		req := mockChatRequest("repeat 3", false)
+		req.Model = "other-model"
+		resp, err := CreateChatCompletion(context.Background(), req, nil)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "第3次", resp.Choices[0].Message.Content)
+		}
+		req.Messages[0].Content = "hi"
+		resp, err = CreateChatCompletion(context.Background(), req, nil)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "other", resp.Choices[0].Message.Content)
+		}
+	})
+
+	t.Run("工具调用", func(t *testing.T) {
+		resp, err := CreateChatCompletion(context.Background(), weatherToolRequest("mock", "mock-model"), nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, openai.FinishReasonToolCalls, resp.Choices[0].FinishReason)
+		if assert.Len(t, resp.Choices[0].Message.ToolCalls, 1) {
+			assert.Equal(t, "get_weather", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+			assert.JSONEq(t, `{"city":"北京"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+		}
+
+		req := weatherToolRequest("mock", "mock-model")
+		req.Stream = true
+		buffer := &bytes.Buffer{}
+		if _, err := CreateChatCompletion(context.Background(), req, buffer); !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, buffer.String(), `"name":"get_weather"`)
+		assert.Contains(t, buffer.String(), `"finish_reason":"tool_calls"`)
+	})
+
+	t.Run("流式分片", func(t *testing.T) {
+		ctx, info := WithCallInfo(context.Background())
+		buffer := &bytes.Buffer{}
+		_, err := CreateChatCompletion(ctx, mockChatRequest("你好，世界", true), buffer)
+		if !assert.NoError(t, err) {
+			return
+		}
+		output := buffer.String()
+		assert.Equal(t, []string{"你好", "，世", "界"}, streamContents(t, output))
+		assert.Contains(t, output, `"role":"assistant"`)
+		assert.Contains(t, output, `"finish_reason":"stop"`)
+		assert.True(t, strings.HasSuffix(output, "data: [DONE]\n\n"))
+		assert.Equal(t, openai.Usage{PromptTokens: 2, CompletionTokens: 2, TotalTokens: 4}, info.Usage)
+	})
+}
+
+// TestMockProviderErrors 测试模拟供应商注入的限流、服务端错误和连接中断
+func TestMockProviderErrors(t *testing.T) {
+	// 各用例使用同一个凭证，避免限流错误触发熔断
+	policy := testRetryPolicy()
+	policy.BreakerThreshold = 100
+	withRetryPolicy(t, policy)
+	useTestCredentials(t, map[string]string{"mock.yaml": `environments:
+  {{env}}:
+    credentials:
+      - name: mock-errors
+        enabled: true
+        weight: 1
+        chunk_size: 2
+        chunk_delay: 5
+        rules:
+          - match: "429"
+            error: {status: 429, message: "Rate limit reached"}
+          - match: "400"
+            error: {status: 400}
+          - match: "disconnect"
+            reply: "abcdef"
+            error: {after_chunks: 2}
+`})
+
+	t.Run("限流", func(t *testing.T) {
+		_, err := CreateChatCompletion(context.Background(), mockChatRequest("429", false), nil)
+		var upstreamErr *UpstreamError
+		if assert.ErrorAs(t, err, &upstreamErr) {
+			assert.Equal(t, ErrorKindRateLimit, upstreamErr.Kind)
+			assert.Equal(t, "mock-errors", upstreamErr.Credential)
+			assert.Equal(t, 3, upstreamErr.Attempts)
+		}
+		assert.Contains(t, err.Error(), "Rate limit reached")
+	})
+
+	t.Run("不可重试的错误", func(t *testing.T) {
+		_, err := CreateChatCompletion(context.Background(), mockChatRequest("400", true), &bytes.Buffer{})
+		var upstreamErr *UpstreamError
+		if assert.ErrorAs(t, err, &upstreamErr) {
+			assert.Equal(t, 1, upstreamErr.Attempts)
+		}
+	})
+
+	t.Run("流式中途断开", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		_, err := CreateChatCompletion(context.Background(), mockChatRequest("disconnect", true), buffer)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, []string{"ab", "cd"}, streamContents(t, buffer.String()))
+		assert.NotContains(t, buffer.String(), "[DONE]")
+
+		// 非流式请求直接返回网络错误
+		_, err = CreateChatCompletion(context.Background(), mockChatRequest("disconnect", false), nil)
+		assert.Equal(t, ErrorKindNetwork, ClassifyError(err))
+	})
+
+	t.Run("取消请求", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Millisecond)
+		defer cancel()
+		_, err := CreateChatCompletion(ctx, mockChatRequest(strings.Repeat("长回复", 100), true), &bytes.Buffer{})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// TestMockProviderFailover 测试凭证返回服务端错误时切换到其他模拟凭证
+func TestMockProviderFailover(t *testing.T) {
+	// 熔断后不会再选择 mock-down，这里只测试单次请求内的切换
+	policy := testRetryPolicy()
+	policy.BreakerThreshold = 100
+	withRetryPolicy(t, policy)
+	useTestCredentials(t, map[string]string{"mock.yaml": `environments:
+  {{env}}:
+    credentials:
+      - name: mock-down
+        enabled: true
+        weight: 1
+        rules:
+          - error: {status: 500}
+      - name: mock-up
+        enabled: true
+        weight: 0
+        rules:
+          - reply: "ok"
+`})
+
+	for _, stream := range []bool{false, true} {
+		ctx, info := WithCallInfo(context.Background())
+		buffer := &bytes.Buffer{}
+		_, err := CreateChatCompletion(ctx, mockChatRequest("hi", stream), buffer)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, "mock-up", info.Credential)
+		assert.Equal(t, 2, info.Attempts)
+	}
+}
+
+// TestMockFixtureDir 规则文件的相对路径相对于加载凭证的配置目录，而不是 LLMConfigPath
+func TestMockFixtureDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"mock.yaml":  "environments:\n  " + currentEnv() + ":\n    credentials:\n      - name: mock\n        enabled: true\n        fixture: rules.yaml\n",
+		"rules.yaml": "rules:\n  - reply: ok\n",
+	}
+	for name, content := range files {
+		if !assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600)) {
+			return
+		}
+	}
+	old := LLMConfigPath
+	LLMConfigPath = t.TempDir()
+	t.Cleanup(func() { LLMConfigPath = old })
+
+	var calls int32
+	creds, err := credentialsFrom[MockCredential](NewCredentialStore(dir, testDecrypt(&calls)), "mock")
+	if assert.NoError(t, err) && assert.Len(t, creds, 1) {
+		assert.Equal(t, filepath.Join(dir, "rules.yaml"), creds[0].Fixture)
+		assert.Len(t, creds[0].Rules, 1)
+	}
+	assert.Empty(t, ValidateCredentials(dir, nil, "", testDecrypt(&calls)))
+}
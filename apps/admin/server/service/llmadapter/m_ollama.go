@@ -0,0 +1,107 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	einoopenai "github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino-ext/libs/acl/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// defaultOllamaHost Ollama服务的默认地址
+const defaultOllamaHost = "http://localhost:11434"
+
+// OllamaCredential 定义本地部署的Ollama服务的凭证配置结构
+// Ollama通过 /v1 提供OpenAI兼容接口，流式、非流式和工具调用都通过该接口完成
+type OllamaCredential struct {
+	CredentialBase   `yaml:",inline"` // 名称、启用、权重、QPS限制、描述、模型列表
+	Host             string           `yaml:"host"`    // Ollama服务地址，默认 http://localhost:11434
+	APIKey           string           `yaml:"api_key"` // 访问密钥，Ollama前有鉴权网关时使用，可选
+	Format           string           `yaml:"format"`  // 响应格式，json表示要求模型输出JSON，可选
+	HTTPClientConfig `yaml:",inline"` // HTTP客户端设置(超时、代理、TLS、连接池)
+}
+
+// decrypted 返回解密敏感字段后的凭证副本，未配置访问密钥时不解密
+func (c OllamaCredential) decrypted(decrypt DecryptFunc) (OllamaCredential, error) {
+	if c.APIKey == "" {
+		return c, nil
+	}
+	var err error
+	if c.APIKey, err = decrypt(c.APIKey); err != nil {
+		return c, fmt.Errorf("解密API密钥失败: %v", err)
+	}
+	return c, nil
+}
+
+// getOllamaConfig 获取Ollama配置
+func (c *Config) getOllamaConfig(ctx context.Context) (*einoopenai.ChatModelConfig, error) {
+	// 从凭证缓存中获取当前环境启用的凭证(已解密)
+	creds, err := enabledCredentials[OllamaCredential](ctx, "ollama")
+	if err != nil {
+		return nil, err
+	}
+
+	// 在支持请求模型且仍有QPS余量的凭证中按权重选择
+	selectedCred, err := selectCredential(ctx, "ollama", c.Model, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	// 请求级别的配置优先于凭证配置
+	host, format := selectedCred.Host, selectedCred.Format
+	if c.VendorOptional != nil && c.VendorOptional.OllamaConfig != nil {
+		if c.VendorOptional.OllamaConfig.Host != "" {
+			host = c.VendorOptional.OllamaConfig.Host
+		}
+		if c.VendorOptional.OllamaConfig.Format != "" {
+			format = c.VendorOptional.OllamaConfig.Format
+		}
+	}
+
+	ollamaConf, err := c.openAICompatibleConfig("ollama", selectedCred.Name, ollamaBaseURL(host), selectedCred.APIKey, selectedCred.HTTPClientConfig)
+	if err != nil {
+		return nil, err
+	}
+	// 请求设置了 response_format 时以请求为准
+	if format == "json" && ollamaConf.ResponseFormat == nil {
+		ollamaConf.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+	return ollamaConf, nil
+}
+
+// ollamaBaseURL 返回Ollama的OpenAI兼容接口地址，host 未包含 /v1 时自动补全
+func ollamaBaseURL(host string) string {
+	if host == "" {
+		host = defaultOllamaHost
+	}
+	host = strings.TrimRight(host, "/")
+	if !strings.HasSuffix(host, "/v1") {
+		host += "/v1"
+	}
+	return host
+}
+
+func init() {
+	registerCredentials[OllamaCredential]("ollama", "ollama.yaml", "Ollama")
+	Register("ollama", func() Provider {
+		return &einoProvider{vendor: "ollama", newChatModel: newOllamaChatModel, params: openAIParams}
+	})
+}
+
+// newOllamaChatModel 根据配置创建Ollama聊天模型
+func newOllamaChatModel(ctx context.Context, conf *Config) (model.ChatModel, error) {
+	// 获取Ollama配置
+	ollamaConf, err := conf.getOllamaConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取Ollama配置失败: %w", err)
+	}
+
+	// 创建聊天模型
+	chatModel, err := einoopenai.NewChatModel(ctx, ollamaConf)
+	if err != nil {
+		return nil, fmt.Errorf("创建聊天模型失败: %v", err)
+	}
+	return chatModel, nil
+}
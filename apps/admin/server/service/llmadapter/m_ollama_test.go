@@ -0,0 +1,39 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOllamaBaseURL 测试Ollama地址补全
+func TestOllamaBaseURL(t *testing.T) {
+	assert.Equal(t, "http://localhost:11434/v1", ollamaBaseURL(""))
+	assert.Equal(t, "http://10.0.0.11:11434/v1", ollamaBaseURL("http://10.0.0.11:11434/"))
+	assert.Equal(t, "http://10.0.0.11:11434/v1", ollamaBaseURL("http://10.0.0.11:11434/v1"))
+}
+
+// TestOllamaProvider 测试Ollama的流式、非流式和工具调用，未配置访问密钥时不解密
+func TestOllamaProvider(t *testing.T) {
+	server := newOpenAITestServer(t)
+	useTestCredentials(t, map[string]string{"ollama.yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: local
+        host: %s
+        format: json
+        enabled: true
+        weight: 1
+        models: ["qwen2.5:7b"]
+`, server.URL)})
+
+	assertOpenAICompatibleChat(t, server, "ollama", "qwen2.5:7b")
+	assertToolHistoryForwarded(t, server, "ollama", "qwen2.5:7b")
+	assert.Equal(t, map[string]any{"type": "json_object"}, server.lastRequest(t).Body["response_format"])
+
+	// 未部署的模型没有可用凭证
+	_, err := CreateChatCompletion(context.Background(), weatherToolRequest("ollama", "llama3.1:8b"), nil)
+	assert.ErrorIs(t, err, ErrNoCapacity)
+}
@@ -0,0 +1,103 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	einoopenai "github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// OpenAICompatibleCredential 定义OpenAI兼容接口(vLLM、llama.cpp server、LocalAI等自建服务)的凭证配置结构
+// 不同的后端通过多个凭证配置，并用 models 区分各自部署的模型
+type OpenAICompatibleCredential struct {
+	CredentialBase   `yaml:",inline"` // 名称、启用、权重、QPS限制、描述、模型列表
+	BaseURL          string           `yaml:"base_url"` // 接口基础URL，必填，例如 http://10.0.0.8:8000/v1
+	APIKey           string           `yaml:"api_key"`  // 访问密钥，服务未开启鉴权时可留空
+	HTTPClientConfig `yaml:",inline"` // HTTP客户端设置(超时、代理、TLS、连接池)
+}
+
+// decrypted 返回解密敏感字段后的凭证副本，未配置访问密钥时不解密
+func (c OpenAICompatibleCredential) decrypted(decrypt DecryptFunc) (OpenAICompatibleCredential, error) {
+	if c.BaseURL == "" {
+		return c, fmt.Errorf("凭证 %s 未配置base_url", c.Name)
+	}
+	if c.APIKey == "" {
+		return c, nil
+	}
+	var err error
+	if c.APIKey, err = decrypt(c.APIKey); err != nil {
+		return c, fmt.Errorf("解密API密钥失败: %v", err)
+	}
+	return c, nil
+}
+
+// getOpenAICompatibleConfig 获取OpenAI兼容接口的配置
+func (c *Config) getOpenAICompatibleConfig(ctx context.Context) (*einoopenai.ChatModelConfig, error) {
+	// 从凭证缓存中获取当前环境启用的凭证(已解密)
+	creds, err := enabledCredentials[OpenAICompatibleCredential](ctx, "openai_compatible")
+	if err != nil {
+		return nil, err
+	}
+
+	// 在支持请求模型且仍有QPS余量的凭证中按权重选择
+	selectedCred, err := selectCredential(ctx, "openai_compatible", c.Model, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.openAICompatibleConfig("openai_compatible", selectedCred.Name, selectedCred.BaseURL, selectedCred.APIKey, selectedCred.HTTPClientConfig)
+}
+
+// openAICompatibleConfig 为通过OpenAI兼容接口调用的供应商(Ollama、通义千问、千帆、火山方舟等)创建模型配置
+// 设置选中凭证的超时时间，并使用凭证独立缓存的HTTP客户端(代理、超时、TLS、连接池)
+func (c *Config) openAICompatibleConfig(vendor, credential, baseURL, apiKey string, httpConf HTTPClientConfig) (*einoopenai.ChatModelConfig, error) {
+	// 凭证配置的超时时间作为单次请求的截止时间
+	c.Timeout = time.Duration(httpConf.Timeout) * time.Second
+
+	httpClient, err := credentialHTTPClient(vendor, credential, httpConf)
+	if err != nil {
+		return nil, err
+	}
+	c.ProxyURL = httpConf.Proxy
+
+	conf := &einoopenai.ChatModelConfig{
+		APIKey:      apiKey,
+		BaseURL:     baseURL,
+		Model:       c.Model,
+		MaxTokens:   &c.MaxTokens,
+		Temperature: c.Temperature,
+		TopP:        c.TopP,
+		Stop:        c.Stop,
+		HTTPClient:  httpClient,
+	}
+	// 请求中的标准参数(seed、惩罚系数、logit_bias、response_format)按OpenAI接口原样传递
+	if err := c.applyOpenAIParams(conf); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func init() {
+	registerCredentials[OpenAICompatibleCredential]("openai_compatible", "openai_compatible.yaml", "OpenAI兼容接口")
+	Register("openai_compatible", func() Provider {
+		return &einoProvider{vendor: "openai_compatible", newChatModel: newOpenAICompatibleChatModel, params: openAIParams}
+	})
+}
+
+// newOpenAICompatibleChatModel 根据配置创建OpenAI兼容接口的聊天模型
+func newOpenAICompatibleChatModel(ctx context.Context, conf *Config) (model.ChatModel, error) {
+	// 获取OpenAI兼容接口配置
+	compatibleConf, err := conf.getOpenAICompatibleConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取OpenAI兼容接口配置失败: %w", err)
+	}
+
+	// 创建聊天模型
+	chatModel, err := einoopenai.NewChatModel(ctx, compatibleConf)
+	if err != nil {
+		return nil, fmt.Errorf("创建聊天模型失败: %v", err)
+	}
+	return chatModel, nil
+}
@@ -0,0 +1,374 @@
+package llmadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	einoopenai "github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// useTestCredentials 将凭证配置写入临时目录并在测试期间作为配置目录
+// 配置内容中的 {{env}} 替换为当前环境
+func useTestCredentials(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		content = strings.ReplaceAll(content, "{{env}}", currentEnv())
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("写入配置文件失败: %v", err)
+		}
+	}
+
+	originalConfigPath := LLMConfigPath
+	t.Cleanup(func() { LLMConfigPath = originalConfigPath })
+	LLMConfigPath = dir
+}
+
+// encryptTestKey 使用RSA公钥加密测试用的API密钥
+func encryptTestKey(t *testing.T, key string) string {
+	t.Helper()
+
+	encrypt, _, err := InitRSAKeyManager()
+	if err != nil {
+		t.Fatalf("初始化RSA密钥管理器失败: %v", err)
+	}
+	encrypted, err := encrypt(key)
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	return encrypted
+}
+
+// openAITestServer 模拟OpenAI兼容的 /chat/completions 接口
+// 非流式请求返回一个工具调用，流式请求按SSE格式返回文本分片
+type openAITestServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []openAITestRequest
+}
+
+// openAITestRequest 模拟接口收到的请求
+type openAITestRequest struct {
+	Path          string
+	Authorization string
+	Body          map[string]any
+}
+
+func newOpenAITestServer(t *testing.T) *openAITestServer {
+	t.Helper()
+
+	s := &openAITestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, openAITestRequest{Path: r.URL.Path, Authorization: r.Header.Get("Authorization"), Body: body})
+		s.mu.Unlock()
+
+		model, _ := body["model"].(string)
+		if stream, _ := body["stream"].(bool); stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i, content := range []string{"你", "好"} {
+				chunk := fmt.Sprintf(`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":%q,"choices":[{"index":0,"delta":{"role":"assistant","content":%q},"finish_reason":null}]}`, model, content)
+				if i == 1 {
+					chunk = strings.Replace(chunk, `"finish_reason":null`, `"finish_reason":"stop"`, 1)
+				}
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+			}
+			// 请求了 include_usage 时最后返回一个只包含用量的分片
+			if options, _ := body["stream_options"].(map[string]any); options["include_usage"] == true {
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", fmt.Sprintf(`{"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":%q,"choices":[],"usage":{"prompt_tokens":9,"completion_tokens":2,"total_tokens":11}}`, model))
+			}
+			_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{
+			"id": "chatcmpl-1", "object": "chat.completion", "created": 1, "model": %q,
+			"choices": [{"index": 0, "finish_reason": "tool_calls", "message": {
+				"role": "assistant", "content": "",
+				"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"北京\"}"}}]
+			}}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 6, "total_tokens": 18}
+		}`, model)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// lastRequest 返回最近一次收到的请求
+func (s *openAITestServer) lastRequest(t *testing.T) openAITestRequest {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		t.Fatal("模拟接口没有收到请求")
+	}
+	return s.requests[len(s.requests)-1]
+}
+
+// weatherToolRequest 构造带工具定义的测试请求
+func weatherToolRequest(provider, model string) ChatRequest {
+	return ChatRequest{Provider: provider, ChatCompletionRequest: openai.ChatCompletionRequest{
+		Model:    model,
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "北京天气怎么样"}},
+		Tools: []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+			Name:        "get_weather",
+			Description: "查询城市天气",
+			Parameters: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string"}},
+				"required":   []any{"city"},
+			},
+		}}},
+	}}
+}
+
+// assertOpenAICompatibleChat 通过统一入口分别发起非流式和流式请求，校验工具调用和流式分片
+func assertOpenAICompatibleChat(t *testing.T, server *openAITestServer, provider, model string) {
+	t.Helper()
+
+	resp, err := CreateChatCompletion(context.Background(), weatherToolRequest(provider, model), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	request := server.lastRequest(t)
+	assert.Equal(t, "/v1/chat/completions", request.Path)
+	assert.Equal(t, model, request.Body["model"])
+	assert.Contains(t, fmt.Sprint(request.Body["tools"]), "get_weather")
+
+	assert.Equal(t, model, resp.Model)
+	assert.Equal(t, openai.FinishReasonToolCalls, resp.Choices[0].FinishReason)
+	if assert.Len(t, resp.Choices[0].Message.ToolCalls, 1) {
+		assert.Equal(t, "get_weather", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"city":"北京"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	}
+	assert.Equal(t, 18, resp.Usage.TotalTokens)
+
+	req := weatherToolRequest(provider, model)
+	req.Stream = true
+	buffer := &bytes.Buffer{}
+	_, err = CreateChatCompletion(context.Background(), req, buffer)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, true, server.lastRequest(t).Body["stream"])
+	output := buffer.String()
+	assert.Contains(t, output, `"content":"你"`)
+	assert.Contains(t, output, `"content":"好"`)
+	assert.Contains(t, output, `"finish_reason":"stop"`)
+	assert.True(t, strings.HasSuffix(output, "data: [DONE]\n\n"))
+}
+
+// assertToolHistoryForwarded 发送包含图片、工具调用和工具结果的对话历史，校验上游请求完整保留这些内容
+func assertToolHistoryForwarded(t *testing.T, server *openAITestServer, provider, model string) {
+	t.Helper()
+
+	req := weatherToolRequest(provider, model)
+	req.Messages = toolHistoryMessages()
+	_, err := CreateChatCompletion(context.Background(), req, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var messages []openai.ChatCompletionMessage
+	data, _ := json.Marshal(server.lastRequest(t).Body["messages"])
+	if !assert.NoError(t, json.Unmarshal(data, &messages)) || !assert.Len(t, messages, 4) {
+		return
+	}
+	assert.Equal(t, "alice", messages[1].Name)
+	assert.Equal(t, toolHistoryMessages()[1].MultiContent, messages[1].MultiContent)
+	if assert.Len(t, messages[2].ToolCalls, 1) {
+		assert.Equal(t, "call_1", messages[2].ToolCalls[0].ID)
+		assert.Equal(t, "get_weather", messages[2].ToolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"city":"北京"}`, messages[2].ToolCalls[0].Function.Arguments)
+	}
+	assert.Equal(t, openai.ChatMessageRoleTool, messages[3].Role)
+	assert.Equal(t, "call_1", messages[3].ToolCallID)
+	assert.Equal(t, `{"weather":"晴"}`, messages[3].Content)
+}
+
+// TestOpenAICompatibleProvider 测试OpenAI兼容接口的流式、非流式和工具调用
+func TestOpenAICompatibleProvider(t *testing.T) {
+	vllm := newOpenAITestServer(t)
+	llamacpp := newOpenAITestServer(t)
+	useTestCredentials(t, map[string]string{"openai_compatible.yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: vllm
+        base_url: %s/v1
+        api_key: "%s"
+        enabled: true
+        weight: 1
+        models: ["Qwen/Qwen2.5-7B-Instruct"]
+      - name: llamacpp
+        base_url: %s/v1
+        enabled: true
+        weight: 1
+        models: ["llama-3.1-8b-instruct"]
+`, vllm.URL, encryptTestKey(t, "vllm-key"), llamacpp.URL)})
+
+	// 按模型路由到对应的后端
+	assertOpenAICompatibleChat(t, vllm, "openai_compatible", "Qwen/Qwen2.5-7B-Instruct")
+	assert.Equal(t, "Bearer vllm-key", vllm.lastRequest(t).Authorization)
+
+	assertOpenAICompatibleChat(t, llamacpp, "openai_compatible", "llama-3.1-8b-instruct")
+	assertToolHistoryForwarded(t, llamacpp, "openai_compatible", "llama-3.1-8b-instruct")
+}
+
+// TestOpenAICompatibleVendors 测试通过OpenAI兼容模式调用的供应商：流式、非流式、工具调用，以及未配置base_url时的接口地址
+func TestOpenAICompatibleVendors(t *testing.T) {
+	tests := []struct {
+		vendor  string
+		model   string
+		region  string          // 凭证配置的地域，为空时不配置
+		options *VendorOptional // 请求的 vendor_options
+		baseURL string          // 未配置base_url时使用的接口地址
+		config  func(*Config, context.Context) (*einoopenai.ChatModelConfig, error)
+	}{
+		{vendor: "qwen", model: "qwen-plus", baseURL: defaultQwenBaseURL, config: (*Config).getQwenConfig},
+		{vendor: "qianfan", model: "ernie-4.0-8k", baseURL: defaultQianFanBaseURL, config: (*Config).getQianFanConfig},
+		// 火山方舟的模型名称使用推理接入点ID，接口地址按地域拼接，请求级别的地域优先
+		{vendor: "ark", model: "ep-20250101000000-abcde", baseURL: "https://ark.cn-beijing.volces.com/api/v3", config: (*Config).getArkConfig},
+		{vendor: "ark", model: "ep-20250101000000-abcde", region: "cn-shanghai", baseURL: "https://ark.cn-shanghai.volces.com/api/v3", config: (*Config).getArkConfig},
+		{
+			vendor: "ark", model: "ep-20250101000000-abcde", region: "cn-shanghai",
+			options: &VendorOptional{ArkConfig: &ArkConfig{Region: "ap-southeast-1"}},
+			baseURL: "https://ark.ap-southeast-1.volces.com/api/v3", config: (*Config).getArkConfig,
+		},
+	}
+	for _, tt := range tests {
+		name := tt.vendor
+		if tt.region != "" {
+			name += "/" + tt.region
+		}
+		if tt.options != nil {
+			name += "/vendor_options"
+		}
+		t.Run(name, func(t *testing.T) {
+			region := ""
+			if tt.region != "" {
+				region = "        region: " + tt.region + "\n"
+			}
+			apiKey := encryptTestKey(t, tt.vendor+"-key")
+
+			server := newOpenAITestServer(t)
+			useTestCredentials(t, map[string]string{tt.vendor + ".yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: mock-%s
+        api_key: "%s"
+        base_url: %s/v1
+        enabled: true
+        weight: 1
+        models: ["%s"]
+%s`, tt.vendor, apiKey, server.URL, tt.model, region)})
+
+			assertOpenAICompatibleChat(t, server, tt.vendor, tt.model)
+			assertToolHistoryForwarded(t, server, tt.vendor, tt.model)
+			assert.Equal(t, "Bearer "+tt.vendor+"-key", server.lastRequest(t).Authorization)
+
+			useTestCredentials(t, map[string]string{tt.vendor + ".yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: default-url
+        api_key: "%s"
+        enabled: true
+        weight: 1
+        timeout: 30
+%s`, apiKey, region)})
+
+			conf := &Config{Vendor: tt.vendor, Model: tt.model, VendorOptional: tt.options}
+			modelConf, err := tt.config(conf, context.Background())
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.baseURL, modelConf.BaseURL)
+			assert.Equal(t, tt.vendor+"-key", modelConf.APIKey)
+			assert.Equal(t, tt.model, modelConf.Model)
+			assert.Equal(t, 30, int(conf.Timeout.Seconds()))
+		})
+	}
+}
+
+// TestCallInfoUsage 测试通过 CallInfo 获取最终使用的凭证、响应ID和Token用量
+func TestCallInfoUsage(t *testing.T) {
+	server := newOpenAITestServer(t)
+	useTestCredentials(t, map[string]string{"openai_compatible.yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: vllm
+        base_url: %s/v1
+        enabled: true
+        weight: 1
+`, server.URL)})
+
+	ctx, info := WithCallInfo(context.Background())
+	resp, err := CreateChatCompletion(ctx, weatherToolRequest("openai_compatible", "qwen2.5"), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "vllm", info.Credential)
+	assert.Equal(t, resp.ID, info.ResponseID)
+	assert.Equal(t, 18, info.Usage.TotalTokens)
+	assert.Zero(t, info.TimeToFirstToken)
+
+	req := weatherToolRequest("openai_compatible", "qwen2.5")
+	req.Stream = true
+	ctx, info = WithCallInfo(context.Background())
+	_, err = CreateChatCompletion(ctx, req, &bytes.Buffer{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, strings.HasPrefix(info.ResponseID, "openai_compatible-stream-"))
+	assert.Equal(t, openai.Usage{PromptTokens: 9, CompletionTokens: 2, TotalTokens: 11}, info.Usage)
+	assert.Positive(t, info.TimeToFirstToken)
+}
+
+// TestMergeUsage 测试分多次返回的Token用量的合并
+func TestMergeUsage(t *testing.T) {
+	usage := mergeUsage(openai.Usage{}, openai.Usage{PromptTokens: 20})
+	usage = mergeUsage(usage, openai.Usage{CompletionTokens: 3})
+	usage = mergeUsage(usage, openai.Usage{CompletionTokens: 8})
+	assert.Equal(t, openai.Usage{PromptTokens: 20, CompletionTokens: 8, TotalTokens: 28}, usage)
+
+	// 缓存命中的Token数同样取最大值
+	usage = mergeUsage(usage, openai.Usage{PromptTokens: 20, PromptTokensDetails: &openai.PromptTokensDetails{CachedTokens: 16}})
+	usage = mergeUsage(usage, openai.Usage{CompletionTokens: 9})
+	if assert.NotNil(t, usage.PromptTokensDetails) {
+		assert.Equal(t, 16, usage.PromptTokensDetails.CachedTokens)
+	}
+}
+
+// TestOpenAICompatibleMissingBaseURL 未配置base_url的凭证拒绝加载
+func TestOpenAICompatibleMissingBaseURL(t *testing.T) {
+	useTestCredentials(t, map[string]string{"openai_compatible.yaml": `environments:
+  {{env}}:
+    credentials:
+      - name: no-url
+        enabled: true
+        weight: 1
+`})
+
+	_, err := CreateChatCompletion(context.Background(), weatherToolRequest("openai_compatible", "any"), nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "未配置base_url")
+	}
+}
@@ -18,9 +18,13 @@ package llmadapter
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"github.com/sashabaranov/go-openai"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -75,7 +79,7 @@ func TestGetOpenAIConfig(t *testing.T) {
 
 		// 由于配置文件可能不存在，使用Mock替代或跳过实际调用
 		// 这里仅测试函数调用不会崩溃
-		_, err := conf.getOpenAIConfig()
+		_, err := conf.getOpenAIConfig(context.Background())
 		if err != nil {
 			// 如果配置文件不存在，会返回错误，这里只是确保函数能被调用
 			t.Logf("获取配置返回错误(可能是配置文件不存在): %v", err)
@@ -87,13 +91,13 @@ func TestGetOpenAIConfig(t *testing.T) {
 func TestOpenAICreateChatCompletion(t *testing.T) {
 	t.Run("测试创建聊天完成请求", func(t *testing.T) {
 		// 准备测试请求
-		req := ChatCompletionRequest{
+		req := openai.ChatCompletionRequest{
 			Model:       "gpt-3.5-turbo",
 			MaxTokens:   1000,
 			Temperature: 0.7,
 			TopP:        0.9,
 			Stop:        []string{"stop"},
-			Messages: []ChatMessage{
+			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    "user",
 					Content: "你好，OpenAI!",
@@ -110,7 +114,7 @@ func TestOpenAICreateChatCompletion(t *testing.T) {
 		t.Log("【开始调用】OpenAICreateChatCompletion...")
 
 		// 调用OpenAI API
-		resp, err := OpenAICreateChatCompletion(req)
+		resp, err := mustGetProvider(t, "openai").Generate(context.Background(), ChatRequest{ChatCompletionRequest: req})
 
 		// 如果有错误
 		if err != nil {
@@ -150,13 +154,15 @@ func TestOpenAICreateChatCompletionToChat(t *testing.T) {
 	t.Run("测试格式转换聊天请求", func(t *testing.T) {
 		// 准备测试请求
 		req := ChatRequest{
-			Model:       "gpt-3.5-turbo",
-			MaxTokens:   1000,
-			Temperature: 0.7,
-			Messages: []ChatMessage{
-				{
-					Role:    "user",
-					Content: "你好，OpenAI!",
+			ChatCompletionRequest: openai.ChatCompletionRequest{
+				Model:       "gpt-3.5-turbo",
+				MaxTokens:   1000,
+				Temperature: 0.7,
+				Messages: []openai.ChatCompletionMessage{
+					{
+						Role:    "user",
+						Content: "你好，OpenAI!",
+					},
 				},
 			},
 		}
@@ -170,7 +176,7 @@ func TestOpenAICreateChatCompletionToChat(t *testing.T) {
 		t.Log("【开始转换调用】OpenAICreateChatCompletionToChat...")
 
 		// 调用格式转换的OpenAI API
-		resp, err := OpenAICreateChatCompletionToChat(req)
+		resp, err := mustGetProvider(t, "openai").Generate(context.Background(), req)
 
 		// 如果有错误
 		if err != nil {
@@ -199,14 +205,14 @@ func TestOpenAICreateChatCompletionToChat(t *testing.T) {
 func TestOpenAIStreamChatCompletion(t *testing.T) {
 	t.Run("测试创建流式聊天完成请求", func(t *testing.T) {
 		// 准备测试请求
-		req := ChatCompletionRequest{
+		req := openai.ChatCompletionRequest{
 			Model:       "gpt-3.5-turbo",
 			MaxTokens:   1000,
 			Temperature: 0.7,
 			TopP:        0.9,
 			Stop:        []string{"stop"},
 			Stream:      true,
-			Messages: []ChatMessage{
+			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    "user",
 					Content: "你好，OpenAI!",
@@ -223,7 +229,7 @@ func TestOpenAIStreamChatCompletion(t *testing.T) {
 		t.Log("【开始流式调用】OpenAIStreamChatCompletion...")
 
 		// 调用OpenAI流式API
-		streamReader, err := OpenAIStreamChatCompletion(req)
+		streamReader, err := mustGetProvider(t, "openai").Stream(context.Background(), ChatRequest{ChatCompletionRequest: req})
 
 		// 如果有错误
 		if err != nil {
@@ -292,14 +298,16 @@ func TestOpenAIStreamChatCompletionToChat(t *testing.T) {
 	t.Run("测试流式聊天完成转换为聊天流格式", func(t *testing.T) {
 		// 准备测试请求
 		req := ChatRequest{
-			Model:       "gpt-3.5-turbo",
-			MaxTokens:   1000,
-			Temperature: 0.7,
-			Stream:      true,
-			Messages: []ChatMessage{
-				{
-					Role:    "user",
-					Content: "你好，OpenAI!",
+			ChatCompletionRequest: openai.ChatCompletionRequest{
+				Model:       "gpt-3.5-turbo",
+				MaxTokens:   1000,
+				Temperature: 0.7,
+				Stream:      true,
+				Messages: []openai.ChatCompletionMessage{
+					{
+						Role:    "user",
+						Content: "你好，OpenAI!",
+					},
 				},
 			},
 		}
@@ -316,7 +324,7 @@ func TestOpenAIStreamChatCompletionToChat(t *testing.T) {
 		t.Log("【开始流式转换调用】OpenAIStreamChatCompletionToChat...")
 
 		// 调用流式转换API
-		err := OpenAIStreamChatCompletionToChat(req, &buf)
+		err := streamToWriter(t, "openai", req, &buf)
 
 		// 如果有错误
 		if err != nil {
@@ -388,22 +396,19 @@ environments:
           - gpt-3.5-turbo
         timeout: 30
 `
-	tempConfigDir := LLMConfigPath
-	err := os.MkdirAll(tempConfigDir, 0755)
-	if err != nil {
-		t.Fatalf("创建临时配置目录失败: %v", err)
-	}
+	// 使用临时配置目录，避免覆盖真实的配置文件
+	tempConfigDir := t.TempDir()
+	originalConfigPath := LLMConfigPath
+	LLMConfigPath = tempConfigDir
+	defer func() {
+		LLMConfigPath = originalConfigPath
+	}()
 
-	tempConfigFile := tempConfigDir + "/openai.yaml"
-	err = os.WriteFile(tempConfigFile, []byte(tempConfigContent), 0644)
+	tempConfigFile := filepath.Join(tempConfigDir, "openai.yaml")
+	err := os.WriteFile(tempConfigFile, []byte(tempConfigContent), 0644)
 	if err != nil {
 		t.Fatalf("创建临时配置文件失败: %v", err)
 	}
-	defer func() {
-		// 测试完成后删除临时文件
-		os.Remove(tempConfigFile)
-		os.Remove(tempConfigDir)
-	}()
 
 	// 设置测试环境变量
 	originalEnv := os.Getenv("ENV")
@@ -432,7 +437,7 @@ environments:
 	// 测试获取配置
 	t.Run("使用Mock配置测试获取OpenAI配置", func(t *testing.T) {
 		// 获取配置
-		openaiConf, err := conf.getOpenAIConfig()
+		openaiConf, err := conf.getOpenAIConfig(context.Background())
 
 		// 因为我们使用的是mock的api_key，实际解密会失败
 		// 所以这里我们只检查函数调用过程，不期望成功获取配置
@@ -476,3 +481,19 @@ func mockOpenAIStreamReceive() []*mockOpenAIMessage {
 		},
 	}
 }
+
+// TestOpenAIToolHistory 测试OpenAI完整转发图片、工具调用和工具结果
+func TestOpenAIToolHistory(t *testing.T) {
+	server := newOpenAITestServer(t)
+	useTestCredentials(t, map[string]string{"openai.yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: mock-openai
+        api_key: "%s"
+        base_url: %s/v1
+        enabled: true
+        weight: 1
+`, encryptTestKey(t, "openai-key"), server.URL)})
+
+	assertToolHistoryForwarded(t, server, "openai", "gpt-4o")
+}
@@ -0,0 +1,78 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+
+	einoopenai "github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// defaultQianFanBaseURL 百度千帆ModelBuilder V2的OpenAI兼容接口地址
+const defaultQianFanBaseURL = "https://qianfan.baidubce.com/v2"
+
+// QianFanCredential 定义百度千帆(文心一言)的凭证配置结构
+// 使用千帆V2接口，API Key(bce-v3/...)以Bearer方式鉴权，请求和响应格式与OpenAI一致
+type QianFanCredential struct {
+	CredentialBase   `yaml:",inline"` // 名称、启用、权重、QPS限制、描述、模型列表
+	APIKey           string           `yaml:"api_key"`  // 千帆API Key
+	BaseURL          string           `yaml:"base_url"` // 接口基础URL，默认 https://qianfan.baidubce.com/v2
+	HTTPClientConfig `yaml:",inline"` // HTTP客户端设置(超时、代理、TLS、连接池)
+}
+
+// decrypted 返回解密敏感字段后的凭证副本
+func (c QianFanCredential) decrypted(decrypt DecryptFunc) (QianFanCredential, error) {
+	var err error
+	if c.APIKey, err = decrypt(c.APIKey); err != nil {
+		return c, fmt.Errorf("解密API密钥失败: %v", err)
+	}
+	return c, nil
+}
+
+// getQianFanConfig 获取千帆配置
+func (c *Config) getQianFanConfig(ctx context.Context) (*einoopenai.ChatModelConfig, error) {
+	// 从凭证缓存中获取当前环境启用的凭证(已解密)
+	creds, err := enabledCredentials[QianFanCredential](ctx, "qianfan")
+	if err != nil {
+		return nil, err
+	}
+
+	// 在支持请求模型且仍有QPS余量的凭证中按权重选择
+	selectedCred, err := selectCredential(ctx, "qianfan", c.Model, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	// 请求级别指定的服务地址优先于凭证配置
+	baseURL := defaultQianFanBaseURL
+	if selectedCred.BaseURL != "" {
+		baseURL = selectedCred.BaseURL
+	}
+	if c.VendorOptional != nil && c.VendorOptional.QianFanConfig != nil && c.VendorOptional.QianFanConfig.Endpoint != "" {
+		baseURL = c.VendorOptional.QianFanConfig.Endpoint
+	}
+	return c.openAICompatibleConfig("qianfan", selectedCred.Name, baseURL, selectedCred.APIKey, selectedCred.HTTPClientConfig)
+}
+
+func init() {
+	registerCredentials[QianFanCredential]("qianfan", "qianfan.yaml", "千帆")
+	Register("qianfan", func() Provider {
+		return &einoProvider{vendor: "qianfan", newChatModel: newQianFanChatModel, params: openAIParams}
+	})
+}
+
+// newQianFanChatModel 根据配置创建千帆聊天模型
+func newQianFanChatModel(ctx context.Context, conf *Config) (model.ChatModel, error) {
+	// 获取千帆配置
+	qianfanConf, err := conf.getQianFanConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取千帆配置失败: %w", err)
+	}
+
+	// 创建聊天模型
+	chatModel, err := einoopenai.NewChatModel(ctx, qianfanConf)
+	if err != nil {
+		return nil, fmt.Errorf("创建聊天模型失败: %v", err)
+	}
+	return chatModel, nil
+}
@@ -0,0 +1,74 @@
+package llmadapter
+
+import (
+	"context"
+	"fmt"
+
+	einoopenai "github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// defaultQwenBaseURL 阿里云百炼(DashScope)的OpenAI兼容接口地址
+const defaultQwenBaseURL = "https://dashscope.aliyuncs.com/compatible-mode/v1"
+
+// QwenCredential 定义阿里通义千问(DashScope)的凭证配置结构
+// 通过DashScope的OpenAI兼容模式调用，流式、非流式和工具调用与OpenAI一致
+type QwenCredential struct {
+	CredentialBase   `yaml:",inline"` // 名称、启用、权重、QPS限制、描述、模型列表
+	APIKey           string           `yaml:"api_key"`  // DashScope API密钥
+	BaseURL          string           `yaml:"base_url"` // 接口基础URL，默认为北京地域，国际站使用 https://dashscope-intl.aliyuncs.com/compatible-mode/v1
+	HTTPClientConfig `yaml:",inline"` // HTTP客户端设置(超时、代理、TLS、连接池)
+}
+
+// decrypted 返回解密敏感字段后的凭证副本
+func (c QwenCredential) decrypted(decrypt DecryptFunc) (QwenCredential, error) {
+	var err error
+	if c.APIKey, err = decrypt(c.APIKey); err != nil {
+		return c, fmt.Errorf("解密API密钥失败: %v", err)
+	}
+	return c, nil
+}
+
+// getQwenConfig 获取通义千问配置
+func (c *Config) getQwenConfig(ctx context.Context) (*einoopenai.ChatModelConfig, error) {
+	// 从凭证缓存中获取当前环境启用的凭证(已解密)
+	creds, err := enabledCredentials[QwenCredential](ctx, "qwen")
+	if err != nil {
+		return nil, err
+	}
+
+	// 在支持请求模型且仍有QPS余量的凭证中按权重选择
+	selectedCred, err := selectCredential(ctx, "qwen", c.Model, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := defaultQwenBaseURL
+	if selectedCred.BaseURL != "" {
+		baseURL = selectedCred.BaseURL
+	}
+	return c.openAICompatibleConfig("qwen", selectedCred.Name, baseURL, selectedCred.APIKey, selectedCred.HTTPClientConfig)
+}
+
+func init() {
+	registerCredentials[QwenCredential]("qwen", "qwen.yaml", "通义千问")
+	Register("qwen", func() Provider {
+		return &einoProvider{vendor: "qwen", newChatModel: newQwenChatModel, params: openAIParams}
+	})
+}
+
+// newQwenChatModel 根据配置创建通义千问聊天模型
+func newQwenChatModel(ctx context.Context, conf *Config) (model.ChatModel, error) {
+	// 获取通义千问配置
+	qwenConf, err := conf.getQwenConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取通义千问配置失败: %w", err)
+	}
+
+	// 创建聊天模型
+	chatModel, err := einoopenai.NewChatModel(ctx, qwenConf)
+	if err != nil {
+		return nil, fmt.Errorf("创建聊天模型失败: %v", err)
+	}
+	return chatModel, nil
+}
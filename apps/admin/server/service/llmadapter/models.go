@@ -0,0 +1,152 @@
+package llmadapter
+
+import (
+	"sort"
+	"strings"
+)
+
+// ModelCapabilities 模型支持的能力
+type ModelCapabilities struct {
+	Tools     bool `json:"tools"`     // 支持工具调用
+	Vision    bool `json:"vision"`    // 支持图片输入
+	Reasoning bool `json:"reasoning"` // 推理模型
+	Streaming bool `json:"streaming"` // 支持流式响应
+}
+
+// ModelInfo 当前环境可调用的模型
+type ModelInfo struct {
+	ID            string            // 模型名称，即 ChatRequest.Model
+	Vendor        string            // 所属供应商，多个供应商都配置了该模型时为排序后的第一个
+	Vendors       []string          // 配置了该模型的所有供应商(按字母排序)
+	ContextWindow int               // 上下文窗口(Token)，未知时为0
+	Capabilities  ModelCapabilities // 模型能力
+}
+
+// modelSpec 已知模型系列的元数据
+type modelSpec struct {
+	contextWindow int
+	capabilities  ModelCapabilities
+}
+
+// knownModels 已知模型系列，键为模型名称前缀(小写)，匹配时取最长的前缀
+// 网关对所有供应商都提供流式响应，因此 Streaming 均为 true
+var knownModels = map[string]modelSpec{
+	// OpenAI / Azure
+	"gpt-35-turbo":  {16385, ModelCapabilities{Tools: true, Streaming: true}},
+	"gpt-3.5-turbo": {16385, ModelCapabilities{Tools: true, Streaming: true}},
+	"gpt-4":         {8192, ModelCapabilities{Tools: true, Streaming: true}},
+	"gpt-4-32k":     {32768, ModelCapabilities{Tools: true, Streaming: true}},
+	"gpt-4-turbo":   {128000, ModelCapabilities{Tools: true, Vision: true, Streaming: true}},
+	"gpt-4o":        {128000, ModelCapabilities{Tools: true, Vision: true, Streaming: true}},
+	"gpt-4.1":       {1047576, ModelCapabilities{Tools: true, Vision: true, Streaming: true}},
+	"o1":            {200000, ModelCapabilities{Tools: true, Vision: true, Reasoning: true, Streaming: true}},
+	"o1-mini":       {128000, ModelCapabilities{Reasoning: true, Streaming: true}},
+	"o3":            {200000, ModelCapabilities{Tools: true, Vision: true, Reasoning: true, Streaming: true}},
+	"o3-mini":       {200000, ModelCapabilities{Tools: true, Reasoning: true, Streaming: true}},
+	"o4-mini":       {200000, ModelCapabilities{Tools: true, Vision: true, Reasoning: true, Streaming: true}},
+	// Claude / Bedrock
+	"claude-3":          {200000, ModelCapabilities{Tools: true, Vision: true, Streaming: true}},
+	"claude-3-7-sonnet": {200000, ModelCapabilities{Tools: true, Vision: true, Reasoning: true, Streaming: true}},
+	"claude-sonnet-4":   {200000, ModelCapabilities{Tools: true, Vision: true, Reasoning: true, Streaming: true}},
+	"claude-opus-4":     {200000, ModelCapabilities{Tools: true, Vision: true, Reasoning: true, Streaming: true}},
+	// DeepSeek
+	"deepseek-chat":     {65536, ModelCapabilities{Tools: true, Streaming: true}},
+	"deepseek-v3":       {65536, ModelCapabilities{Tools: true, Streaming: true}},
+	"deepseek-reasoner": {65536, ModelCapabilities{Reasoning: true, Streaming: true}},
+	"deepseek-r1":       {65536, ModelCapabilities{Reasoning: true, Streaming: true}},
+	// Gemini
+	"gemini-1.5-pro":   {2097152, ModelCapabilities{Tools: true, Vision: true, Streaming: true}},
+	"gemini-1.5-flash": {1048576, ModelCapabilities{Tools: true, Vision: true, Streaming: true}},
+	"gemini-2.0-flash": {1048576, ModelCapabilities{Tools: true, Vision: true, Streaming: true}},
+	"gemini-2.5":       {1048576, ModelCapabilities{Tools: true, Vision: true, Reasoning: true, Streaming: true}},
+	// 通义千问
+	"qwen-turbo": {1000000, ModelCapabilities{Tools: true, Streaming: true}},
+	"qwen-plus":  {131072, ModelCapabilities{Tools: true, Streaming: true}},
+	"qwen-max":   {32768, ModelCapabilities{Tools: true, Streaming: true}},
+	"qwen-vl":    {32768, ModelCapabilities{Vision: true, Streaming: true}},
+	"qwq":        {131072, ModelCapabilities{Reasoning: true, Streaming: true}},
+	// 火山方舟
+	"doubao-1.5-pro-32k":    {32768, ModelCapabilities{Tools: true, Streaming: true}},
+	"doubao-1.5-pro-256k":   {262144, ModelCapabilities{Tools: true, Streaming: true}},
+	"doubao-1.5-vision-pro": {32768, ModelCapabilities{Vision: true, Streaming: true}},
+	// 千帆
+	"ernie-4.0": {8192, ModelCapabilities{Tools: true, Streaming: true}},
+	"ernie-3.5": {8192, ModelCapabilities{Tools: true, Streaming: true}},
+	// Ollama
+	"llama3":   {8192, ModelCapabilities{Streaming: true}},
+	"llama3.1": {131072, ModelCapabilities{Tools: true, Streaming: true}},
+	"qwen2.5":  {32768, ModelCapabilities{Tools: true, Streaming: true}},
+}
+
+// lookupModelSpec 按最长前缀查找模型的元数据
+// 模型名称可能带有地域或供应商前缀(如Bedrock的 us.anthropic.claude-3-5-sonnet-...)，
+// 依次去掉 . 或 / 之前的部分再匹配；未知模型只标记支持流式响应
+func lookupModelSpec(model string) modelSpec {
+	name := strings.ToLower(model)
+	best, bestLen := modelSpec{capabilities: ModelCapabilities{Streaming: true}}, 0
+	for {
+		for prefix, spec := range knownModels {
+			if len(prefix) > bestLen && strings.HasPrefix(name, prefix) {
+				best, bestLen = spec, len(prefix)
+			}
+		}
+		i := strings.IndexAny(name, "./")
+		if i < 0 {
+			return best
+		}
+		name = name[i+1:]
+	}
+}
+
+// ListModels 返回当前环境(ENV)下所有启用凭证配置的模型(按名称排序)
+// 未配置 models 的凭证支持任意模型，不会出现在列表中；配置无效的供应商被忽略
+func ListModels() []ModelInfo {
+	return DefaultCredentialStore().Models()
+}
+
+// LookupModel 返回当前环境下指定名称的模型，未被任何启用凭证配置时返回false
+func LookupModel(id string) (ModelInfo, bool) {
+	for _, info := range ListModels() {
+		if info.ID == id {
+			return info, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+// Models 返回凭证缓存中启用凭证配置的模型并集(按名称排序)
+func (s *CredentialStore) Models() []ModelInfo {
+	snapshot := s.snapshot.Load()
+
+	vendorsByModel := make(map[string][]string)
+	for vendor, entry := range snapshot.vendors {
+		if entry.err != nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, base := range credentialLoaders[vendor].bases(entry.environments, snapshot.env) {
+			for _, model := range base.Models {
+				if model == "" || seen[model] {
+					continue
+				}
+				seen[model] = true
+				vendorsByModel[model] = append(vendorsByModel[model], vendor)
+			}
+		}
+	}
+
+	models := make([]ModelInfo, 0, len(vendorsByModel))
+	for id, vendors := range vendorsByModel {
+		sort.Strings(vendors)
+		spec := lookupModelSpec(id)
+		models = append(models, ModelInfo{
+			ID:            id,
+			Vendor:        vendors[0],
+			Vendors:       vendors,
+			ContextWindow: spec.contextWindow,
+			Capabilities:  spec.capabilities,
+		})
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models
+}
@@ -0,0 +1,87 @@
+package llmadapter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCredentialStoreModels 只列出当前环境启用凭证配置的模型，同名模型合并供应商
+func TestCredentialStoreModels(t *testing.T) {
+	dir := t.TempDir()
+	azure := fmt.Sprintf(`environments:
+  %s:
+    credentials:
+      - name: a
+        api_key: enc:a
+        enabled: true
+        models: ["gpt-4o", "gpt-35-turbo"]
+      - name: b
+        api_key: enc:b
+        enabled: true
+        models: ["gpt-4o"]
+      - name: disabled
+        api_key: enc:c
+        enabled: false
+        models: ["gpt-4-32k"]
+  other:
+    credentials:
+      - name: other
+        api_key: YOUR_API_KEY_HERE
+        enabled: true
+        models: ["gpt-4-turbo"]
+`, currentEnv())
+	openai := fmt.Sprintf(`environments:
+  %s:
+    credentials:
+      - name: a
+        api_key: enc:a
+        enabled: true
+        models: ["gpt-4o", "o3-mini"]
+`, currentEnv())
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "azure.yaml"), []byte(azure), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "openai.yaml"), []byte(openai), 0644))
+
+	var calls int32
+	models := NewCredentialStore(dir, testDecrypt(&calls)).Models()
+
+	ids := make([]string, len(models))
+	for i, m := range models {
+		ids[i] = m.ID
+	}
+	assert.Equal(t, []string{"gpt-35-turbo", "gpt-4o", "o3-mini"}, ids)
+
+	assert.Equal(t, "azure", models[1].Vendor)
+	assert.Equal(t, []string{"azure", "openai"}, models[1].Vendors)
+	assert.Equal(t, 128000, models[1].ContextWindow)
+	assert.Equal(t, ModelCapabilities{Tools: true, Vision: true, Streaming: true}, models[1].Capabilities)
+
+	assert.Equal(t, "openai", models[2].Vendor)
+	assert.True(t, models[2].Capabilities.Reasoning)
+}
+
+// TestLookupModelSpec 按最长前缀匹配，忽略地域和供应商前缀
+func TestLookupModelSpec(t *testing.T) {
+	tests := []struct {
+		model         string
+		contextWindow int
+		capabilities  ModelCapabilities
+	}{
+		{"gpt-4o-mini", 128000, ModelCapabilities{Tools: true, Vision: true, Streaming: true}},
+		{"gpt-4-32k", 32768, ModelCapabilities{Tools: true, Streaming: true}},
+		{"gpt-4.1-mini", 1047576, ModelCapabilities{Tools: true, Vision: true, Streaming: true}},
+		{"us.anthropic.claude-3-7-sonnet-20250219-v1:0", 200000, ModelCapabilities{Tools: true, Vision: true, Reasoning: true, Streaming: true}},
+		{"Qwen/QwQ-32B", 131072, ModelCapabilities{Reasoning: true, Streaming: true}},
+		{"my-private-model", 0, ModelCapabilities{Streaming: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			spec := lookupModelSpec(tt.model)
+			assert.Equal(t, tt.contextWindow, spec.contextWindow)
+			assert.Equal(t, tt.capabilities, spec.capabilities)
+		})
+	}
+}
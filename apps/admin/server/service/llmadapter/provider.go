@@ -0,0 +1,127 @@
+package llmadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ErrUnsupportedProvider 请求的供应商未注册
+var ErrUnsupportedProvider = errors.New("不支持的AI供应商")
+
+// ErrInvalidRequest 请求参数校验失败，如消息格式、工具定义或 response_format 不正确
+// 可通过 errors.Is(err, ErrInvalidRequest) 判断，与凭证、配置等服务端错误区分
+var ErrInvalidRequest = errors.New("请求参数错误")
+
+// invalidRequestError 请求参数校验失败的错误，Error 只返回具体原因
+type invalidRequestError struct {
+	err error
+}
+
+// Error 实现 error 接口
+func (e *invalidRequestError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e *invalidRequestError) Unwrap() error {
+	return e.err
+}
+
+// Is 使 errors.Is(err, ErrInvalidRequest) 成立
+func (e *invalidRequestError) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+// invalidRequestf 按格式创建请求参数校验失败的错误
+func invalidRequestf(format string, args ...any) error {
+	return &invalidRequestError{err: fmt.Errorf(format, args...)}
+}
+
+// Provider 定义了LLM供应商的统一调用接口
+// 每个供应商在自己的 m_xxx.go 中实现该接口，并在 init() 中通过 Register 注册，
+// CreateChatCompletion 根据 ChatRequest.Provider 查找对应的实现，新增供应商无需修改分发逻辑
+type Provider interface {
+	// Generate 非流式调用，返回OpenAI格式的完整响应
+	// ctx 取消或超时后应尽快中止上游请求
+	Generate(ctx context.Context, req ChatRequest) (*openai.ChatCompletionResponse, error)
+
+	// Stream 流式调用，返回OpenAI格式的增量响应流
+	// 调用方需要读取到 io.EOF 或者主动 Close 返回的 StreamReader，
+	// ctx 取消后上游流和转换协程都会退出，StreamReader 返回 ctx 的错误
+	Stream(ctx context.Context, req ChatRequest) (*schema.StreamReader[*openai.ChatCompletionStreamResponse], error)
+}
+
+// MultiChoiceProvider 供应商可以实现的可选接口
+// 返回true表示 Generate 会按 req.N 返回多个候选回复；未实现时 n 大于1的请求由 CreateChatCompletion 分别调用 n 次
+type MultiChoiceProvider interface {
+	SupportsMultipleChoices() bool
+}
+
+// ProviderFactory 创建 Provider 实例的工厂函数
+type ProviderFactory func() Provider
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]ProviderFactory)
+)
+
+// Register 注册供应商
+// 一般在供应商实现文件的 init() 中调用，name 即 ChatRequest.Provider 的取值
+// 重复注册同名供应商或 factory 为 nil 时直接 panic，便于在启动阶段暴露问题
+func Register(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if name == "" {
+		panic("llmadapter: 供应商名称不能为空")
+	}
+	if factory == nil {
+		panic("llmadapter: 供应商 " + name + " 的工厂函数为nil")
+	}
+	if _, exists := providers[name]; exists {
+		panic("llmadapter: 供应商 " + name + " 重复注册")
+	}
+	providers[name] = factory
+}
+
+// GetProvider 根据名称获取供应商实例
+// 未注册时返回包装了 ErrUnsupportedProvider 的错误
+func GetProvider(name string) (Provider, error) {
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedProvider, name)
+	}
+	return factory(), nil
+}
+
+// Providers 返回所有已注册的供应商名称(按字母排序)
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withRequestTimeout 为单次请求附加凭证配置的超时时间
+// timeout<=0 时不设置截止时间，仅返回可取消的上下文
+func withRequestTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,255 @@
+package llmadapter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// mustGetProvider 获取已注册的供应商，未注册时直接终止测试
+func mustGetProvider(t *testing.T, name string) Provider {
+	t.Helper()
+	provider, err := GetProvider(name)
+	if err != nil {
+		t.Fatalf("获取供应商 %s 失败: %v", name, err)
+	}
+	return provider
+}
+
+// streamToWriter 通过统一入口以流式方式调用指定供应商，并将SSE输出写入writer
+func streamToWriter(t *testing.T, name string, req ChatRequest, writer io.Writer) error {
+	t.Helper()
+	req.Provider = name
+	req.Stream = true
+	_, err := CreateChatCompletion(context.Background(), req, writer)
+	return err
+}
+
+// skipIfNotConfigured 依赖真实API的用例在供应商凭证未配置(读取或解密失败)时跳过
+func skipIfNotConfigured(t *testing.T, err error) {
+	t.Helper()
+	if err != nil && strings.Contains(err.Error(), "配置失败") {
+		t.Skipf("供应商凭证未配置，跳过: %v", err)
+	}
+}
+
+// fakeProvider 用于测试分发逻辑的供应商实现
+type fakeProvider struct {
+	content string
+}
+
+func (p *fakeProvider) Generate(ctx context.Context, req ChatRequest) (*openai.ChatCompletionResponse, error) {
+	return &openai.ChatCompletionResponse{
+		ID:     "fake-id",
+		Object: "chat.completion",
+		Model:  req.Model,
+		Choices: []openai.ChatCompletionChoice{
+			{
+				Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: p.content},
+				FinishReason: openai.FinishReasonStop,
+			},
+		},
+	}, nil
+}
+
+func (p *fakeProvider) Stream(ctx context.Context, req ChatRequest) (*schema.StreamReader[*openai.ChatCompletionStreamResponse], error) {
+	chunks := make([]*openai.ChatCompletionStreamResponse, 0, len(p.content))
+	for _, r := range p.content {
+		chunks = append(chunks, &openai.ChatCompletionStreamResponse{
+			ID:     "fake-id",
+			Object: "chat.completion.chunk",
+			Model:  req.Model,
+			Choices: []openai.ChatCompletionStreamChoice{
+				{Delta: openai.ChatCompletionStreamChoiceDelta{Content: string(r)}},
+			},
+		})
+	}
+	return schema.StreamReaderFromArray(chunks), nil
+}
+
+// TestBuiltinProvidersRegistered 测试内置供应商均已注册
+func TestBuiltinProvidersRegistered(t *testing.T) {
+	names := Providers()
+	for _, name := range []string{"ark", "azure", "bedrock", "claude", "deepseek", "gemini", "ollama", "openai", "openai_compatible", "qianfan", "qwen"} {
+		assert.Contains(t, names, name)
+	}
+	assert.IsIncreasing(t, names, "供应商名称应按字母排序")
+}
+
+// TestRegisterProvider 测试注册与查找供应商
+func TestRegisterProvider(t *testing.T) {
+	Register("fake-register", func() Provider { return &fakeProvider{content: "ok"} })
+
+	provider, err := GetProvider("fake-register")
+	assert.NoError(t, err)
+	assert.IsType(t, &fakeProvider{}, provider)
+
+	// 重复注册应当panic
+	assert.Panics(t, func() {
+		Register("fake-register", func() Provider { return &fakeProvider{} })
+	})
+	// 工厂函数为nil应当panic
+	assert.Panics(t, func() {
+		Register("fake-nil", nil)
+	})
+}
+
+// TestGetProviderUnsupported 测试获取未注册的供应商
+func TestGetProviderUnsupported(t *testing.T) {
+	provider, err := GetProvider("not-exists")
+	assert.Nil(t, provider)
+	assert.True(t, errors.Is(err, ErrUnsupportedProvider))
+	assert.Equal(t, "不支持的AI供应商: not-exists", err.Error())
+
+	_, err = CreateChatCompletion(context.Background(), ChatRequest{Provider: "not-exists"}, nil)
+	assert.True(t, errors.Is(err, ErrUnsupportedProvider))
+
+	// 未指定供应商，且没有凭证配置该模型
+	_, err = CreateChatCompletion(context.Background(), ChatRequest{ChatCompletionRequest: openai.ChatCompletionRequest{Model: "not-configured-model"}}, nil)
+	assert.True(t, errors.Is(err, ErrUnsupportedProvider))
+}
+
+// TestCreateChatCompletionDispatch 测试统一入口按供应商分发流式与非流式请求
+func TestCreateChatCompletionDispatch(t *testing.T) {
+	Register("fake-dispatch", func() Provider { return &fakeProvider{content: "你好"} })
+
+	req := ChatRequest{
+		Provider: "fake-dispatch",
+		ChatCompletionRequest: openai.ChatCompletionRequest{
+			Model: "fake-model",
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleUser, Content: "hi"},
+			},
+		},
+	}
+
+	// 非流式
+	resp, err := CreateChatCompletion(context.Background(), req, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "你好", resp.Choices[0].Message.Content)
+	assert.Equal(t, "fake-model", resp.Model)
+
+	// 流式
+	buffer := &bytes.Buffer{}
+	req.Stream = true
+	resp, err = CreateChatCompletion(context.Background(), req, buffer)
+	assert.NoError(t, err)
+	assert.Nil(t, resp)
+
+	output := buffer.String()
+	assert.Equal(t, 3, strings.Count(output, "data: "), "应包含两个分片和一个结束标记")
+	assert.Contains(t, output, `"content":"你"`)
+	assert.Contains(t, output, `"content":"好"`)
+	assert.True(t, strings.HasSuffix(output, "data: [DONE]\n\n"))
+}
+
+// TestChatCompletionStreamCancel 测试调用方取消后，转换协程关闭上游流并释放上游请求
+func TestChatCompletionStreamCancel(t *testing.T) {
+	upstreamReader, upstreamWriter := schema.Pipe[*schema.Message](0)
+
+	ctx, cancelCaller := context.WithCancel(context.Background())
+	upstreamCtx, cancelUpstream := withRequestTimeout(ctx, 0)
+	resultReader := toChatCompletionStream(upstreamCtx, cancelUpstream, "fake", "fake-model", upstreamReader)
+
+	// 上游正常输出一个分片
+	go upstreamWriter.Send(&schema.Message{Role: schema.Assistant, Content: "你"}, nil)
+	chunk, err := resultReader.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "你", chunk.Choices[0].Delta.Content)
+
+	// 模拟客户端断开
+	cancelCaller()
+	go upstreamWriter.Send(&schema.Message{Role: schema.Assistant, Content: "好"}, nil)
+
+	_, err = resultReader.Recv()
+	for err == nil {
+		_, err = resultReader.Recv()
+	}
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, upstreamCtx.Err(), context.Canceled, "上游请求的上下文应被取消")
+
+	// 上游读取端已关闭，后续写入立即返回closed
+	assert.Eventually(t, func() bool {
+		return upstreamWriter.Send(&schema.Message{}, nil)
+	}, time.Second, 10*time.Millisecond)
+	upstreamWriter.Close()
+}
+
+// blockingProvider 流式调用在ctx取消前不会结束，用于测试客户端断开
+type blockingProvider struct {
+	fakeProvider
+	upstreamDone chan struct{}
+}
+
+func (p *blockingProvider) Stream(ctx context.Context, req ChatRequest) (*schema.StreamReader[*openai.ChatCompletionStreamResponse], error) {
+	sr, sw := schema.Pipe[*openai.ChatCompletionStreamResponse](0)
+	go func() {
+		defer close(p.upstreamDone)
+		defer sw.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				sw.Send(nil, ctx.Err())
+				return
+			case <-time.After(10 * time.Millisecond):
+				chunk := &openai.ChatCompletionStreamResponse{
+					Choices: []openai.ChatCompletionStreamChoice{{Delta: openai.ChatCompletionStreamChoiceDelta{Content: "."}}},
+				}
+				if closed := sw.Send(chunk, nil); closed {
+					return
+				}
+			}
+		}
+	}()
+	return sr, nil
+}
+
+// TestCreateChatCompletionContextCancel 测试客户端断开后统一入口停止写入并结束上游流
+func TestCreateChatCompletionContextCancel(t *testing.T) {
+	provider := &blockingProvider{upstreamDone: make(chan struct{})}
+	Register("fake-blocking", func() Provider { return provider })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req := ChatRequest{
+		Provider: "fake-blocking",
+		ChatCompletionRequest: openai.ChatCompletionRequest{
+			Model:  "fake-model",
+			Stream: true,
+		},
+	}
+	buffer := &bytes.Buffer{}
+	_, err := CreateChatCompletion(ctx, req, buffer)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotContains(t, buffer.String(), "[DONE]", "取消后不应写入结束标记")
+
+	select {
+	case <-provider.upstreamDone:
+	case <-time.After(time.Second):
+		t.Fatal("上游流在ctx取消后没有结束")
+	}
+}
+
+// TestWithRequestTimeout 测试凭证超时时间作为请求截止时间
+func TestWithRequestTimeout(t *testing.T) {
+	ctx, cancel := withRequestTimeout(context.Background(), 0)
+	_, hasDeadline := ctx.Deadline()
+	assert.False(t, hasDeadline)
+	cancel()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	ctx, cancel = withRequestTimeout(context.Background(), time.Second)
+	defer cancel()
+	deadline, hasDeadline := ctx.Deadline()
+	assert.True(t, hasDeadline)
+	assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+}
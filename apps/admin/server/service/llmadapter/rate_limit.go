@@ -0,0 +1,122 @@
+package llmadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrNoCapacity 没有可以处理请求的凭证
+// 请求的模型不被任何启用的凭证支持，或者支持该模型的凭证QPS都已用尽、处于熔断冷却中
+var ErrNoCapacity = errors.New("没有可用的凭证")
+
+// NoCapacityError 凭证选择失败的详细信息，可通过 errors.Is(err, ErrNoCapacity) 判断
+type NoCapacityError struct {
+	Vendor string // 供应商
+	Model  string // 请求的模型
+	// Candidates 支持该模型的启用凭证数量，为0表示没有凭证支持该模型，否则表示这些凭证的QPS都已用尽或处于熔断冷却中
+	Candidates int
+}
+
+// Error 实现 error 接口
+func (e *NoCapacityError) Error() string {
+	if e.Candidates == 0 {
+		return fmt.Sprintf("%v: 供应商 %s 没有支持模型 %s 的凭证", ErrNoCapacity, e.Vendor, e.Model)
+	}
+	return fmt.Sprintf("%v: 供应商 %s 支持模型 %s 的 %d 个凭证暂不可用(QPS已用尽或熔断冷却中)", ErrNoCapacity, e.Vendor, e.Model, e.Candidates)
+}
+
+// Is 使 errors.Is(err, ErrNoCapacity) 成立
+func (e *NoCapacityError) Is(target error) bool {
+	return target == ErrNoCapacity
+}
+
+// RateLimiter 凭证QPS限流器
+type RateLimiter interface {
+	// Allow 尝试为 key 消耗一个令牌，limit 为每秒产生的令牌数(同时也是桶的容量)
+	// 返回false表示当前没有剩余令牌
+	Allow(ctx context.Context, key string, limit int) (bool, error)
+}
+
+// localRateLimiter 进程内的令牌桶限流器，单实例部署时使用
+type localRateLimiter struct {
+	limiters sync.Map // key -> *rate.Limiter
+}
+
+// NewLocalRateLimiter 创建进程内的令牌桶限流器
+func NewLocalRateLimiter() RateLimiter {
+	return &localRateLimiter{}
+}
+
+// Allow 实现 RateLimiter 接口
+func (l *localRateLimiter) Allow(_ context.Context, key string, limit int) (bool, error) {
+	value, ok := l.limiters.Load(key)
+	if !ok {
+		value, _ = l.limiters.LoadOrStore(key, rate.NewLimiter(rate.Limit(limit), limit))
+	}
+	limiter := value.(*rate.Limiter)
+
+	// 凭证的qps_limit热更新后调整令牌桶
+	if limiter.Burst() != limit {
+		limiter.SetLimit(rate.Limit(limit))
+		limiter.SetBurst(limit)
+	}
+	return limiter.Allow(), nil
+}
+
+// rateLimiter 当前使用的限流器
+var rateLimiter atomic.Value
+
+func init() {
+	rateLimiter.Store(rateLimiterHolder{NewLocalRateLimiter()})
+}
+
+// rateLimiterHolder 保证 atomic.Value 中存储的类型一致
+type rateLimiterHolder struct {
+	RateLimiter
+}
+
+// SetRateLimiter 设置凭证QPS限流器，多实例部署时可替换为基于Redis的实现
+// limiter 为nil时恢复为进程内的令牌桶限流器
+func SetRateLimiter(limiter RateLimiter) {
+	if limiter == nil {
+		limiter = NewLocalRateLimiter()
+	}
+	rateLimiter.Store(rateLimiterHolder{limiter})
+}
+
+// rateLimitFailClosed 限流器出错时是否拒绝使用该凭证，见 SetRateLimitFailClosed
+var rateLimitFailClosed atomic.Bool
+
+// SetRateLimitFailClosed 设置限流器出错(如Redis不可用)时的处理方式，两种方式都会记录警告日志
+// closed 为false(默认)时放行，该凭证本次不受QPS限制；为true时跳过该凭证，所有候选凭证都无法检查时返回 ErrNoCapacity
+func SetRateLimitFailClosed(closed bool) {
+	rateLimitFailClosed.Store(closed)
+}
+
+// currentRateLimiter 返回当前使用的限流器
+func currentRateLimiter() RateLimiter {
+	return rateLimiter.Load().(rateLimiterHolder).RateLimiter
+}
+
+// rateLimitKey 凭证的限流键
+func rateLimitKey(vendor, name string) string {
+	return "llmadapter:qps:" + vendor + ":" + name
+}
+
+// supportsModel 凭证是否支持指定模型，models 为空表示不限制模型
+func supportsModel(base CredentialBase, model string) bool {
+	if len(base.Models) == 0 {
+		return true
+	}
+	for _, m := range base.Models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
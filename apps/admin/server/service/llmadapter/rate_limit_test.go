@@ -0,0 +1,186 @@
+package llmadapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCredential 构造测试用的凭证
+func testCredential(name string, weight, qps int, models ...string) AzureCredential {
+	return AzureCredential{CredentialBase: CredentialBase{
+		Name:     name,
+		Enabled:  true,
+		Weight:   weight,
+		QPSLimit: qps,
+		Models:   models,
+	}}
+}
+
+// withRateLimiter 在测试期间替换限流器
+func withRateLimiter(t *testing.T, limiter RateLimiter) {
+	t.Helper()
+	SetRateLimiter(limiter)
+	t.Cleanup(func() { SetRateLimiter(nil) })
+}
+
+// errRateLimiter 总是返回错误的限流器，模拟Redis不可用
+type errRateLimiter struct{}
+
+func (errRateLimiter) Allow(context.Context, string, int) (bool, error) {
+	return false, errors.New("redis不可用")
+}
+
+// TestSelectCredentialModels 只选择支持请求模型的凭证，未配置模型列表的凭证支持所有模型
+func TestSelectCredentialModels(t *testing.T) {
+	withRateLimiter(t, NewLocalRateLimiter())
+
+	creds := []AzureCredential{
+		testCredential("gpt4", 100, 0, "gpt-4o"),
+		testCredential("gpt35", 100, 0, "gpt-35-turbo"),
+	}
+	for i := 0; i < 20; i++ {
+		cred, err := selectCredential(context.Background(), "azure", "gpt-4o", creds)
+		assert.NoError(t, err)
+		assert.Equal(t, "gpt4", cred.Name)
+	}
+
+	_, err := selectCredential(context.Background(), "azure", "o1", creds)
+	assert.ErrorIs(t, err, ErrNoCapacity)
+	var noCapacity *NoCapacityError
+	if assert.ErrorAs(t, err, &noCapacity) {
+		assert.Equal(t, 0, noCapacity.Candidates)
+		assert.Equal(t, "o1", noCapacity.Model)
+	}
+
+	creds = append(creds, testCredential("any", 1, 0))
+	cred, err := selectCredential(context.Background(), "azure", "o1", creds)
+	assert.NoError(t, err)
+	assert.Equal(t, "any", cred.Name)
+}
+
+// TestSelectCredentialQPSLimit QPS用尽的凭证不再被选中，全部用尽时返回 NoCapacityError
+func TestSelectCredentialQPSLimit(t *testing.T) {
+	withRateLimiter(t, NewLocalRateLimiter())
+
+	creds := []AzureCredential{
+		testCredential("qps-a", 1, 2, "gpt-4o"),
+		testCredential("qps-b", 1, 3, "gpt-4o"),
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 5; i++ {
+		cred, err := selectCredential(context.Background(), "qps-test", "gpt-4o", creds)
+		if !assert.NoError(t, err) {
+			return
+		}
+		counts[cred.Name]++
+	}
+	assert.Equal(t, map[string]int{"qps-a": 2, "qps-b": 3}, counts, "每个凭证最多使用其QPS限制的次数")
+
+	_, err := selectCredential(context.Background(), "qps-test", "gpt-4o", creds)
+	var noCapacity *NoCapacityError
+	if assert.ErrorAs(t, err, &noCapacity) {
+		assert.Equal(t, 2, noCapacity.Candidates)
+		assert.Equal(t, "qps-test", noCapacity.Vendor)
+	}
+
+	// 令牌按QPS恢复
+	assert.Eventually(t, func() bool {
+		_, err := selectCredential(context.Background(), "qps-test", "gpt-4o", creds)
+		return err == nil
+	}, 2*time.Second, 50*time.Millisecond)
+}
+
+// TestSelectCredentialLimiterError 限流器出错时记录警告，默认放行，设置 fail-closed 后跳过凭证
+func TestSelectCredentialLimiterError(t *testing.T) {
+	withRateLimiter(t, errRateLimiter{})
+	logs := withObservedLogger(t)
+	creds := []AzureCredential{testCredential("a", 1, 1, "gpt-4o")}
+
+	cred, err := selectCredential(context.Background(), "azure", "gpt-4o", creds)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", cred.Name)
+
+	SetRateLimitFailClosed(true)
+	t.Cleanup(func() { SetRateLimitFailClosed(false) })
+	_, err = selectCredential(context.Background(), "azure", "gpt-4o", creds)
+	assert.ErrorIs(t, err, ErrNoCapacity)
+
+	entries := logs.FilterMessage("凭证限流检查失败").All()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "a", entries[0].ContextMap()["credential"])
+		assert.Equal(t, false, entries[0].ContextMap()["failClosed"])
+		assert.Equal(t, true, entries[1].ContextMap()["failClosed"])
+	}
+}
+
+// TestLocalRateLimiterLimitChange qps_limit 变化后调整令牌桶
+func TestLocalRateLimiterLimitChange(t *testing.T) {
+	limiter := NewLocalRateLimiter()
+	ctx := context.Background()
+
+	allowed, _ := limiter.Allow(ctx, "k", 1)
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow(ctx, "k", 1)
+	assert.False(t, allowed)
+
+	// 提高限制后令牌按新的速率恢复
+	assert.Eventually(t, func() bool {
+		allowed, _ := limiter.Allow(ctx, "k", 100)
+		return allowed
+	}, 200*time.Millisecond, 5*time.Millisecond)
+}
+
+// TestCreateChatCompletionNoCapacity 没有支持请求模型的凭证时，统一入口返回 ErrNoCapacity
+func TestCreateChatCompletionNoCapacity(t *testing.T) {
+	encrypt, _, err := InitRSAKeyManager()
+	if err != nil {
+		t.Fatalf("初始化RSA密钥管理器失败: %v", err)
+	}
+	apiKey, err := encrypt("test-key")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+
+	dir := t.TempDir()
+	content := fmt.Sprintf(`environments:
+  %s:
+    credentials:
+      - name: only-gpt4
+        api_key: "%s"
+        endpoint: https://example.com
+        enabled: true
+        weight: 1
+        models:
+          - gpt-4o
+`, currentEnv(), apiKey)
+	if err := os.WriteFile(filepath.Join(dir, "azure.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("写入配置文件失败: %v", err)
+	}
+
+	originalConfigPath := LLMConfigPath
+	defer func() {
+		LLMConfigPath = originalConfigPath
+	}()
+	LLMConfigPath = dir
+
+	req := ChatRequest{Provider: "azure", ChatCompletionRequest: openai.ChatCompletionRequest{
+		Model:    "o1",
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "你好"}},
+	}}
+	_, err = CreateChatCompletion(context.Background(), req, nil)
+	assert.ErrorIs(t, err, ErrNoCapacity)
+
+	req.Stream = true
+	_, err = CreateChatCompletion(context.Background(), req, io.Discard)
+	assert.ErrorIs(t, err, ErrNoCapacity)
+}
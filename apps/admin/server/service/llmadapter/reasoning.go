@@ -0,0 +1,142 @@
+package llmadapter
+
+import (
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino-ext/components/model/deepseek"
+	"github.com/cloudwego/eino/schema"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// ThinkingEnabled 开启推理(思考)
+	ThinkingEnabled = "enabled"
+	// ThinkingDisabled 关闭推理(思考)，供应商支持关闭时不输出推理内容
+	ThinkingDisabled = "disabled"
+)
+
+// defaultThinkingBudget 开启推理但未指定 budget_tokens 时的推理Token预算
+const defaultThinkingBudget = 2048
+
+// ThinkingOptions 请求的推理(思考)设置，格式与Claude的 thinking 参数一致
+// 各供应商的映射：
+//   - claude、bedrock: 开启extended thinking，budget_tokens 即推理Token预算，最小1024
+//   - mock: 规则配置了 reasoning 时输出推理内容，关闭时不输出
+//   - deepseek 等推理模型始终输出推理内容，不支持设置预算；其他供应商忽略该参数
+type ThinkingOptions struct {
+	Type         string `json:"type"`                    // enabled 或 disabled，为空时按 budget_tokens 是否大于0判断
+	BudgetTokens int    `json:"budget_tokens,omitempty"` // 推理Token预算，为0时使用默认值2048
+}
+
+// enabled 是否开启推理
+func (t *ThinkingOptions) enabled() bool {
+	if t == nil {
+		return false
+	}
+	switch t.Type {
+	case ThinkingEnabled:
+		return true
+	case ThinkingDisabled:
+		return false
+	default:
+		return t.BudgetTokens > 0
+	}
+}
+
+// disabled 请求是否明确关闭了推理
+func (t *ThinkingOptions) disabled() bool {
+	return t != nil && t.Type == ThinkingDisabled
+}
+
+// budget 推理Token预算，未开启时返回0
+func (t *ThinkingOptions) budget() int {
+	if !t.enabled() {
+		return 0
+	}
+	if t.BudgetTokens > 0 {
+		return t.BudgetTokens
+	}
+	return defaultThinkingBudget
+}
+
+// reasoningContent 获取eino消息中的推理内容
+// Claude(含Bedrock)写入 ReasoningContent 字段，DeepSeek组件写入 Extra
+func reasoningContent(msg *schema.Message) string {
+	if msg.ReasoningContent != "" {
+		return msg.ReasoningContent
+	}
+	if reasoning, ok := deepseek.GetReasoningContent(msg); ok {
+		return reasoning
+	}
+	return ""
+}
+
+// withReasoningTokens 在用量中记录推理Token数
+// 供应商已经返回推理Token数时以供应商为准，否则按推理内容每4个字符1个Token估算，且不超过补全Token数；
+// 推理Token包含在补全Token中，不影响总Token数和费用
+func withReasoningTokens(usage openai.Usage, reasoning string) openai.Usage {
+	if reasoning == "" || reasoningTokens(usage) > 0 {
+		return usage
+	}
+	tokens := (utf8.RuneCountInString(reasoning) + 3) / 4
+	if usage.CompletionTokens > 0 {
+		tokens = min(tokens, usage.CompletionTokens)
+	}
+	details := openai.CompletionTokensDetails{}
+	if usage.CompletionTokensDetails != nil {
+		details = *usage.CompletionTokensDetails
+	}
+	details.ReasoningTokens = tokens
+	usage.CompletionTokensDetails = &details
+	return usage
+}
+
+// reasoningTokens 用量中的推理Token数
+func reasoningTokens(usage openai.Usage) int {
+	if usage.CompletionTokensDetails == nil {
+		return 0
+	}
+	return usage.CompletionTokensDetails.ReasoningTokens
+}
+
+// hideReasoning 去掉完整响应中的推理内容，用量中的推理Token数保留
+func hideReasoning(resp *openai.ChatCompletionResponse) {
+	for i := range resp.Choices {
+		resp.Choices[i].Message.ReasoningContent = ""
+	}
+}
+
+// withoutReasoning 返回去掉推理内容的分片
+// 去掉后没有其他内容的选项不再保留，只有角色的选项仅在尚未输出过角色(roleSent 为false)时保留
+func withoutReasoning(response *openai.ChatCompletionStreamResponse, roleSent bool) *openai.ChatCompletionStreamResponse {
+	chunk := *response
+	chunk.Choices = make([]openai.ChatCompletionStreamChoice, 0, len(response.Choices))
+	for _, choice := range response.Choices {
+		if choice.Delta.ReasoningContent == "" {
+			chunk.Choices = append(chunk.Choices, choice)
+			continue
+		}
+		choice.Delta.ReasoningContent = ""
+		if !emptyDelta(choice) || (choice.Delta.Role != "" && !roleSent) {
+			chunk.Choices = append(chunk.Choices, choice)
+		}
+	}
+	return &chunk
+}
+
+// hasRole 判断分片中是否包含角色
+func hasRole(response *openai.ChatCompletionStreamResponse) bool {
+	for _, choice := range response.Choices {
+		if choice.Delta.Role != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// emptyDelta 判断流式分片的选项除角色外是否没有需要输出的内容
+func emptyDelta(choice openai.ChatCompletionStreamChoice) bool {
+	delta := choice.Delta
+	return delta.Content == "" && delta.Refusal == "" && len(delta.ToolCalls) == 0 &&
+		delta.FunctionCall == nil && choice.FinishReason == ""
+}
@@ -0,0 +1,203 @@
+package llmadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino-ext/components/model/claude"
+	"github.com/cloudwego/eino-ext/components/model/deepseek"
+	"github.com/cloudwego/eino/schema"
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// streamDeltas 返回流式输出中所有分片的增量
+func streamDeltas(t *testing.T, output string) []openai.ChatCompletionStreamChoiceDelta {
+	t.Helper()
+	var deltas []openai.ChatCompletionStreamChoiceDelta
+	for _, line := range strings.Split(output, "\n\n") {
+		data := strings.TrimPrefix(line, "data: ")
+		if data == "" || data == "[DONE]" {
+			continue
+		}
+		var chunk openai.ChatCompletionStreamResponse
+		if !assert.NoError(t, json.Unmarshal([]byte(data), &chunk)) {
+			return nil
+		}
+		for _, choice := range chunk.Choices {
+			deltas = append(deltas, choice.Delta)
+		}
+	}
+	return deltas
+}
+
+// TestReasoningContent 测试推理内容在流式和非流式响应中的输出、隐藏和用量
+func TestReasoningContent(t *testing.T) {
+	useTestCredentials(t, map[string]string{"mock.yaml": `environments:
+  {{env}}:
+    credentials:
+      - name: mock-reasoning
+        enabled: true
+        weight: 1
+        chunk_size: 4
+        rules:
+          - reply: "答案是42"
+            reasoning: "先想一想再回答"
+            prompt_tokens: 5
+            completion_tokens: 9
+            reasoning_tokens: 6
+`})
+
+	t.Run("非流式", func(t *testing.T) {
+		ctx, info := WithCallInfo(context.Background())
+		resp, err := CreateChatCompletion(ctx, mockChatRequest("问题", false), nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "答案是42", resp.Choices[0].Message.Content)
+		assert.Equal(t, "先想一想再回答", resp.Choices[0].Message.ReasoningContent)
+		assert.Equal(t, 6, reasoningTokens(resp.Usage))
+		assert.Equal(t, 6, reasoningTokens(info.Usage))
+	})
+
+	t.Run("非流式隐藏推理内容", func(t *testing.T) {
+		req := mockChatRequest("问题", false)
+		req.HideReasoning = true
+		resp, err := CreateChatCompletion(context.Background(), req, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Empty(t, resp.Choices[0].Message.ReasoningContent)
+		assert.Equal(t, "答案是42", resp.Choices[0].Message.Content)
+		assert.Equal(t, 6, reasoningTokens(resp.Usage))
+	})
+
+	t.Run("关闭推理", func(t *testing.T) {
+		req := mockChatRequest("问题", false)
+		req.Thinking = &ThinkingOptions{Type: ThinkingDisabled}
+		resp, err := CreateChatCompletion(context.Background(), req, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Empty(t, resp.Choices[0].Message.ReasoningContent)
+		assert.Nil(t, resp.Usage.CompletionTokensDetails)
+	})
+
+	t.Run("流式", func(t *testing.T) {
+		ctx, info := WithCallInfo(context.Background())
+		req := mockChatRequest("问题", true)
+		req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+		buffer := &bytes.Buffer{}
+		_, err := CreateChatCompletion(ctx, req, buffer)
+		if !assert.NoError(t, err) {
+			return
+		}
+		var reasoning, content strings.Builder
+		deltas := streamDeltas(t, buffer.String())
+		for _, delta := range deltas {
+			reasoning.WriteString(delta.ReasoningContent)
+			content.WriteString(delta.Content)
+		}
+		assert.Equal(t, "先想一想再回答", reasoning.String())
+		assert.Equal(t, "答案是42", content.String())
+		assert.Equal(t, openai.ChatMessageRoleAssistant, deltas[0].Role)
+		assert.NotEmpty(t, deltas[0].ReasoningContent)
+		assert.Contains(t, buffer.String(), `"reasoning_tokens":6`)
+		assert.Equal(t, 6, reasoningTokens(info.Usage))
+	})
+
+	t.Run("流式隐藏推理内容", func(t *testing.T) {
+		ctx, info := WithCallInfo(context.Background())
+		req := mockChatRequest("问题", true)
+		req.HideReasoning = true
+		buffer := &bytes.Buffer{}
+		_, err := CreateChatCompletion(ctx, req, buffer)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.NotContains(t, buffer.String(), "reasoning_content")
+		deltas := streamDeltas(t, buffer.String())
+		// 角色只输出一次，只包含推理内容的分片不输出
+		assert.Equal(t, openai.ChatMessageRoleAssistant, deltas[0].Role)
+		assert.Empty(t, deltas[0].Content)
+		assert.Equal(t, []string{"答案是4", "2"}, streamContents(t, buffer.String()))
+		assert.Len(t, deltas, 4)
+		assert.Equal(t, 6, reasoningTokens(info.Usage))
+	})
+}
+
+// TestReasoningConversion 测试eino消息中推理内容的转换和推理Token估算
+func TestReasoningConversion(t *testing.T) {
+	t.Run("Claude", func(t *testing.T) {
+		msg := &schema.Message{
+			Role:             schema.Assistant,
+			Content:          "你好",
+			ReasoningContent: "用户在打招呼",
+			ResponseMeta:     &schema.ResponseMeta{Usage: &schema.TokenUsage{PromptTokens: 3, CompletionTokens: 10, TotalTokens: 13}},
+		}
+		resp := toChatCompletionResponse("claude", "claude-sonnet-4", msg)
+		assert.Equal(t, "用户在打招呼", resp.Choices[0].Message.ReasoningContent)
+		// 供应商未返回推理Token数，按字符数估算
+		assert.Equal(t, 2, reasoningTokens(resp.Usage))
+		assert.Equal(t, 13, resp.Usage.TotalTokens)
+	})
+
+	t.Run("DeepSeek", func(t *testing.T) {
+		msg := &schema.Message{Role: schema.Assistant, Content: "42"}
+		deepseek.SetReasoningContent(msg, "计算中")
+		resp := toChatCompletionResponse("deepseek", "deepseek-reasoner", msg)
+		assert.Equal(t, "计算中", resp.Choices[0].Message.ReasoningContent)
+	})
+
+	t.Run("估算不超过补全Token数", func(t *testing.T) {
+		usage := withReasoningTokens(openai.Usage{CompletionTokens: 3}, strings.Repeat("想", 100))
+		assert.Equal(t, 3, reasoningTokens(usage))
+		usage = withReasoningTokens(openai.Usage{CompletionTokensDetails: &openai.CompletionTokensDetails{ReasoningTokens: 7}}, "想")
+		assert.Equal(t, 7, reasoningTokens(usage))
+	})
+
+	t.Run("合并用量", func(t *testing.T) {
+		usage := mergeUsage(openai.Usage{}, openai.Usage{CompletionTokens: 5, CompletionTokensDetails: &openai.CompletionTokensDetails{ReasoningTokens: 3}})
+		usage = mergeUsage(usage, openai.Usage{CompletionTokens: 8})
+		assert.Equal(t, 3, reasoningTokens(usage))
+		usage = addUsage(usage, openai.Usage{CompletionTokensDetails: &openai.CompletionTokensDetails{ReasoningTokens: 2}})
+		assert.Equal(t, 5, reasoningTokens(usage))
+	})
+}
+
+// TestApplyClaudeThinking 测试推理设置到Claude extended thinking 的映射
+func TestApplyClaudeThinking(t *testing.T) {
+	temperature := float32(0.5)
+	topK := int32(40)
+	tests := []struct {
+		name      string
+		thinking  *ThinkingOptions
+		maxTokens int
+		want      *claude.Thinking
+		wantMax   int
+	}{
+		{name: "未设置", maxTokens: 1000, wantMax: 1000},
+		{name: "关闭", thinking: &ThinkingOptions{Type: ThinkingDisabled, BudgetTokens: 4000}, maxTokens: 1000, wantMax: 1000},
+		{name: "默认预算", thinking: &ThinkingOptions{Type: ThinkingEnabled}, maxTokens: 8000, want: &claude.Thinking{Enable: true, BudgetTokens: defaultThinkingBudget}, wantMax: 8000},
+		{name: "最小预算", thinking: &ThinkingOptions{BudgetTokens: 100}, maxTokens: 8000, want: &claude.Thinking{Enable: true, BudgetTokens: claudeMinThinkingBudget}, wantMax: 8000},
+		{name: "max_tokens不大于预算", thinking: &ThinkingOptions{BudgetTokens: 5000}, maxTokens: 1000, want: &claude.Thinking{Enable: true, BudgetTokens: 5000}, wantMax: 5000 + claudeThinkingAnswerTokens},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &Config{Thinking: tt.thinking}
+			claudeConf := &claude.Config{MaxTokens: tt.maxTokens, Temperature: &temperature, TopK: &topK}
+			conf.applyClaudeThinking(claudeConf)
+			assert.Equal(t, tt.want, claudeConf.Thinking)
+			assert.Equal(t, tt.wantMax, claudeConf.MaxTokens)
+			if tt.want != nil {
+				assert.Nil(t, claudeConf.Temperature)
+				assert.Nil(t, claudeConf.TopK)
+			} else {
+				assert.Equal(t, &temperature, claudeConf.Temperature)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package llmadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	einoopenai "github.com/cloudwego/eino-ext/components/model/openai"
+	aclopenai "github.com/cloudwego/eino-ext/libs/acl/openai"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/sashabaranov/go-openai"
+)
+
+// 供应商可能不支持的OpenAI标准请求参数，供应商通过 ParamsProvider 声明支持其中哪些
+const (
+	ParamSeed              = "seed"
+	ParamPresencePenalty   = "presence_penalty"
+	ParamFrequencyPenalty  = "frequency_penalty"
+	ParamLogitBias         = "logit_bias"
+	ParamResponseFormat    = "response_format"             // type 为 json_object
+	ParamJSONSchema        = "response_format.json_schema" // type 为 json_schema
+	ParamParallelToolCalls = "parallel_tool_calls"         // 仅为false时需要供应商支持
+	ParamToolChoice        = "tool_choice"
+	ParamFileContent       = "messages.content.file" // 消息中的文件内容，所有供应商都不支持，见 toSchemaMessageParts
+)
+
+// openAIParams 通过OpenAI(及兼容)接口调用的供应商支持的参数
+// eino的OpenAI组件不支持 parallel_tool_calls
+var openAIParams = []string{ParamSeed, ParamPresencePenalty, ParamFrequencyPenalty, ParamLogitBias, ParamResponseFormat, ParamJSONSchema, ParamToolChoice}
+
+// ParamsProvider 供应商可以实现的可选接口
+// 返回支持的OpenAI标准请求参数(Param 开头的常量)，请求中设置了其他参数时 UnsupportedParams 返回这些参数；
+// 未实现时视为都不支持
+type ParamsProvider interface {
+	SupportedParams() []string
+}
+
+// requestParams 返回请求中设置了的、需要供应商支持的标准参数
+func requestParams(req ChatRequest) []string {
+	var params []string
+	if req.Seed != nil {
+		params = append(params, ParamSeed)
+	}
+	if req.PresencePenalty != 0 {
+		params = append(params, ParamPresencePenalty)
+	}
+	if req.FrequencyPenalty != 0 {
+		params = append(params, ParamFrequencyPenalty)
+	}
+	if len(req.LogitBias) > 0 {
+		params = append(params, ParamLogitBias)
+	}
+	if req.ResponseFormat != nil {
+		switch req.ResponseFormat.Type {
+		case openai.ChatCompletionResponseFormatTypeJSONObject:
+			params = append(params, ParamResponseFormat)
+		case openai.ChatCompletionResponseFormatTypeJSONSchema:
+			params = append(params, ParamJSONSchema)
+		}
+	}
+	if parallel, ok := req.ParallelToolCalls.(bool); ok && !parallel {
+		params = append(params, ParamParallelToolCalls)
+	}
+	if choice, _ := parseToolChoice(req.ToolChoice); len(req.Tools) > 0 && choice != "" && choice != toolChoiceAuto {
+		params = append(params, ParamToolChoice)
+	}
+	if hasFileContent(req.Messages) {
+		params = append(params, ParamFileContent)
+	}
+	return params
+}
+
+// hasFileContent 消息中是否包含文件内容
+func hasFileContent(messages []openai.ChatCompletionMessage) bool {
+	for _, msg := range messages {
+		for _, part := range msg.MultiContent {
+			if part.Type == chatMessagePartTypeFile {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UnsupportedParams 返回请求中设置了、但请求的供应商不支持而被忽略的参数
+// 包括供应商不支持的OpenAI标准参数(见 ParamsProvider)，以及 vendor_options 中不属于该供应商或不允许请求设置的字段；
+// 未指定供应商时按模型查找，找不到供应商时返回nil。故障转移到其他供应商时以实际调用的供应商为准，不再重新计算
+func UnsupportedParams(req ChatRequest) []string {
+	name, err := providerName(req)
+	if err != nil {
+		return nil
+	}
+	provider, err := GetProvider(name)
+	if err != nil {
+		return nil
+	}
+
+	var supported []string
+	if p, ok := provider.(ParamsProvider); ok {
+		supported = p.SupportedParams()
+	}
+	var unsupported []string
+	for _, param := range requestParams(req) {
+		if !slices.Contains(supported, param) {
+			unsupported = append(unsupported, param)
+		}
+	}
+	_, ignored := requestVendorOptions(name, req.VendorOptions)
+	return append(unsupported, ignored...)
+}
+
+// unsupportedParamsKey 上下文中保存不支持参数回调的键
+type unsupportedParamsKey struct{}
+
+// WithUnsupportedParamsHandler 返回携带回调的上下文
+// CreateChatCompletion 确定供应商后、输出任何响应之前调用一次 fn，参数为 UnsupportedParams 的结果(可能为空)，
+// 调用方可以据此设置响应头等；流式响应中 fn 返回前不会写入任何数据
+func WithUnsupportedParamsHandler(ctx context.Context, fn func(params []string)) context.Context {
+	return context.WithValue(ctx, unsupportedParamsKey{}, fn)
+}
+
+// notifyUnsupportedParams 调用上下文中的不支持参数回调
+func notifyUnsupportedParams(ctx context.Context, req ChatRequest) {
+	if fn, ok := ctx.Value(unsupportedParamsKey{}).(func(params []string)); ok && fn != nil {
+		fn(UnsupportedParams(req))
+	}
+}
+
+// requestVendorOptions 返回请求的 vendor_options 中对供应商 vendor 生效的部分，以及被忽略的字段
+// 只保留该供应商的配置段中的生成参数，地址、密钥、超时、HTTP客户端等连接设置始终以凭证为准，
+// 避免客户端借此改写凭证或让服务端访问任意地址
+func requestVendorOptions(vendor string, options *VendorOptional) (*VendorOptional, []string) {
+	if options == nil {
+		return nil, nil
+	}
+
+	var ignored []string
+	ignore := func(field string, set bool) {
+		if set {
+			ignored = append(ignored, "vendor_options."+field)
+		}
+	}
+
+	// 其他供应商的配置段
+	sections := []struct {
+		field  string
+		vendor string
+		set    bool
+	}{
+		{"openai_config", "openai", options.OpenAIConfig != nil},
+		{"azure_config", "azure", options.AzureConfig != nil},
+		{"claude_config", "claude", options.ClaudeConfig != nil},
+		{"bedrock_config", "bedrock", options.BedrockConfig != nil},
+		{"deepseek_config", "deepseek", options.DeepSeekConfig != nil},
+		{"gemini_config", "gemini", options.GeminiConfig != nil},
+		{"qianfan_config", "qianfan", options.QianFanConfig != nil},
+		{"qwen_config", "qwen", options.QwenConfig != nil},
+		{"ollama_config", "ollama", options.OllamaConfig != nil},
+		{"ark_config", "ark", options.ArkConfig != nil},
+	}
+	for _, section := range sections {
+		ignore(section.field, section.set && section.vendor != vendor)
+	}
+
+	result := &VendorOptional{}
+	switch vendor {
+	case "openai":
+		if conf := options.OpenAIConfig; conf != nil {
+			ignore("openai_config.timeout", conf.Timeout != 0)
+			ignore("openai_config.http_client", conf.HTTPClient != nil)
+			result.OpenAIConfig = &OpenAIConfig{
+				PresencePenalty:  conf.PresencePenalty,
+				ResponseFormat:   conf.ResponseFormat,
+				Seed:             conf.Seed,
+				FrequencyPenalty: conf.FrequencyPenalty,
+				LogitBias:        conf.LogitBias,
+				User:             conf.User,
+			}
+		}
+	case "azure":
+		if conf := options.AzureConfig; conf != nil {
+			ignore("azure_config.timeout", conf.Timeout != 0)
+			ignore("azure_config.http_client", conf.HTTPClient != nil)
+			result.AzureConfig = &AzureConfig{
+				PresencePenalty:  conf.PresencePenalty,
+				ResponseFormat:   conf.ResponseFormat,
+				Seed:             conf.Seed,
+				FrequencyPenalty: conf.FrequencyPenalty,
+				LogitBias:        conf.LogitBias,
+				User:             conf.User,
+			}
+		}
+	case "claude":
+		if conf := options.ClaudeConfig; conf != nil {
+			ignore("claude_config.base_url", conf.BaseURL != nil)
+			ignore("claude_config.api_key", conf.APIKey != "")
+			result.ClaudeConfig = &ClaudeConfig{TopK: conf.TopK}
+		}
+	case "bedrock":
+		if conf := options.BedrockConfig; conf != nil {
+			ignore("bedrock_config.access_key", conf.AccessKey != "")
+			ignore("bedrock_config.secret_access_key", conf.SecretAccessKey != "")
+			ignore("bedrock_config.session_token", conf.SessionToken != "")
+			ignore("bedrock_config.region", conf.Region != "")
+			result.BedrockConfig = &BedrockConfig{TopK: conf.TopK}
+		}
+	case "deepseek":
+		if conf := options.DeepSeekConfig; conf != nil {
+			ignore("deepseek_config.base_url", conf.BaseURL != "")
+			ignore("deepseek_config.proxy", conf.Proxy != "")
+			ignore("deepseek_config.api_key", conf.APIKey != "")
+			ignore("deepseek_config.timeout", conf.Timeout != 0)
+			result.DeepSeekConfig = &DeepSeekConfig{
+				PresencePenalty:    conf.PresencePenalty,
+				ResponseFormatType: conf.ResponseFormatType,
+				FrequencyPenalty:   conf.FrequencyPenalty,
+			}
+		}
+	case "gemini":
+		if conf := options.GeminiConfig; conf != nil {
+			ignore("gemini_config.api_endpoint", conf.APIEndpoint != "")
+			ignore("gemini_config.generation_config", len(conf.GenerationConfig) > 0)
+			result.GeminiConfig = &GeminiConfig{
+				SafetySettings:      conf.SafetySettings,
+				TopK:                conf.TopK,
+				EnableCodeExecution: conf.EnableCodeExecution,
+				ResponseSchema:      conf.ResponseSchema,
+			}
+		}
+	case "ollama":
+		if conf := options.OllamaConfig; conf != nil {
+			ignore("ollama_config.host", conf.Host != "")
+			result.OllamaConfig = &OllamaConfig{Format: conf.Format}
+		}
+	case "qianfan":
+		// 千帆、通义千问、火山方舟的配置段都是连接设置
+		ignore("qianfan_config", options.QianFanConfig != nil)
+	case "qwen":
+		ignore("qwen_config", options.QwenConfig != nil)
+	case "ark":
+		ignore("ark_config", options.ArkConfig != nil)
+	}
+	return result, ignored
+}
+
+// applyOpenAIParams 将请求的OpenAI标准参数设置到eino的OpenAI模型配置中
+// vendor_options 中已经设置的同名参数优先，不覆盖
+func (c *Config) applyOpenAIParams(conf *einoopenai.ChatModelConfig) error {
+	if conf.Seed == nil {
+		conf.Seed = c.Seed
+	}
+	if conf.PresencePenalty == nil && c.PresencePenalty != 0 {
+		presencePenalty := c.PresencePenalty
+		conf.PresencePenalty = &presencePenalty
+	}
+	if conf.FrequencyPenalty == nil && c.FrequencyPenalty != 0 {
+		frequencyPenalty := c.FrequencyPenalty
+		conf.FrequencyPenalty = &frequencyPenalty
+	}
+	if conf.LogitBias == nil {
+		conf.LogitBias = c.LogitBias
+	}
+	if conf.ResponseFormat == nil && c.ResponseFormat != nil {
+		format, err := toEinoResponseFormat(c.ResponseFormat)
+		if err != nil {
+			return err
+		}
+		conf.ResponseFormat = format
+	}
+	return nil
+}
+
+// toEinoResponseFormat 将OpenAI格式的 response_format 转换为eino OpenAI组件的格式
+// JSON Schema 作为 openapi3.Schema 的扩展字段保存，序列化时原样输出，不受 openapi3 与JSON Schema差异的影响(如 type 为数组)
+func toEinoResponseFormat(format *openai.ChatCompletionResponseFormat) (*aclopenai.ChatCompletionResponseFormat, error) {
+	result := &aclopenai.ChatCompletionResponseFormat{Type: aclopenai.ChatCompletionResponseFormatType(format.Type)}
+	if format.JSONSchema == nil {
+		return result, nil
+	}
+
+	result.JSONSchema = &aclopenai.ChatCompletionResponseFormatJSONSchema{
+		Name:        format.JSONSchema.Name,
+		Description: format.JSONSchema.Description,
+		Strict:      format.JSONSchema.Strict,
+	}
+	if format.JSONSchema.Schema != nil {
+		data, err := json.Marshal(format.JSONSchema.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("序列化response_format的JSON Schema失败: %v", err)
+		}
+		var schema map[string]any
+		if err := json.Unmarshal(data, &schema); err != nil {
+			return nil, fmt.Errorf("解析response_format的JSON Schema失败: %v", err)
+		}
+		result.JSONSchema.Schema = &openapi3.Schema{Extensions: schema}
+	}
+	return result, nil
+}
@@ -0,0 +1,221 @@
+package llmadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStandardParamsForwarded 测试OpenAI标准参数和 vendor_options 传递到OpenAI接口
+func TestStandardParamsForwarded(t *testing.T) {
+	server := newOpenAITestServer(t)
+	useTestCredentials(t, map[string]string{
+		"openai.yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: openai-test
+        base_url: %s/v1
+        api_key: "%s"
+        enabled: true
+        weight: 1
+        models: ["gpt-4o"]
+`, server.URL, encryptTestKey(t, "openai-key")),
+		"openai_compatible.yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: vllm
+        base_url: %s/v1
+        enabled: true
+        weight: 1
+        models: ["qwen"]
+`, server.URL),
+	})
+
+	seed := 42
+	t.Run("标准参数", func(t *testing.T) {
+		req := weatherToolRequest("openai_compatible", "qwen")
+		req.Seed = &seed
+		req.PresencePenalty = 0.5
+		req.FrequencyPenalty = 0.25
+		req.LogitBias = map[string]int{"50256": -100}
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+		req.ToolChoice = "required"
+		_, err := CreateChatCompletion(context.Background(), req, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		body := server.lastRequest(t).Body
+		assert.Equal(t, float64(42), body["seed"])
+		assert.Equal(t, 0.5, body["presence_penalty"])
+		assert.Equal(t, 0.25, body["frequency_penalty"])
+		assert.Equal(t, map[string]any{"50256": float64(-100)}, body["logit_bias"])
+		assert.Equal(t, map[string]any{"type": "json_object"}, body["response_format"])
+		// 只有一个工具时强制调用该工具
+		assert.Contains(t, fmt.Sprint(body["tool_choice"]), "get_weather")
+		assert.Empty(t, UnsupportedParams(req))
+	})
+
+	t.Run("指定函数和禁止调用", func(t *testing.T) {
+		req := weatherToolRequest("openai_compatible", "qwen")
+		req.Tools = append(req.Tools, openai.Tool{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+			Name: "get_time", Parameters: map[string]any{"type": "object", "properties": map[string]any{}},
+		}})
+		req.ToolChoice = map[string]any{"type": "function", "function": map[string]any{"name": "get_time"}}
+		_, err := CreateChatCompletion(context.Background(), req, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		body := server.lastRequest(t).Body
+		assert.Contains(t, fmt.Sprint(body["tools"]), "get_time")
+		assert.NotContains(t, fmt.Sprint(body["tools"]), "get_weather")
+		assert.Contains(t, fmt.Sprint(body["tool_choice"]), "get_time")
+
+		req.ToolChoice = "none"
+		_, err = CreateChatCompletion(context.Background(), req, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Nil(t, server.lastRequest(t).Body["tools"])
+
+		req.ToolChoice = map[string]any{"type": "function", "function": map[string]any{"name": "not_exist"}}
+		_, err = CreateChatCompletion(context.Background(), req, nil)
+		assert.ErrorContains(t, err, "not_exist")
+	})
+
+	t.Run("vendor_options优先", func(t *testing.T) {
+		vendorSeed := 7
+		req := weatherToolRequest("openai", "gpt-4o")
+		req.Seed = &seed
+		req.PresencePenalty = 0.5
+		req.VendorOptions = &VendorOptional{
+			OpenAIConfig: &OpenAIConfig{Seed: &vendorSeed, Timeout: time.Millisecond, HTTPClient: &http.Client{}},
+			ClaudeConfig: &ClaudeConfig{},
+		}
+		_, err := CreateChatCompletion(context.Background(), req, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		body := server.lastRequest(t).Body
+		assert.Equal(t, float64(7), body["seed"])
+		assert.Equal(t, 0.5, body["presence_penalty"])
+		// 请求设置的HTTP客户端和超时被忽略，仍然使用凭证的设置
+		assert.Equal(t, "Bearer openai-key", server.lastRequest(t).Authorization)
+		assert.Equal(t, []string{"vendor_options.claude_config", "vendor_options.openai_config.timeout", "vendor_options.openai_config.http_client"}, UnsupportedParams(req))
+	})
+}
+
+// TestUnsupportedParams 测试不支持的参数检测以及回调
+func TestUnsupportedParams(t *testing.T) {
+	useTestCredentials(t, map[string]string{"mock.yaml": `environments:
+  {{env}}:
+    credentials:
+      - name: mock-params
+        enabled: true
+        weight: 1
+        rules:
+          - reply: "好的"
+`})
+
+	seed := 1
+	req := mockChatRequest("你好", false)
+	req.Seed = &seed
+	req.LogitBias = map[string]int{"1": 1}
+	req.ParallelToolCalls = false
+	req.VendorOptions = &VendorOptional{ClaudeConfig: &ClaudeConfig{}}
+	assert.Equal(t, []string{ParamSeed, ParamLogitBias, ParamParallelToolCalls, "vendor_options.claude_config"}, UnsupportedParams(req))
+
+	// Claude只支持 tool_choice
+	claudeReq := weatherToolRequest("claude", "claude-sonnet-4")
+	claudeReq.ToolChoice = "required"
+	claudeReq.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	claudeReq.VendorOptions = &VendorOptional{ClaudeConfig: &ClaudeConfig{APIKey: "sk-other"}}
+	assert.Equal(t, []string{ParamResponseFormat, "vendor_options.claude_config.api_key"}, UnsupportedParams(claudeReq))
+
+	// 文件内容所有供应商都不支持
+	fileReq := mockChatRequest("", false)
+	fileReq.Messages[0].MultiContent = []openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeText, Text: "总结这份文件"}, {Type: "file"}}
+	assert.Equal(t, []string{ParamFileContent}, UnsupportedParams(fileReq))
+
+	t.Run("流式输出前调用回调", func(t *testing.T) {
+		streamReq := req
+		streamReq.Stream = true
+		buffer := &bytes.Buffer{}
+		var got []string
+		ctx := WithUnsupportedParamsHandler(context.Background(), func(params []string) {
+			assert.Zero(t, buffer.Len())
+			got = params
+		})
+		_, err := CreateChatCompletion(ctx, streamReq, buffer)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, UnsupportedParams(req), got)
+		assert.Contains(t, buffer.String(), "好的")
+	})
+}
+
+// TestRequestVendorOptions 测试请求的 vendor_options 只保留对当前供应商生效的生成参数
+func TestRequestVendorOptions(t *testing.T) {
+	var options VendorOptional
+	err := json.Unmarshal([]byte(`{
+		"claude_config": {"top_k": 40, "base_url": "http://evil.example.com", "api_key": "sk-other"},
+		"gemini_config": {"top_k": 10, "safety_settings": {"harassment": "none"}},
+		"ollama_config": {"host": "http://10.0.0.1:11434", "format": "json"}
+	}`), &options)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	claudeOptions, ignored := requestVendorOptions("claude", &options)
+	assert.Equal(t, &VendorOptional{ClaudeConfig: &ClaudeConfig{TopK: options.ClaudeConfig.TopK}}, claudeOptions)
+	assert.Equal(t, int32(40), *claudeOptions.ClaudeConfig.TopK)
+	assert.Equal(t, []string{
+		"vendor_options.gemini_config", "vendor_options.ollama_config",
+		"vendor_options.claude_config.base_url", "vendor_options.claude_config.api_key",
+	}, ignored)
+
+	ollamaOptions, ignored := requestVendorOptions("ollama", &options)
+	assert.Equal(t, &OllamaConfig{Format: "json"}, ollamaOptions.OllamaConfig)
+	assert.Contains(t, ignored, "vendor_options.ollama_config.host")
+
+	geminiOptions, _ := requestVendorOptions("gemini", &options)
+	assert.Equal(t, map[string]interface{}{"harassment": "none"}, geminiOptions.GeminiConfig.SafetySettings)
+
+	qianfanOptions, ignored := requestVendorOptions("qianfan", &VendorOptional{QianFanConfig: &QianFanConfig{Endpoint: "http://10.0.0.1"}})
+	assert.Nil(t, qianfanOptions.QianFanConfig)
+	assert.Equal(t, []string{"vendor_options.qianfan_config"}, ignored)
+
+	none, ignored := requestVendorOptions("openai", nil)
+	assert.Nil(t, none)
+	assert.Empty(t, ignored)
+}
+
+// TestParseToolChoice 测试 tool_choice 的解析
+func TestParseToolChoice(t *testing.T) {
+	tests := []struct {
+		toolChoice   any
+		wantChoice   string
+		wantFunction string
+	}{
+		{toolChoice: nil},
+		{toolChoice: "none", wantChoice: toolChoiceNone},
+		{toolChoice: "auto", wantChoice: toolChoiceAuto},
+		{toolChoice: "required", wantChoice: toolChoiceRequired},
+		{toolChoice: "any", wantChoice: toolChoiceRequired},
+		{toolChoice: "unknown"},
+		{toolChoice: openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: "get_weather"}}, wantChoice: toolChoiceRequired, wantFunction: "get_weather"},
+		{toolChoice: map[string]any{"type": "function"}},
+	}
+	for _, tt := range tests {
+		choice, function := parseToolChoice(tt.toolChoice)
+		assert.Equal(t, tt.wantChoice, choice, "%v", tt.toolChoice)
+		assert.Equal(t, tt.wantFunction, function, "%v", tt.toolChoice)
+	}
+}
@@ -1,6 +1,10 @@
 package llmadapter
 
-import "github.com/sashabaranov/go-openai"
+import (
+	"encoding/json"
+
+	"github.com/sashabaranov/go-openai"
+)
 
 // ChatCompletionRequest 聊天完成请求
 type ChatCompletionRequest struct {
@@ -69,13 +73,61 @@ type ChatCompletionStreamChoice struct {
 type ChatCompletionStreamDelta struct {
 	Role             string `json:"role,omitempty"`              // 角色
 	Content          string `json:"content,omitempty"`           // 内容
-	ReasoningContent string `json:"reasoning_content,omitempty"` // 推理内容，用于DeepSeek模型
+	ReasoningContent string `json:"reasoning_content,omitempty"` // 推理内容，DeepSeek、Claude等推理模型输出
 }
 
 // ChatRequest 聊天请求
 type ChatRequest struct {
 	Provider string `json:"provider,omitempty"` // 供应商：openai, azure等
 	openai.ChatCompletionRequest
+
+	// Thinking 推理(思考)设置，按供应商映射为对应的参数，见 ThinkingOptions
+	Thinking *ThinkingOptions `json:"thinking,omitempty"`
+	// HideReasoning 为true时不向客户端返回推理内容(reasoning_content)，推理Token仍计入用量
+	HideReasoning bool `json:"hide_reasoning,omitempty"`
+	// VendorOptions 供应商特有的生成参数，如Claude的 top_k、Gemini的 safety_settings，格式见 VendorOptional；
+	// 只有请求的供应商对应的配置段生效，地址、密钥等连接设置不允许通过请求设置，被忽略的字段见 UnsupportedParams
+	VendorOptions *VendorOptional `json:"vendor_options,omitempty"`
+
+	// Fallbacks 本次请求的备用供应商和模型，由调用方的路由规则设置；不为空时代替 RetryPolicy 中的故障转移规则
+	Fallbacks []FallbackTarget `json:"-"`
+}
+
+// UnmarshalJSON 解析聊天请求
+// go-openai 中 response_format.json_schema.schema 的类型为 json.Marshaler 接口，无法直接解析，这里保存为 json.RawMessage
+func (r *ChatRequest) UnmarshalJSON(data []byte) error {
+	type plain ChatRequest
+	aux := struct {
+		*plain
+		ResponseFormat *struct {
+			Type       openai.ChatCompletionResponseFormatType `json:"type"`
+			JSONSchema *struct {
+				Name        string          `json:"name"`
+				Description string          `json:"description"`
+				Schema      json.RawMessage `json:"schema"`
+				Strict      bool            `json:"strict"`
+			} `json:"json_schema"`
+		} `json:"response_format"`
+	}{plain: (*plain)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	r.ResponseFormat = nil
+	if format := aux.ResponseFormat; format != nil {
+		r.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: format.Type}
+		if jsonSchema := format.JSONSchema; jsonSchema != nil {
+			r.ResponseFormat.JSONSchema = &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:        jsonSchema.Name,
+				Description: jsonSchema.Description,
+				Strict:      jsonSchema.Strict,
+			}
+			if len(jsonSchema.Schema) > 0 && string(jsonSchema.Schema) != "null" {
+				r.ResponseFormat.JSONSchema.Schema = jsonSchema.Schema
+			}
+		}
+	}
+	return nil
 }
 
 // ChatResponse 聊天响应
@@ -0,0 +1,103 @@
+package llmadapter
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStreamHeartbeat 流式响应默认的心跳间隔
+const DefaultStreamHeartbeat = 15 * time.Second
+
+// streamHeartbeat 当前使用的心跳间隔，<=0 表示不发送心跳
+var streamHeartbeat atomic.Int64
+
+func init() {
+	streamHeartbeat.Store(int64(DefaultStreamHeartbeat))
+}
+
+// SetStreamHeartbeat 设置流式响应的心跳间隔
+// 上游超过 interval 没有输出时(如模型长时间思考、等待重试)向客户端发送 ": ping" 注释行，避免代理因连接空闲断开；
+// interval<=0 时不发送心跳
+func SetStreamHeartbeat(interval time.Duration) {
+	streamHeartbeat.Store(int64(interval))
+}
+
+// sseWriter 向客户端写入SSE事件，每次写入后立即刷新(writer 实现了 http.Flusher 时)
+// 心跳协程和转发流式分片的协程共用同一个 sseWriter，写入时加锁
+type sseWriter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	lastWrite time.Time
+}
+
+func newSSEWriter(w io.Writer) *sseWriter {
+	return &sseWriter{w: w, lastWrite: time.Now()}
+}
+
+// write 写入一个完整的事件并刷新
+func (s *sseWriter) write(event []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(event); err != nil {
+		return err
+	}
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	s.lastWrite = time.Now()
+	return nil
+}
+
+// writeData 写入一个 data 事件
+func (s *sseWriter) writeData(data []byte) error {
+	event := make([]byte, 0, len(data)+8)
+	event = append(event, "data: "...)
+	event = append(event, data...)
+	event = append(event, "\n\n"...)
+	return s.write(event)
+}
+
+// idle 距离上次写入的时间
+func (s *sseWriter) idle() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.lastWrite)
+}
+
+// startHeartbeat 开始定期检查，超过 interval 没有写入时发送心跳
+// 返回的 stop 等待心跳协程退出，调用后不会再写入 writer
+func (s *sseWriter) startHeartbeat(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if s.idle() < interval {
+					continue
+				}
+				// 写入失败说明客户端已断开，由转发流程处理
+				if err := s.write([]byte(": ping\n\n")); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
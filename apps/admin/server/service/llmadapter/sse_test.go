@@ -0,0 +1,115 @@
+package llmadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// flushRecorder 记录写入内容和刷新次数
+type flushRecorder struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	flushes int
+}
+
+func (r *flushRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(p)
+}
+
+func (r *flushRecorder) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushes++
+}
+
+// TestStreamEvents 测试流式响应的刷新、心跳和用量分片
+func TestStreamEvents(t *testing.T) {
+	useTestCredentials(t, map[string]string{"mock.yaml": `environments:
+  {{env}}:
+    credentials:
+      - name: mock-sse
+        enabled: true
+        weight: 1
+        chunk_size: 2
+        rules:
+          - reply: "abcd"
+            prompt_tokens: 5
+            completion_tokens: 2
+`})
+
+	t.Run("每个分片刷新", func(t *testing.T) {
+		recorder := &flushRecorder{}
+		_, err := CreateChatCompletion(context.Background(), mockChatRequest("hi", true), recorder)
+		if !assert.NoError(t, err) {
+			return
+		}
+		output := recorder.buf.String()
+		// 两个内容分片、结束分片和 [DONE]，供应商返回的用量不输出
+		assert.Equal(t, 4, strings.Count(output, "data: "))
+		assert.Equal(t, 4, recorder.flushes)
+		assert.NotContains(t, output, `"usage"`)
+	})
+
+	t.Run("用量分片", func(t *testing.T) {
+		req := mockChatRequest("hi", true)
+		req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+		buffer := &bytes.Buffer{}
+		_, err := CreateChatCompletion(context.Background(), req, buffer)
+		if !assert.NoError(t, err) {
+			return
+		}
+		events := strings.Split(strings.TrimSuffix(buffer.String(), "\n\n"), "\n\n")
+		if !assert.GreaterOrEqual(t, len(events), 2) {
+			return
+		}
+		assert.Equal(t, "data: [DONE]", events[len(events)-1])
+		var chunk openai.ChatCompletionStreamResponse
+		if assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[len(events)-2], "data: ")), &chunk)) {
+			assert.Empty(t, chunk.Choices)
+			assert.Equal(t, &openai.Usage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7}, chunk.Usage)
+			assert.Equal(t, "chat.completion.chunk", chunk.Object)
+			assert.Equal(t, "mock-model", chunk.Model)
+		}
+		assert.Contains(t, events[len(events)-2], `"choices":[]`)
+		assert.Equal(t, 1, strings.Count(buffer.String(), `"usage"`))
+	})
+
+	t.Run("心跳", func(t *testing.T) {
+		SetStreamHeartbeat(10 * time.Millisecond)
+		t.Cleanup(func() { SetStreamHeartbeat(DefaultStreamHeartbeat) })
+		useTestCredentials(t, map[string]string{"mock.yaml": `environments:
+  {{env}}:
+    credentials:
+      - name: mock-slow
+        enabled: true
+        weight: 1
+        first_token_delay: 80
+`})
+
+		recorder := &flushRecorder{}
+		_, err := CreateChatCompletion(context.Background(), mockChatRequest("slow", true), recorder)
+		if !assert.NoError(t, err) {
+			return
+		}
+		output := recorder.buf.String()
+		assert.True(t, strings.HasPrefix(output, ": ping\n\n"), output)
+		assert.True(t, strings.HasSuffix(output, "data: [DONE]\n\n"))
+
+		// 返回后不再发送心跳
+		length := len(output)
+		time.Sleep(30 * time.Millisecond)
+		recorder.mu.Lock()
+		assert.Equal(t, length, recorder.buf.Len())
+		recorder.mu.Unlock()
+	})
+}
@@ -0,0 +1,267 @@
+package llmadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/sashabaranov/go-openai"
+)
+
+// structuredOutputMode 供应商实现 response_format 为 json_schema 的方式
+type structuredOutputMode int
+
+const (
+	// structuredOutputNative 供应商原生支持，直接传递JSON Schema，如OpenAI及兼容接口、Gemini
+	structuredOutputNative structuredOutputMode = iota
+	// structuredOutputTool 定义一个参数为该Schema的工具并强制调用，将调用参数作为回复内容，如Claude、Bedrock
+	structuredOutputTool
+	// structuredOutputPrompt 使用 json_object 模式，并在系统提示词中给出Schema，如DeepSeek
+	structuredOutputPrompt
+)
+
+// structuredOutputToolName structuredOutputTool 方式定义的工具名称
+const structuredOutputToolName = "structured_output"
+
+// StructuredOutputError 请求 response_format 为 json_schema 时，模型的输出自动修复一次后仍不符合JSON Schema
+type StructuredOutputError struct {
+	Output string   // 修复后的输出
+	Errors []string // 不符合的位置和原因，如 "$.items[0].price: 类型应为 number，实际为 string"
+}
+
+// Error 实现 error 接口
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("模型输出不符合 response_format 的JSON Schema: %s", strings.Join(e.Errors, "; "))
+}
+
+// isJSONSchemaFormat 判断请求的 response_format 是否为 json_schema
+func isJSONSchemaFormat(format *openai.ChatCompletionResponseFormat) bool {
+	return format != nil && format.Type == openai.ChatCompletionResponseFormatTypeJSONSchema
+}
+
+// responseJSONSchema 返回 response_format 中的JSON Schema
+func responseJSONSchema(format *openai.ChatCompletionResponseFormat) (map[string]any, error) {
+	if format.JSONSchema == nil || format.JSONSchema.Schema == nil {
+		return nil, invalidRequestf("response_format 为 json_schema 时必须设置 json_schema.schema")
+	}
+	jsonSchema, err := toJSONMap(format.JSONSchema.Schema)
+	if err != nil || jsonSchema == nil {
+		return nil, invalidRequestf("解析 response_format 的JSON Schema失败: %v", err)
+	}
+	return jsonSchema, nil
+}
+
+// providerGenerate 调用供应商的非流式接口，用于 callWithFailover
+func providerGenerate(ctx context.Context, provider Provider, req ChatRequest) (*openai.ChatCompletionResponse, error) {
+	return provider.Generate(ctx, req)
+}
+
+// generate 非流式调用，按重试策略重试和故障转移
+// response_format 为 json_schema 时校验输出是否符合Schema，见 generateStructured
+func generate(ctx context.Context, req ChatRequest) (*openai.ChatCompletionResponse, error) {
+	if !isJSONSchemaFormat(req.ResponseFormat) {
+		return callWithFailover(ctx, req, providerGenerate)
+	}
+	return generateStructured(ctx, req)
+}
+
+// generateStructured 生成符合 response_format 中JSON Schema的回复，只处理一个候选回复
+// 输出不是合法的JSON或不符合Schema时，将输出和不符合的原因发回给第一次调用实际使用的供应商和模型，要求修正一次；
+// 修正后仍不符合时返回 *StructuredOutputError。回复调用了请求中的工具时不校验。
+// 回复内容去掉Markdown代码块标记；响应和 CallInfo 的用量为两次调用之和
+func generateStructured(ctx context.Context, req ChatRequest) (*openai.ChatCompletionResponse, error) {
+	jsonSchema, err := responseJSONSchema(req.ResponseFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	info := callInfoFromContext(ctx)
+	firstCtx, first := WithCallInfo(ctx)
+	resp, err := callWithFailover(firstCtx, req, providerGenerate)
+	if info != nil {
+		*info = *first
+	}
+	if err != nil {
+		return nil, err
+	}
+	output, failures := checkStructuredOutput(jsonSchema, resp)
+	if len(failures) == 0 {
+		return resp, nil
+	}
+
+	repairReq := req
+	repairReq.Provider, repairReq.Model = first.Provider, first.Model
+	repairReq.Fallbacks = nil
+	repairReq.Messages = append(slices.Clone(req.Messages),
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: output},
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: structuredRepairPrompt(failures)},
+	)
+	repairCtx, repair := WithCallInfo(ctx)
+	repaired, err := callWithFailover(repairCtx, repairReq, providerGenerate)
+	usage := resp.Usage
+	if repaired != nil {
+		usage = addUsage(usage, repaired.Usage)
+	}
+	if info != nil {
+		*info = *repair
+		info.Attempts += first.Attempts
+		info.Fallback = info.Fallback || first.Fallback
+		info.Usage = usage
+	}
+	if err != nil {
+		return nil, err
+	}
+	repaired.Usage = usage
+	if output, failures = checkStructuredOutput(jsonSchema, repaired); len(failures) > 0 {
+		return nil, &StructuredOutputError{Output: output, Errors: failures}
+	}
+	return repaired, nil
+}
+
+// checkStructuredOutput 校验响应的第一个候选回复，返回去掉代码块标记后的内容和不符合Schema的原因
+// 内容符合Schema时替换为去掉代码块标记后的内容
+func checkStructuredOutput(jsonSchema map[string]any, resp *openai.ChatCompletionResponse) (string, []string) {
+	if len(resp.Choices) == 0 {
+		return "", []string{"$: 没有返回回复"}
+	}
+	message := &resp.Choices[0].Message
+	if len(message.ToolCalls) > 0 {
+		return message.Content, nil
+	}
+
+	output := trimCodeFence(message.Content)
+	var value any
+	if err := json.Unmarshal([]byte(output), &value); err != nil {
+		return output, []string{fmt.Sprintf("$: 不是合法的JSON: %v", err)}
+	}
+	if failures := validateJSONSchema(jsonSchema, value); len(failures) > 0 {
+		return output, failures
+	}
+	message.Content = output
+	return output, nil
+}
+
+// trimCodeFence 去掉模型输出中包裹JSON的Markdown代码块标记，如 ```json ... ```
+func trimCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") || !strings.HasSuffix(content, "```") || len(content) < 6 {
+		return content
+	}
+	content = strings.TrimSuffix(strings.TrimPrefix(content, "```"), "```")
+	// 去掉语言标记所在的第一行
+	if newline := strings.IndexByte(content, '\n'); newline >= 0 && !strings.ContainsAny(content[:newline], "{[") {
+		content = content[newline+1:]
+	}
+	return strings.TrimSpace(content)
+}
+
+// structuredRepairPrompt 要求模型修正不符合Schema的输出的提示词
+func structuredRepairPrompt(failures []string) string {
+	return "上一条回复不符合要求的JSON Schema:\n- " + strings.Join(failures, "\n- ") +
+		"\n请修正以上问题后重新回复，只输出符合JSON Schema的JSON，不要包含任何其他内容。"
+}
+
+// structuredSystemPrompt structuredOutputPrompt 方式在系统提示词中给出JSON Schema
+func structuredSystemPrompt(format *openai.ChatCompletionResponseFormat) (string, error) {
+	jsonSchema, err := responseJSONSchema(format)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(jsonSchema)
+	if err != nil {
+		return "", fmt.Errorf("序列化 response_format 的JSON Schema失败: %v", err)
+	}
+	prompt := "只输出一个符合以下JSON Schema的JSON，不要包含任何其他内容:\n" + string(data)
+	if format.JSONSchema.Description != "" {
+		prompt = format.JSONSchema.Description + "\n" + prompt
+	}
+	return prompt, nil
+}
+
+// structuredOutputToolInfo structuredOutputTool 方式定义的工具，参数为 response_format 中的JSON Schema
+// 工具参数只能是对象，因此要求Schema的根类型为 object；$ref 替换为所指向的Schema
+func structuredOutputToolInfo(format *openai.ChatCompletionResponseFormat) (*schema.ToolInfo, error) {
+	jsonSchema, err := responseJSONSchema(format)
+	if err != nil {
+		return nil, err
+	}
+	if jsonSchema, err = inlineSchemaRefs(jsonSchema); err != nil {
+		return nil, err
+	}
+	if jsonSchema["type"] != "object" {
+		return nil, invalidRequestf("该供应商通过工具调用实现 json_schema，JSON Schema的根类型必须为 object")
+	}
+
+	// 属性直接使用原始的JSON Schema，序列化时原样输出，保留嵌套的对象、数组和枚举
+	properties, err := schemaProperties(jsonSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := format.JSONSchema.Description
+	if desc == "" {
+		desc = "以JSON格式输出最终回复，参数即回复内容"
+	}
+	return &schema.ToolInfo{
+		Name: structuredOutputToolName,
+		Desc: desc,
+		ParamsOneOf: schema.NewParamsOneOfByOpenAPIV3(&openapi3.Schema{
+			Type:       openapi3.TypeObject,
+			Properties: properties,
+			Required:   getRequiredFields(jsonSchema),
+		}),
+	}, nil
+}
+
+// takeStructuredOutput 将 structuredOutputTool 方式的工具调用参数作为回复内容，并从工具调用中去掉
+func takeStructuredOutput(msg *schema.Message) {
+	index := slices.IndexFunc(msg.ToolCalls, func(toolCall schema.ToolCall) bool {
+		return toolCall.Function.Name == structuredOutputToolName
+	})
+	if index < 0 {
+		return
+	}
+	msg.Content = msg.ToolCalls[index].Function.Arguments
+	msg.ToolCalls = slices.Delete(msg.ToolCalls, index, index+1)
+	if len(msg.ToolCalls) == 0 && msg.ResponseMeta != nil {
+		msg.ResponseMeta.FinishReason = string(openai.FinishReasonStop)
+	}
+}
+
+// responseChunks 将完整的响应转换为增量响应流，每个候选回复一个分片，最后一个分片携带用量
+// 用于需要完整输出后才能返回的流式请求，如 response_format 为 json_schema
+func responseChunks(resp *openai.ChatCompletionResponse) *schema.StreamReader[*openai.ChatCompletionStreamResponse] {
+	chunks := make([]*openai.ChatCompletionStreamResponse, 0, len(resp.Choices))
+	for _, choice := range resp.Choices {
+		toolCalls := slices.Clone(choice.Message.ToolCalls)
+		for i := range toolCalls {
+			index := i
+			toolCalls[i].Index = &index
+		}
+		chunks = append(chunks, &openai.ChatCompletionStreamResponse{
+			ID:      resp.ID,
+			Object:  "chat.completion.chunk",
+			Created: resp.Created,
+			Model:   resp.Model,
+			Choices: []openai.ChatCompletionStreamChoice{{
+				Index: choice.Index,
+				Delta: openai.ChatCompletionStreamChoiceDelta{
+					Role:             choice.Message.Role,
+					Content:          choice.Message.Content,
+					ReasoningContent: choice.Message.ReasoningContent,
+					ToolCalls:        toolCalls,
+				},
+				FinishReason: choice.FinishReason,
+			}},
+		})
+	}
+	if len(chunks) > 0 {
+		usage := resp.Usage
+		chunks[len(chunks)-1].Usage = &usage
+	}
+	return schema.StreamReaderFromArray(chunks)
+}
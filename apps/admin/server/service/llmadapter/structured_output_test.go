@@ -0,0 +1,287 @@
+package llmadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+// cityJSONSchema 测试使用的JSON Schema
+const cityJSONSchema = `{
+	"type": "object",
+	"properties": {"city": {"type": "string"}, "temp": {"type": "integer"}},
+	"required": ["city", "temp"],
+	"additionalProperties": false
+}`
+
+// jsonSchemaFormat 构造 json_schema 类型的 response_format
+func jsonSchemaFormat(schema string) *openai.ChatCompletionResponseFormat {
+	return &openai.ChatCompletionResponseFormat{
+		Type:       openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{Name: "weather", Schema: json.RawMessage(schema)},
+	}
+}
+
+// TestChatRequestUnmarshalJSONSchema 测试请求中的 json_schema.schema 能够解析，并原样传递给OpenAI组件
+func TestChatRequestUnmarshalJSONSchema(t *testing.T) {
+	var req ChatRequest
+	err := json.Unmarshal([]byte(`{
+		"provider": "openai",
+		"model": "gpt-4o",
+		"messages": [{"role": "user", "content": "你好"}],
+		"hide_reasoning": true,
+		"response_format": {"type": "json_schema", "json_schema": {"name": "weather", "strict": true,
+			"schema": {"type": "object", "properties": {"note": {"type": ["string", "null"]}}}}}
+	}`), &req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "openai", req.Provider)
+	assert.Equal(t, "gpt-4o", req.Model)
+	assert.Len(t, req.Messages, 1)
+	assert.True(t, req.HideReasoning)
+	if assert.NotNil(t, req.ResponseFormat) && assert.NotNil(t, req.ResponseFormat.JSONSchema) {
+		assert.Equal(t, "weather", req.ResponseFormat.JSONSchema.Name)
+		assert.True(t, req.ResponseFormat.JSONSchema.Strict)
+	}
+
+	format, err := toEinoResponseFormat(req.ResponseFormat)
+	if assert.NoError(t, err) {
+		data, err := json.Marshal(format.JSONSchema.Schema)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"type": "object", "properties": {"note": {"type": ["string", "null"]}}}`, string(data))
+	}
+
+	// 没有 response_format 以及只有 type 的请求
+	req = ChatRequest{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"model": "gpt-4o", "response_format": {"type": "json_object"}}`), &req))
+	assert.Equal(t, &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}, req.ResponseFormat)
+	req = ChatRequest{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"model": "gpt-4o"}`), &req))
+	assert.Nil(t, req.ResponseFormat)
+}
+
+// TestStructuredOutputRepair 测试输出不符合Schema时自动修正一次，仍不符合时返回 StructuredOutputError
+func TestStructuredOutputRepair(t *testing.T) {
+	useTestCredentials(t, map[string]string{"mock.yaml": `environments:
+  {{env}}:
+    credentials:
+      - name: mock-structured
+        enabled: true
+        weight: 1
+        rules:
+          - model: bad-model
+            reply: '{"city": 1}'
+          - match: "修正"
+            reply: '{"city": "北京", "temp": 20}'
+            prompt_tokens: 30
+            completion_tokens: 10
+          - match: "代码块"
+            reply: "` + "```json\\n{\\\"city\\\": \\\"上海\\\", \\\"temp\\\": 25}\\n```" + `"
+          - reply: '{"city": "北京"}'
+            prompt_tokens: 10
+            completion_tokens: 5
+`})
+
+	t.Run("修正后符合", func(t *testing.T) {
+		req := mockChatRequest("北京天气", false)
+		req.ResponseFormat = jsonSchemaFormat(cityJSONSchema)
+		ctx, info := WithCallInfo(context.Background())
+		resp, err := CreateChatCompletion(ctx, req, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.JSONEq(t, `{"city": "北京", "temp": 20}`, resp.Choices[0].Message.Content)
+		assert.Equal(t, openai.Usage{PromptTokens: 40, CompletionTokens: 15, TotalTokens: 55}, resp.Usage)
+		assert.Equal(t, resp.Usage, info.Usage)
+		assert.Equal(t, 2, info.Attempts)
+		assert.Equal(t, "mock", info.Provider)
+	})
+
+	t.Run("去掉代码块标记", func(t *testing.T) {
+		req := mockChatRequest("代码块", false)
+		req.ResponseFormat = jsonSchemaFormat(cityJSONSchema)
+		ctx, info := WithCallInfo(context.Background())
+		resp, err := CreateChatCompletion(ctx, req, nil)
+		if assert.NoError(t, err) {
+			assert.Equal(t, `{"city": "上海", "temp": 25}`, resp.Choices[0].Message.Content)
+			assert.Equal(t, 1, info.Attempts)
+		}
+	})
+
+	t.Run("流式和多个候选回复", func(t *testing.T) {
+		req := mockChatRequest("北京天气", true)
+		req.ResponseFormat = jsonSchemaFormat(cityJSONSchema)
+		req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+		buffer := &bytes.Buffer{}
+		_, err := CreateChatCompletion(context.Background(), req, buffer)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{`{"city": "北京", "temp": 20}`}, streamContents(t, buffer.String()))
+			assert.Contains(t, buffer.String(), `"total_tokens":55`)
+		}
+
+		req = mockChatRequest("代码块", false)
+		req.ResponseFormat = jsonSchemaFormat(cityJSONSchema)
+		req.N = 2
+		resp, err := CreateChatCompletion(context.Background(), req, nil)
+		if assert.NoError(t, err) && assert.Len(t, resp.Choices, 2) {
+			assert.Equal(t, `{"city": "上海", "temp": 25}`, resp.Choices[1].Message.Content)
+		}
+	})
+
+	t.Run("修正后仍不符合", func(t *testing.T) {
+		req := mockChatRequest("北京天气", false)
+		req.Model = "bad-model"
+		req.ResponseFormat = jsonSchemaFormat(cityJSONSchema)
+		ctx, info := WithCallInfo(context.Background())
+		resp, err := CreateChatCompletion(ctx, req, nil)
+		assert.Nil(t, resp)
+		var structuredErr *StructuredOutputError
+		if assert.True(t, errors.As(err, &structuredErr), "%v", err) {
+			assert.Equal(t, `{"city": 1}`, structuredErr.Output)
+			assert.Equal(t, []string{"$: 缺少必填字段 temp", "$.city: 类型应为 string，实际为 number"}, structuredErr.Errors)
+		}
+		assert.Equal(t, 2, info.Attempts)
+		assert.NotZero(t, info.Usage.TotalTokens)
+
+		// 流式请求同样在输出之前返回错误
+		req.Stream = true
+		buffer := &bytes.Buffer{}
+		_, err = CreateChatCompletion(context.Background(), req, buffer)
+		assert.True(t, errors.As(err, &structuredErr))
+		assert.NotContains(t, buffer.String(), "city")
+	})
+
+	t.Run("缺少Schema", func(t *testing.T) {
+		req := mockChatRequest("北京天气", false)
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONSchema}
+		_, err := CreateChatCompletion(context.Background(), req, nil)
+		assert.ErrorContains(t, err, "json_schema.schema")
+	})
+}
+
+// TestStructuredOutputClaude 测试Claude通过强制调用输出工具实现 json_schema
+func TestStructuredOutputClaude(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{
+			"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-3-5-sonnet-20241022",
+			"content": [{"type": "tool_use", "id": "toolu_1", "name": "structured_output", "input": {"city": "北京", "temp": 20}}],
+			"stop_reason": "tool_use", "stop_sequence": null,
+			"usage": {"input_tokens": 20, "output_tokens": 5}
+		}`)
+	}))
+	defer server.Close()
+
+	useTestCredentials(t, map[string]string{"claude.yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: mock-claude
+        api_key: "%s"
+        base_url: %s
+        enabled: true
+        weight: 1
+`, encryptTestKey(t, "claude-key"), server.URL)})
+
+	req := weatherToolRequest("claude", "claude-3-5-sonnet-20241022")
+	req.MaxTokens = 1024
+	req.ToolChoice = "none"
+	req.ResponseFormat = jsonSchemaFormat(`{
+		"type": "object",
+		"properties": {"city": {"$ref": "#/$defs/city"}, "temp": {"type": "integer"}},
+		"required": ["city", "temp"],
+		"$defs": {"city": {"type": "string", "enum": ["北京", "上海"]}}
+	}`)
+	assert.Empty(t, UnsupportedParams(req))
+	resp, err := CreateChatCompletion(context.Background(), req, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.JSONEq(t, `{"city": "北京", "temp": 20}`, resp.Choices[0].Message.Content)
+	assert.Empty(t, resp.Choices[0].Message.ToolCalls)
+	assert.Equal(t, openai.FinishReasonStop, resp.Choices[0].FinishReason)
+
+	// tool_choice 为 none 时只传递输出工具，$ref 展开后作为工具参数
+	assert.Equal(t, map[string]any{"type": "tool", "name": "structured_output"}, body["tool_choice"])
+	tools, _ := body["tools"].([]any)
+	if assert.Len(t, tools, 1) {
+		tool := tools[0].(map[string]any)
+		assert.Equal(t, "structured_output", tool["name"])
+		assert.JSONEq(t, `{
+			"type": "object",
+			"properties": {"city": {"type": "string", "enum": ["北京", "上海"]}, "temp": {"type": "integer"}},
+			"required": ["city", "temp"]
+		}`, compactJSON(tool["input_schema"]))
+	}
+
+	// 根类型不是 object 时无法通过工具实现
+	req.ResponseFormat = jsonSchemaFormat(`{"type": "array", "items": {"type": "string"}}`)
+	_, err = CreateChatCompletion(context.Background(), req, nil)
+	assert.ErrorContains(t, err, "object")
+}
+
+// TestStructuredOutputNative 测试OpenAI兼容接口和Gemini原生传递JSON Schema
+func TestStructuredOutputNative(t *testing.T) {
+	t.Run("OpenAI兼容接口", func(t *testing.T) {
+		server := newOpenAITestServer(t)
+		useTestCredentials(t, map[string]string{"openai_compatible.yaml": fmt.Sprintf(`environments:
+  {{env}}:
+    credentials:
+      - name: vllm
+        base_url: %s/v1
+        enabled: true
+        weight: 1
+        models: ["qwen"]
+`, server.URL)})
+
+		// 模拟接口返回工具调用，不校验回复
+		req := weatherToolRequest("openai_compatible", "qwen")
+		req.ResponseFormat = jsonSchemaFormat(`{"type": "object", "properties": {"note": {"type": ["string", "null"]}}}`)
+		_, err := CreateChatCompletion(context.Background(), req, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		format, _ := server.lastRequest(t).Body["response_format"].(map[string]any)
+		assert.Equal(t, "json_schema", format["type"])
+		assert.JSONEq(t, `{"name": "weather", "strict": false, "schema": {"type": "object", "properties": {"note": {"type": ["string", "null"]}}}}`, compactJSON(format["json_schema"]))
+	})
+
+	t.Run("Gemini", func(t *testing.T) {
+		var requestBody map[string]any
+		newGeminiTestServer(t, func(w http.ResponseWriter, path string, body map[string]any) {
+			requestBody = body
+			_, _ = w.Write([]byte(`[{
+				"candidates": [{"content": {"role": "model", "parts": [{"text": "{\"city\": \"北京\", \"temp\": 20}"}]}, "finishReason": "STOP"}],
+				"usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 5, "totalTokenCount": 15}
+			}]`))
+		})
+
+		req := ChatRequest{Provider: "gemini", ChatCompletionRequest: openai.ChatCompletionRequest{
+			Model:          "gemini-1.5-pro",
+			Messages:       []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "北京天气"}},
+			ResponseFormat: jsonSchemaFormat(cityJSONSchema),
+		}}
+		resp, err := CreateChatCompletion(context.Background(), req, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.JSONEq(t, `{"city": "北京", "temp": 20}`, resp.Choices[0].Message.Content)
+		config, _ := requestBody["generationConfig"].(map[string]any)
+		assert.Equal(t, "application/json", config["responseMimeType"])
+		// genai的REST客户端按枚举值序列化类型：1为STRING，3为INTEGER，6为OBJECT
+		assert.JSONEq(t, `{"type": 6, "properties": {"city": {"type": 1}, "temp": {"type": 3}}, "required": ["city", "temp"]}`, compactJSON(config["responseSchema"]))
+	})
+}
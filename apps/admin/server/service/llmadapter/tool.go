@@ -22,7 +22,7 @@
 // 2. 保存和加载RSA密钥对
 // 3. 使用RSA公钥加密数据和使用私钥解密数据
 // 4. 为配置设置RSA加密和解密函数
-// 5. 自动管理密钥文件（检查、生成或加载）
+// 5. 通过密钥环(Keyring)管理多个版本的密钥，使用信封加密(AES-GCM数据密钥 + RSA)
 //
 // 使用示例:
 //
@@ -57,15 +57,13 @@
 //	var config map[string]string
 //	json.Unmarshal([]byte(decryptedData), &config)
 //
-// 使用RSA密钥管理器自动管理密钥（如果不存在则创建）:
+// 使用RSA密钥管理器加载默认密钥环（密钥不存在时返回错误）:
 //
-//	// 指定密钥文件路径
-//	privateKeyPath := "/path/to/private.pem"
-//	publicKeyPath := "/path/to/public.pem"
+//	// 指定密钥目录，也可以通过环境变量 LLM_KEY_DIR 或 LLM_PRIVATE_KEYS 指定
+//	SetKeyDir("/path/to/keys")
 //
 //	// 初始化RSA密钥管理器
-//	// 如果密钥文件不存在，将自动生成并保存
-//	encryptFunc, decryptFunc, err := InitRSAKeyManager(privateKeyPath, publicKeyPath)
+//	encryptFunc, decryptFunc, err := InitRSAKeyManager()
 //	if err != nil {
 //		log.Fatalf("初始化RSA密钥管理器失败: %v", err)
 //	}
@@ -93,11 +91,7 @@
 //	dataToEncrypt := "sensitive_credential=xyz123"
 //
 //	// 一步完成初始化和加密
-//	encryptedData, decryptFunc, err := InitRSAKeyManagerWithEncryption(
-//		"/path/to/private.pem",
-//		"/path/to/public.pem",
-//		dataToEncrypt,
-//	)
+//	encryptedData, decryptFunc, err := InitRSAKeyManagerWithEncryption(dataToEncrypt)
 //	if err != nil {
 //		log.Fatalf("加密失败: %v", err)
 //	}
@@ -159,11 +153,10 @@ func init() {
 	dir := filepath.Dir(filename)
 
 	// 设置RSA密钥目录为当前包目录下的rsa_keys子目录
+	// 只适合开发环境，部署时通过 SetKeyDir、LLM_KEY_DIR 或 LLM_PRIVATE_KEYS 指定密钥
 	DefaultRSAKeysDir = filepath.Join(dir, "rsa_keys")
 	DefaultPrivateKeyPath = filepath.Join(DefaultRSAKeysDir, "private_key.pem")
 	DefaultPublicKeyPath = filepath.Join(DefaultRSAKeysDir, "public_key.pem")
-
-	fmt.Printf("RSA密钥将存储在: %s\n", DefaultRSAKeysDir)
 }
 
 // RSAKeyPair 保存RSA密钥对
@@ -403,66 +396,22 @@ func DecryptSensitiveConfig(privateKeyPath, encryptedData string) (string, error
 }
 
 // InitRSAKeyManager 初始化RSA密钥管理器
-// 检查是否已存在默认路径的密钥文件；如果不存在，则生成新的密钥对并保存
-// 返回用于加密和解密的函数，可以直接用于处理敏感数据
+// 返回默认密钥环(见 DefaultKeyring)的加密和解密函数，可以直接用于处理敏感数据；
+// 加密使用当前密钥做信封加密，解密同时支持轮换前的密钥和旧版RSA-OAEP密文。
+// 密钥不存在时返回 ErrKeyNotFound，不会自动生成新密钥，以免已有的密文无法解密
 func InitRSAKeyManager() (
 	encryptFunc func(string) (string, error),
 	decryptFunc func(string) (string, error),
 	err error) {
 
-	// 确保使用绝对路径
-	privateKeyPath := DefaultPrivateKeyPath
-	publicKeyPath := DefaultPublicKeyPath
-
-	// 检查公钥和私钥文件是否都存在
-	privateKeyExists := true
-	publicKeyExists := true
-
-	if _, err := os.Stat(privateKeyPath); os.IsNotExist(err) {
-		privateKeyExists = false
-	}
-
-	if _, err := os.Stat(publicKeyPath); os.IsNotExist(err) {
-		publicKeyExists = false
-	}
-
-	var keyPair *RSAKeyPair
-
-	// 如果任一密钥文件不存在，则生成新的密钥对
-	if !privateKeyExists || !publicKeyExists {
-		// 确保目录存在
-		if err := os.MkdirAll(DefaultRSAKeysDir, 0755); err != nil {
-			return nil, nil, fmt.Errorf("创建密钥目录失败: %v", err)
-		}
-
-		// 生成新的RSA密钥对
-		keyPair, err = GenerateRSAKeyPair()
-		if err != nil {
-			return nil, nil, fmt.Errorf("生成RSA密钥对失败: %v", err)
-		}
-
-		// 保存到文件
-		err = SaveRSAKeyPair(keyPair, privateKeyPath, publicKeyPath)
-		if err != nil {
-			return nil, nil, fmt.Errorf("保存RSA密钥对失败: %v", err)
-		}
-	} else {
-		// 如果密钥文件已存在，则加载它们
-		keyPair, err = LoadRSAKeyPair(privateKeyPath, publicKeyPath)
-		if err != nil {
-			return nil, nil, fmt.Errorf("加载RSA密钥对失败: %v", err)
-		}
+	keyring, err := DefaultKeyring()
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载密钥失败: %w", err)
 	}
-
-	// 创建加密和解密函数
-	encryptFunc = SetRSAEncryptFunc(keyPair.PublicKey)
-	decryptFunc = SetRSADecryptFunc(keyPair.PrivateKey)
-
-	return encryptFunc, decryptFunc, nil
+	return keyring.Encrypt, keyring.Decrypt, nil
 }
 
 // InitRSAKeyManagerWithEncryption 初始化RSA密钥管理器并立即加密指定的数据
-// 如果密钥文件不存在，会生成新的密钥对并保存
 // 返回加密后的数据以及用于后续解密的函数
 func InitRSAKeyManagerWithEncryption(dataToEncrypt string) (
 	encryptedData string,
@@ -481,8 +430,6 @@ func InitRSAKeyManagerWithEncryption(dataToEncrypt string) (
 		return "", nil, fmt.Errorf("加密数据失败: %v", err)
 	}
 
-	fmt.Printf("密钥文件应该存储在: 私钥=%s, 公钥=%s\n", DefaultPrivateKeyPath, DefaultPublicKeyPath)
-
 	return encryptedData, decryptFunc, nil
 }
 
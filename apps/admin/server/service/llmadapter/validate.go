@@ -0,0 +1,221 @@
+package llmadapter
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IssueLevel 凭证配置问题的级别
+type IssueLevel string
+
+const (
+	// IssueError 凭证在该环境中无法使用
+	IssueError IssueLevel = "error"
+	// IssueWarning 可能是配置错误，不影响使用
+	IssueWarning IssueLevel = "warning"
+)
+
+// ValidationIssue 凭证配置校验发现的问题
+type ValidationIssue struct {
+	Level      IssueLevel
+	Vendor     string
+	Env        string // 为空表示整个配置文件
+	Credential string // 为空表示整个环境
+	Message    string
+}
+
+// String 返回 vendor/env/credential: message 格式的描述
+func (i ValidationIssue) String() string {
+	location := i.Vendor
+	if i.Env != "" {
+		location += "/" + i.Env
+	}
+	if i.Credential != "" {
+		location += "/" + i.Credential
+	}
+	return fmt.Sprintf("[%s] %s: %s", i.Level, location, i.Message)
+}
+
+// HasErrors 判断是否有 IssueError 级别的问题
+func HasErrors(issues []ValidationIssue) bool {
+	for _, issue := range issues {
+		if issue.Level == IssueError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateCredentials 校验配置目录和 source 中所有供应商的凭证配置，source 可以为nil
+// 检查配置文件格式和未知字段(如拼写错误)、凭证名称和权重，并对 env 环境中启用的凭证逐个检查：
+// 敏感字段不能是占位符且能够解密、接口地址和代理地址格式正确、启用的凭证权重不能都为0。
+// env 为空时使用当前环境，decrypt 为nil时使用默认密钥环；结果按供应商、环境和凭证名称排序
+func ValidateCredentials(dir string, source CredentialSource, env string, decrypt DecryptFunc) []ValidationIssue {
+	if env == "" {
+		env = currentEnv()
+	}
+	var issues []ValidationIssue
+	if decrypt == nil {
+		var err error
+		if decrypt, err = defaultDecrypt(); err != nil {
+			return []ValidationIssue{{Level: IssueError, Message: err.Error()}}
+		}
+	}
+
+	for _, vendor := range CredentialVendors() {
+		loader := credentialLoaders[vendor]
+		var configs []CredentialConfig
+
+		data, err := os.ReadFile(filepath.Join(dir, loader.file))
+		if err != nil && !os.IsNotExist(err) {
+			issues = append(issues, ValidationIssue{Level: IssueError, Vendor: vendor, Message: err.Error()})
+			continue
+		}
+		if err == nil {
+			fileConfigs, err := checkCredentialData(vendor, data)
+			if err != nil {
+				issues = append(issues, ValidationIssue{Level: IssueError, Vendor: vendor, Message: loader.file + ": " + err.Error()})
+				continue
+			}
+			configs = fileConfigs
+		}
+
+		if source != nil {
+			data, err := source.Credentials(vendor)
+			if err != nil {
+				issues = append(issues, ValidationIssue{Level: IssueError, Vendor: vendor, Message: fmt.Sprintf("读取凭证来源失败: %v", err)})
+				continue
+			}
+			if data != nil {
+				sourceConfigs, err := checkCredentialData(vendor, data)
+				if err != nil {
+					issues = append(issues, ValidationIssue{Level: IssueError, Vendor: vendor, Message: "凭证来源: " + err.Error()})
+					continue
+				}
+				configs = mergeCredentialConfigs(configs, sourceConfigs)
+			}
+		}
+
+		issues = append(issues, validateEnv(vendor, dir, env, configs, decrypt)...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Vendor != b.Vendor {
+			return a.Vendor < b.Vendor
+		}
+		if a.Env != b.Env {
+			return a.Env < b.Env
+		}
+		return a.Credential < b.Credential
+	})
+	return issues
+}
+
+// checkCredentialData 严格解析供应商的凭证配置(不允许未知字段)，并检查所有环境中凭证的名称和权重
+func checkCredentialData(vendor string, data []byte) ([]CredentialConfig, error) {
+	loader := credentialLoaders[vendor]
+	if err := loader.strict(data); err != nil {
+		return nil, err
+	}
+	// 环境名称不会为空，因此不会解密任何凭证
+	if _, err := loader.parse(data, "", "", nil); err != nil {
+		return nil, err
+	}
+	return parseCredentialConfigs(vendor, data)
+}
+
+// mergeCredentialConfigs 合并凭证配置，override 中同一环境的同名凭证替换 base 中的凭证
+func mergeCredentialConfigs(base, override []CredentialConfig) []CredentialConfig {
+	merged := append([]CredentialConfig(nil), base...)
+	index := make(map[[2]string]int, len(merged))
+	for i, config := range merged {
+		index[[2]string{config.Env, config.Name}] = i
+	}
+	for _, config := range override {
+		if i, ok := index[[2]string{config.Env, config.Name}]; ok {
+			merged[i] = config
+			continue
+		}
+		merged = append(merged, config)
+	}
+	return merged
+}
+
+// validateEnv 检查 env 环境中启用的凭证
+func validateEnv(vendor, dir, env string, configs []CredentialConfig, decrypt DecryptFunc) []ValidationIssue {
+	loader := credentialLoaders[vendor]
+	var issues []ValidationIssue
+	enabled, totalWeight := 0, 0
+	for _, config := range configs {
+		if config.Env != env || !config.Enabled {
+			continue
+		}
+		enabled++
+		totalWeight += config.Weight
+
+		issue := func(level IssueLevel, format string, args ...any) {
+			issues = append(issues, ValidationIssue{Level: level, Vendor: vendor, Env: env, Credential: config.Name, Message: fmt.Sprintf(format, args...)})
+		}
+		placeholder := false
+		for _, field := range SecretFields(vendor) {
+			if isPlaceholder(config.Secrets[field]) {
+				issue(IssueError, "%s 仍是占位符，请填写加密后的值", field)
+				placeholder = true
+			}
+		}
+		for field, value := range map[string]string{baseURLField(vendor): config.BaseURL, "proxy": config.Proxy} {
+			if value == "" {
+				continue
+			}
+			if isPlaceholder(value) {
+				issue(IssueError, "%s 仍是占位符", field)
+				continue
+			}
+			if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+				issue(IssueError, "%s 不是有效的URL: %s", field, value)
+			}
+		}
+		if placeholder {
+			continue
+		}
+
+		// 与加载配置时相同的方式解密单个凭证
+		data, err := MarshalCredentials([]CredentialConfig{config})
+		if err != nil {
+			issue(IssueError, "%v", err)
+			continue
+		}
+		if _, err := loader.parse(data, dir, env, decrypt); err != nil {
+			issue(IssueError, "%v", err)
+		}
+	}
+	if enabled > 1 && totalWeight == 0 {
+		issues = append(issues, ValidationIssue{Level: IssueWarning, Vendor: vendor, Env: env, Message: "启用的凭证权重都为0，请求将平均分配"})
+	}
+	return issues
+}
+
+// isPlaceholder 判断敏感字段是否为示例配置中的占位符，如 YOUR_API_KEY_HERE
+func isPlaceholder(value string) bool {
+	return strings.HasPrefix(value, "YOUR_")
+}
+
+// MaskSecret 隐藏敏感字段的内容，只保留格式和密钥ID，用于打印配置
+func MaskSecret(value string) string {
+	switch {
+	case value == "":
+		return ""
+	case isPlaceholder(value):
+		return value
+	case strings.HasPrefix(value, envelopePrefix):
+		if id, _, ok := strings.Cut(strings.TrimPrefix(value, envelopePrefix), ":"); ok {
+			return envelopePrefix + id + ":******"
+		}
+	}
+	return "******"
+}
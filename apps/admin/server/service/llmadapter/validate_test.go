@@ -0,0 +1,90 @@
+package llmadapter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestValidateCredentials 校验拼写错误、占位符、无法解密的密文和权重，来源中的同名凭证覆盖配置文件
+func TestValidateCredentials(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		// 字段名拼写错误
+		"bedrock.yaml": `environments:
+  production:
+    credentials:
+      - name: a
+        acess_key: enc:a
+        secret_access_key: enc:b
+        enabled: true
+`,
+		"azure.yaml": `environments:
+  production:
+    credentials:
+      - name: placeholder
+        api_key: YOUR_API_KEY_HERE
+        endpoint: YOUR_ENDPOINT_URL_HERE
+        enabled: true
+        weight: 1
+      - name: broken
+        api_key: not-encrypted
+        endpoint: east.openai.azure.com
+        enabled: true
+      - name: disabled
+        api_key: YOUR_API_KEY_HERE
+        enabled: false
+  development:
+    credentials:
+      - name: dev
+        api_key: YOUR_API_KEY_HERE
+        enabled: true
+`,
+		"claude.yaml": `environments:
+  production:
+    credentials:
+      - name: a
+        api_key: enc:a
+        enabled: true
+        weight: 0
+      - name: b
+        api_key: YOUR_API_KEY_HERE
+        enabled: true
+        weight: 0
+`,
+	}
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	// 来源中的同名凭证覆盖配置文件中的占位符
+	source := staticSource{
+		"claude": {{Env: "production", Vendor: "claude", CredentialBase: CredentialBase{Name: "b", Enabled: true}, Secrets: map[string]string{"api_key": "enc:b"}}},
+	}
+
+	var calls int32
+	issues := ValidateCredentials(dir, source, "production", testDecrypt(&calls))
+	got := make([]string, len(issues))
+	for i, issue := range issues {
+		got[i] = issue.String()
+	}
+	assert.Equal(t, []string{
+		"[error] azure/production/broken: endpoint 不是有效的URL: east.openai.azure.com",
+		"[error] azure/production/broken: 环境 production 中凭证 broken 解密失败: 无效的数据: not-encrypted",
+		"[error] azure/production/placeholder: api_key 仍是占位符，请填写加密后的值",
+		"[error] azure/production/placeholder: endpoint 仍是占位符",
+		"[error] bedrock: bedrock.yaml: yaml: unmarshal errors:\n  line 5: field acess_key not found in type llmadapter.BedrockCredential",
+		"[warning] claude/production: 启用的凭证权重都为0，请求将平均分配",
+	}, got)
+	assert.True(t, HasErrors(issues))
+	assert.False(t, HasErrors(issues[len(issues)-1:]))
+}
+
+// TestMaskSecret 打印配置时只保留密钥ID和占位符
+func TestMaskSecret(t *testing.T) {
+	assert.Equal(t, "", MaskSecret(""))
+	assert.Equal(t, "YOUR_API_KEY_HERE", MaskSecret("YOUR_API_KEY_HERE"))
+	assert.Equal(t, "v2:k1:******", MaskSecret("v2:k1:abc:def"))
+	assert.Equal(t, "******", MaskSecret("bGVnYWN5"))
+}
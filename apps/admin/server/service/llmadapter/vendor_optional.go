@@ -129,12 +129,12 @@ type ClaudeConfig struct {
 
 	// TopK controls diversity by limiting the top K tokens to sample from
 	// Optional. Example: int32(40)
-	TopK *int32
+	TopK *int32 `yaml:"top_k" json:"top_k,omitempty"`
 }
 
 // AWS BedrockConfig 定义Bedrock特定的配置参数
 type BedrockConfig struct {
-	ByBedrock bool `yaml:"by_bedrock" json:"by_bedrock" binding:"required"` // 必须使用Bedrock = true
+	ByBedrock bool `yaml:"by_bedrock" json:"by_bedrock"` // 是否使用Bedrock，bedrock供应商总是使用Bedrock，请求中无需设置
 
 	AccessKey       string `yaml:"access_key" json:"access_key"`               // Bedrock API 访问密钥
 	SecretAccessKey string `yaml:"secret_access_key" json:"secret_access_key"` // Bedrock API 密钥
@@ -146,14 +146,14 @@ type BedrockConfig struct {
 	SessionToken string `yaml:"session_token" json:"session_token"` // Bedrock API 会话令牌（可选）
 	// TopK controls diversity by limiting the top K tokens to sample from
 	// Optional. Example: int32(40)
-	TopK *int32
+	TopK *int32 `yaml:"top_k" json:"top_k,omitempty"`
 }
 
 // GeminiConfig 定义Google Gemini特定的配置参数
 type GeminiConfig struct {
 	// Client is the Gemini API client instance
 	// Required for making API calls to Gemini
-	Client              *genai.Client
+	Client              *genai.Client          `yaml:"-" json:"-"`
 	APIEndpoint         string                 `yaml:"api_endpoint" json:"api_endpoint"`                   // API端点URL
 	SafetySettings      map[string]interface{} `yaml:"safety_settings" json:"safety_settings"`             // 安全设置
 	GenerationConfig    map[string]interface{} `yaml:"generation_config" json:"generation_config"`         // 生成配置
@@ -185,6 +185,41 @@ func (i *initApi) InitializeData(ctx context.Context) (context.Context, error) {
 		{ApiGroup: "媒体库分类", Method: "GET", Path: "/attachmentCategory/getCategoryList", Description: "分类列表"},
 		{ApiGroup: "媒体库分类", Method: "POST", Path: "/attachmentCategory/addCategory", Description: "添加/编辑分类"},
 		{ApiGroup: "媒体库分类", Method: "POST", Path: "/attachmentCategory/deleteCategory", Description: "删除分类"},
+
+		{ApiGroup: "AI访问密钥", Method: "POST", Path: "/aiApiKey/createApiKey", Description: "创建访问密钥"},
+		{ApiGroup: "AI访问密钥", Method: "PUT", Path: "/aiApiKey/updateApiKey", Description: "更新访问密钥"},
+		{ApiGroup: "AI访问密钥", Method: "POST", Path: "/aiApiKey/revokeApiKey", Description: "吊销访问密钥"},
+		{ApiGroup: "AI访问密钥", Method: "DELETE", Path: "/aiApiKey/deleteApiKey", Description: "删除访问密钥"},
+		{ApiGroup: "AI访问密钥", Method: "GET", Path: "/aiApiKey/getApiKeyList", Description: "获取访问密钥列表"},
+
+		{ApiGroup: "AI预算", Method: "POST", Path: "/aiBudget/createBudget", Description: "创建预算"},
+		{ApiGroup: "AI预算", Method: "PUT", Path: "/aiBudget/updateBudget", Description: "更新预算"},
+		{ApiGroup: "AI预算", Method: "DELETE", Path: "/aiBudget/deleteBudget", Description: "删除预算"},
+		{ApiGroup: "AI预算", Method: "POST", Path: "/aiBudget/topUpBudget", Description: "临时增加预算额度"},
+		{ApiGroup: "AI预算", Method: "GET", Path: "/aiBudget/getBudgetList", Description: "获取预算列表"},
+		{ApiGroup: "AI预算", Method: "GET", Path: "/aiBudget/getBudgetRemaining", Description: "获取预算剩余额度"},
+
+		{ApiGroup: "模型价格", Method: "POST", Path: "/aiModelPrice/createModelPrice", Description: "创建模型价格"},
+		{ApiGroup: "模型价格", Method: "PUT", Path: "/aiModelPrice/updateModelPrice", Description: "更新模型价格"},
+		{ApiGroup: "模型价格", Method: "DELETE", Path: "/aiModelPrice/deleteModelPrice", Description: "删除模型价格"},
+		{ApiGroup: "模型价格", Method: "GET", Path: "/aiModelPrice/getModelPriceList", Description: "获取模型价格列表"},
+
+		{ApiGroup: "模型路由", Method: "POST", Path: "/aiModelRoute/createModelRoute", Description: "创建逻辑模型路由"},
+		{ApiGroup: "模型路由", Method: "PUT", Path: "/aiModelRoute/updateModelRoute", Description: "更新逻辑模型路由"},
+		{ApiGroup: "模型路由", Method: "DELETE", Path: "/aiModelRoute/deleteModelRoute", Description: "删除逻辑模型路由"},
+		{ApiGroup: "模型路由", Method: "GET", Path: "/aiModelRoute/getModelRouteList", Description: "获取逻辑模型路由列表"},
+
+		{ApiGroup: "LLM凭证", Method: "POST", Path: "/aiLlmCredential/createLlmCredential", Description: "创建LLM凭证"},
+		{ApiGroup: "LLM凭证", Method: "PUT", Path: "/aiLlmCredential/updateLlmCredential", Description: "更新LLM凭证"},
+		{ApiGroup: "LLM凭证", Method: "POST", Path: "/aiLlmCredential/disableLlmCredential", Description: "停用LLM凭证"},
+		{ApiGroup: "LLM凭证", Method: "DELETE", Path: "/aiLlmCredential/deleteLlmCredential", Description: "删除LLM凭证"},
+		{ApiGroup: "LLM凭证", Method: "POST", Path: "/aiLlmCredential/testLlmCredential", Description: "测试LLM凭证"},
+		{ApiGroup: "LLM凭证", Method: "POST", Path: "/aiLlmCredential/importLlmCredentials", Description: "导入配置文件中的LLM凭证"},
+		{ApiGroup: "LLM凭证", Method: "GET", Path: "/aiLlmCredential/getLlmCredentialList", Description: "获取LLM凭证列表"},
+
+		{ApiGroup: "LLM用量统计", Method: "GET", Path: "/gaia-x/v1/llm-usage/getUsageSummary", Description: "获取LLM用量汇总"},
+		{ApiGroup: "LLM用量统计", Method: "GET", Path: "/gaia-x/v1/llm-usage/getUsageRecordList", Description: "获取LLM用量记录列表"},
+		{ApiGroup: "LLM用量统计", Method: "GET", Path: "/gaia-x/v1/llm-usage/getCostReport", Description: "获取LLM费用报表"},
 	}
 	if err := db.Create(&entities).Error; err != nil {
 		return ctx, errors.Wrap(err, sysModel.SysApi{}.TableName()+"表数据初始化失败!")
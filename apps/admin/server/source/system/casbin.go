@@ -189,6 +189,41 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "888", V1: "/attachmentCategory/addCategory", V2: "POST"},
 		{Ptype: "p", V0: "888", V1: "/attachmentCategory/deleteCategory", V2: "POST"},
 
+		{Ptype: "p", V0: "888", V1: "/aiApiKey/createApiKey", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/aiApiKey/updateApiKey", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/aiApiKey/revokeApiKey", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/aiApiKey/deleteApiKey", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/aiApiKey/getApiKeyList", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/aiBudget/createBudget", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/aiBudget/updateBudget", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/aiBudget/deleteBudget", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/aiBudget/topUpBudget", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/aiBudget/getBudgetList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/aiBudget/getBudgetRemaining", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/aiModelPrice/createModelPrice", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/aiModelPrice/updateModelPrice", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/aiModelPrice/deleteModelPrice", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/aiModelPrice/getModelPriceList", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/aiModelRoute/createModelRoute", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/aiModelRoute/updateModelRoute", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/aiModelRoute/deleteModelRoute", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/aiModelRoute/getModelRouteList", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/aiLlmCredential/createLlmCredential", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/aiLlmCredential/updateLlmCredential", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/aiLlmCredential/disableLlmCredential", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/aiLlmCredential/deleteLlmCredential", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/aiLlmCredential/testLlmCredential", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/aiLlmCredential/importLlmCredentials", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/aiLlmCredential/getLlmCredentialList", V2: "GET"},
+
+		{Ptype: "p", V0: "888", V1: "/gaia-x/v1/llm-usage/getUsageSummary", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia-x/v1/llm-usage/getUsageRecordList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/gaia-x/v1/llm-usage/getCostReport", V2: "GET"},
+
 		{Ptype: "p", V0: "8881", V1: "/user/admin_register", V2: "POST"},
 		{Ptype: "p", V0: "8881", V1: "/api/createApi", V2: "POST"},
 		{Ptype: "p", V0: "8881", V1: "/api/getApiList", V2: "POST"},
@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/ai"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAiApiKey 从Gin的Context中获取鉴权通过的AI访问密钥，使用登录令牌鉴权时返回nil
+func GetAiApiKey(c *gin.Context) *ai.AiApiKey {
+	if apiKey, exists := c.Get(ai.ApiKeyContextKey); exists {
+		return apiKey.(*ai.AiApiKey)
+	}
+	return nil
+}